@@ -3,8 +3,29 @@ module github.com/xhd2015/presentationer
 go 1.24
 
 require (
+	github.com/alecthomas/chroma/v2 v2.24.1
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/xhd2015/kool v0.0.94
 	github.com/xhd2015/xgo v1.1.7
+	github.com/yuin/goldmark v1.8.2
 )
 
-require github.com/xhd2015/less-gen v0.0.19
+require (
+	github.com/xhd2015/less-gen v0.0.19
+	modernc.org/sqlite v1.46.1
+)
+
+require (
+	github.com/dlclark/regexp2 v1.12.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v1.0.0 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/exp v0.0.0-20251023183803-a4bb9ffd2546 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+	modernc.org/libc v1.67.6 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
+)
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/workspace"
+)
+
+// HomeEnv overrides the presentationer home directory.
+const HomeEnv = "PRESENTATIONER_HOME"
+
+// FileName is the name of the config file in the home directory.
+const FileName = "config.json"
+
+// Config holds the defaults read from <home>/config.json. Every field is
+// optional; command line flags take precedence.
+//
+//	{
+//	  "root": "~/decks",
+//	  "store": "file",
+//	  "git": true,
+//	  "port": 8080,
+//	  "workspace": "work",
+//	  "workspaces": [
+//	    {"name": "work", "root": "~/work/decks", "git": true}
+//	  ],
+//	  "sourceRoots": ["~/src"]
+//	}
+type Config struct {
+	// Root is the directory of the default workspace; the home directory
+	// when empty.
+	Root string `json:"root,omitempty"`
+	// Store is the store spec of the default workspace, see workspace.Open.
+	Store string `json:"store,omitempty"`
+	// Git commits every change of the default workspace to git.
+	Git bool `json:"git,omitempty"`
+	// Port is the first port the server tries, 8080 when zero.
+	Port int `json:"port,omitempty"`
+
+	// Workspace is the workspace selected at startup.
+	Workspace string `json:"workspace,omitempty"`
+	// Workspaces are the named workspaces besides the default one.
+	Workspaces []workspace.Workspace `json:"workspaces,omitempty"`
+
+	// SourceRoots are the directories, besides the working directory,
+	// code pages may link to files in.
+	SourceRoots []string `json:"sourceRoots,omitempty"`
+}
+
+// Home returns $PRESENTATIONER_HOME, or ~/.presentationer.
+func Home() (string, error) {
+	if home := os.Getenv(HomeEnv); home != "" {
+		return ExpandHome(home)
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("find home directory: %w, set %s", err, HomeEnv)
+	}
+	return filepath.Join(userHome, ".presentationer"), nil
+}
+
+// ExpandHome replaces a leading ~ with the user's home directory.
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userHome, path[1:]), nil
+}
+
+// ResolvePath expands ~ and makes a relative path relative to home.
+func ResolvePath(home string, path string) (string, error) {
+	path, err := ExpandHome(path)
+	if err != nil {
+		return "", err
+	}
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(home, path)
+	}
+	return path, nil
+}
+
+// Load reads the config file in home. A missing file is an empty config.
+func Load(home string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(filepath.Join(home, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(home, FileName), err)
+	}
+	return cfg, nil
+}
+
+// Save writes cfg to the config file in home.
+func Save(home string, cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(home, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(home, FileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,452 @@
+// Package diff compares two versions of a text line by line with the
+// Myers algorithm, as code_diff pages show them: hunks of changed lines
+// with some unchanged lines around them, and within a changed line paired
+// with its new version, the words that changed.
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultContext is the number of unchanged lines shown around changes.
+const DefaultContext = 3
+
+// Kind tells which side of a diff a line is on.
+type Kind string
+
+const (
+	// Context is an unchanged line, on both sides.
+	Context Kind = "context"
+	// Delete is a line of the old text only.
+	Delete Kind = "delete"
+	// Insert is a line of the new text only.
+	Insert Kind = "insert"
+)
+
+// Span is a part of a changed line.
+type Span struct {
+	Text string `json:"text"`
+	// Changed is set on the text the line does not share with its other
+	// version.
+	Changed bool `json:"changed,omitempty"`
+}
+
+// Line is a line of a diff.
+type Line struct {
+	Kind Kind `json:"kind"`
+	// Old and New are the numbers of the line in the old and new text,
+	// zero on the side the line is not on.
+	Old  int    `json:"old,omitempty"`
+	New  int    `json:"new,omitempty"`
+	Text string `json:"text"`
+	// Spans split a deleted line paired with an inserted one, and the
+	// other way round, into the text they share and the text that
+	// changed. They are nil for other lines and for lines that changed
+	// too much to pair.
+	Spans []Span `json:"spans,omitempty"`
+}
+
+// Hunk is a run of changes with the unchanged lines around them.
+type Hunk struct {
+	// OldStart and NewStart are the first lines of the hunk in the old and
+	// new text; with no lines on a side, the line before the hunk.
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Header returns the hunk header of a unified diff, like "@@ -3,7 +3,8 @@".
+func (h *Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start int, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// Texts returns the old and new text the hunk spans.
+func (h *Hunk) Texts() (string, string) {
+	var old, new []string
+	for _, l := range h.Lines {
+		if l.Kind != Insert {
+			old = append(old, l.Text)
+		}
+		if l.Kind != Delete {
+			new = append(new, l.Text)
+		}
+	}
+	return strings.Join(old, "\n"), strings.Join(new, "\n")
+}
+
+// Options control how texts are compared.
+type Options struct {
+	// Context is the number of unchanged lines shown around changes; with
+	// a negative Context, a single hunk holds all lines.
+	Context int
+	// OldStart and NewStart are the numbers of the first lines of the
+	// texts, for texts taken from the middle of a file; 1 when zero.
+	OldStart int
+	NewStart int
+}
+
+// Compare returns the hunks turning old into new. Identical texts have no
+// hunks, unless Context is negative.
+func Compare(old string, new string, opts Options) []Hunk {
+	a, b := splitLines(old), splitLines(new)
+	oldStart, newStart := max(opts.OldStart, 1), max(opts.NewStart, 1)
+
+	var lines []Line
+	i, j := 0, 0
+	for _, op := range myers(a, b) {
+		switch op {
+		case opEqual:
+			lines = append(lines, Line{Kind: Context, Old: oldStart + i, New: newStart + j, Text: a[i]})
+			i++
+			j++
+		case opDelete:
+			lines = append(lines, Line{Kind: Delete, Old: oldStart + i, Text: a[i]})
+			i++
+		case opInsert:
+			lines = append(lines, Line{Kind: Insert, New: newStart + j, Text: b[j]})
+			j++
+		}
+	}
+	pairLines(lines)
+
+	if opts.Context < 0 {
+		if len(lines) == 0 {
+			return nil
+		}
+		return []Hunk{newHunk(lines, oldStart, newStart)}
+	}
+	var hunks []Hunk
+	for k := 0; k < len(lines); {
+		if lines[k].Kind == Context {
+			k++
+			continue
+		}
+		start := max(k-opts.Context, 0)
+		// the last change of the hunk: changes closer than twice the
+		// context share their hunk
+		end := k
+		for n := k + 1; n < len(lines) && n-end-1 <= 2*opts.Context; n++ {
+			if lines[n].Kind != Context {
+				end = n
+			}
+		}
+		stop := min(end+opts.Context+1, len(lines))
+		hunks = append(hunks, newHunk(lines[start:stop], firstLine(lines, start, true, oldStart), firstLine(lines, start, false, newStart)))
+		k = stop
+	}
+	return hunks
+}
+
+// firstLine returns the number on a side of the line at index start, or
+// of the line after it on that side.
+func firstLine(lines []Line, start int, old bool, first int) int {
+	n := first
+	for _, l := range lines[:start] {
+		if old && l.Kind != Insert || !old && l.Kind != Delete {
+			n++
+		}
+	}
+	return n
+}
+
+func newHunk(lines []Line, oldStart int, newStart int) Hunk {
+	h := Hunk{OldStart: oldStart, NewStart: newStart, Lines: lines}
+	for _, l := range lines {
+		if l.Kind != Insert {
+			h.OldLines++
+		}
+		if l.Kind != Delete {
+			h.NewLines++
+		}
+	}
+	// as in unified diffs, an empty side starts at the line before
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+	return h
+}
+
+// splitLines splits a text into lines, ignoring a final newline.
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// pairLines sets the spans of the changed lines: in each run of deleted
+// lines followed by inserted lines, the first deleted line pairs with the
+// first inserted line, and so on.
+func pairLines(lines []Line) {
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != Delete {
+			i++
+			continue
+		}
+		dels := i
+		for i < len(lines) && lines[i].Kind == Delete {
+			i++
+		}
+		ins := i
+		for i < len(lines) && lines[i].Kind == Insert {
+			i++
+		}
+		for k := 0; dels+k < ins && ins+k < i; k++ {
+			lines[dels+k].Spans, lines[ins+k].Spans = compareWords(lines[dels+k].Text, lines[ins+k].Text)
+		}
+	}
+}
+
+// compareWords returns the spans of two versions of a line, or nil when
+// they share less than a third of their text, which reads better as
+// whole lines changed.
+func compareWords(old string, new string) ([]Span, []Span) {
+	a, b := words(old), words(new)
+	var oldSpans, newSpans []Span
+	add := func(spans []Span, text string, changed bool) []Span {
+		if n := len(spans); n > 0 && spans[n-1].Changed == changed {
+			spans[n-1].Text += text
+			return spans
+		}
+		return append(spans, Span{Text: text, Changed: changed})
+	}
+	shared := 0
+	i, j := 0, 0
+	for _, op := range myers(a, b) {
+		switch op {
+		case opEqual:
+			if strings.TrimSpace(a[i]) != "" {
+				shared += len(a[i])
+			}
+			oldSpans = add(oldSpans, a[i], false)
+			newSpans = add(newSpans, b[j], false)
+			i++
+			j++
+		case opDelete:
+			oldSpans = add(oldSpans, a[i], true)
+			i++
+		case opInsert:
+			newSpans = add(newSpans, b[j], true)
+			j++
+		}
+	}
+	if shared*3 < max(len(strings.TrimSpace(old)), len(strings.TrimSpace(new))) {
+		return nil, nil
+	}
+	return oldSpans, newSpans
+}
+
+// words splits a line into identifiers and numbers, runs of spaces, and
+// single other characters.
+func words(s string) []string {
+	var out []string
+	class := func(r rune) int {
+		switch {
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		}
+		return 0
+	}
+	start := 0
+	prev := -1
+	for i, r := range s {
+		c := class(r)
+		if i > start && (c != prev || c == 0) {
+			out = append(out, s[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(s) {
+		out = append(out, s[start:])
+	}
+	return out
+}
+
+type op int
+
+const (
+	opEqual op = iota
+	opDelete
+	opInsert
+)
+
+// maxCost bounds the work of a comparison, in diagonals searched. Past it
+// the parts not compared yet are taken as changed whole, so very different
+// texts give a longer edit script instead of a long wait.
+const maxCost = 1 << 24
+
+// myers returns the shortest edit script turning a into b, as in
+// "An O(ND) Difference Algorithm and Its Variations" by Eugene Myers, in
+// its linear space variant: the middle snake of the shortest path splits
+// the texts in two, compared the same way.
+func myers[T comparable](a []T, b []T) []op {
+	d := &differ[T]{
+		a:       a,
+		b:       b,
+		deleted: make([]bool, len(a)),
+		added:   make([]bool, len(b)),
+		budget:  maxCost,
+	}
+	d.compare(0, len(a), 0, len(b))
+
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && d.deleted[i]:
+			ops = append(ops, opDelete)
+			i++
+		case j < len(b) && d.added[j]:
+			ops = append(ops, opInsert)
+			j++
+		default:
+			ops = append(ops, opEqual)
+			i++
+			j++
+		}
+	}
+	return ops
+}
+
+type differ[T comparable] struct {
+	a, b    []T
+	deleted []bool
+	added   []bool
+	// forward and backward hold the furthest x reached on each diagonal
+	// by the searches from the start and from the end
+	forward  []int
+	backward []int
+	budget   int
+}
+
+// compare marks the lines of a[aLo:aHi] and b[bLo:bHi] that are not
+// on the shortest path.
+func (d *differ[T]) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+	if aLo < aHi && bLo < bHi {
+		// a split at either end would not make the problem smaller
+		if x, y, ok := d.split(aLo, aHi, bLo, bHi); ok && x+y > aLo+bLo && x+y < aHi+bHi {
+			d.compare(aLo, x, bLo, y)
+			d.compare(x, aHi, y, bHi)
+			return
+		}
+	}
+	for i := aLo; i < aHi; i++ {
+		d.deleted[i] = true
+	}
+	for j := bLo; j < bHi; j++ {
+		d.added[j] = true
+	}
+}
+
+// split finds the middle snake of the shortest path through a[aLo:aHi]
+// and b[bLo:bHi], searching from both ends at once, and returns the point
+// it ends at. It fails when the texts share nothing or the budget is
+// spent. The ranges have no common prefix or suffix.
+func (d *differ[T]) split(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2 * maxD
+	if cap(d.forward) < size+2 {
+		d.forward = make([]int, size+2)
+		d.backward = make([]int, size+2)
+	}
+	vf, vb := d.forward[:size+2], d.backward[:size+2]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+	delta := n - m
+	// with an odd delta the paths meet on a forward step, otherwise on
+	// a backward one
+	front := delta%2 != 0
+	// diagonals that ran off the edges are not searched again
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for step := 0; step < maxD; step++ {
+		d.budget -= 2*step + 1
+		if d.budget < 0 {
+			return 0, 0, false
+		}
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || k != step && vf[i-1] < vf[i+1] {
+				x = vf[i+1]
+			} else {
+				x = vf[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[i] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				if j := offset + delta - k; j >= 0 && j < size && vb[j] != -1 && x >= n-vb[j] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || k != step && vb[i-1] < vb[i+1] {
+				x = vb[i+1]
+			} else {
+				x = vb[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			vb[i] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				if j := offset + delta - k; j >= 0 && j < size && vf[j] != -1 {
+					fx := vf[j]
+					fy := fx - (j - offset)
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,233 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// apply runs an edit script on a, checking it against b.
+func apply(t *testing.T, a []byte, b []byte, ops []op) int {
+	t.Helper()
+	var out []byte
+	edits := 0
+	i, j := 0, 0
+	for _, o := range ops {
+		switch o {
+		case opEqual:
+			if i >= len(a) || j >= len(b) || a[i] != b[j] {
+				t.Fatalf("%q -> %q: equal op at %d,%d on different elements", a, b, i, j)
+			}
+			out = append(out, a[i])
+			i++
+			j++
+		case opDelete:
+			i++
+			edits++
+		case opInsert:
+			out = append(out, b[j])
+			j++
+			edits++
+		}
+	}
+	if i != len(a) || j != len(b) || string(out) != string(b) {
+		t.Fatalf("%q -> %q: script gives %q", a, b, out)
+	}
+	return edits
+}
+
+func lcs(a []byte, b []byte) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+// TestMyersShortest checks the edit scripts of random texts are valid
+// and as short as the longest common subsequence allows.
+func TestMyersShortest(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	text := func() []byte {
+		b := make([]byte, rnd.Intn(20))
+		for i := range b {
+			b[i] = "abc"[rnd.Intn(3)]
+		}
+		return b
+	}
+	for n := 0; n < 5000; n++ {
+		a, b := text(), text()
+		edits := apply(t, a, b, myers(a, b))
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("%q -> %q: %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+// TestMyersLarge compares unrelated texts too long to search through:
+// the comparison stays in linear space and gives up on the shortest
+// script rather than running for long.
+func TestMyersLarge(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20000; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	ops := myers(a, b)
+	runtime.ReadMemStats(&after)
+	if len(ops) != len(a)+len(b) {
+		t.Errorf("got %d ops, want %d", len(ops), len(a)+len(b))
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Errorf("allocated %d MB", alloc>>20)
+	}
+
+	// long texts with few changes still get the shortest script
+	c := append([]string(nil), a...)
+	for i := 100; i < len(c); i += 1000 {
+		c[i] = "changed"
+	}
+	edits := 0
+	for _, o := range myers(a, c) {
+		if o != opEqual {
+			edits++
+		}
+	}
+	if edits != 2*20 {
+		t.Errorf("got %d edits, want %d", edits, 2*20)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	lines := func(from, to int) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(&b, "line %d\n", i)
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		opts    Options
+		headers []string
+	}{
+		{"identical", lines(1, 5), lines(1, 5), Options{Context: 3}, nil},
+		{"identical, all lines", lines(1, 5), lines(1, 5), Options{Context: -1}, []string{"@@ -1,5 +1,5 @@"}},
+		{"change in the middle", lines(1, 10), strings.Replace(lines(1, 10), "line 5\n", "line five\n", 1), Options{Context: 3}, []string{"@@ -2,7 +2,7 @@"}},
+		{"insert at the start", lines(2, 10), lines(1, 10), Options{Context: 3}, []string{"@@ -1,3 +1,4 @@"}},
+		{"delete at the end", lines(1, 10), lines(1, 9), Options{Context: 3}, []string{"@@ -7,4 +7,3 @@"}},
+		{"new file", "", lines(1, 2), Options{Context: 3}, []string{"@@ -0,0 +1,2 @@"}},
+		{"deleted file", lines(1, 2), "", Options{Context: 3}, []string{"@@ -1,2 +0,0 @@"}},
+		{"changes far apart", lines(1, 20), strings.NewReplacer("line 2\n", "x\n", "line 19\n", "y\n").Replace(lines(1, 20)), Options{Context: 3}, []string{"@@ -1,5 +1,5 @@", "@@ -16,5 +16,5 @@"}},
+		{"changes close together", lines(1, 20), strings.NewReplacer("line 5\n", "x\n", "line 11\n", "y\n").Replace(lines(1, 20)), Options{Context: 3}, []string{"@@ -2,13 +2,13 @@"}},
+		{"no context", lines(1, 10), strings.Replace(lines(1, 10), "line 5\n", "line five\n", 1), Options{Context: 0}, []string{"@@ -5 +5 @@"}},
+		{"from the middle of a file", lines(1, 3), lines(1, 2) + "three\n", Options{Context: 1, OldStart: 100, NewStart: 200}, []string{"@@ -101,2 +201,2 @@"}},
+		{"CRLF and no final newline", "a\r\nb\r\n", "a\nb", Options{Context: 3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			for _, h := range Compare(tt.old, tt.new, tt.opts) {
+				headers = append(headers, h.Header())
+				// the hunk must give back the lines it spans
+				old, new := h.Texts()
+				if got := len(splitLines(old)); got != h.OldLines {
+					t.Errorf("%s: %d old lines", h.Header(), got)
+				}
+				if got := len(splitLines(new)); got != h.NewLines {
+					t.Errorf("%s: %d new lines", h.Header(), got)
+				}
+			}
+			if !reflect.DeepEqual(headers, tt.headers) {
+				t.Errorf("got hunks %q, want %q", headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestCompareLines(t *testing.T) {
+	hunks := Compare("a\nb := 1\nc\n", "a\nb := 2\nc\nd\n", Options{Context: 1})
+	want := []Line{
+		{Kind: Context, Old: 1, New: 1, Text: "a"},
+		{Kind: Delete, Old: 2, Text: "b := 1", Spans: []Span{{Text: "b := "}, {Text: "1", Changed: true}}},
+		{Kind: Insert, New: 2, Text: "b := 2", Spans: []Span{{Text: "b := "}, {Text: "2", Changed: true}}},
+		{Kind: Context, Old: 3, New: 3, Text: "c"},
+		{Kind: Insert, New: 4, Text: "d"},
+	}
+	if len(hunks) != 1 || !reflect.DeepEqual(hunks[0].Lines, want) {
+		t.Errorf("got %+v", hunks)
+	}
+}
+
+func TestCompareWords(t *testing.T) {
+	tests := []struct {
+		old, new string
+		// the changed text of each side, nil when the lines don't pair
+		oldChanged, newChanged []string
+	}{
+		{"x := 1", "x := 2", []string{"1"}, []string{"2"}},
+		{"return foo(a, b)", "return foo(a, b, c)", nil, []string{", c"}},
+		{"fmt.Println(name)", "fmt.Printf(name)", []string{"Println"}, []string{"Printf"}},
+		{"\tif err != nil {", "\tif err == nil {", []string{"!"}, []string{"="}},
+		{"completely different", "nothing alike here", nil, nil},
+		{"a", "", nil, nil},
+	}
+	changed := func(spans []Span) []string {
+		var out []string
+		for _, s := range spans {
+			if s.Changed {
+				out = append(out, s.Text)
+			}
+		}
+		return out
+	}
+	text := func(spans []Span) string {
+		var b strings.Builder
+		for _, s := range spans {
+			b.WriteString(s.Text)
+		}
+		return b.String()
+	}
+	for _, tt := range tests {
+		oldSpans, newSpans := compareWords(tt.old, tt.new)
+		if tt.oldChanged == nil && tt.newChanged == nil {
+			if oldSpans != nil || newSpans != nil {
+				t.Errorf("%q -> %q: paired as %+v, %+v", tt.old, tt.new, oldSpans, newSpans)
+			}
+			continue
+		}
+		if text(oldSpans) != tt.old || text(newSpans) != tt.new {
+			t.Errorf("%q -> %q: spans %+v, %+v don't spell the lines", tt.old, tt.new, oldSpans, newSpans)
+		}
+		if got := changed(oldSpans); !reflect.DeepEqual(got, tt.oldChanged) {
+			t.Errorf("%q -> %q: old changed %q, want %q", tt.old, tt.new, got, tt.oldChanged)
+		}
+		if got := changed(newSpans); !reflect.DeepEqual(got, tt.newChanged) {
+			t.Errorf("%q -> %q: new changed %q, want %q", tt.old, tt.new, got, tt.newChanged)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	got := words("\tfoo_bar(x1,  y) // ok")
+	want := []string{"\t", "foo_bar", "(", "x1", ",", "  ", "y", ")", " ", "/", "/", " ", "ok"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
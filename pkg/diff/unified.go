@@ -0,0 +1,239 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// File is the diff of one file in a unified diff.
+type File struct {
+	// OldPath and NewPath are empty for a file created or deleted.
+	OldPath string `json:"oldPath,omitempty"`
+	NewPath string `json:"newPath,omitempty"`
+	// Binary is set for binary files, which have no hunks.
+	Binary bool   `json:"binary,omitempty"`
+	Hunks  []Hunk `json:"hunks"`
+}
+
+// Path returns the path of the file, the new one unless it was deleted.
+func (f *File) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// String formats the hunk as in a unified diff: its header, then its
+// lines prefixed with " ", "-" or "+".
+func (h *Hunk) String() string {
+	var b strings.Builder
+	b.WriteString(h.Header())
+	b.WriteString("\n")
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case Delete:
+			b.WriteString("-")
+		case Insert:
+			b.WriteString("+")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(l.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParseUnified parses a unified diff, as written by diff -u or git diff.
+// Lines outside of the file diffs, like the commit headers of git show or
+// git format-patch, are skipped; hunks before any file header belong to a
+// file without paths.
+func ParseUnified(text string) ([]File, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var files []File
+	var file *File
+	// a "diff --git" header opens a file its ---/+++ lines belong to
+	gitHeader := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, File{})
+			file = &files[len(files)-1]
+			file.OldPath, file.NewPath = gitPaths(strings.TrimPrefix(line, "diff --git "))
+			gitHeader = true
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if !gitHeader {
+				files = append(files, File{})
+				file = &files[len(files)-1]
+			}
+			file.OldPath = headerPath(strings.TrimPrefix(line, "--- "))
+			file.NewPath = headerPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			gitHeader = false
+			i++
+		case file != nil && gitHeader && strings.HasPrefix(line, "new file mode"):
+			file.OldPath = ""
+		case file != nil && gitHeader && strings.HasPrefix(line, "deleted file mode"):
+			file.NewPath = ""
+		case file != nil && gitHeader && strings.HasPrefix(line, "rename from "):
+			file.OldPath = strings.TrimPrefix(line, "rename from ")
+		case file != nil && gitHeader && strings.HasPrefix(line, "rename to "):
+			file.NewPath = strings.TrimPrefix(line, "rename to ")
+		case file != nil && (strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch"):
+			file.Binary = true
+		case strings.HasPrefix(line, "@@ "):
+			if file == nil {
+				// hunks without a file header
+				files = append(files, File{})
+				file = &files[len(files)-1]
+			}
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, hunk)
+			gitHeader = false
+			i = next - 1
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file diffs found")
+	}
+	return files, nil
+}
+
+// parseHunk parses the hunk whose header is at lines[at], returning it
+// and the index of the line after it.
+func parseHunk(lines []string, at int) (Hunk, int, error) {
+	m := hunkHeaderPattern.FindStringSubmatch(lines[at])
+	if m == nil {
+		return Hunk{}, 0, fmt.Errorf("line %d: invalid hunk header %q", at+1, lines[at])
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	h := Hunk{OldLines: count(m[2]), NewLines: count(m[4])}
+	h.OldStart, _ = strconv.Atoi(m[1])
+	h.NewStart, _ = strconv.Atoi(m[3])
+
+	old, new := h.OldStart, h.NewStart
+	if h.OldLines == 0 {
+		old++
+	}
+	if h.NewLines == 0 {
+		new++
+	}
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	i := at + 1
+	for ; i < len(lines) && (oldLeft > 0 || newLeft > 0); i++ {
+		line := lines[i]
+		if line == "" {
+			// an unchanged empty line whose leading space was trimmed
+			line = " "
+		}
+		text := line[1:]
+		switch line[0] {
+		case ' ':
+			if oldLeft == 0 || newLeft == 0 {
+				return Hunk{}, 0, fmt.Errorf("line %d: hunk longer than its header %s", i+1, h.Header())
+			}
+			h.Lines = append(h.Lines, Line{Kind: Context, Old: old, New: new, Text: text})
+			old++
+			new++
+			oldLeft--
+			newLeft--
+		case '-':
+			if oldLeft == 0 {
+				return Hunk{}, 0, fmt.Errorf("line %d: hunk longer than its header %s", i+1, h.Header())
+			}
+			h.Lines = append(h.Lines, Line{Kind: Delete, Old: old, Text: text})
+			old++
+			oldLeft--
+		case '+':
+			if newLeft == 0 {
+				return Hunk{}, 0, fmt.Errorf("line %d: hunk longer than its header %s", i+1, h.Header())
+			}
+			h.Lines = append(h.Lines, Line{Kind: Insert, New: new, Text: text})
+			new++
+			newLeft--
+		case '\\':
+			// "\ No newline at end of file"
+		default:
+			return Hunk{}, 0, fmt.Errorf("line %d: unexpected line in hunk %s", i+1, h.Header())
+		}
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return Hunk{}, 0, fmt.Errorf("hunk %s is truncated", h.Header())
+	}
+	for i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		i++
+	}
+	pairLines(h.Lines)
+	return h, i, nil
+}
+
+// gitPaths splits the "a/old b/new" of a diff --git header. Paths with
+// spaces are only split right when both are the same, as they are unless
+// the file was renamed, which the rename lines tell.
+func gitPaths(s string) (string, string) {
+	if strings.HasPrefix(s, `"`) {
+		// quoted paths of unusual characters
+		if old, rest, ok := cutQuoted(s); ok {
+			new, _, _ := cutQuoted(strings.TrimSpace(rest))
+			return strings.TrimPrefix(old, "a/"), strings.TrimPrefix(new, "b/")
+		}
+	}
+	if n := len(s); n%2 == 1 && strings.HasPrefix(s, "a/") && s[n/2] == ' ' && s[n/2+1:n/2+3] == "b/" && s[2:n/2] == s[n/2+3:] {
+		return s[2 : n/2], s[n/2+3:]
+	}
+	old, new, _ := strings.Cut(s, " b/")
+	return strings.TrimPrefix(old, "a/"), new
+}
+
+func cutQuoted(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		field, rest, _ := strings.Cut(s, " ")
+		return field, rest, true
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			unquoted, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", false
+			}
+			return unquoted, s[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// headerPath returns the path of a ---/+++ line, without the a/ or b/ of
+// git and the timestamp of diff -u; empty for /dev/null.
+func headerPath(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, _, ok := cutQuoted(s); ok {
+			s = unquoted
+		}
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
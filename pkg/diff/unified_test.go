@@ -0,0 +1,234 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUnified(t *testing.T) {
+	type file struct {
+		old, new string
+		binary   bool
+		hunks    []string
+	}
+	tests := []struct {
+		name  string
+		diff  string
+		files []file
+		err   string
+	}{
+		{
+			name: "git diff",
+			diff: `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
+ // end
+`,
+			files: []file{{old: "main.go", new: "main.go", hunks: []string{"@@ -1,3 +1,3 @@"}}},
+		},
+		{
+			name: "diff -u with timestamps",
+			diff: `--- old/a.txt	2024-01-01 10:00:00.000000000 +0000
++++ new/a.txt	2024-01-02 10:00:00.000000000 +0000
+@@ -1 +1,2 @@
+ a
++b
+`,
+			files: []file{{old: "old/a.txt", new: "new/a.txt", hunks: []string{"@@ -1 +1,2 @@"}}},
+		},
+		{
+			name: "new and deleted files",
+			diff: `diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++one
++two
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 1111111..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+`,
+			files: []file{
+				{new: "new.txt", hunks: []string{"@@ -0,0 +1,2 @@"}},
+				{old: "gone.txt", hunks: []string{"@@ -1 +0,0 @@"}},
+			},
+		},
+		{
+			name: "rename",
+			diff: `diff --git a/old name.go b/new name.go
+similarity index 100%
+rename from old name.go
+rename to new name.go
+diff --git a/a.go b/b.go
+similarity index 90%
+rename from a.go
+rename to b.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/b.go
+@@ -1 +1 @@
+-x
++y
+`,
+			files: []file{
+				{old: "old name.go", new: "new name.go"},
+				{old: "a.go", new: "b.go", hunks: []string{"@@ -1 +1 @@"}},
+			},
+		},
+		{
+			name: "quoted paths",
+			diff: `diff --git "a/caf\303\251.txt" "b/caf\303\251.txt"
+index 1111111..2222222 100644
+--- "a/caf\303\251.txt"
++++ "b/caf\303\251.txt"
+@@ -1 +1 @@
+-a
++b
+`,
+			files: []file{{old: "café.txt", new: "café.txt", hunks: []string{"@@ -1 +1 @@"}}},
+		},
+		{
+			name: "paths with spaces",
+			diff: `diff --git a/my file.txt b/my file.txt
+index 1111111..2222222 100644
+--- a/my file.txt
++++ b/my file.txt
+@@ -1 +1 @@
+-a
++b
+`,
+			files: []file{{old: "my file.txt", new: "my file.txt", hunks: []string{"@@ -1 +1 @@"}}},
+		},
+		{
+			name: "no newline at end of file",
+			diff: `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+@@ -10 +10 @@
+-x
++y
+`,
+			files: []file{{old: "a.txt", new: "a.txt", hunks: []string{"@@ -1,2 +1,2 @@", "@@ -10 +10 @@"}}},
+		},
+		{
+			name: "binary",
+			diff: `diff --git a/img.png b/img.png
+index 1111111..2222222 100644
+Binary files a/img.png and b/img.png differ
+`,
+			files: []file{{old: "img.png", new: "img.png", binary: true}},
+		},
+		{
+			name: "commit header skipped",
+			diff: `commit 0123456789abcdef
+Author: A <a@example.com>
+
+    subject
+
+diff --git a/a b/a
+--- a/a
++++ b/a
+@@ -1 +1 @@
+-1
++2
+`,
+			files: []file{{old: "a", new: "a", hunks: []string{"@@ -1 +1 @@"}}},
+		},
+		{
+			name:  "hunk without a file header",
+			diff:  "@@ -1 +1 @@\n-a\n+b\n",
+			files: []file{{hunks: []string{"@@ -1 +1 @@"}}},
+		},
+		{
+			name: "truncated hunk",
+			diff: "--- a/a\n+++ b/a\n@@ -1,3 +1,3 @@\n a\n-b\n",
+			err:  "hunk @@ -1,3 +1,3 @@ is truncated",
+		},
+		{
+			name: "hunk longer than its header",
+			diff: "--- a/a\n+++ b/a\n@@ -1 +1 @@\n-a\n-b\n+c\n",
+			err:  "line 5: hunk longer than its header",
+		},
+		{
+			name: "unexpected line",
+			diff: "--- a/a\n+++ b/a\n@@ -1,2 +1,2 @@\n-a\n?b\n",
+			err:  "line 5: unexpected line in hunk",
+		},
+		{
+			name: "invalid hunk header",
+			diff: "--- a/a\n+++ b/a\n@@ -x +1 @@\n",
+			err:  "line 3: invalid hunk header",
+		},
+		{
+			name: "not a diff",
+			diff: "hello\n",
+			err:  "no file diffs found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ParseUnified(tt.diff)
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []file
+			for _, f := range files {
+				g := file{old: f.OldPath, new: f.NewPath, binary: f.Binary}
+				for _, h := range f.Hunks {
+					g.hunks = append(g.hunks, h.Header())
+				}
+				got = append(got, g)
+			}
+			if !reflect.DeepEqual(got, tt.files) {
+				t.Errorf("got %+v, want %+v", got, tt.files)
+			}
+		})
+	}
+}
+
+// TestParseUnifiedLines checks the lines of a parsed hunk are numbered
+// and paired like those of Compare, and that String writes the hunk back.
+func TestParseUnifiedLines(t *testing.T) {
+	hunk := "@@ -3,3 +3,4 @@\n a\n-b := 1\n+b := 2\n+c\n \n"
+	files, err := ParseUnified("--- a/x.go\n+++ b/x.go\n" + hunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := files[0].Hunks[0]
+	want := []Line{
+		{Kind: Context, Old: 3, New: 3, Text: "a"},
+		{Kind: Delete, Old: 4, Text: "b := 1", Spans: []Span{{Text: "b := "}, {Text: "1", Changed: true}}},
+		{Kind: Insert, New: 4, Text: "b := 2", Spans: []Span{{Text: "b := "}, {Text: "2", Changed: true}}},
+		{Kind: Insert, New: 5, Text: "c"},
+		{Kind: Context, Old: 5, New: 6, Text: ""},
+	}
+	if !reflect.DeepEqual(h.Lines, want) {
+		t.Errorf("got %+v", h.Lines)
+	}
+	if got := h.String(); got != hunk {
+		t.Errorf("String() = %q, want %q", got, hunk)
+	}
+}
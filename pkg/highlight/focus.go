@@ -0,0 +1,180 @@
+package highlight
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Focus applies a focus config: when it names any lines, the others are
+// no longer focused, and the text matches on the lines named are marked.
+func (c *Code) Focus(lines []FocusLine) {
+	if len(lines) == 0 {
+		return
+	}
+	focused := make(map[int]bool, len(lines))
+	for _, f := range lines {
+		focused[f.Line] = true
+		if f.Text != "" && f.Line >= 1 && f.Line <= len(c.Lines) {
+			c.Lines[f.Line-1].Tokens = mark(c.Lines[f.Line-1].Tokens, f.Text)
+		}
+	}
+	for i := range c.Lines {
+		c.Lines[i].Focused = focused[c.Lines[i].Number]
+	}
+}
+
+// mark splits the tokens of a line so every occurrence of text is in
+// tokens of its own, marked.
+func mark(line []Token, text string) []Token {
+	var full strings.Builder
+	for _, s := range line {
+		full.WriteString(s.Text)
+	}
+	var marked []bool
+	str := full.String()
+	for i := 0; ; {
+		j := strings.Index(str[i:], text)
+		if j < 0 {
+			break
+		}
+		if marked == nil {
+			marked = make([]bool, len(str))
+		}
+		for k := i + j; k < i+j+len(text); k++ {
+			marked[k] = true
+		}
+		i += j + len(text)
+	}
+	if marked == nil {
+		return line
+	}
+	return split(line, marked)
+}
+
+// Mark splits the tokens of a line so the text from byte offset from to
+// byte offset to is in tokens of its own, marked.
+func Mark(line []Token, from int, to int) []Token {
+	n := 0
+	for _, s := range line {
+		n += len(s.Text)
+	}
+	from, to = max(from, 0), min(to, n)
+	if from >= to {
+		return line
+	}
+	marked := make([]bool, n)
+	for k := from; k < to; k++ {
+		marked[k] = true
+	}
+	return split(line, marked)
+}
+
+// split splits the tokens where marked, one flag per byte of the line,
+// changes, marking the tokens it is set on.
+func split(line []Token, marked []bool) []Token {
+	var out []Token
+	pos := 0
+	for _, s := range line {
+		start := 0
+		for k := 1; k <= len(s.Text); k++ {
+			if k == len(s.Text) || marked[pos+k] != marked[pos+start] {
+				part := s
+				part.Text = s.Text[start:k]
+				part.Marked = s.Marked || marked[pos+start]
+				out = append(out, part)
+				start = k
+			}
+		}
+		pos += len(s.Text)
+	}
+	return out
+}
+
+// FocusLine is one entry of a focus config: a line, optionally with a text
+// on it to point at.
+type FocusLine struct {
+	Line int    `json:"line"`
+	Text string `json:"textMatch,omitempty"`
+}
+
+var focusMatchPattern = regexp.MustCompile(`^(\d+)\s*\{([^}]+)\}$`)
+
+// ParseFocus parses a focus config as the code presenter does:
+// comma separated lines, ranges like 3-8, and text matches like 6{text}
+// or 6{"quoted text"}.
+func ParseFocus(spec string) []FocusLine {
+	var lines []FocusLine
+	for _, part := range splitFocusConfig(spec) {
+		part = strings.TrimSpace(part)
+		if m := focusMatchPattern.FindStringSubmatch(part); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			text := m[2]
+			if strings.HasPrefix(text, `"`) && strings.HasSuffix(text, `"`) {
+				var unquoted string
+				if json.Unmarshal([]byte(text), &unquoted) == nil {
+					text = unquoted
+				}
+			}
+			lines = append(lines, FocusLine{Line: line, Text: text})
+			continue
+		}
+		bounds := strings.Split(part, "-")
+		start, ok := leadingInt(bounds[0])
+		if !ok {
+			continue
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, ok = leadingInt(bounds[1]); !ok {
+				continue
+			}
+		}
+		for n := start; n <= end && n-start < 10000; n++ {
+			lines = append(lines, FocusLine{Line: n})
+		}
+	}
+	return lines
+}
+
+// splitFocusConfig splits on the commas outside of braces the way the
+// code presenter's /,(?![^{]*})/ does: a comma is kept when a } comes
+// before the next {.
+func splitFocusConfig(spec string) []string {
+	if strings.TrimSpace(spec) == "" {
+		return nil
+	}
+	var parts []string
+	start := 0
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != ',' {
+			continue
+		}
+		if j := strings.IndexAny(spec[i+1:], "{}"); j >= 0 && spec[i+1+j] == '}' {
+			continue
+		}
+		parts = append(parts, spec[start:i])
+		start = i + 1
+	}
+	return append(parts, spec[start:])
+}
+
+// leadingInt parses the number s starts with, like JavaScript's parseInt:
+// an optional sign, then digits.
+func leadingInt(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	end := 0
+	if end < len(s) && (s[end] == '+' || s[end] == '-') {
+		end++
+	}
+	digits := end
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	if end == digits {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:end])
+	return n, err == nil
+}
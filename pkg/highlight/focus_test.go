@@ -0,0 +1,122 @@
+package highlight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseFocus checks ParseFocus reads configs as parseLineConfig in
+// presentationer-react/src/components/code-presenter/focus.ts does.
+func TestParseFocus(t *testing.T) {
+	lines := func(numbers ...int) []FocusLine {
+		var out []FocusLine
+		for _, n := range numbers {
+			out = append(out, FocusLine{Line: n})
+		}
+		return out
+	}
+	tests := []struct {
+		spec string
+		want []FocusLine
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"6", lines(6)},
+		{"3-8", lines(3, 4, 5, 6, 7, 8)},
+		{" 3 - 5 , 9", lines(3, 4, 5, 9)},
+		{"1,,2", lines(1, 2)},
+		{`6{"a,b"}`, []FocusLine{{Line: 6, Text: "a,b"}}},
+		{"6{a,b},7", []FocusLine{{Line: 6, Text: "a,b"}, {Line: 7}}},
+		{`6 {"say \"hi\""}`, []FocusLine{{Line: 6, Text: `say "hi"`}}},
+		{`6{"bad \x"}`, []FocusLine{{Line: 6, Text: `"bad \x"`}}},
+		{`6{"}`, []FocusLine{{Line: 6, Text: `"`}}},
+		// malformed entries are read as far as parseInt reads them, or
+		// skipped
+		{"8-3", nil},
+		{"3-", nil},
+		{"-3", nil},
+		{"1-2-3", lines(1)},
+		{"+2-3", lines(2, 3)},
+		{"4x", lines(4)},
+		{"6{}", lines(6)},
+		{"abc", nil},
+		{"1,2}", lines(1)},
+		{"6{a", lines(6)},
+	}
+	for _, tt := range tests {
+		if got := ParseFocus(tt.spec); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFocus(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+	if got := len(ParseFocus("1-1000000")); got != 10000 {
+		t.Errorf("a huge range gives %d lines, want 10000", got)
+	}
+}
+
+func tokens(texts ...string) []Token {
+	var out []Token
+	for i, text := range texts {
+		out = append(out, Token{Text: text, Type: "T" + strings.Repeat("x", i)})
+	}
+	return out
+}
+
+// describe writes the tokens as text, marked parts in brackets.
+func describe(line []Token) string {
+	var b strings.Builder
+	for _, tok := range line {
+		if tok.Marked {
+			b.WriteString("[" + tok.Text + "]")
+		} else {
+			b.WriteString(tok.Text + "|")
+		}
+	}
+	return b.String()
+}
+
+func TestMark(t *testing.T) {
+	tests := []struct {
+		line []Token
+		text string
+		want string
+	}{
+		{tokens("foo", "(", "bar", ")"), "bar", "foo|(|[bar])|"},
+		{tokens("a := a + a"), "a", "[a] := |[a] + |[a]"},
+		{tokens("aaa"), "aa", "[aa]a|"},
+		{tokens("fmt", ".", "Println"), "t.P", "fm|[t][.][P]rintln|"},
+		{tokens("foo"), "bar", "foo|"},
+	}
+	for _, tt := range tests {
+		got := mark(tt.line, tt.text)
+		if d := describe(got); d != tt.want {
+			t.Errorf("mark(%q) = %s, want %s", tt.text, d, tt.want)
+		}
+		// the pieces keep the type of the token they come from
+		for _, tok := range got {
+			if tok.Type == "" {
+				t.Errorf("mark(%q): token %q lost its type", tt.text, tok.Text)
+			}
+		}
+	}
+}
+
+func TestMarkRange(t *testing.T) {
+	line := tokens("return", " ", "x")
+	tests := []struct {
+		from, to int
+		want     string
+	}{
+		{0, 6, "[return] |x|"},
+		{3, 8, "ret|[urn][ ][x]"},
+		{-5, 2, "[re]turn| |x|"},
+		{7, 100, "return| |[x]"},
+		{4, 4, "return| |x|"},
+		{9, 12, "return| |x|"},
+	}
+	for _, tt := range tests {
+		if got := describe(Mark(line, tt.from, tt.to)); got != tt.want {
+			t.Errorf("Mark(%d, %d) = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
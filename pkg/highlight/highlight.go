@@ -0,0 +1,179 @@
+// Package highlight colours source code on the server, so every renderer
+// in Go shares one highlighter, and applies the focus configs of code
+// pages the way the code presenter does.
+package highlight
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// DefaultStyle is the chroma style code is coloured in unless another is
+// asked for, dark like the frontend's vs2015 theme.
+const DefaultStyle = "github-dark"
+
+// ErrUnknownStyle is returned for a style chroma does not have.
+var ErrUnknownStyle = errors.New("unknown style")
+
+// Code is highlighted source, split into lines.
+type Code struct {
+	// Language is the name of the lexer used, "plaintext" when there is
+	// none for the language asked for.
+	Language string `json:"language"`
+	// Background and Foreground are the style's colours as RRGGBB.
+	Background string `json:"background"`
+	Foreground string `json:"foreground"`
+	Lines      []Line `json:"lines"`
+}
+
+// Line is a line of code.
+type Line struct {
+	// Number counts from 1.
+	Number int `json:"number"`
+	// Focused is false for the lines a focus config leaves out.
+	Focused bool    `json:"focused"`
+	Tokens  []Token `json:"tokens"`
+}
+
+// Token is a run of code in one colour.
+type Token struct {
+	Text string `json:"text"`
+	// Type is the chroma token type, like "KeywordDeclaration".
+	Type   string `json:"type"`
+	Color  string `json:"color"` // RRGGBB
+	Bold   bool   `json:"bold,omitempty"`
+	Italic bool   `json:"italic,omitempty"`
+	// Marked is set on the text a focus config points at.
+	Marked bool `json:"marked,omitempty"`
+}
+
+// Styles returns the names of the styles, sorted.
+func Styles() []string {
+	names := styles.Names()
+	sort.Strings(names)
+	return names
+}
+
+// Highlight colours code as language in style, DefaultStyle when empty.
+// The language defaults to go as in the code presenter, and code in a
+// language without a lexer is plain text. Tabs are expanded to four
+// spaces. All lines are focused.
+func Highlight(code string, language string, style string) (*Code, error) {
+	if style == "" {
+		style = DefaultStyle
+	}
+	s, ok := styles.Registry[style]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStyle, style)
+	}
+	background := s.Get(chroma.Background)
+	c := &Code{
+		Background: colourHex(background.Background, "1E1E1E"),
+		Foreground: colourHex(background.Colour, "D4D4D4"),
+	}
+	if language == "" {
+		language = "go"
+	}
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+		c.Language = "plaintext"
+	} else {
+		c.Language = lexer.Config().Name
+	}
+
+	code = strings.ReplaceAll(strings.TrimRight(code, "\n"), "\t", "    ")
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		for i, line := range strings.Split(code, "\n") {
+			c.Lines = append(c.Lines, Line{Number: i + 1, Focused: true, Tokens: []Token{{Text: line, Type: chroma.Text.String(), Color: c.Foreground}}})
+		}
+		return c, nil
+	}
+	for i, tokens := range chroma.SplitTokensIntoLines(it.Tokens()) {
+		line := Line{Number: i + 1, Focused: true, Tokens: []Token{}}
+		for _, tok := range tokens {
+			text := strings.TrimRight(tok.Value, "\n")
+			if text == "" {
+				continue
+			}
+			entry := s.Get(tok.Type)
+			line.Tokens = append(line.Tokens, Token{
+				Text:   text,
+				Type:   tok.Type.String(),
+				Color:  colourHex(entry.Colour, c.Foreground),
+				Bold:   entry.Bold == chroma.Yes,
+				Italic: entry.Italic == chroma.Yes,
+			})
+		}
+		c.Lines = append(c.Lines, line)
+	}
+	return c, nil
+}
+
+func colourHex(c chroma.Colour, fallback string) string {
+	if !c.IsSet() {
+		return fallback
+	}
+	return strings.ToUpper(strings.TrimPrefix(c.String(), "#"))
+}
+
+// HTML renders the code as the code presenter shows it: a line number
+// before each line, the lines out of focus faded and blurred, and the
+// text matches marked.
+func (c *Code) HTML() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<pre class="highlight" style="margin: 0; font-size: 14px; line-height: 1.5; background-color: #%s; color: #%s;"><code style="font-family: monospace;">`, c.Background, c.Foreground)
+	for _, line := range c.Lines {
+		style := "opacity: 1; display: block; position: relative;"
+		if !line.Focused {
+			style = "opacity: 0.3; filter: blur(0.5px); display: block; position: relative;"
+		}
+		fmt.Fprintf(&b, `<div class="line-%d" style="%s">`, line.Number, style)
+		fmt.Fprintf(&b, `<span style="display: inline-block; width: 30px; color: #666; text-align: right; margin-right: 15px; user-select: none;">%d</span>`, line.Number)
+		if len(line.Tokens) == 0 {
+			b.WriteString("&nbsp;")
+		}
+		for _, tok := range line.Tokens {
+			css := "color: #" + tok.Color + ";"
+			if tok.Bold {
+				css += " font-weight: bold;"
+			}
+			if tok.Italic {
+				css += " font-style: italic;"
+			}
+			if tok.Marked {
+				css = "background-color: #2d5e38; color: #fff; border-radius: 2px; box-shadow: 0 0 0 1px #45a049;"
+			}
+			fmt.Fprintf(&b, `<span style="%s">%s</span>`, css, html.EscapeString(tok.Text))
+		}
+		b.WriteString("</div>")
+	}
+	b.WriteString("</code></pre>")
+	return b.String()
+}
+
+// String returns the plain code.
+func (l Line) String() string {
+	var b strings.Builder
+	for _, tok := range l.Tokens {
+		b.WriteString(tok.Text)
+	}
+	return b.String()
+}
+
+// Width returns the length of the longest line in characters, at least 1.
+func (c *Code) Width() int {
+	longest := 1
+	for _, line := range c.Lines {
+		longest = max(longest, len([]rune(line.String())))
+	}
+	return longest
+}
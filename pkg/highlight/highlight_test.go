@@ -0,0 +1,99 @@
+package highlight
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func lineText(l Line) string {
+	var b strings.Builder
+	for _, tok := range l.Tokens {
+		b.WriteString(tok.Text)
+	}
+	return b.String()
+}
+
+func TestHighlight(t *testing.T) {
+	code, err := Highlight("package main\n\nfunc main() {\n\tprintln(\"x\")\n}\n", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code.Language != "Go" || code.Background == "" || code.Foreground == "" {
+		t.Errorf("got %s on %s/%s", code.Language, code.Background, code.Foreground)
+	}
+	want := []string{"package main", "", "func main() {", `    println("x")`, "}"}
+	if len(code.Lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(code.Lines), len(want))
+	}
+	for i, l := range code.Lines {
+		if l.Number != i+1 || !l.Focused || lineText(l) != want[i] {
+			t.Errorf("line %d: %+v", i+1, l)
+		}
+	}
+	if tok := code.Lines[0].Tokens[0]; tok.Text != "package" || !strings.HasPrefix(tok.Type, "Keyword") {
+		t.Errorf("first token %+v", tok)
+	}
+
+	plain, err := Highlight("a\nb", "no-such-language", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Language != "plaintext" || len(plain.Lines) != 2 {
+		t.Errorf("got %s with %d lines", plain.Language, len(plain.Lines))
+	}
+	if _, err := Highlight("x", "go", "no-such-style"); !errors.Is(err, ErrUnknownStyle) {
+		t.Errorf("unknown style: %v", err)
+	}
+}
+
+func TestFocus(t *testing.T) {
+	code, err := Highlight("a := 1\nb := a\nc := a + b\n", "go", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code.Focus(ParseFocus(`2,3{"a + b"}`))
+	for _, l := range code.Lines {
+		if l.Focused != (l.Number != 1) {
+			t.Errorf("line %d focused: %v", l.Number, l.Focused)
+		}
+	}
+	var marked string
+	for _, tok := range code.Lines[2].Tokens {
+		if tok.Marked {
+			marked += tok.Text
+		}
+	}
+	if marked != "a + b" {
+		t.Errorf("marked %q on line 3", marked)
+	}
+
+	html := code.HTML()
+	for _, want := range []string{
+		`<div class="line-1" style="opacity: 0.3; filter: blur(0.5px);`,
+		`<div class="line-2" style="opacity: 1;`,
+		`background-color: #2d5e38`,
+		`:=`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML lacks %q:\n%s", want, html)
+		}
+	}
+
+	// an empty config leaves every line focused, one naming lines past
+	// the end none
+	code, err = Highlight("x\ny", "go", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code.Focus(ParseFocus(""))
+	if !code.Lines[0].Focused || !code.Lines[1].Focused {
+		t.Error("an empty config unfocused lines")
+	}
+	code.Focus(ParseFocus(`9{"x"}`))
+	for _, l := range code.Lines {
+		if l.Focused {
+			t.Errorf("line %d focused by a config naming only line 9", l.Number)
+		}
+	}
+}
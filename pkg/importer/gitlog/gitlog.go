@@ -0,0 +1,290 @@
+// Package gitlog turns a range of commits of a local repository into a
+// walkthrough session. Each commit gets a chat_thread page with its author
+// telling the commit message, then a page per file it changed, titled by
+// the commit subject: a code_diff page for a changed, renamed or deleted
+// file and a code page for an added one. Binary files and files larger
+// than maxFileSize are left out.
+//
+// Everything is read from the local repository by the git command.
+package gitlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/importer/patch"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// maxFileSize is the size of the largest file a page is made of; larger
+// ones are generated or data files nobody reads through in a deck.
+const maxFileSize = 1 << 20
+
+// Options selects the commits to import.
+type Options struct {
+	// Repo is a directory in the repository, default the current one.
+	Repo string
+	// Range is a revision range like A..B, or a single commit.
+	Range string
+	// Paths limits the commits and files to these paths, relative to Repo.
+	Paths []string
+}
+
+// Commit is a commit of the range with the files it changed.
+type Commit struct {
+	Hash    string
+	Author  string
+	Time    time.Time
+	Message string
+	Files   []File
+}
+
+// Subject returns the first line of the message.
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return strings.TrimSpace(subject)
+}
+
+// File is a file changed by a commit. OldPath is empty for an added file
+// and NewPath for a deleted one; they differ for a renamed file.
+type File struct {
+	OldPath string
+	NewPath string
+}
+
+// Path returns the path of the file after the commit, or before it for a
+// deleted file.
+func (f *File) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// Import reads the commits selected by opts and creates the session in s,
+// named name, or else defaultName. It returns the name the session was
+// created as.
+func Import(ctx context.Context, s store.SessionStore, opts Options, name string, defaultName string, onConflict store.OnConflict) (string, error) {
+	pages, err := Pages(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = defaultName
+	}
+	if err := model.ValidatePages(pages); err != nil {
+		return "", err
+	}
+	return store.ImportSession(ctx, s, &model.Session{Name: name, Pages: pages}, nil, onConflict)
+}
+
+// Pages reads the commits selected by opts, oldest first, and returns
+// their pages.
+func Pages(ctx context.Context, opts Options) ([]model.Page, error) {
+	commits, err := Log(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]bool)
+	var pages []model.Page
+	add := func(title string, c model.Content) error {
+		page := model.Page{ID: model.NewPageID(), Title: patch.UniqueTitle(titles, title)}
+		if err := page.EncodeContent(c); err != nil {
+			return err
+		}
+		pages = append(pages, page)
+		return nil
+	}
+	for _, c := range commits {
+		subject := c.Subject()
+		if subject == "" {
+			subject = shortHash(c.Hash)
+		}
+		if err := add(subject, annotation(c)); err != nil {
+			return nil, err
+		}
+		for _, f := range c.Files {
+			content, err := fileContent(ctx, opts.Repo, c, f)
+			if err != nil {
+				return nil, err
+			}
+			if content == nil {
+				continue
+			}
+			if err := add(subject+": "+f.Path(), content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return pages, nil
+}
+
+// annotation returns the chat thread of a commit: its author telling the
+// message.
+func annotation(c Commit) *model.ChatThreadContent {
+	return &model.ChatThreadContent{Messages: []model.Message{{
+		Sender:   c.Author,
+		Content:  c.Message,
+		SendTime: c.Time.Format("2006-01-02 15:04"),
+	}}}
+}
+
+// fileContent returns the page content showing how c changed f, or nil
+// for a binary or too large file.
+func fileContent(ctx context.Context, repo string, c Commit, f File) (model.Content, error) {
+	var old, new string
+	var err error
+	for _, side := range []struct{ rev, path string }{{c.Hash + "^", f.OldPath}, {c.Hash, f.NewPath}} {
+		if side.path == "" {
+			continue
+		}
+		n, err := size(ctx, repo, side.rev, side.path)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxFileSize {
+			return nil, nil
+		}
+	}
+	if f.OldPath != "" {
+		old, err = show(ctx, repo, c.Hash+"^", f.OldPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.NewPath != "" {
+		new, err = show(ctx, repo, c.Hash, f.NewPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if binary(old) || binary(new) {
+		return nil, nil
+	}
+	language := patch.Language(f.Path())
+	if f.OldPath == "" {
+		return &model.CodeContent{Code: new, Language: language}, nil
+	}
+	return &model.CodeDiffContent{OldCode: old, NewCode: new, Language: language, Path: f.Path()}, nil
+}
+
+// Log lists the commits selected by opts, oldest first, leaving out
+// merges, with the files each changed under the paths.
+func Log(ctx context.Context, opts Options) ([]Commit, error) {
+	rev := opts.Range
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return nil, store.Errorf(store.ErrInvalid, "invalid range %q", rev)
+	}
+	if !strings.Contains(rev, "..") {
+		// a single commit
+		rev += "^!"
+	}
+	args := []string{"log", "--reverse", "--no-merges", "--format=%x1e%H%x1f%an%x1f%aI%x1f%B", rev, "--"}
+	out, err := git(ctx, opts.Repo, append(args, opts.Paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(record, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", shortHash(fields[0]), err)
+		}
+		c := Commit{Hash: fields[0], Author: fields[1], Time: t, Message: strings.TrimSpace(fields[3])}
+		c.Files, err = changedFiles(ctx, opts.Repo, c.Hash, opts.Paths)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	if len(commits) == 0 {
+		return nil, store.Errorf(store.ErrInvalid, "no commits in %s", opts.Range)
+	}
+	return commits, nil
+}
+
+// changedFiles lists the files commit changed under paths, compared to
+// its first parent, with renames detected.
+func changedFiles(ctx context.Context, repo string, commit string, paths []string) ([]File, error) {
+	args := []string{"diff-tree", "-r", "-M", "--root", "--no-commit-id", "--name-status", "-z", commit, "--"}
+	out, err := git(ctx, repo, append(args, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	var files []File
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, name := fields[i], fields[i+1]
+		switch status[0] {
+		case 'A':
+			files = append(files, File{NewPath: name})
+		case 'D':
+			files = append(files, File{OldPath: name})
+		case 'R', 'C':
+			// followed by the new path
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("git diff-tree: missing path after %s", name)
+			}
+			oldPath := name
+			if status[0] == 'C' {
+				oldPath = ""
+			}
+			files = append(files, File{OldPath: oldPath, NewPath: fields[i+2]})
+			i++
+		default:
+			files = append(files, File{OldPath: name, NewPath: name})
+		}
+	}
+	return files, nil
+}
+
+// show returns the content of file, relative to the top of the
+// repository, at commit.
+func show(ctx context.Context, repo string, commit string, file string) (string, error) {
+	return git(ctx, repo, "show", commit+":"+file)
+}
+
+// size returns the size in bytes of file at commit.
+func size(ctx context.Context, repo string, commit string, file string) (int64, error) {
+	out, err := git(ctx, repo, "cat-file", "-s", commit+":"+file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+}
+
+// binary reports whether text looks binary the way git decides it: a NUL
+// byte in the first 8000 bytes.
+func binary(text string) bool {
+	return strings.IndexByte(text[:min(len(text), 8000)], 0) >= 0
+}
+
+func shortHash(hash string) string {
+	return hash[:min(len(hash), 7)]
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,183 @@
+package gitlog
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+// testRepo makes a repository with a commit adding, changing, renaming,
+// deleting, and adding binary and large files, and returns it with the
+// hashes of the commits, oldest first.
+func testRepo(t *testing.T) (string, []string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := git(context.Background(), dir, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(out)
+	}
+	write := func(name string, text string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var hashes []string
+	commit := func(message string) {
+		t.Helper()
+		run("add", "-A")
+		run("-c", "user.name=Alice", "-c", "user.email=alice@example.com", "-c", "commit.gpgsign=false",
+			"commit", "-q", "--no-verify", "-m", message)
+		hashes = append(hashes, run("rev-parse", "HEAD"))
+	}
+	run("init", "-q")
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("docs/intro.md", "# Intro\n")
+	commit("Add main\n\nThe body.")
+	write("main.go", "package main\n\nfunc main() {\n\tprintln(1)\n}\n")
+	commit("Print one")
+	if err := os.Rename(filepath.Join(dir, "main.go"), filepath.Join(dir, "app.go")); err != nil {
+		t.Fatal(err)
+	}
+	commit("Rename main")
+	if err := os.Remove(filepath.Join(dir, "docs/intro.md")); err != nil {
+		t.Fatal(err)
+	}
+	commit("Remove intro")
+	write("logo.png", "\x89PNG\x00\x00")
+	write("data.txt", strings.Repeat("x", maxFileSize+1))
+	write("docs/notes.md", "notes\n")
+	commit("Add assets")
+	return dir, hashes
+}
+
+func TestPages(t *testing.T) {
+	repo, hashes := testRepo(t)
+	type page struct {
+		title string
+		kind  model.PageKind
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		pages []page
+	}{
+		{
+			name: "range",
+			opts: Options{Range: hashes[0] + ".." + hashes[4]},
+			pages: []page{
+				{"Print one", model.PageKindChatThread},
+				{"Print one: main.go", model.PageKindCodeDiff},
+				{"Rename main", model.PageKindChatThread},
+				{"Rename main: app.go", model.PageKindCodeDiff},
+				{"Remove intro", model.PageKindChatThread},
+				{"Remove intro: docs/intro.md", model.PageKindCodeDiff},
+				{"Add assets", model.PageKindChatThread},
+				{"Add assets: docs/notes.md", model.PageKindCode},
+			},
+		},
+		{
+			name: "single commit",
+			opts: Options{Range: hashes[0]},
+			pages: []page{
+				{"Add main", model.PageKindChatThread},
+				{"Add main: docs/intro.md", model.PageKindCode},
+				{"Add main: main.go", model.PageKindCode},
+			},
+		},
+		{
+			name: "paths",
+			opts: Options{Range: hashes[0] + ".." + hashes[4], Paths: []string{"docs"}},
+			pages: []page{
+				{"Remove intro", model.PageKindChatThread},
+				{"Remove intro: docs/intro.md", model.PageKindCodeDiff},
+				{"Add assets", model.PageKindChatThread},
+				{"Add assets: docs/notes.md", model.PageKindCode},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Repo = repo
+			pages, err := Pages(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []page
+			for _, p := range pages {
+				got = append(got, page{p.Title, p.Kind})
+			}
+			if !reflect.DeepEqual(got, tt.pages) {
+				t.Errorf("got pages\n%v\nwant\n%v", got, tt.pages)
+			}
+			if err := model.ValidatePages(pages); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLog(t *testing.T) {
+	repo, hashes := testRepo(t)
+	commits, err := Log(context.Background(), Options{Repo: repo, Range: hashes[0] + ".." + hashes[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits))
+	}
+	c := commits[1]
+	if c.Hash != hashes[2] || c.Author != "Alice" || c.Time.IsZero() || c.Subject() != "Rename main" {
+		t.Errorf("got %+v", c)
+	}
+	if want := []File{{OldPath: "main.go", NewPath: "app.go"}}; !reflect.DeepEqual(c.Files, want) {
+		t.Errorf("files %+v, want %+v", c.Files, want)
+	}
+
+	for _, rev := range []string{"", "--all", hashes[4] + ".." + hashes[0]} {
+		if _, err := Log(context.Background(), Options{Repo: repo, Range: rev}); err == nil {
+			t.Errorf("Log(%q) succeeded", rev)
+		}
+	}
+}
+
+func TestFileContent(t *testing.T) {
+	repo, hashes := testRepo(t)
+	content, err := fileContent(context.Background(), repo, Commit{Hash: hashes[1]}, File{OldPath: "main.go", NewPath: "main.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &model.CodeDiffContent{
+		OldCode:  "package main\n\nfunc main() {}\n",
+		NewCode:  "package main\n\nfunc main() {\n\tprintln(1)\n}\n",
+		Language: "go",
+		Path:     "main.go",
+	}
+	if !reflect.DeepEqual(content, want) {
+		t.Errorf("got %+v", content)
+	}
+	// binary and large files have no page
+	for _, name := range []string{"logo.png", "data.txt"} {
+		content, err := fileContent(context.Background(), repo, Commit{Hash: hashes[4]}, File{NewPath: name})
+		if err != nil || content != nil {
+			t.Errorf("%s: got %v, %v", name, content, err)
+		}
+	}
+}
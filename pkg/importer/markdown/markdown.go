@@ -0,0 +1,472 @@
+// Package markdown turns a Markdown outline into a session.
+//
+// Each # or ## heading starts a page titled by it. What follows the heading
+// decides the page kind:
+//
+//	fenced code block            code, with the block's language
+//	ordered list                 numbered_list; "**Title**: text" items
+//	                             are split into title and description
+//	table with a numeric column  chart; a preceding "*Line chart*" picks
+//	                             the chart type
+//	other two-column table       stats
+//	blockquote transcript        chat_thread, one message per paragraph:
+//	                             **Alice** (10:02): hi
+//	anything else                rectangle with the text and bullet items
+//
+// A section holding several of these becomes several pages. A # heading
+// opening the document right above a ## heading, with no other # heading,
+// names the session instead, which is the shape written by
+// pkg/render/markdown.
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Import parses source and creates the session in s, named name, or after
+// the document title, or else defaultName. It returns the name the session
+// was created as.
+func Import(ctx context.Context, s store.SessionStore, source []byte, name string, defaultName string, onConflict store.OnConflict) (string, error) {
+	session, err := Parse(source)
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		session.Name = name
+	} else if session.Name == "" {
+		session.Name = defaultName
+	}
+	if err := model.ValidatePages(session.Pages); err != nil {
+		return "", err
+	}
+	return store.ImportSession(ctx, s, session, nil, onConflict)
+}
+
+// Parse converts a Markdown document to a session. The session is named
+// after the document title when there is one, otherwise its name is left
+// for the caller to set.
+func Parse(source []byte) (*model.Session, error) {
+	md := goldmark.New(goldmark.WithExtensions(extension.Table))
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	p := &parser{source: source, titles: make(map[string]bool)}
+	session := &model.Session{}
+
+	var blocks []ast.Node
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		blocks = append(blocks, n)
+	}
+	// a lone # heading right above the first ## section names the session
+	if isTitle(blocks) {
+		session.Name = p.plainText(blocks[0])
+		blocks = blocks[1:]
+	}
+
+	var title string
+	var section []ast.Node
+	flush := func() error {
+		if title == "" && len(section) == 0 {
+			return nil
+		}
+		if title == "" {
+			title = "Introduction"
+		}
+		if err := p.section(title, section); err != nil {
+			return err
+		}
+		title, section = "", nil
+		return nil
+	}
+	for _, n := range blocks {
+		if h, ok := n.(*ast.Heading); ok && h.Level <= 2 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			title = p.plainText(h)
+			continue
+		}
+		section = append(section, n)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	session.Pages = p.pages
+	if session.Pages == nil {
+		session.Pages = []model.Page{}
+	}
+	return session, nil
+}
+
+func isTitle(blocks []ast.Node) bool {
+	if len(blocks) < 2 || headingLevel(blocks[0]) != 1 || headingLevel(blocks[1]) != 2 {
+		return false
+	}
+	for _, n := range blocks[1:] {
+		if headingLevel(n) == 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func headingLevel(n ast.Node) int {
+	if h, ok := n.(*ast.Heading); ok {
+		return h.Level
+	}
+	return 0
+}
+
+type parser struct {
+	source []byte
+	pages  []model.Page
+	titles map[string]bool
+}
+
+var (
+	chartTypePattern = regexp.MustCompile(`(?i)^\*?(line|bar|pie) chart\*?$`)
+	focusPattern     = regexp.MustCompile(`^Focus: lines (.+)$`)
+	messagePattern   = regexp.MustCompile(`^\*\*(.+?)\*\*(?:\s*\(([^)]*)\))?:\s?(.*)$`)
+	itemPattern      = regexp.MustCompile(`^\*\*(.+?)\*\*(?::\s*(.*))?$`)
+	numberPattern    = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+)
+
+// section turns the blocks under one heading into pages.
+func (p *parser) section(title string, blocks []ast.Node) error {
+	var rest []ast.Node
+	var chartType model.ChartType
+	added := 0
+	add := func(c model.Content) error {
+		page := model.Page{ID: model.NewPageID(), Title: p.uniqueTitle(title)}
+		if err := page.EncodeContent(c); err != nil {
+			return err
+		}
+		p.pages = append(p.pages, page)
+		added++
+		return nil
+	}
+
+	for i := 0; i < len(blocks); i++ {
+		switch n := blocks[i].(type) {
+		case *ast.FencedCodeBlock:
+			code := &model.CodeContent{
+				Code:     strings.TrimRight(p.lines(n), "\n"),
+				Language: string(n.Language(p.source)),
+			}
+			if i+1 < len(blocks) {
+				if m := focusPattern.FindStringSubmatch(p.rawText(blocks[i+1])); m != nil {
+					code.ConfigList = []model.FocusConfig{{ID: "1", Name: "Focus", Lines: m[1]}}
+					code.SelectedConfigID = "1"
+					i++
+				}
+			}
+			if err := add(code); err != nil {
+				return err
+			}
+		case *ast.List:
+			if !n.IsOrdered() {
+				rest = append(rest, n)
+				continue
+			}
+			if err := add(p.numberedList(n)); err != nil {
+				return err
+			}
+		case *east.Table:
+			if c := p.chart(n, chartType); c != nil {
+				if err := add(c); err != nil {
+					return err
+				}
+			} else if c := p.stats(n); c != nil {
+				if err := add(c); err != nil {
+					return err
+				}
+			} else {
+				rest = append(rest, n)
+			}
+		case *ast.Blockquote:
+			if c := p.chatThread(n); c != nil {
+				if err := add(c); err != nil {
+					return err
+				}
+			} else {
+				rest = append(rest, n)
+			}
+		case *ast.Paragraph:
+			if m := chartTypePattern.FindStringSubmatch(p.rawText(n)); m != nil {
+				chartType = model.ChartType(strings.ToLower(m[1]))
+				continue
+			}
+			rest = append(rest, n)
+		default:
+			rest = append(rest, n)
+		}
+	}
+
+	if added > 0 {
+		return nil
+	}
+	return add(p.rectangle(title, rest))
+}
+
+// uniqueTitle returns title, or title with a number when it is taken.
+func (p *parser) uniqueTitle(title string) string {
+	unique := title
+	for n := 2; p.titles[unique]; n++ {
+		unique = title + " " + strconv.Itoa(n)
+	}
+	p.titles[unique] = true
+	return unique
+}
+
+func (p *parser) numberedList(list *ast.List) *model.NumberedListContent {
+	c := &model.NumberedListContent{}
+	start := list.Start
+	if start == 0 {
+		start = 1
+	}
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		raw := strings.TrimSpace(p.rawText(item))
+		numbered := model.NumberedItem{Number: fmt.Sprintf("%02d", start+len(c.Items))}
+		if m := itemPattern.FindStringSubmatch(firstLine(raw)); m != nil {
+			numbered.Title = m[1]
+			numbered.Description = strings.TrimSpace(m[2] + " " + afterFirstLine(raw))
+		} else {
+			numbered.Title = p.plainText(item)
+		}
+		c.Items = append(c.Items, numbered)
+	}
+	return c
+}
+
+// cells returns the plain text of every row of a table, header first.
+func (p *parser) cells(table *east.Table) [][]string {
+	var rows [][]string
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			text := strings.TrimSpace(p.plainText(cell))
+			cells = append(cells, strings.ReplaceAll(text, `\|`, "|"))
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// chart reads a table with a numeric column: names from the first other
+// column, values from the first numeric one.
+func (p *parser) chart(table *east.Table, chartType model.ChartType) *model.ChartContent {
+	rows := p.cells(table)
+	if len(rows) < 2 {
+		return nil
+	}
+	header, body := rows[0], rows[1:]
+	valueCol, nameCol := -1, -1
+	for col := range header {
+		if valueCol < 0 && numericColumn(body, col) {
+			valueCol = col
+		} else if nameCol < 0 {
+			nameCol = col
+		}
+	}
+	if valueCol < 0 {
+		return nil
+	}
+	if chartType == "" {
+		chartType = model.ChartTypeBar
+	}
+	c := &model.ChartContent{ChartType: chartType}
+	for _, row := range body {
+		value, _ := number(cell(row, valueCol))
+		c.Items = append(c.Items, model.ChartItem{Name: cell(row, nameCol), Value: value})
+	}
+	return c
+}
+
+func numericColumn(rows [][]string, col int) bool {
+	for _, row := range rows {
+		if _, ok := number(cell(row, col)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// number parses a finite decimal number. strconv.ParseFloat alone also
+// takes "NaN", "Inf" and hex floats, which are words in a table and no
+// value a chart can hold.
+func number(s string) (float64, bool) {
+	if !numberPattern.MatchString(s) {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
+
+// stats reads a two-column table of labels and values.
+func (p *parser) stats(table *east.Table) *model.StatsContent {
+	rows := p.cells(table)
+	if len(rows) < 2 || len(rows[0]) != 2 {
+		return nil
+	}
+	c := &model.StatsContent{}
+	for _, row := range rows[1:] {
+		c.Items = append(c.Items, model.StatItem{Label: cell(row, 0), Value: cell(row, 1)})
+	}
+	return c
+}
+
+func cell(row []string, col int) string {
+	if col < 0 || col >= len(row) {
+		return ""
+	}
+	return row[col]
+}
+
+// chatThread reads a blockquote whose paragraphs all start with a
+// **Sender** (time): prefix, or returns nil. Every line with the prefix
+// starts a message, so consecutive lines of a quote make a thread even
+// without blank lines between them; other lines continue the message
+// above.
+func (p *parser) chatThread(quote *ast.Blockquote) *model.ChatThreadContent {
+	c := &model.ChatThreadContent{}
+	for n := quote.FirstChild(); n != nil; n = n.NextSibling() {
+		para, ok := n.(*ast.Paragraph)
+		if !ok {
+			return nil
+		}
+		for i, line := range strings.Split(p.rawText(para), "\n") {
+			if m := messagePattern.FindStringSubmatch(line); m != nil {
+				c.Messages = append(c.Messages, model.Message{Sender: m[1], SendTime: m[2], Content: m[3]})
+				continue
+			}
+			if i == 0 {
+				return nil
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				last := &c.Messages[len(c.Messages)-1]
+				last.Content += "\n" + line
+			}
+		}
+	}
+	if len(c.Messages) == 0 {
+		return nil
+	}
+	return c
+}
+
+// rectangle keeps the remaining text of a section: the first paragraph or
+// heading as the box text, the other paragraphs as subtext and bullets as
+// items.
+func (p *parser) rectangle(title string, blocks []ast.Node) *model.RectangleContent {
+	c := &model.RectangleContent{}
+	var subtext []string
+	for _, n := range blocks {
+		switch n := n.(type) {
+		case *ast.List:
+			for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+				c.Items = append(c.Items, model.RectangleItem{Type: "text", Value: p.plainText(item)})
+			}
+		case *ast.Paragraph, *ast.Heading:
+			if c.Text == "" {
+				c.Text = p.plainText(n)
+			} else {
+				subtext = append(subtext, p.plainText(n))
+			}
+		default:
+			if s := strings.TrimSpace(p.plainText(n)); s != "" {
+				subtext = append(subtext, s)
+			}
+		}
+	}
+	if c.Text == "" {
+		c.Text = title
+	}
+	c.Subtext = strings.Join(subtext, "\n\n")
+	return c
+}
+
+// lines returns the raw source lines of a block.
+func (p *parser) lines(n ast.Node) string {
+	var b bytes.Buffer
+	segments := n.Lines()
+	for i := 0; i < segments.Len(); i++ {
+		seg := segments.At(i)
+		b.Write(seg.Value(p.source))
+	}
+	return b.String()
+}
+
+// rawText returns the Markdown source of a block's text, descending into
+// containers such as list items.
+func (p *parser) rawText(n ast.Node) string {
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return strings.TrimRight(p.lines(n), "\n")
+	}
+	var parts []string
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.Type() != ast.TypeBlock {
+			continue
+		}
+		if s := p.rawText(c); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// plainText returns the text of a node with the Markdown markup removed.
+func (p *parser) plainText(n ast.Node) string {
+	var b strings.Builder
+	var walk func(n ast.Node)
+	walk = func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Value(p.source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+			return
+		case *ast.String:
+			b.Write(n.Value)
+			return
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			b.WriteString(p.lines(n))
+			return
+		}
+		if n.Type() == ast.TypeBlock && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func afterFirstLine(s string) string {
+	_, rest, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(rest)
+}
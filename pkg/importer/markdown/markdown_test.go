@@ -0,0 +1,101 @@
+package markdown
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/memory"
+)
+
+func TestParse(t *testing.T) {
+	source := "# Deck\n\n" +
+		"## Code\n\n```go\nfunc main() {}\n```\n\nFocus: lines 1\n\n" +
+		"## Steps\n\n1. **Plan**: think\n2. Build\n\n" +
+		"## Growth\n\n*Line chart*\n\n| Month | Users |\n|---|---|\n| Jan | 10 |\n| Feb | 2.5e1 |\n\n" +
+		"## Numbers\n\n| Metric | Value |\n|---|---|\n| Uptime | 99% |\n\n" +
+		"## Chat\n\n> **Alice** (10:02): hi\n> **Bob**: hello\n> there\n\n" +
+		"## Notes\n\nThe text.\n\nMore.\n\n- one\n- two\n\n" +
+		"## Notes\n\nAgain.\n"
+	session, err := Parse([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Name != "Deck" {
+		t.Errorf("name %q, want Deck", session.Name)
+	}
+	want := []struct {
+		title   string
+		content model.Content
+	}{
+		{"Code", &model.CodeContent{Code: "func main() {}", Language: "go", ConfigList: []model.FocusConfig{{ID: "1", Name: "Focus", Lines: "1"}}, SelectedConfigID: "1"}},
+		{"Steps", &model.NumberedListContent{Items: []model.NumberedItem{{Number: "01", Title: "Plan", Description: "think"}, {Number: "02", Title: "Build"}}}},
+		{"Growth", &model.ChartContent{ChartType: model.ChartTypeLine, Items: []model.ChartItem{{Name: "Jan", Value: 10}, {Name: "Feb", Value: 25}}}},
+		{"Numbers", &model.StatsContent{Items: []model.StatItem{{Label: "Uptime", Value: "99%"}}}},
+		{"Chat", &model.ChatThreadContent{Messages: []model.Message{{Sender: "Alice", SendTime: "10:02", Content: "hi"}, {Sender: "Bob", Content: "hello\nthere"}}}},
+		{"Notes", &model.RectangleContent{Text: "The text.", Subtext: "More.", Items: []model.RectangleItem{{Type: "text", Value: "one"}, {Type: "text", Value: "two"}}}},
+		{"Notes 2", &model.RectangleContent{Text: "Again."}},
+	}
+	if len(session.Pages) != len(want) {
+		t.Fatalf("got %d pages, want %d", len(session.Pages), len(want))
+	}
+	for i, w := range want {
+		page := session.Pages[i]
+		content, err := page.DecodeContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Title != w.title || !reflect.DeepEqual(content, w.content) {
+			t.Errorf("page %d: %q %+v, want %q %+v", i, page.Title, content, w.title, w.content)
+		}
+	}
+}
+
+func TestNumericColumn(t *testing.T) {
+	tests := []struct {
+		value   string
+		numeric bool
+	}{
+		{"1", true},
+		{"-2.5", true},
+		{"+.5", true},
+		{"3.", true},
+		{"1e3", true},
+		{"1E-3", true},
+		{"", false},
+		{"NaN", false},
+		{"nan", false},
+		{"Inf", false},
+		{"-infinity", false},
+		{"0x1p3", false},
+		{"1_000", false},
+		{"1e400", false},
+		{"12%", false},
+		{"1,000", false},
+	}
+	for _, tt := range tests {
+		if got := numericColumn([][]string{{"name", tt.value}}, 1); got != tt.numeric {
+			t.Errorf("numericColumn(%q) = %v, want %v", tt.value, got, tt.numeric)
+		}
+	}
+}
+
+// TestImportWordColumn checks a table of words that strconv reads as
+// numbers imports as stats rather than as a chart that can't be saved.
+func TestImportWordColumn(t *testing.T) {
+	s := memory.New()
+	source := "## Results\n\n| Test | Result |\n|---|---|\n| a | nan |\n| b | inf |\n"
+	name, err := Import(context.Background(), s, []byte(source), "", "results", store.ConflictFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := s.Get(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Pages) != 1 || session.Pages[0].Kind != model.PageKindStats {
+		t.Errorf("got pages %+v", session.Pages)
+	}
+}
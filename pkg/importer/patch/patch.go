@@ -0,0 +1,133 @@
+// Package patch turns a unified diff, as written by diff -u or git diff,
+// into a session of code_diff pages: a page per hunk, titled by the path
+// of its file.
+package patch
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Import parses source and creates the session in s, named name, or else
+// defaultName. It returns the name the session was created as.
+func Import(ctx context.Context, s store.SessionStore, source []byte, name string, defaultName string, onConflict store.OnConflict) (string, error) {
+	pages, err := Parse(source)
+	if err != nil {
+		return "", store.Errorf(store.ErrInvalid, "%v", err)
+	}
+	if name == "" {
+		name = defaultName
+	}
+	if err := model.ValidatePages(pages); err != nil {
+		return "", err
+	}
+	return store.ImportSession(ctx, s, &model.Session{Name: name, Pages: pages}, nil, onConflict)
+}
+
+// Parse converts a unified diff to code_diff pages. Binary files and files
+// only renamed have no hunks, and no pages.
+func Parse(source []byte) ([]model.Page, error) {
+	files, err := diff.ParseUnified(string(source))
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[string]bool)
+	var pages []model.Page
+	for _, f := range files {
+		for i, h := range f.Hunks {
+			title := f.Path()
+			if title == "" {
+				title = "Changes"
+			}
+			if len(f.Hunks) > 1 {
+				title += fmt.Sprintf(" (%d/%d)", i+1, len(f.Hunks))
+			}
+			page := model.Page{ID: model.NewPageID(), Title: UniqueTitle(titles, title)}
+			if err := page.EncodeContent(HunkContent(f.Path(), h)); err != nil {
+				return nil, err
+			}
+			pages = append(pages, page)
+		}
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no changes to import")
+	}
+	return pages, nil
+}
+
+// HunkContent returns the content of a code_diff page showing a hunk of
+// the file at path: the old and new lines of the hunk, all shown.
+func HunkContent(path string, h diff.Hunk) *model.CodeDiffContent {
+	old, new := h.Texts()
+	c := &model.CodeDiffContent{
+		OldCode:  old,
+		NewCode:  new,
+		Language: Language(path),
+		Path:     path,
+		Context:  -1,
+		OldStart: h.OldStart,
+		NewStart: h.NewStart,
+	}
+	// an empty side starts at the line before the hunk
+	if h.OldLines == 0 {
+		c.OldStart++
+	}
+	if h.NewLines == 0 {
+		c.NewStart++
+	}
+	return c
+}
+
+// UniqueTitle returns title, or title with a number after it when it is
+// in titles already, and adds it to titles.
+func UniqueTitle(titles map[string]bool, title string) string {
+	unique := title
+	for n := 2; titles[unique]; n++ {
+		unique = title + " " + strconv.Itoa(n)
+	}
+	titles[unique] = true
+	return unique
+}
+
+// languages maps file extensions to the languages the code presenter
+// highlights.
+var languages = map[string]string{
+	".go":   "go",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".mjs":  "javascript",
+	".ts":   "typescript",
+	".tsx":  "typescript",
+	".json": "json",
+	".sh":   "bash",
+	".bash": "bash",
+	".py":   "python",
+	".java": "java",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".hpp":  "cpp",
+	".cs":   "csharp",
+	".rs":   "rust",
+	".sql":  "sql",
+	".html": "html",
+	".xml":  "html",
+	".css":  "css",
+	".md":   "markdown",
+	".yaml": "yaml",
+	".yml":  "yaml",
+}
+
+// Language returns the language of a file by its extension, "" when the
+// code presenter does not know it.
+func Language(file string) string {
+	return languages[strings.ToLower(path.Ext(file))]
+}
@@ -0,0 +1,116 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+const sample = `diff --git a/server/main.go b/server/main.go
+--- a/server/main.go
++++ b/server/main.go
+@@ -1,2 +1,2 @@
+ package main
+-var x = 1
++var x = 2
+@@ -20 +20,2 @@
+ func f() {}
++func g() {}
+diff --git a/README.md b/README.md
+new file mode 100644
+--- /dev/null
++++ b/README.md
+@@ -0,0 +1 @@
++# Hi
+diff --git a/old.go b/new.go
+similarity index 100%
+rename from old.go
+rename to new.go
+diff --git a/img.png b/img.png
+Binary files a/img.png and b/img.png differ
+`
+
+func TestParse(t *testing.T) {
+	pages, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		title    string
+		path     string
+		language string
+		old, new string
+		oldStart int
+		newStart int
+	}{
+		{"server/main.go (1/2)", "server/main.go", "go", "package main\nvar x = 1", "package main\nvar x = 2", 1, 1},
+		{"server/main.go (2/2)", "server/main.go", "go", "func f() {}", "func f() {}\nfunc g() {}", 20, 20},
+		{"README.md", "README.md", "markdown", "", "# Hi", 1, 1},
+	}
+	if len(pages) != len(want) {
+		t.Fatalf("got %d pages, want %d", len(pages), len(want))
+	}
+	for i, w := range want {
+		p := pages[i]
+		if p.Title != w.title || p.Kind != model.PageKindCodeDiff {
+			t.Errorf("page %d: %s %q, want code_diff %q", i, p.Kind, p.Title, w.title)
+			continue
+		}
+		content, err := p.DecodeContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := content.(*model.CodeDiffContent)
+		if c.Path != w.path || c.Language != w.language || c.OldCode != w.old || c.NewCode != w.new || c.OldStart != w.oldStart || c.NewStart != w.newStart || c.Context != -1 {
+			t.Errorf("page %d: got %+v", i, c)
+		}
+		// the page shows the hunk it was made from
+		if hunks := c.Hunks(); len(hunks) != 1 {
+			t.Errorf("page %d: %d hunks", i, len(hunks))
+		}
+	}
+}
+
+func TestParseNoChanges(t *testing.T) {
+	for _, source := range []string{
+		"diff --git a/a b/b\nsimilarity index 100%\nrename from a\nrename to b\n",
+		"not a diff\n",
+	} {
+		if _, err := Parse([]byte(source)); err == nil {
+			t.Errorf("Parse(%q) succeeded", source)
+		}
+	}
+}
+
+func TestHunkContent(t *testing.T) {
+	hunks := diff.Compare("a\nb\nc\n", "a\nc\n", diff.Options{Context: 0})
+	c := HunkContent("x.py", hunks[0])
+	// the deleted line has nothing on the new side, which starts after it
+	if c.OldCode != "b" || c.NewCode != "" || c.OldStart != 2 || c.NewStart != 2 || c.Language != "python" {
+		t.Errorf("got %+v", c)
+	}
+}
+
+func TestUniqueTitle(t *testing.T) {
+	titles := make(map[string]bool)
+	var got []string
+	for _, title := range []string{"a", "a", "b", "a", "a 2"} {
+		got = append(got, UniqueTitle(titles, title))
+	}
+	want := []string{"a", "a 2", "b", "a 3", "a 2 2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("titles %q, want %q", got, want)
+			break
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	for file, want := range map[string]string{"a.go": "go", "dir/B.TSX": "typescript", "Makefile": "", "x.unknown": ""} {
+		if got := Language(file); got != want {
+			t.Errorf("Language(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+)
+
+// Content is the typed form of Page.Content for a single page kind.
+type Content interface {
+	Kind() PageKind
+	validate(v *validator, field string)
+}
+
+// Dimension is an export width or height as typed into the preview
+// controls, e.g. "800" or "100%". Numbers are accepted when decoding.
+type Dimension string
+
+func (d *Dimension) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*d = Dimension(s)
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("dimension must be a string or number")
+	}
+	*d = Dimension(strconv.FormatFloat(f, 'f', -1, 64))
+	return nil
+}
+
+// ExportSize holds the PNG export dimensions stored next to page content.
+type ExportSize struct {
+	ExportWidth  Dimension `json:"exportWidth,omitempty"`
+	ExportHeight Dimension `json:"exportHeight,omitempty"`
+}
+
+// FocusConfig is a named set of focus lines on a code page, e.g. "3-8,12".
+type FocusConfig struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Lines string `json:"lines"`
+}
+
+// SourceRef links a code page to the file its code was taken from. Code
+// holds a snapshot of the lines referenced, updated by a refresh; the
+// server shows the lines as they are now and reports the drift.
+type SourceRef struct {
+	// Root is the repository Path is relative to, absolute, under ~ or
+	// relative to the working directory of presentationer; the working
+	// directory when empty.
+	Root string `json:"root,omitempty"`
+	Path string `json:"path"`
+	// Lines is a line range like "10-30", or one line. Without Lines or
+	// Symbol the whole file is referenced.
+	Lines string `json:"lines,omitempty"`
+	// Symbol is a Go function, type, const or var in the file, and
+	// methods as Type.Method.
+	Symbol string `json:"symbol,omitempty"`
+	// Elide shows the bodies of the functions nested in Symbol as "...".
+	Elide bool `json:"elide,omitempty"`
+	// Line is the line of the file the snapshot starts at.
+	Line int `json:"line,omitempty"`
+}
+
+// ParseLineRange parses a range of lines like "10-30", or a single line
+// like "12", counting from 1.
+func ParseLineRange(s string) (int, int, error) {
+	first, last, isRange := strings.Cut(s, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid line range %q", s)
+	}
+	end := start
+	if isRange {
+		end, err = strconv.Atoi(strings.TrimSpace(last))
+		if err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid line range %q", s)
+		}
+	}
+	return start, end, nil
+}
+
+type CodeContent struct {
+	Code             string        `json:"code"`
+	Language         string        `json:"language,omitempty"`
+	ConfigList       []FocusConfig `json:"configList,omitempty"`
+	SelectedConfigID string        `json:"selectedConfigId,omitempty"`
+	ShowHTML         bool          `json:"showHtml,omitempty"`
+	Source           *SourceRef    `json:"source,omitempty"`
+	ExportSize
+}
+
+func (c *CodeContent) Kind() PageKind { return PageKindCode }
+
+// SelectedConfig returns the focus config currently selected, or nil.
+func (c *CodeContent) SelectedConfig() *FocusConfig {
+	if c.SelectedConfigID == "" {
+		return nil
+	}
+	for i := range c.ConfigList {
+		if c.ConfigList[i].ID == c.SelectedConfigID {
+			return &c.ConfigList[i]
+		}
+	}
+	return nil
+}
+
+type Message struct {
+	Sender   string `json:"sender"`
+	Content  string `json:"content"`
+	SendTime string `json:"sendTime"`
+	Avatar   string `json:"avatar,omitempty"`
+	IsMe     bool   `json:"isMe,omitempty"`
+	IsBot    bool   `json:"is_bot,omitempty"`
+}
+
+// CodeDiffContent shows the changes between two versions of some code.
+type CodeDiffContent struct {
+	OldCode  string `json:"oldCode"`
+	NewCode  string `json:"newCode"`
+	Language string `json:"language,omitempty"`
+	// Path is the file the code is from, shown above the changes.
+	Path string `json:"path,omitempty"`
+	// Context is the number of unchanged lines shown around the changes,
+	// diff.DefaultContext when zero; all lines are shown when negative.
+	Context int `json:"context,omitempty"`
+	// OldStart and NewStart are the numbers of the first lines of the code
+	// in their files, for code from the middle of a file; 1 when zero.
+	OldStart int `json:"oldStart,omitempty"`
+	NewStart int `json:"newStart,omitempty"`
+	ExportSize
+}
+
+func (c *CodeDiffContent) Kind() PageKind { return PageKindCodeDiff }
+
+// Hunks compares the old and new code.
+func (c *CodeDiffContent) Hunks() []diff.Hunk {
+	context := c.Context
+	if context == 0 {
+		context = diff.DefaultContext
+	}
+	return diff.Compare(c.OldCode, c.NewCode, diff.Options{Context: context, OldStart: c.OldStart, NewStart: c.NewStart})
+}
+
+type ChatThreadContent struct {
+	Messages []Message
+	ExportSize
+}
+
+func (c *ChatThreadContent) Kind() PageKind { return PageKindChatThread }
+
+type ChartType string
+
+const (
+	ChartTypeLine ChartType = "line"
+	ChartTypeBar  ChartType = "bar"
+	ChartTypePie  ChartType = "pie"
+)
+
+type ChartItem struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Color string  `json:"color,omitempty"`
+}
+
+type ChartContent struct {
+	ChartType ChartType
+	Items     []ChartItem
+	ExportSize
+}
+
+func (c *ChartContent) Kind() PageKind { return PageKindChart }
+
+type RectangleItem struct {
+	Type  string `json:"type,omitempty"` // "text" or "icon"
+	Value string `json:"value"`
+	Color string `json:"color,omitempty"`
+	Size  string `json:"size,omitempty"`
+	Bold  bool   `json:"bold,omitempty"`
+}
+
+type RectangleContent struct {
+	Text            string          `json:"text"`
+	Subtext         string          `json:"subtext,omitempty"`
+	Color           string          `json:"color,omitempty"`
+	BackgroundColor string          `json:"backgroundColor,omitempty"`
+	BorderColor     string          `json:"borderColor,omitempty"`
+	TextColor       string          `json:"textColor,omitempty"`
+	Icon            string          `json:"icon,omitempty"`
+	Width           float64         `json:"width,omitempty"`
+	Height          float64         `json:"height,omitempty"`
+	Animate         bool            `json:"animate,omitempty"`
+	Items           []RectangleItem `json:"items,omitempty"`
+	ExportSize      `json:"-"`
+}
+
+func (c *RectangleContent) Kind() PageKind { return PageKindRectangle }
+
+// CRNode is a box in a connected rectangles page.
+type CRNode struct {
+	ID      string `json:"id"`
+	Text    string `json:"text"`
+	Subtext string `json:"subtext,omitempty"`
+	Icon    string `json:"icon,omitempty"`
+	Color   string `json:"color,omitempty"`
+	IsPulse bool   `json:"isPulse,omitempty"`
+}
+
+// CREdge is an arrow between two CRNodes.
+type CREdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+type ConnectedRectanglesContent struct {
+	Layout     string   `json:"layout,omitempty"` // "row" or "column"
+	Gap        float64  `json:"gap,omitempty"`
+	Nodes      []CRNode `json:"nodes"`
+	Edges      []CREdge `json:"edges,omitempty"`
+	ExportSize `json:"-"`
+}
+
+func (c *ConnectedRectanglesContent) Kind() PageKind { return PageKindConnectedRectangles }
+
+type UserFeedbackItem struct {
+	Quote  string `json:"quote"`
+	Author string `json:"author"`
+}
+
+type UserFeedbackContent struct {
+	Items              []UserFeedbackItem `json:"items"`
+	FontSizeMultiplier float64            `json:"fontSizeMultiplier,omitempty"`
+	ExportSize         `json:"-"`
+}
+
+func (c *UserFeedbackContent) Kind() PageKind { return PageKindUserFeedback }
+
+// UnmarshalJSON also accepts the older form where the feedback JSON was
+// a bare array of items.
+func (c *UserFeedbackContent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, &c.Items)
+	}
+	type plain UserFeedbackContent
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+type StructureItem struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content,omitempty"`
+	Color       string `json:"color,omitempty"`
+}
+
+type StructureBreakdownContent struct {
+	Items      []StructureItem `json:"items"`
+	Columns    int             `json:"columns,omitempty"`
+	ExportSize `json:"-"`
+}
+
+func (c *StructureBreakdownContent) Kind() PageKind { return PageKindStructureBreakdown }
+
+type StatItem struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Color string `json:"color,omitempty"`
+}
+
+type StatsContent struct {
+	Items      []StatItem `json:"items"`
+	Columns    int        `json:"columns,omitempty"`
+	ExportSize `json:"-"`
+}
+
+func (c *StatsContent) Kind() PageKind { return PageKindStats }
+
+type NumberedItem struct {
+	Number      string `json:"number"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       string `json:"color,omitempty"`
+}
+
+type NumberedListContent struct {
+	Items      []NumberedItem `json:"items"`
+	Columns    int            `json:"columns,omitempty"`
+	ExportSize `json:"-"`
+}
+
+func (c *NumberedListContent) Kind() PageKind { return PageKindNumberedList }
+
+type ConceptItem struct {
+	Icon        string `json:"icon"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Color       string `json:"color,omitempty"`
+}
+
+type ConceptCardContent struct {
+	Items      []ConceptItem `json:"items"`
+	Columns    int           `json:"columns,omitempty"`
+	ExportSize `json:"-"`
+}
+
+func (c *ConceptCardContent) Kind() PageKind { return PageKindConceptCard }
+
+// jsonEnvelope is how the frontend stores pages edited through a JSON
+// editor: the editor text plus preview settings.
+type jsonEnvelope struct {
+	JSON      string    `json:"json,omitempty"`
+	ChartType ChartType `json:"chartType,omitempty"`
+	ExportSize
+}
+
+// NewContent returns an empty Content for kind, or nil if kind is unknown.
+func NewContent(kind PageKind) Content {
+	switch kind {
+	case PageKindCode:
+		return &CodeContent{}
+	case PageKindCodeDiff:
+		return &CodeDiffContent{}
+	case PageKindChatThread:
+		return &ChatThreadContent{}
+	case PageKindChart:
+		return &ChartContent{}
+	case PageKindRectangle:
+		return &RectangleContent{}
+	case PageKindConnectedRectangles:
+		return &ConnectedRectanglesContent{}
+	case PageKindUserFeedback:
+		return &UserFeedbackContent{}
+	case PageKindStructureBreakdown:
+		return &StructureBreakdownContent{}
+	case PageKindStats:
+		return &StatsContent{}
+	case PageKindNumberedList:
+		return &NumberedListContent{}
+	case PageKindConceptCard:
+		return &ConceptCardContent{}
+	}
+	return nil
+}
+
+// DecodeContent parses Page.Content into the typed content for Page.Kind.
+// Empty content decodes to the zero value. Decoding errors are returned
+// as a *ValidationError naming the offending field.
+func (p *Page) DecodeContent() (Content, error) {
+	c := NewContent(p.Kind)
+	if c == nil {
+		return nil, newValidationError("kind", fmt.Sprintf("unknown page kind %q", p.Kind))
+	}
+	raw := bytes.TrimSpace(p.Content)
+	if len(raw) == 0 || string(raw) == "null" {
+		return c, nil
+	}
+
+	switch c.(type) {
+	case *CodeContent, *CodeDiffContent:
+		// the code editors store their state as it is
+		if err := json.Unmarshal(raw, c); err != nil {
+			return nil, newValidationError("content", err.Error())
+		}
+		return c, nil
+	}
+
+	var env jsonEnvelope
+	if raw[0] == '"' {
+		// a bare string is the editor text itself
+		if err := json.Unmarshal(raw, &env.JSON); err != nil {
+			return nil, newValidationError("content", err.Error())
+		}
+	} else if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, newValidationError("content", err.Error())
+	}
+
+	var target interface{} = c
+	switch c := c.(type) {
+	case *ChatThreadContent:
+		c.ExportSize = env.ExportSize
+		target = &c.Messages
+	case *ChartContent:
+		c.ExportSize = env.ExportSize
+		c.ChartType = env.ChartType
+		target = &c.Items
+	case *RectangleContent:
+		c.ExportSize = env.ExportSize
+	case *ConnectedRectanglesContent:
+		c.ExportSize = env.ExportSize
+	case *UserFeedbackContent:
+		c.ExportSize = env.ExportSize
+	case *StructureBreakdownContent:
+		c.ExportSize = env.ExportSize
+	case *StatsContent:
+		c.ExportSize = env.ExportSize
+	case *NumberedListContent:
+		c.ExportSize = env.ExportSize
+	case *ConceptCardContent:
+		c.ExportSize = env.ExportSize
+	}
+
+	text := bytes.TrimSpace([]byte(env.JSON))
+	if len(text) == 0 {
+		return c, nil
+	}
+	if err := json.Unmarshal(text, target); err != nil {
+		return nil, newValidationError("content.json", err.Error())
+	}
+	return c, nil
+}
+
+// EncodeContent stores c as the page content, in the same shape the
+// frontend editors write, and sets Page.Kind to match.
+func (p *Page) EncodeContent(c Content) error {
+	var data []byte
+	var err error
+	switch c.(type) {
+	case *CodeContent, *CodeDiffContent:
+		data, err = json.Marshal(c)
+	default:
+		env := jsonEnvelope{}
+		var inner interface{} = c
+		switch c := c.(type) {
+		case *ChatThreadContent:
+			env.ExportSize = c.ExportSize
+			inner = nonNil(c.Messages)
+		case *ChartContent:
+			env.ExportSize = c.ExportSize
+			env.ChartType = c.ChartType
+			inner = nonNil(c.Items)
+		case *RectangleContent:
+			env.ExportSize = c.ExportSize
+		case *ConnectedRectanglesContent:
+			env.ExportSize = c.ExportSize
+		case *UserFeedbackContent:
+			env.ExportSize = c.ExportSize
+		case *StructureBreakdownContent:
+			env.ExportSize = c.ExportSize
+		case *StatsContent:
+			env.ExportSize = c.ExportSize
+		case *NumberedListContent:
+			env.ExportSize = c.ExportSize
+		case *ConceptCardContent:
+			env.ExportSize = c.ExportSize
+		}
+		var text []byte
+		text, err = json.MarshalIndent(inner, "", "  ")
+		if err != nil {
+			return err
+		}
+		env.JSON = string(text)
+		data, err = json.Marshal(env)
+	}
+	if err != nil {
+		return err
+	}
+	p.Kind = c.Kind()
+	p.Content = data
+	return nil
+}
+
+// nonNil makes empty lists encode as [] rather than null, which the
+// frontend previews cannot parse.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
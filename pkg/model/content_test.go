@@ -0,0 +1,91 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestContentRoundtrip(t *testing.T) {
+	size := ExportSize{ExportWidth: "800", ExportHeight: "100%"}
+	contents := []Content{
+		&CodeContent{Code: "package main", Language: "go", ConfigList: []FocusConfig{{ID: "1", Name: "all", Lines: "1"}}, SelectedConfigID: "1", Source: &SourceRef{Root: "~/src", Path: "main.go", Lines: "1-3", Line: 1}, ExportSize: size},
+		&CodeDiffContent{OldCode: "a", NewCode: "b", Language: "go", Path: "a.go", Context: -1, OldStart: 3, NewStart: 4, ExportSize: size},
+		&ChatThreadContent{Messages: []Message{{Sender: "alice", Content: "hi", SendTime: "10:02", Avatar: "a.png", IsMe: true}}, ExportSize: size},
+		&ChartContent{ChartType: ChartTypeLine, Items: []ChartItem{{Name: "jan", Value: 1.5, Color: "#fff"}}, ExportSize: size},
+		&RectangleContent{Text: "x", Subtext: "y", Width: 200, Animate: true, Items: []RectangleItem{{Type: "text", Value: "v"}}, ExportSize: size},
+		&ConnectedRectanglesContent{Layout: "row", Gap: 8, Nodes: []CRNode{{ID: "a", Text: "A"}, {ID: "b", Text: "B", IsPulse: true}}, Edges: []CREdge{{From: "a", To: "b", Label: "calls"}}, ExportSize: size},
+		&UserFeedbackContent{Items: []UserFeedbackItem{{Quote: "q", Author: "a"}}, FontSizeMultiplier: 1.2, ExportSize: size},
+		&StructureBreakdownContent{Items: []StructureItem{{Title: "t", Description: "d", Content: "c"}}, Columns: 3, ExportSize: size},
+		&StatsContent{Items: []StatItem{{Value: "1", Label: "l"}}, Columns: 2, ExportSize: size},
+		&NumberedListContent{Items: []NumberedItem{{Number: "01", Title: "t", Description: "d"}}, Columns: 2, ExportSize: size},
+		&ConceptCardContent{Items: []ConceptItem{{Icon: "i", Title: "t", Description: "d"}}, Columns: 4, ExportSize: size},
+	}
+	if len(contents) != len(PageKinds) {
+		t.Errorf("%d kinds tested, want all %d", len(contents), len(PageKinds))
+	}
+	for _, c := range contents {
+		t.Run(string(c.Kind()), func(t *testing.T) {
+			var p Page
+			if err := p.EncodeContent(c); err != nil {
+				t.Fatal(err)
+			}
+			if p.Kind != c.Kind() {
+				t.Errorf("kind %s", p.Kind)
+			}
+			got, err := p.DecodeContent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c) {
+				t.Errorf("got %+v\nwant %+v", got, c)
+			}
+			// decoding what was encoded gives the same content again
+			again := p
+			if err := again.EncodeContent(got); err != nil {
+				t.Fatal(err)
+			}
+			if string(again.Content) != string(p.Content) {
+				t.Errorf("encoded %s, then %s", p.Content, again.Content)
+			}
+		})
+	}
+}
+
+// TestDecodeContent checks the other shapes the frontend editors have
+// stored content in.
+func TestDecodeContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    PageKind
+		content string
+		want    Content
+	}{
+		{"empty", PageKindStats, ``, &StatsContent{}},
+		{"null", PageKindChart, `null`, &ChartContent{}},
+		{"bare editor text", PageKindStats, `"{\"items\":[{\"value\":\"1\",\"label\":\"l\"}]}"`, &StatsContent{Items: []StatItem{{Value: "1", Label: "l"}}}},
+		{"empty editor text", PageKindChatThread, `{"json":"  ","exportWidth":"640"}`, &ChatThreadContent{ExportSize: ExportSize{ExportWidth: "640"}}},
+		{"numeric size", PageKindChart, `{"json":"[]","chartType":"bar","exportWidth":800,"exportHeight":600.5}`, &ChartContent{ChartType: ChartTypeBar, Items: []ChartItem{}, ExportSize: ExportSize{ExportWidth: "800", ExportHeight: "600.5"}}},
+		{"feedback as a bare list", PageKindUserFeedback, `{"json":"[{\"quote\":\"q\",\"author\":\"a\"}]"}`, &UserFeedbackContent{Items: []UserFeedbackItem{{Quote: "q", Author: "a"}}}},
+		{"code as it is", PageKindCode, `{"code":"x","showHtml":true}`, &CodeContent{Code: "x", ShowHTML: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Page{Kind: tt.kind, Content: json.RawMessage(tt.content)}
+			got, err := p.DecodeContent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, content := range []string{`{"exportWidth":true}`, `{"json":"[1]"}`, `[`} {
+		p := Page{Kind: PageKindChatThread, Content: json.RawMessage(content)}
+		if _, err := p.DecodeContent(); err == nil {
+			t.Errorf("DecodeContent(%s) succeeded", content)
+		}
+	}
+}
@@ -2,17 +2,53 @@ package model
 
 import (
 	"encoding/json"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
 type PageKind string
 
 const (
-	PageKindCode       PageKind = "code"
-	PageKindChatThread PageKind = "chat_thread"
-	PageKindChart      PageKind = "chart"
+	PageKindCode                PageKind = "code"
+	PageKindCodeDiff            PageKind = "code_diff"
+	PageKindChatThread          PageKind = "chat_thread"
+	PageKindChart               PageKind = "chart"
+	PageKindRectangle           PageKind = "rectangle"
+	PageKindConnectedRectangles PageKind = "connected_rectangles"
+	PageKindUserFeedback        PageKind = "user_feedback"
+	PageKindStructureBreakdown  PageKind = "structure_breakdown"
+	PageKindStats               PageKind = "stats"
+	PageKindNumberedList        PageKind = "numbered_list"
+	PageKindConceptCard         PageKind = "concept_card"
 )
 
+// PageKinds lists every page kind known to the server, in the order
+// the frontend registers them.
+var PageKinds = []PageKind{
+	PageKindCode,
+	PageKindChatThread,
+	PageKindChart,
+	PageKindRectangle,
+	PageKindConnectedRectangles,
+	PageKindUserFeedback,
+	PageKindStructureBreakdown,
+	PageKindStats,
+	PageKindNumberedList,
+	PageKindConceptCard,
+	PageKindCodeDiff,
+}
+
+// Known reports whether k is one of PageKinds.
+func (k PageKind) Known() bool {
+	for _, kind := range PageKinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
 type Page struct {
 	ID      string          `json:"id"`
 	Title   string          `json:"title"`
@@ -23,5 +59,49 @@ type Page struct {
 type Session struct {
 	Name         string    `json:"name"`
 	LastModified time.Time `json:"lastModified"`
-	Pages        []Page    `json:"pages,omitempty"`
+	// Revision increases by one on every change to the session's pages.
+	Revision int64  `json:"revision"`
+	Pages    []Page `json:"pages,omitempty"`
+}
+
+// RevisionInfo describes a past revision of a session kept in its history.
+type RevisionInfo struct {
+	Revision int64     `json:"revision"`
+	Time     time.Time `json:"time"`
+	Pages    int       `json:"pages"`
+}
+
+// Commit is a version control commit that touched a session.
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+var lastPageID atomic.Int64
+
+// NewPageID returns a unique page ID in the millisecond timestamp form
+// the frontend uses, bumped forward when called twice in a millisecond.
+func NewPageID() string {
+	for {
+		last := lastPageID.Load()
+		id := time.Now().UnixMilli()
+		if id <= last {
+			id = last + 1
+		}
+		if lastPageID.CompareAndSwap(last, id) {
+			return strconv.FormatInt(id, 10)
+		}
+	}
+}
+
+// CopyTitle returns the title for a copy of a page: "<title> Copy", then
+// "<title> Copy 2" and so on until one is not taken.
+func CopyTitle(title string, taken func(string) bool) string {
+	newTitle := title + " Copy"
+	for n := 2; taken(newTitle); n++ {
+		newTitle = title + " Copy " + strconv.Itoa(n)
+	}
+	return newTitle
 }
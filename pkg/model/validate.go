@@ -0,0 +1,238 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// FieldError describes a problem with a single field, addressed by a
+// path such as "content.messages[2].sender".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a page or session is malformed.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return "invalid content: " + strings.Join(msgs, "; ")
+}
+
+func newValidationError(field string, msg string) *ValidationError {
+	return &ValidationError{Errors: []FieldError{{Field: field, Message: msg}}}
+}
+
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+	}
+}
+
+func (v *validator) oneOf(field string, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// merge adds errors from err, prefixing their fields. Errors that are not
+// a *ValidationError are reported against prefix itself.
+func (v *validator) merge(prefix string, err error) {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		v.add(prefix, "%v", err)
+		return
+	}
+	for _, fe := range ve.Errors {
+		field := fe.Field
+		if prefix != "" {
+			field = prefix + "." + field
+		}
+		v.errors = append(v.errors, FieldError{Field: field, Message: fe.Message})
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errors}
+}
+
+// Validate checks the page header and decodes and checks its content.
+// It returns a *ValidationError listing every problem found.
+func (p *Page) Validate() error {
+	v := &validator{}
+	v.required("id", p.ID)
+	v.required("title", p.Title)
+	if !p.Kind.Known() {
+		v.add("kind", "unknown page kind %q", p.Kind)
+		return v.err()
+	}
+	c, err := p.DecodeContent()
+	if err != nil {
+		v.merge("", err)
+		return v.err()
+	}
+	c.validate(v, "content")
+	return v.err()
+}
+
+// ValidatePages validates every page and checks that page IDs and
+// titles are unique within the list.
+func ValidatePages(pages []Page) error {
+	v := &validator{}
+	ids := make(map[string]bool, len(pages))
+	titles := make(map[string]bool, len(pages))
+	for i, p := range pages {
+		prefix := fmt.Sprintf("pages[%d]", i)
+		if err := p.Validate(); err != nil {
+			v.merge(prefix, err)
+		}
+		if p.ID != "" {
+			if ids[p.ID] {
+				v.add(prefix+".id", "duplicate page id %q", p.ID)
+			}
+			ids[p.ID] = true
+		}
+		if p.Title != "" {
+			if titles[p.Title] {
+				v.add(prefix+".title", "duplicate page title %q", p.Title)
+			}
+			titles[p.Title] = true
+		}
+	}
+	return v.err()
+}
+
+func (c *CodeContent) validate(v *validator, field string) {
+	ids := make(map[string]bool, len(c.ConfigList))
+	for i, cfg := range c.ConfigList {
+		f := fmt.Sprintf("%s.configList[%d]", field, i)
+		v.required(f+".id", cfg.ID)
+		if ids[cfg.ID] {
+			v.add(f+".id", "duplicate config id %q", cfg.ID)
+		}
+		ids[cfg.ID] = true
+	}
+	if src := c.Source; src != nil {
+		f := field + ".source"
+		v.required(f+".path", src.Path)
+		if src.Path != "" && !filepath.IsLocal(filepath.FromSlash(src.Path)) {
+			v.add(f+".path", "must be relative to the root, without ..")
+		}
+		if src.Lines != "" && src.Symbol != "" {
+			v.add(f, "lines and symbol are exclusive")
+		}
+		if src.Elide && src.Symbol == "" {
+			v.add(f+".elide", "only applies to a symbol")
+		}
+		if src.Lines != "" {
+			if _, _, err := ParseLineRange(src.Lines); err != nil {
+				v.add(f+".lines", "%v", err)
+			}
+		}
+	}
+}
+
+func (c *CodeDiffContent) validate(v *validator, field string) {
+	if c.OldStart < 0 {
+		v.add(field+".oldStart", "must not be negative")
+	}
+	if c.NewStart < 0 {
+		v.add(field+".newStart", "must not be negative")
+	}
+}
+
+func (c *ChatThreadContent) validate(v *validator, field string) {
+	for i, m := range c.Messages {
+		v.required(fmt.Sprintf("%s.messages[%d].sender", field, i), m.Sender)
+	}
+}
+
+func (c *ChartContent) validate(v *validator, field string) {
+	v.oneOf(field+".chartType", string(c.ChartType), string(ChartTypeLine), string(ChartTypeBar), string(ChartTypePie))
+	for i, item := range c.Items {
+		v.required(fmt.Sprintf("%s.items[%d].name", field, i), item.Name)
+	}
+}
+
+func (c *RectangleContent) validate(v *validator, field string) {
+	for i, item := range c.Items {
+		v.oneOf(fmt.Sprintf("%s.items[%d].type", field, i), item.Type, "text", "icon")
+	}
+}
+
+func (c *ConnectedRectanglesContent) validate(v *validator, field string) {
+	v.oneOf(field+".layout", c.Layout, "row", "column")
+	ids := make(map[string]bool, len(c.Nodes))
+	for i, n := range c.Nodes {
+		f := fmt.Sprintf("%s.nodes[%d].id", field, i)
+		v.required(f, n.ID)
+		if n.ID != "" && ids[n.ID] {
+			v.add(f, "duplicate node id %q", n.ID)
+		}
+		ids[n.ID] = true
+	}
+	for i, e := range c.Edges {
+		f := fmt.Sprintf("%s.edges[%d]", field, i)
+		if !ids[e.From] {
+			v.add(f+".from", "unknown node %q", e.From)
+		}
+		if !ids[e.To] {
+			v.add(f+".to", "unknown node %q", e.To)
+		}
+	}
+}
+
+func (c *UserFeedbackContent) validate(v *validator, field string) {
+	for i, item := range c.Items {
+		v.required(fmt.Sprintf("%s.items[%d].quote", field, i), item.Quote)
+	}
+}
+
+func (c *StructureBreakdownContent) validate(v *validator, field string) {
+	for i, item := range c.Items {
+		v.required(fmt.Sprintf("%s.items[%d].title", field, i), item.Title)
+	}
+}
+
+func (c *StatsContent) validate(v *validator, field string) {
+	for i, item := range c.Items {
+		v.required(fmt.Sprintf("%s.items[%d].value", field, i), item.Value)
+	}
+}
+
+func (c *NumberedListContent) validate(v *validator, field string) {
+	for i, item := range c.Items {
+		v.required(fmt.Sprintf("%s.items[%d].title", field, i), item.Title)
+	}
+}
+
+func (c *ConceptCardContent) validate(v *validator, field string) {
+	for i, item := range c.Items {
+		v.required(fmt.Sprintf("%s.items[%d].title", field, i), item.Title)
+	}
+}
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func encodedPage(t *testing.T, c Content) Page {
+	t.Helper()
+	p := Page{ID: "1", Title: "Page"}
+	if err := p.EncodeContent(c); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// fields returns the fields a validation error names.
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("got %T %v, want a *ValidationError", err, err)
+	}
+	var out []string
+	for _, fe := range ve.Errors {
+		out = append(out, fe.Field)
+	}
+	return out
+}
+
+func TestValidateContent(t *testing.T) {
+	tests := []struct {
+		kind    PageKind
+		valid   Content
+		invalid Content
+		fields  []string
+	}{
+		{
+			PageKindCode,
+			&CodeContent{Code: "x", ConfigList: []FocusConfig{{ID: "1", Lines: "1"}}, Source: &SourceRef{Path: "a.go", Symbol: "F", Elide: true}},
+			&CodeContent{ConfigList: []FocusConfig{{ID: "1"}, {ID: "1"}, {}}, Source: &SourceRef{Path: "../a.go", Lines: "9-3", Symbol: "F"}},
+			[]string{"content.configList[1].id", "content.configList[2].id", "content.source.path", "content.source", "content.source.lines"},
+		},
+		{
+			PageKindCodeDiff,
+			&CodeDiffContent{OldCode: "a", NewCode: "b", OldStart: 10, NewStart: 12, Context: -1},
+			&CodeDiffContent{OldStart: -1, NewStart: -2},
+			[]string{"content.oldStart", "content.newStart"},
+		},
+		{
+			PageKindChatThread,
+			&ChatThreadContent{Messages: []Message{{Sender: "alice", Content: "hi"}}},
+			&ChatThreadContent{Messages: []Message{{Sender: "alice"}, {Sender: " ", Content: "hi"}}},
+			[]string{"content.messages[1].sender"},
+		},
+		{
+			PageKindChart,
+			&ChartContent{ChartType: ChartTypePie, Items: []ChartItem{{Name: "a", Value: 1}}},
+			&ChartContent{ChartType: "donut", Items: []ChartItem{{Value: 1}}},
+			[]string{"content.chartType", "content.items[0].name"},
+		},
+		{
+			PageKindRectangle,
+			&RectangleContent{Text: "x", Items: []RectangleItem{{Type: "icon", Value: "star"}, {Value: "plain"}}},
+			&RectangleContent{Items: []RectangleItem{{Type: "video"}}},
+			[]string{"content.items[0].type"},
+		},
+		{
+			PageKindConnectedRectangles,
+			&ConnectedRectanglesContent{Layout: "column", Nodes: []CRNode{{ID: "a"}, {ID: "b"}}, Edges: []CREdge{{From: "a", To: "b"}}},
+			&ConnectedRectanglesContent{Layout: "grid", Nodes: []CRNode{{ID: "a"}, {ID: "a"}, {}}, Edges: []CREdge{{From: "a", To: "c"}}},
+			[]string{"content.layout", "content.nodes[1].id", "content.nodes[2].id", "content.edges[0].to"},
+		},
+		{
+			PageKindUserFeedback,
+			&UserFeedbackContent{Items: []UserFeedbackItem{{Quote: "great", Author: "bob"}}},
+			&UserFeedbackContent{Items: []UserFeedbackItem{{Author: "bob"}}},
+			[]string{"content.items[0].quote"},
+		},
+		{
+			PageKindStructureBreakdown,
+			&StructureBreakdownContent{Items: []StructureItem{{Title: "api"}}},
+			&StructureBreakdownContent{Items: []StructureItem{{Description: "no title"}}},
+			[]string{"content.items[0].title"},
+		},
+		{
+			PageKindStats,
+			&StatsContent{Items: []StatItem{{Value: "99%", Label: "uptime"}}},
+			&StatsContent{Items: []StatItem{{Label: "uptime"}}},
+			[]string{"content.items[0].value"},
+		},
+		{
+			PageKindNumberedList,
+			&NumberedListContent{Items: []NumberedItem{{Number: "01", Title: "plan"}}},
+			&NumberedListContent{Items: []NumberedItem{{Number: "01"}}},
+			[]string{"content.items[0].title"},
+		},
+		{
+			PageKindConceptCard,
+			&ConceptCardContent{Items: []ConceptItem{{Title: "idea"}}},
+			&ConceptCardContent{Items: []ConceptItem{{Icon: "star"}}},
+			[]string{"content.items[0].title"},
+		},
+	}
+	if len(tests) != len(PageKinds) {
+		t.Errorf("%d kinds tested, want all %d", len(tests), len(PageKinds))
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			if tt.valid.Kind() != tt.kind || tt.invalid.Kind() != tt.kind {
+				t.Fatalf("contents of kinds %s and %s", tt.valid.Kind(), tt.invalid.Kind())
+			}
+			valid := encodedPage(t, tt.valid)
+			if err := valid.Validate(); err != nil {
+				t.Errorf("valid content: %v", err)
+			}
+			invalid := encodedPage(t, tt.invalid)
+			if got := fields(t, invalid.Validate()); !reflect.DeepEqual(got, tt.fields) {
+				t.Errorf("invalid content: fields %q, want %q", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidatePage(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   Page
+		fields []string
+	}{
+		{"empty content", Page{ID: "1", Title: "a", Kind: PageKindStats}, nil},
+		{"no id or title", Page{Kind: PageKindStats}, []string{"id", "title"}},
+		{"unknown kind", Page{ID: "1", Title: "a", Kind: "slide"}, []string{"kind"}},
+		{"content not JSON", Page{ID: "1", Title: "a", Kind: PageKindCode, Content: json.RawMessage(`{"code": 1}`)}, []string{"content"}},
+		{"editor text not JSON", Page{ID: "1", Title: "a", Kind: PageKindStats, Content: json.RawMessage(`{"json": "{"}`)}, []string{"content.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields(t, tt.page.Validate()); !reflect.DeepEqual(got, tt.fields) {
+				t.Errorf("fields %q, want %q", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidatePages(t *testing.T) {
+	stats := func(id string, title string) Page {
+		return Page{ID: id, Title: title, Kind: PageKindStats}
+	}
+	pages := []Page{stats("1", "a"), stats("2", "b"), stats("1", "c"), stats("3", "a"), {ID: "4", Title: "d"}}
+	want := []string{"pages[2].id", "pages[3].title", "pages[4].kind"}
+	if got := fields(t, ValidatePages(pages)); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields %q, want %q", got, want)
+	}
+	if err := ValidatePages(pages[:2]); err != nil {
+		t.Error(err)
+	}
+}
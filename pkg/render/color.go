@@ -0,0 +1,72 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ChartColors are the series colours of the frontend's charts, as RRGGBB.
+var ChartColors = []string{"0088FE", "00C49F", "FFBB28", "FF8042", "8884D8", "82CA9D"}
+
+// Palette is a named colour of the frontend's card kinds in the shades
+// the exports use, as RRGGBB.
+type Palette struct {
+	Accent string // -500, borders and titles
+	Light  string // -400, titles on dark cards
+	Tint   string // -100, backgrounds
+}
+
+var palettes = map[string]Palette{
+	"blue":   {"3B82F6", "60A5FA", "DBEAFE"},
+	"green":  {"22C55E", "4ADE80", "DCFCE7"},
+	"purple": {"A855F7", "C084FC", "F3E8FF"},
+	"red":    {"EF4444", "F87171", "FEE2E2"},
+	"yellow": {"EAB308", "FACC15", "FEF9C3"},
+	"orange": {"F97316", "FB923C", "FFEDD5"},
+	"gray":   {"6B7280", "9CA3AF", "F3F4F6"},
+}
+
+// PaletteOf returns the palette of a colour name or a #rgb or #rrggbb
+// colour, gray for anything else.
+func PaletteOf(color string) Palette {
+	if hex, ok := ParseHex(color); ok {
+		return Palette{Accent: hex, Light: hex, Tint: Mix(hex, "FFFFFF", 0.85)}
+	}
+	if p, ok := palettes[strings.ToLower(color)]; ok {
+		return p
+	}
+	return palettes["gray"]
+}
+
+// ParseHex parses a #rgb or #rrggbb colour into RRGGBB.
+func ParseHex(color string) (string, bool) {
+	hex, ok := strings.CutPrefix(strings.TrimSpace(color), "#")
+	if !ok {
+		return "", false
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return "", false
+	}
+	return strings.ToUpper(hex), true
+}
+
+// Mix blends RRGGBB colour a toward b by t from 0 to 1.
+func Mix(a string, b string, t float64) string {
+	x, _ := strconv.ParseUint(a, 16, 32)
+	y, _ := strconv.ParseUint(b, 16, 32)
+	var out uint64
+	for shift := 16; shift >= 0; shift -= 8 {
+		ca := float64(x >> shift & 0xff)
+		cb := float64(y >> shift & 0xff)
+		out |= uint64(math.Round(ca+(cb-ca)*t)) << shift
+	}
+	return fmt.Sprintf("%06X", out)
+}
@@ -0,0 +1,39 @@
+// Package render holds what the exporters share; each output format lives
+// in a package of its own below it.
+package render
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Deck is a session with everything needed to render it on its own.
+type Deck struct {
+	Session *model.Session
+	// Avatars maps avatar names to image data.
+	Avatars map[string][]byte
+}
+
+// LoadDeck reads a session and its avatars from s.
+func LoadDeck(ctx context.Context, s store.SessionStore, name string) (*Deck, error) {
+	session, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	names, err := s.ListAvatars(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	avatars := make(map[string][]byte, len(names))
+	for _, avatar := range names {
+		data, err := s.GetAvatar(ctx, name, avatar)
+		if err != nil {
+			return nil, fmt.Errorf("read avatar %s: %w", avatar, err)
+		}
+		avatars[avatar] = data
+	}
+	return &Deck{Session: session, Avatars: avatars}, nil
+}
@@ -0,0 +1,108 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/highlight"
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+// Diff colours, as RRGGBB, mixed into the code background for the lines
+// deleted and inserted.
+const (
+	DeleteColor = "F85149"
+	InsertColor = "3FB950"
+)
+
+// CodeDiff is a code diff page highlighted for the exporters.
+type CodeDiff struct {
+	Background string
+	Foreground string
+	Hunks      []DiffHunk
+}
+
+// DiffHunk is a hunk of highlighted lines.
+type DiffHunk struct {
+	Header string
+	Lines  []DiffLine
+}
+
+// DiffLine is a line of a diff with the tokens of its highlighted code,
+// tabs expanded; the tokens of the text that changed within the line are
+// marked.
+type DiffLine struct {
+	Kind   diff.Kind
+	Old    int
+	New    int
+	Tokens []highlight.Token
+}
+
+// Width returns the length of the longest line, in characters.
+func (c *CodeDiff) Width() int {
+	width := 0
+	for _, h := range c.Hunks {
+		for _, l := range h.Lines {
+			n := 0
+			for _, tok := range l.Tokens {
+				n += len([]rune(tok.Text))
+			}
+			width = max(width, n)
+		}
+	}
+	return width
+}
+
+// Lines returns the number of lines of all hunks.
+func (c *CodeDiff) Lines() int {
+	n := 0
+	for _, h := range c.Hunks {
+		n += len(h.Lines)
+	}
+	return n
+}
+
+// HighlightDiff compares the old and new code of a page and highlights
+// the lines of the hunks, each with the code of its side.
+func HighlightDiff(c *model.CodeDiffContent) (*CodeDiff, error) {
+	old, err := highlight.Highlight(c.OldCode, c.Language, "")
+	if err != nil {
+		return nil, err
+	}
+	new, err := highlight.Highlight(c.NewCode, c.Language, "")
+	if err != nil {
+		return nil, err
+	}
+	oldStart, newStart := max(c.OldStart, 1), max(c.NewStart, 1)
+	tokens := func(code *highlight.Code, n int) []highlight.Token {
+		if n >= 0 && n < len(code.Lines) {
+			return code.Lines[n].Tokens
+		}
+		return []highlight.Token{}
+	}
+
+	d := &CodeDiff{Background: new.Background, Foreground: new.Foreground}
+	for _, h := range c.Hunks() {
+		dh := DiffHunk{Header: h.Header(), Lines: make([]DiffLine, 0, len(h.Lines))}
+		for _, l := range h.Lines {
+			line := DiffLine{Kind: l.Kind, Old: l.Old, New: l.New}
+			if l.Kind == diff.Insert {
+				line.Tokens = tokens(new, l.New-newStart)
+			} else {
+				line.Tokens = tokens(old, l.Old-oldStart)
+			}
+			// the spans are of the text before highlighting expands tabs
+			pos := 0
+			for _, span := range l.Spans {
+				n := len(strings.ReplaceAll(span.Text, "\t", "    "))
+				if span.Changed {
+					line.Tokens = highlight.Mark(line.Tokens, pos, pos+n)
+				}
+				pos += n
+			}
+			dh.Lines = append(dh.Lines, line)
+		}
+		d.Hunks = append(d.Hunks, dh)
+	}
+	return d, nil
+}
@@ -0,0 +1,246 @@
+// Package html exports a session as a static deck: the frontend build in
+// its read-only viewer mode plus the session data, openable from file://.
+package html
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+// exportVar is the global the viewer reads the deck from; keep in sync
+// with presentationer-react/src/components/viewer/DeckViewer.tsx.
+const exportVar = "__PRESENTATIONER_EXPORT__"
+
+// exportData is the deck as the viewer reads it.
+type exportData struct {
+	Session *model.Session `json:"session"`
+	// Avatars maps avatar names to URLs, relative or data URIs.
+	Avatars map[string]string `json:"avatars"`
+	// Diffs maps the IDs of code_diff pages to their hunks, which the
+	// editor gets from the server.
+	Diffs map[string][]diff.Hunk `json:"diffs,omitempty"`
+}
+
+// WriteFile writes the deck as a single HTML file, with the frontend code,
+// styles and avatars inlined.
+func WriteFile(w io.Writer, dist fs.FS, deck *render.Deck) error {
+	avatars := make(map[string]string, len(deck.Avatars))
+	for name, data := range deck.Avatars {
+		avatars[name] = dataURI(name, data)
+	}
+	page, _, err := build(dist, deck.Session, avatars, true)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(page)
+	return err
+}
+
+// WriteDir writes the deck to dir as index.html, with the avatars in
+// avatars/, assets the frontend references in assets/ and the session
+// itself in session.json.
+func WriteDir(dir string, dist fs.FS, deck *render.Deck) error {
+	files := make(map[string][]byte)
+	avatars := make(map[string]string, len(deck.Avatars))
+	for name, data := range deck.Avatars {
+		files["avatars/"+name] = data
+		avatars[name] = "avatars/" + url.PathEscape(name)
+	}
+	page, assets, err := build(dist, deck.Session, avatars, false)
+	if err != nil {
+		return err
+	}
+	sessionJSON, err := json.MarshalIndent(deck.Session, "", "  ")
+	if err != nil {
+		return err
+	}
+	files["index.html"] = page
+	files["session.json"] = sessionJSON
+	for name, data := range assets {
+		files[name] = data
+	}
+
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	scriptPattern     = regexp.MustCompile(`<script\b[^>]*\bsrc="([^"]+)"[^>]*>\s*</script>`)
+	stylesheetPattern = regexp.MustCompile(`<link\b[^>]*\brel="stylesheet"[^>]*>`)
+	preloadPattern    = regexp.MustCompile(`<link\b[^>]*\brel="modulepreload"[^>]*>\s*`)
+	hrefPattern       = regexp.MustCompile(`\bhref="([^"]+)"`)
+	titlePattern      = regexp.MustCompile(`<title>[^<]*</title>`)
+)
+
+// build turns the frontend's index.html into the deck page. Scripts and
+// stylesheets are inlined since browsers refuse module scripts loaded from
+// file://. Other files of the build the page refers to by absolute path are
+// inlined as data URIs when inline is set, or returned to be written next
+// to the page and referred to relatively.
+func build(dist fs.FS, session *model.Session, avatars map[string]string, inline bool) ([]byte, map[string][]byte, error) {
+	index, err := fs.ReadFile(dist, "index.html")
+	if err != nil {
+		return nil, nil, fmt.Errorf("read frontend build: %w", err)
+	}
+	page := string(index)
+
+	var readErr error
+	readAsset := func(ref string) string {
+		data, err := fs.ReadFile(dist, strings.TrimPrefix(ref, "/"))
+		if err != nil && readErr == nil {
+			readErr = fmt.Errorf("read frontend build: %w", err)
+		}
+		return string(data)
+	}
+	page = preloadPattern.ReplaceAllString(page, "")
+	page = scriptPattern.ReplaceAllStringFunc(page, func(tag string) string {
+		src := scriptPattern.FindStringSubmatch(tag)[1]
+		return `<script type="module">` + escapeScript(readAsset(src)) + `</script>`
+	})
+	page = stylesheetPattern.ReplaceAllStringFunc(page, func(tag string) string {
+		m := hrefPattern.FindStringSubmatch(tag)
+		if m == nil {
+			return tag
+		}
+		return "<style>" + strings.ReplaceAll(readAsset(m[1]), "</style", `<\/style`) + "</style>"
+	})
+	if readErr != nil {
+		return nil, nil, readErr
+	}
+
+	page, assets, err := linkAssets(dist, page, inline)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	diffs, err := pageDiffs(session.Pages)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := json.Marshal(exportData{Session: session, Avatars: avatars, Diffs: diffs})
+	if err != nil {
+		return nil, nil, err
+	}
+	title := "<title>" + template.HTMLEscapeString(session.Name) + "</title>"
+	if titlePattern.MatchString(page) {
+		page = titlePattern.ReplaceAllLiteralString(page, title)
+	} else {
+		page = strings.Replace(page, "</head>", title+"</head>", 1)
+	}
+	// json.Marshal escapes <, > and &, so the data cannot end the script
+	script := "<script>window." + exportVar + " = " + string(data) + ";</script>\n"
+	if i := strings.Index(page, "</head>"); i >= 0 {
+		page = page[:i] + script + page[i:]
+	} else {
+		page = script + page
+	}
+	return []byte(page), assets, nil
+}
+
+// pageDiffs compares the code of the code_diff pages.
+func pageDiffs(pages []model.Page) (map[string][]diff.Hunk, error) {
+	var diffs map[string][]diff.Hunk
+	for i := range pages {
+		if pages[i].Kind != model.PageKindCodeDiff {
+			continue
+		}
+		c, err := pages[i].DecodeContent()
+		if err != nil {
+			return nil, fmt.Errorf("page %q: %w", pages[i].Title, err)
+		}
+		if diffs == nil {
+			diffs = make(map[string][]diff.Hunk)
+		}
+		hunks := c.(*model.CodeDiffContent).Hunks()
+		if hunks == nil {
+			hunks = []diff.Hunk{}
+		}
+		diffs[pages[i].ID] = hunks
+	}
+	return diffs, nil
+}
+
+// linkAssets rewrites absolute references to files of the build other than
+// index.html, longest paths first so one path that prefixes another does not
+// break it.
+func linkAssets(dist fs.FS, page string, inline bool) (string, map[string][]byte, error) {
+	var names []string
+	err := fs.WalkDir(dist, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && name != "index.html" {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("read frontend build: %w", err)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	assets := make(map[string][]byte)
+	for _, name := range names {
+		ref := "/" + name
+		if !strings.Contains(page, ref) {
+			continue
+		}
+		data, err := fs.ReadFile(dist, name)
+		if err != nil {
+			return "", nil, fmt.Errorf("read frontend build: %w", err)
+		}
+		if inline {
+			page = strings.ReplaceAll(page, ref, dataURI(name, data))
+		} else {
+			page = strings.ReplaceAll(page, ref, "./"+name)
+			assets[name] = data
+		}
+	}
+	return page, assets, nil
+}
+
+// escapeScript keeps inlined code from ending its script element early.
+func escapeScript(code string) string {
+	code = strings.ReplaceAll(code, "</script", `<\/script`)
+	return strings.ReplaceAll(code, "<!--", `<\!--`)
+}
+
+func dataURI(name string, data []byte) string {
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+	var b bytes.Buffer
+	b.WriteString("data:")
+	b.WriteString(contentType)
+	b.WriteString(";base64,")
+	b.WriteString(base64.StdEncoding.EncodeToString(data))
+	return b.String()
+}
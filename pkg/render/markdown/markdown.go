@@ -0,0 +1,308 @@
+// Package markdown renders a session as Markdown, for wiki pages and PR
+// descriptions.
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+// Write writes the deck as a Markdown document: the session name as the
+// title and each page as a section.
+func Write(w io.Writer, deck *render.Deck) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", oneLine(deck.Session.Name))
+	for i := range deck.Session.Pages {
+		page := &deck.Session.Pages[i]
+		body, err := Page(page)
+		if err != nil {
+			return fmt.Errorf("page %q: %w", page.Title, err)
+		}
+		fmt.Fprintf(&b, "\n## %s\n", oneLine(page.Title))
+		if body != "" {
+			b.WriteString("\n")
+			b.WriteString(body)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Page renders the content of a page, without its title.
+func Page(page *model.Page) (string, error) {
+	content, err := page.DecodeContent()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	switch c := content.(type) {
+	case *model.CodeContent:
+		writeCode(&b, c)
+	case *model.CodeDiffContent:
+		writeCodeDiff(&b, c)
+	case *model.ChatThreadContent:
+		writeChatThread(&b, c)
+	case *model.ChartContent:
+		writeChart(&b, c)
+	case *model.RectangleContent:
+		writeRectangle(&b, c)
+	case *model.ConnectedRectanglesContent:
+		writeConnectedRectangles(&b, c)
+	case *model.UserFeedbackContent:
+		writeUserFeedback(&b, c)
+	case *model.StructureBreakdownContent:
+		writeStructureBreakdown(&b, c)
+	case *model.StatsContent:
+		writeStats(&b, c)
+	case *model.NumberedListContent:
+		writeNumberedList(&b, c)
+	case *model.ConceptCardContent:
+		writeConceptCards(&b, c)
+	}
+	return b.String(), nil
+}
+
+func writeCode(b *strings.Builder, c *model.CodeContent) {
+	language := c.Language
+	if language == "" {
+		// the code presenter's default
+		language = "go"
+	}
+	fence := codeFence(c.Code)
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, language, strings.TrimRight(c.Code, "\n"), fence)
+	if cfg := c.SelectedConfig(); cfg != nil && cfg.Lines != "" {
+		fmt.Fprintf(b, "\nFocus: lines %s\n", cfg.Lines)
+	}
+}
+
+// writeCodeDiff writes the changes as a unified diff in a diff block.
+func writeCodeDiff(b *strings.Builder, c *model.CodeDiffContent) {
+	var d strings.Builder
+	if c.Path != "" {
+		fmt.Fprintf(&d, "--- a/%s\n+++ b/%s\n", c.Path, c.Path)
+	}
+	for _, h := range c.Hunks() {
+		d.WriteString(h.String())
+	}
+	if d.Len() == 0 {
+		b.WriteString("No changes.\n")
+		return
+	}
+	fence := codeFence(d.String())
+	fmt.Fprintf(b, "%sdiff\n%s%s\n", fence, d.String(), fence)
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in
+// code.
+func codeFence(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// writeChatThread writes the messages as a quoted transcript, one
+// paragraph per message: **Sender** (time): content.
+func writeChatThread(b *strings.Builder, c *model.ChatThreadContent) {
+	for i, msg := range c.Messages {
+		if i > 0 {
+			b.WriteString(">\n")
+		}
+		line := "**" + oneLine(msg.Sender) + "**"
+		if msg.SendTime != "" {
+			line += " (" + oneLine(msg.SendTime) + ")"
+		}
+		line += ": " + msg.Content
+		writeQuoted(b, line)
+	}
+}
+
+func writeChart(b *strings.Builder, c *model.ChartContent) {
+	if c.ChartType != "" {
+		fmt.Fprintf(b, "*%s chart*\n\n", strings.ToUpper(string(c.ChartType[:1]))+string(c.ChartType[1:]))
+	}
+	rows := make([][]string, 0, len(c.Items))
+	for _, item := range c.Items {
+		rows = append(rows, []string{item.Name, strconv.FormatFloat(item.Value, 'f', -1, 64)})
+	}
+	writeTable(b, []string{"Name", "Value"}, rows)
+}
+
+func writeRectangle(b *strings.Builder, c *model.RectangleContent) {
+	title := c.Text
+	if c.Icon != "" {
+		title = c.Icon + " " + title
+	}
+	if strings.TrimSpace(title) != "" {
+		fmt.Fprintf(b, "**%s**\n", oneLine(title))
+	}
+	if c.Subtext != "" {
+		fmt.Fprintf(b, "\n%s\n", c.Subtext)
+	}
+	if len(c.Items) > 0 {
+		b.WriteString("\n")
+		for _, item := range c.Items {
+			value := oneLine(item.Value)
+			if item.Bold {
+				value = "**" + value + "**"
+			}
+			fmt.Fprintf(b, "- %s\n", value)
+		}
+	}
+}
+
+// writeConnectedRectangles writes the boxes and arrows as a Mermaid
+// flowchart.
+func writeConnectedRectangles(b *strings.Builder, c *model.ConnectedRectanglesContent) {
+	direction := "LR"
+	if c.Layout == "column" {
+		direction = "TB"
+	}
+	// node IDs are free text; Mermaid gets n1, n2, ... instead
+	ids := make(map[string]string, len(c.Nodes))
+	nodeID := func(id string) string {
+		if _, ok := ids[id]; !ok {
+			ids[id] = "n" + strconv.Itoa(len(ids)+1)
+		}
+		return ids[id]
+	}
+	fmt.Fprintf(b, "```mermaid\nflowchart %s\n", direction)
+	for _, node := range c.Nodes {
+		label := node.Text
+		if node.Icon != "" {
+			label = node.Icon + " " + label
+		}
+		if node.Subtext != "" {
+			label += "<br/>" + node.Subtext
+		}
+		fmt.Fprintf(b, "    %s[\"%s\"]\n", nodeID(node.ID), mermaidText(label))
+	}
+	for _, edge := range c.Edges {
+		if edge.Label != "" {
+			fmt.Fprintf(b, "    %s -->|\"%s\"| %s\n", nodeID(edge.From), mermaidText(edge.Label), nodeID(edge.To))
+		} else {
+			fmt.Fprintf(b, "    %s --> %s\n", nodeID(edge.From), nodeID(edge.To))
+		}
+	}
+	b.WriteString("```\n")
+}
+
+func mermaidText(s string) string {
+	return strings.ReplaceAll(oneLine(s), `"`, "#quot;")
+}
+
+func writeUserFeedback(b *strings.Builder, c *model.UserFeedbackContent) {
+	for i, item := range c.Items {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeQuoted(b, item.Quote)
+		if item.Author != "" {
+			b.WriteString(">\n")
+			writeQuoted(b, "— "+item.Author)
+		}
+	}
+}
+
+func writeStructureBreakdown(b *strings.Builder, c *model.StructureBreakdownContent) {
+	for i, item := range c.Items {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "### %s\n", oneLine(item.Title))
+		if item.Description != "" {
+			fmt.Fprintf(b, "\n*%s*\n", oneLine(item.Description))
+		}
+		if item.Content != "" {
+			fmt.Fprintf(b, "\n%s\n", strings.TrimRight(item.Content, "\n"))
+		}
+	}
+}
+
+func writeStats(b *strings.Builder, c *model.StatsContent) {
+	rows := make([][]string, 0, len(c.Items))
+	for _, item := range c.Items {
+		rows = append(rows, []string{item.Label, item.Value})
+	}
+	writeTable(b, []string{"Label", "Value"}, rows)
+}
+
+func writeNumberedList(b *strings.Builder, c *model.NumberedListContent) {
+	for i, item := range c.Items {
+		fmt.Fprintf(b, "%d. **%s**", i+1, oneLine(item.Title))
+		if item.Description != "" {
+			fmt.Fprintf(b, ": %s", oneLine(item.Description))
+		}
+		b.WriteString("\n")
+	}
+}
+
+func writeConceptCards(b *strings.Builder, c *model.ConceptCardContent) {
+	for _, item := range c.Items {
+		title := item.Title
+		if item.Icon != "" {
+			title = item.Icon + " " + title
+		}
+		fmt.Fprintf(b, "- **%s**", oneLine(title))
+		if item.Description != "" {
+			fmt.Fprintf(b, ": %s", oneLine(item.Description))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// writeTable writes a table; it writes nothing when there are no rows.
+func writeTable(b *strings.Builder, header []string, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for _, cell := range cells {
+			b.WriteString(" " + tableCell(cell) + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(header)
+	b.WriteString("|")
+	for range header {
+		b.WriteString(" --- |")
+	}
+	b.WriteString("\n")
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
+
+func tableCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// writeQuoted writes text as a blockquote, line by line.
+func writeQuoted(b *strings.Builder, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			b.WriteString(">\n")
+		} else {
+			b.WriteString("> " + line + "\n")
+		}
+	}
+}
+
+// oneLine joins the lines of s for places that must stay on one line,
+// like headings.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,205 @@
+package pdf
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+const (
+	axisColor = "666666"
+	gridColor = "CCCCCC"
+)
+
+// chart draws a chart in b the way the frontend's charts look: bars and
+// lines over a dashed grid with a legend below, or a pie with its slices
+// labelled and their shares in the legend.
+func (d *doc) chart(c *model.ChartContent, b box) {
+	if len(c.Items) == 0 {
+		return
+	}
+	if c.ChartType == model.ChartTypePie {
+		d.pie(c.Items, b)
+		return
+	}
+	d.cartesian(c.ChartType, c.Items, b)
+}
+
+func (d *doc) cartesian(chartType model.ChartType, items []model.ChartItem, b box) {
+	label := run{size: 11, color: axisColor}
+	color := render.ChartColors[0]
+
+	lo, hi := 0.0, 0.0
+	for _, item := range items {
+		lo, hi = min(lo, item.Value), max(hi, item.Value)
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	step := niceStep((hi - lo) / 5)
+	lo = math.Floor(lo/step) * step
+	hi = math.Ceil(hi/step) * step
+
+	var ticks []string
+	axisW := 0.0
+	for v := lo; v <= hi+step/2; v += step {
+		t := formatTick(v, step)
+		ticks = append(ticks, t)
+		axisW = max(axisW, d.width(label, t))
+	}
+	plot := box{b.x + axisW + 12, b.y + 8, b.w - axisW - 20, b.h - 8 - 22 - 28}
+	y := func(v float64) float64 { return plot.y + plot.h - (v-lo)/(hi-lo)*plot.h }
+
+	d.pdf.SetDashPattern([]float64{3, 3}, 0)
+	d.setDraw(gridColor, 0.75)
+	for i, t := range ticks {
+		ty := y(lo + float64(i)*step)
+		d.pdf.Line(plot.x, ty, plot.x+plot.w, ty)
+		d.text(label, t, plot.x-6-d.width(label, t), ty+label.size*0.35)
+	}
+	d.pdf.SetDashPattern(nil, 0)
+	d.setDraw(axisColor, 0.75)
+	d.pdf.Line(plot.x, plot.y, plot.x, plot.y+plot.h)
+	d.pdf.Line(plot.x, y(0), plot.x+plot.w, y(0))
+
+	band := plot.w / float64(len(items))
+	for i, item := range items {
+		cx := plot.x + band*(float64(i)+0.5)
+		name := oneLine(item.Name)
+		d.text(label, name, cx-d.width(label, name)/2, plot.y+plot.h+16)
+	}
+
+	if chartType == model.ChartTypeLine {
+		points := make([]fpdf.PointType, len(items))
+		for i, item := range items {
+			points[i] = fpdf.PointType{X: plot.x + band*(float64(i)+0.5), Y: y(item.Value)}
+		}
+		d.setDraw(color, 1.5)
+		for i := 1; i < len(points); i++ {
+			d.pdf.Line(points[i-1].X, points[i-1].Y, points[i].X, points[i].Y)
+		}
+		d.setFill("FFFFFF")
+		for _, p := range points {
+			d.pdf.Circle(p.X, p.Y, 3, "FD")
+		}
+	} else {
+		d.setFill(color)
+		for i, item := range items {
+			x := plot.x + band*(float64(i)+0.1)
+			top, bottom := y(max(item.Value, 0)), y(min(item.Value, 0))
+			d.pdf.Rect(x, top, band*0.8, bottom-top, "F")
+		}
+	}
+
+	d.legend([]legendItem{{color: color, text: "value"}}, box{b.x, b.y + b.h - 20, b.w, 20})
+}
+
+func (d *doc) pie(items []model.ChartItem, b box) {
+	// largest slice first, as in the frontend
+	items = append([]model.ChartItem(nil), items...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Value > items[j].Value })
+	var total float64
+	for _, item := range items {
+		total += max(item.Value, 0)
+	}
+	label := run{size: 12}
+
+	legendH := 22.0
+	area := box{b.x, b.y, b.w, b.h - legendH - 8}
+	r := min(area.w, area.h)/2 - 24
+	cx, cy := area.x+area.w/2, area.y+area.h/2
+
+	legend := make([]legendItem, len(items))
+	angle := 0.0
+	for i, item := range items {
+		color := render.ChartColors[i%len(render.ChartColors)]
+		if hex, ok := render.ParseHex(item.Color); ok {
+			color = hex
+		}
+		share := 0.0
+		if total > 0 {
+			share = max(item.Value, 0) / total
+		}
+		legend[i] = legendItem{color: color, text: oneLine(item.Name) + " (" + strconv.FormatFloat(share*100, 'f', 1, 64) + "%)"}
+		if share == 0 {
+			continue
+		}
+
+		// counterclockwise from three o'clock, in one degree steps
+		sweep := share * 2 * math.Pi
+		points := []fpdf.PointType{{X: cx, Y: cy}}
+		steps := max(2, int(math.Ceil(sweep/(math.Pi/180))))
+		for s := 0; s <= steps; s++ {
+			a := angle + sweep*float64(s)/float64(steps)
+			points = append(points, fpdf.PointType{X: cx + r*math.Cos(a), Y: cy - r*math.Sin(a)})
+		}
+		d.setFill(color)
+		d.setDraw("FFFFFF", 1)
+		d.pdf.Polygon(points, "FD")
+
+		// the name outside the middle of the slice, in its colour
+		mid := angle + sweep/2
+		lr := label
+		lr.color = color
+		name := oneLine(item.Name)
+		lx, ly := cx+(r+10)*math.Cos(mid), cy-(r+10)*math.Sin(mid)
+		if math.Cos(mid) < 0 {
+			lx -= d.width(lr, name)
+		}
+		d.text(lr, name, lx, ly+lr.size*0.35)
+		angle += sweep
+	}
+	d.legend(legend, box{b.x, b.y + b.h - legendH, b.w, legendH})
+}
+
+type legendItem struct {
+	color string
+	text  string
+}
+
+// legend draws the items in a centered row of coloured squares and texts.
+func (d *doc) legend(items []legendItem, b box) {
+	text := run{size: 12, color: "333333"}
+	square, spacing := 10.0, 16.0
+	var total float64
+	for _, item := range items {
+		total += square + 4 + d.width(text, item.text) + spacing
+	}
+	x := b.x + (b.w-total+spacing)/2
+	base := b.y + b.h/2 + text.size*0.35
+	for _, item := range items {
+		d.setFill(item.color)
+		d.pdf.Rect(x, b.y+(b.h-square)/2, square, square, "F")
+		x += square + 4
+		d.text(text, item.text, x, base)
+		x += d.width(text, item.text) + spacing
+	}
+}
+
+// niceStep rounds a tick interval up to 1, 2 or 5 times a power of ten.
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(raw)))
+	switch f := raw / exp; {
+	case f <= 1:
+		return exp
+	case f <= 2:
+		return 2 * exp
+	case f <= 5:
+		return 5 * exp
+	default:
+		return 10 * exp
+	}
+}
+
+// formatTick formats v with as many decimals as the step has.
+func formatTick(v float64, step float64) string {
+	decimals := max(0, int(-math.Floor(math.Log10(step))))
+	return strconv.FormatFloat(math.Round(v/step)*step, 'f', decimals, 64)
+}
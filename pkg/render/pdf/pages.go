@@ -0,0 +1,595 @@
+package pdf
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/highlight"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+var (
+	margin = 36.0
+	// content is the area below the page title
+	content = box{margin, 93.6, slideWidth - 2*margin, slideHeight - 93.6 - 28.8}
+	gap     = 21.6
+)
+
+const (
+	textColor  = "1F2937"
+	mutedColor = "6B7280"
+)
+
+// page draws a page in slide coordinates: its title and the content below.
+func (d *doc) page(page *model.Page) error {
+	if page.Title != "" {
+		title := para{runs: []run{{text: oneLine(page.Title), size: 28, bold: true, color: textColor}}}
+		d.textBox(box{margin, 25.2, content.w, 57.6}, []para{title}, "b")
+	}
+	c, err := page.DecodeContent()
+	if err != nil {
+		return err
+	}
+	switch c := c.(type) {
+	case *model.CodeContent:
+		if err := d.code(c); err != nil {
+			return err
+		}
+	case *model.CodeDiffContent:
+		if err := d.codeDiff(c); err != nil {
+			return err
+		}
+	case *model.ChatThreadContent:
+		d.chatThread(c)
+	case *model.ChartContent:
+		d.chart(c, content)
+	case *model.RectangleContent:
+		d.rectangle(c)
+	case *model.ConnectedRectanglesContent:
+		d.connectedRectangles(c)
+	case *model.UserFeedbackContent:
+		d.userFeedback(c)
+	case *model.StructureBreakdownContent:
+		d.structureBreakdown(c)
+	case *model.StatsContent:
+		d.stats(c)
+	case *model.NumberedListContent:
+		d.numberedList(c)
+	case *model.ConceptCardContent:
+		d.conceptCards(c)
+	}
+	return d.pdf.Error()
+}
+
+// shape fills b and outlines it when line is set, with corners rounded by
+// radius.
+func (d *doc) shape(b box, radius float64, fill string, line string, lineW float64) {
+	style := ""
+	if fill != "" {
+		d.setFill(fill)
+		style += "F"
+	}
+	if line != "" {
+		d.setDraw(line, lineW)
+		style += "D"
+	}
+	if style == "" {
+		return
+	}
+	if radius > 0 {
+		d.pdf.RoundedRect(b.x, b.y, b.w, b.h, min(radius, b.w/2, b.h/2), "1234", style)
+	} else {
+		d.pdf.Rect(b.x, b.y, b.w, b.h, style)
+	}
+}
+
+// code sets the code in a monospaced font, coloured by token, on a panel
+// in the style's background, as the code presenter shows it: with line
+// numbers, lines outside the selected focus at 0.3 opacity and the text
+// matches of the focus marked.
+func (d *doc) code(c *model.CodeContent) error {
+	code, err := highlight.Highlight(c.Code, c.Language, "")
+	if err != nil {
+		return err
+	}
+	if cfg := c.SelectedConfig(); cfg != nil {
+		code.Focus(highlight.ParseFocus(cfg.Lines))
+	}
+	d.shape(content, 8, code.Background, "", 0)
+
+	// size the font so the longest line and all lines fit; Courier
+	// advances 0.6em, and the line numbers take their digits and a gutter
+	inner := content.inset(18)
+	n := max(len(code.Lines), 1)
+	digits := 0.6 * float64(max(len(strconv.Itoa(n)), 2))
+	gutter := digits + 1.2
+	size := min(20, inner.h/(1.5*float64(n)), inner.w/(0.6*float64(code.Width())+gutter))
+	size = max(4, math.Floor(size*2)/2)
+	lh := size * 1.5
+
+	for i, line := range code.Lines {
+		top := inner.y + float64(i)*lh
+		base := top + lh/2 + size*0.3
+		if !line.Focused {
+			d.pdf.SetAlpha(0.3, "Normal")
+		}
+		number := strconv.Itoa(line.Number)
+		numberRun := run{size: size, mono: true, color: "666666"}
+		d.text(numberRun, number, inner.x+digits*size-d.width(numberRun, number), base)
+
+		x := inner.x + gutter*size
+		for _, tok := range line.Tokens {
+			r := run{size: size, mono: true, bold: tok.Bold, italic: tok.Italic, color: tok.Color}
+			w := d.width(r, tok.Text)
+			if tok.Marked {
+				d.shape(box{x - 1, top + lh*0.1, w + 2, lh * 0.8}, 2, "2D5E38", "45A049", 0.75)
+				r.color = "FFFFFF"
+			}
+			d.text(r, tok.Text, x, base)
+			x += w
+		}
+		d.pdf.SetAlpha(1, "Normal")
+	}
+	return nil
+}
+
+// codeDiff draws the hunks of a code diff like code, each line after its
+// old and new line numbers and a "-" or "+" for the lines deleted and
+// inserted, which are on a red or green tint, with the text that changed
+// within them on a stronger one.
+func (d *doc) codeDiff(c *model.CodeDiffContent) error {
+	diffs, err := render.HighlightDiff(c)
+	if err != nil {
+		return err
+	}
+	panel := content
+	if c.Path != "" {
+		pathH := 21.6
+		d.textBox(box{content.x, content.y, content.w, pathH}, []para{{runs: []run{{text: c.Path, size: 11, mono: true, color: mutedColor}}}}, "ctr")
+		panel = box{content.x, content.y + pathH, content.w, content.h - pathH}
+	}
+	d.shape(panel, 8, diffs.Background, "", 0)
+	if len(diffs.Hunks) == 0 {
+		d.textBox(panel, []para{{runs: []run{{text: "No changes.", size: 16, color: diffs.Foreground}}, align: "ctr"}}, "ctr")
+		return nil
+	}
+
+	// hunk headers take a line each, except a single hunk's; the gutter
+	// holds both line numbers and the sign
+	n := diffs.Lines()
+	if len(diffs.Hunks) > 1 {
+		n += len(diffs.Hunks)
+	}
+	last := 0
+	for _, h := range diffs.Hunks {
+		for _, l := range h.Lines {
+			last = max(last, l.Old, l.New)
+		}
+	}
+	inner := panel.inset(18)
+	digits := 0.6 * float64(max(len(strconv.Itoa(last)), 2))
+	gutter := 2*digits + 0.6*4
+	size := min(20, inner.h/(1.5*float64(n)), inner.w/(0.6*float64(diffs.Width())+gutter))
+	size = max(4, math.Floor(size*2)/2)
+	lh := size * 1.5
+
+	numberRun := run{size: size, mono: true, color: "666666"}
+	number := func(n int, right float64, base float64) {
+		if n > 0 {
+			text := strconv.Itoa(n)
+			d.text(numberRun, text, right-d.width(numberRun, text), base)
+		}
+	}
+	top := inner.y
+	for _, h := range diffs.Hunks {
+		if len(diffs.Hunks) > 1 {
+			d.text(run{size: size, mono: true, color: render.Mix(diffs.Foreground, diffs.Background, 0.5)}, h.Header, inner.x, top+lh/2+size*0.3)
+			top += lh
+		}
+		for _, line := range h.Lines {
+			base := top + lh/2 + size*0.3
+			sign, color := "", ""
+			switch line.Kind {
+			case diff.Delete:
+				sign, color = "-", render.DeleteColor
+			case diff.Insert:
+				sign, color = "+", render.InsertColor
+			}
+			if color != "" {
+				d.shape(box{panel.x, top, panel.w, lh}, 0, render.Mix(diffs.Background, color, 0.2), "", 0)
+			}
+			number(line.Old, inner.x+digits*size, base)
+			number(line.New, inner.x+(2*digits+0.6)*size, base)
+			if sign != "" {
+				d.text(run{size: size, mono: true, bold: true, color: color}, sign, inner.x+(2*digits+1.2)*size, base)
+			}
+
+			x := inner.x + gutter*size
+			for _, tok := range line.Tokens {
+				r := run{size: size, mono: true, bold: tok.Bold, italic: tok.Italic, color: tok.Color}
+				w := d.width(r, tok.Text)
+				if tok.Marked && color != "" {
+					d.shape(box{x, top + lh*0.1, w, lh * 0.8}, 1, render.Mix(diffs.Background, color, 0.5), "", 0)
+				}
+				d.text(r, tok.Text, x, base)
+				x += w
+			}
+			top += lh
+		}
+	}
+	return nil
+}
+
+// chatThread draws the messages top to bottom as speech bubbles next to
+// the sender's avatar, on the right for the viewer's own messages.
+func (d *doc) chatThread(c *model.ChatThreadContent) {
+	if len(c.Messages) == 0 {
+		return
+	}
+	avatarSize := 39.6
+	bubbleMax := 612.0
+	pad := 8.6
+	tail := 10.8
+	rowGap := 10.8
+
+	bubbleParas := func(msg model.Message, scale float64) []para {
+		header := []run{{text: msg.Sender, size: 14 * scale, bold: true, color: textColor}}
+		if msg.IsBot {
+			header = append(header, run{text: "  BOT", size: 10 * scale, bold: true, color: mutedColor})
+		}
+		if msg.SendTime != "" {
+			header = append(header, run{text: "  " + msg.SendTime, size: 11 * scale, color: mutedColor})
+		}
+		paras := []para{{runs: header}}
+		return append(paras, lines(msg.Content, run{size: 16 * scale, color: textColor}, "")...)
+	}
+	// the bubble of a message at a scale, as wide as its longest line, and
+	// its text laid out
+	measure := func(msg model.Message, scale float64) (float64, float64, []textLine) {
+		paras := bubbleParas(msg, scale)
+		var width float64
+		for _, p := range paras {
+			var w float64
+			for _, r := range p.runs {
+				w += d.width(r, r.text)
+			}
+			width = max(width, w)
+		}
+		w := min(bubbleMax, width+2*pad+1)
+		text := d.layout(paras, w-2*pad)
+		h := max(avatarSize, textHeight(text)+2*pad)
+		return w, h, text
+	}
+	scale := 1.0
+	for ; scale > 0.5; scale -= 0.05 {
+		var total float64
+		for _, msg := range c.Messages {
+			_, h, _ := measure(msg, scale)
+			total += h + rowGap
+		}
+		if total-rowGap <= content.h {
+			break
+		}
+	}
+
+	y := content.y
+	for _, msg := range c.Messages {
+		w, h, text := measure(msg, scale)
+		size := avatarSize * max(scale, 0.7)
+		avatar := box{content.x, y, size, size}
+		bubble := box{content.x + size + tail + 7.2, y, w, h}
+		fill := "F3F4F6"
+		if msg.IsMe {
+			avatar.x = content.x + content.w - size
+			bubble.x = avatar.x - tail - 7.2 - w
+			fill = "DBEAFE"
+		}
+		d.avatar(msg, avatar)
+
+		// the tail points at the side of the avatar
+		tipX, edge := avatar.x+size+3.6, bubble.x+1
+		if msg.IsMe {
+			tipX, edge = avatar.x-3.6, bubble.x+bubble.w-1
+		}
+		tipY := avatar.y + size/2
+		d.setFill(fill)
+		d.pdf.Polygon([]fpdf.PointType{{X: edge, Y: tipY - 6}, {X: tipX, Y: tipY}, {X: edge, Y: tipY + 6}}, "F")
+		d.shape(bubble, 8, fill, "", 0)
+		d.drawLines(text, bubble.x+pad, bubble.y+pad, bubble.w-2*pad)
+		y += h + rowGap
+	}
+}
+
+// avatar draws the sender's avatar image clipped to a circle, or their
+// initial on a circle when the avatar is not in the session or not an
+// image.
+func (d *doc) avatar(msg model.Message, b box) {
+	if msg.Avatar != "" {
+		if img := d.image(msg.Avatar); img != nil {
+			d.pdf.ClipEllipse(b.x+b.w/2, b.y+b.h/2, b.w/2, b.h/2, false)
+			// cover the circle, keeping the aspect ratio
+			scale := max(b.w/img.w, b.h/img.h)
+			w, h := img.w*scale, img.h*scale
+			d.pdf.ImageOptions(img.name, b.x+(b.w-w)/2, b.y+(b.h-h)/2, w, h, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+			d.pdf.ClipEnd()
+			return
+		}
+	}
+	initial := "?"
+	for _, r := range msg.Sender {
+		initial = strings.ToUpper(string(r))
+		break
+	}
+	d.setFill("9CA3AF")
+	d.pdf.Ellipse(b.x+b.w/2, b.y+b.h/2, b.w/2, b.h/2, 0, "F")
+	r := run{size: b.h * 0.45, bold: true, color: "FFFFFF"}
+	d.text(r, initial, b.x+(b.w-d.width(r, initial))/2, b.y+b.h/2+r.size*0.35)
+}
+
+// arrow draws a line from (x1, y1) to (x2, y2) with a head at the end.
+func (d *doc) arrow(x1, y1, x2, y2 float64, color string) {
+	length := math.Hypot(x2-x1, y2-y1)
+	if length == 0 {
+		return
+	}
+	ux, uy := (x2-x1)/length, (y2-y1)/length
+	head := 9.0
+	d.setDraw(color, 2)
+	d.pdf.Line(x1, y1, x2-ux*head, y2-uy*head)
+	d.setFill(color)
+	d.pdf.Polygon([]fpdf.PointType{
+		{X: x2, Y: y2},
+		{X: x2 - ux*head - uy*head/2, Y: y2 - uy*head + ux*head/2},
+		{X: x2 - ux*head + uy*head/2, Y: y2 - uy*head - ux*head/2},
+	}, "F")
+}
+
+// withIcon prefixes text with icon when the icon can be shown.
+func (d *doc) withIcon(icon string, text string) string {
+	if !d.visible(icon) {
+		return text
+	}
+	return icon + " " + text
+}
+
+// pxPt converts CSS pixels to points.
+const pxPt = 0.75
+
+func (d *doc) rectangle(c *model.RectangleContent) {
+	w, h := 432.0, 230.4
+	if c.Width > 0 {
+		w = c.Width * pxPt
+	}
+	if c.Height > 0 {
+		h = c.Height * pxPt
+	}
+	b := content.center(min(w, content.w), min(h, content.h))
+
+	p := render.PaletteOf(c.Color)
+	fill, line := p.Tint, p.Accent
+	if hex, ok := render.ParseHex(c.BackgroundColor); ok {
+		fill = hex
+	}
+	if hex, ok := render.ParseHex(c.BorderColor); ok {
+		line = hex
+	}
+	color := textColor
+	if hex, ok := render.ParseHex(c.TextColor); ok {
+		color = hex
+	}
+	d.shape(b, 0.08*min(b.w, b.h), fill, line, 2)
+
+	title := d.withIcon(c.Icon, c.Text)
+	var paras []para
+	if strings.TrimSpace(title) != "" {
+		paras = append(paras, lines(title, run{size: 28, bold: true, color: color}, "ctr")...)
+	}
+	if c.Subtext != "" {
+		sub := lines(c.Subtext, run{size: 18, color: render.Mix(color, fill, 0.3)}, "ctr")
+		sub[0].spaceBefore = 6
+		paras = append(paras, sub...)
+	}
+	for i, item := range c.Items {
+		r := run{text: item.Value, size: 16, bold: item.Bold, color: color}
+		if hex, ok := render.ParseHex(item.Color); ok {
+			r.color = hex
+		}
+		if px, err := strconv.ParseFloat(strings.TrimSuffix(item.Size, "px"), 64); err == nil && px > 0 {
+			r.size = px * pxPt
+		}
+		p := para{runs: []run{r}, align: "ctr"}
+		if i == 0 {
+			p.spaceBefore = 12
+		}
+		paras = append(paras, p)
+	}
+	d.textBox(b.inset(14.4), paras, "ctr")
+}
+
+// connectedRectangles draws the nodes in a row, or a column, with arrows
+// for the edges.
+func (d *doc) connectedRectangles(c *model.ConnectedRectanglesContent) {
+	n := len(c.Nodes)
+	if n == 0 {
+		return
+	}
+	column := c.Layout == "column"
+	arrowGap := 64.8
+	var w, h float64
+	if column {
+		w = 324
+		h = min(93.6, (content.h-arrowGap*float64(n-1))/float64(n))
+	} else {
+		w = min(216, (content.w-arrowGap*float64(n-1))/float64(n))
+		h = 115.2
+	}
+	total := w*float64(n) + arrowGap*float64(n-1)
+	if column {
+		total = h*float64(n) + arrowGap*float64(n-1)
+	}
+
+	boxes := make(map[string]box, n)
+	order := make(map[string]int, n)
+	for i, node := range c.Nodes {
+		var b box
+		if column {
+			b = box{content.x + (content.w-w)/2, content.y + (content.h-total)/2 + float64(i)*(h+arrowGap), w, h}
+		} else {
+			b = box{content.x + (content.w-total)/2 + float64(i)*(w+arrowGap), content.y + (content.h-h)/2, w, h}
+		}
+		boxes[node.ID] = b
+		order[node.ID] = i
+
+		p := render.PaletteOf(node.Color)
+		d.shape(b, 0.17*min(b.w, b.h), p.Tint, p.Accent, 2)
+		paras := lines(d.withIcon(node.Icon, node.Text), run{size: 18, bold: true, color: textColor}, "ctr")
+		if node.Subtext != "" {
+			paras = append(paras, lines(node.Subtext, run{size: 12, color: mutedColor}, "ctr")...)
+		}
+		d.textBox(b.inset(7.2), paras, "ctr")
+	}
+
+	for _, edge := range c.Edges {
+		from, ok1 := boxes[edge.From]
+		to, ok2 := boxes[edge.To]
+		if !ok1 || !ok2 || edge.From == edge.To {
+			continue
+		}
+		forward := order[edge.From] < order[edge.To]
+		var x1, y1, x2, y2 float64
+		switch {
+		case column && forward:
+			x1, y1, x2, y2 = from.x+from.w/2, from.y+from.h, to.x+to.w/2, to.y
+		case column:
+			x1, y1, x2, y2 = from.x+from.w/2, from.y, to.x+to.w/2, to.y+to.h
+		case forward:
+			x1, y1, x2, y2 = from.x+from.w, from.y+from.h/2, to.x, to.y+to.h/2
+		default:
+			x1, y1, x2, y2 = from.x, from.y+from.h/2, to.x+to.w, to.y+to.h/2
+		}
+		d.arrow(x1, y1, x2, y2, "9CA3AF")
+		if edge.Label != "" {
+			midX, midY := (x1+x2)/2, (y1+y2)/2
+			label := box{midX - 72, midY - 32.4, 144, 28.8}
+			align := "ctr"
+			if column {
+				label = box{midX + 7.2, midY - 14.4, 144, 28.8}
+				align = "l"
+			}
+			d.textBox(label, []para{{runs: []run{{text: oneLine(edge.Label), size: 11, color: mutedColor}}, align: align}}, "b")
+		}
+	}
+}
+
+func (d *doc) userFeedback(c *model.UserFeedbackContent) {
+	columns := 2
+	if len(c.Items) == 1 {
+		columns = 1
+	}
+	scale := 1.0
+	if c.FontSizeMultiplier > 0 {
+		scale = c.FontSizeMultiplier
+	}
+	for i, b := range grid(len(c.Items), columns, 187.2) {
+		item := c.Items[i]
+		d.shape(b, 0.06*min(b.w, b.h), "F9FAFB", "E5E7EB", 1)
+		paras := lines("“"+item.Quote+"”", run{size: 18 * scale, italic: true, color: textColor}, "")
+		if item.Author != "" {
+			paras = append(paras, para{runs: []run{{text: "— " + item.Author, size: 14 * scale, color: mutedColor}}, align: "r", spaceBefore: 8})
+		}
+		d.textBox(b.inset(14.4), paras, "ctr")
+	}
+}
+
+// structureBreakdown draws each item with a coloured bar on its left.
+func (d *doc) structureBreakdown(c *model.StructureBreakdownContent) {
+	for i, b := range grid(len(c.Items), c.Columns, 201.6) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		d.shape(box{b.x, b.y, 4.3, b.h}, 0, p.Accent, "", 0)
+
+		paras := lines(item.Title, run{size: 20, bold: true, color: textColor}, "")
+		if item.Description != "" {
+			paras = append(paras, lines(item.Description, run{size: 14, italic: true, color: mutedColor}, "")...)
+		}
+		if item.Content != "" {
+			code := lines(item.Content, run{size: 13, mono: true, color: p.Accent}, "")
+			code[0].spaceBefore = 8
+			paras = append(paras, code...)
+		}
+		d.textBox(box{b.x + 14.4, b.y, b.w - 14.4, b.h}.inset(7.2), paras, "t")
+	}
+}
+
+func (d *doc) stats(c *model.StatsContent) {
+	for i, b := range grid(len(c.Items), c.Columns, 144) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		d.shape(b, 0.08*min(b.w, b.h), p.Tint, "", 0)
+		paras := []para{
+			{runs: []run{{text: oneLine(item.Value), size: 40, bold: true, color: p.Accent}}, align: "ctr"},
+			{runs: []run{{text: oneLine(item.Label), size: 14, color: mutedColor}}, align: "ctr", spaceBefore: 4},
+		}
+		d.textBox(b.inset(10.8), paras, "ctr")
+	}
+}
+
+func (d *doc) numberedList(c *model.NumberedListContent) {
+	for i, b := range grid(len(c.Items), c.Columns, 187.2) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		paras := []para{
+			{runs: []run{{text: oneLine(item.Number), size: 36, color: "B3B3B3"}}},
+			{runs: []run{{text: oneLine(item.Title), size: 20, bold: true, color: p.Accent}}, spaceBefore: 8},
+		}
+		if item.Description != "" {
+			paras = append(paras, lines(item.Description, run{size: 14, color: "4B5563"}, "")...)
+		}
+		d.textBox(b.inset(10.8), paras, "t")
+	}
+}
+
+// conceptCards draws dark cards with a coloured border, as the frontend
+// does.
+func (d *doc) conceptCards(c *model.ConceptCardContent) {
+	for i, b := range grid(len(c.Items), c.Columns, 216) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		d.shape(b, 0.08*min(b.w, b.h), "1F2937", p.Accent, 2)
+		var paras []para
+		if d.visible(item.Icon) {
+			paras = append(paras, para{runs: []run{{text: item.Icon, size: 40, color: "FFFFFF"}}, align: "ctr"})
+		}
+		paras = append(paras, para{runs: []run{{text: oneLine(item.Title), size: 22, bold: true, color: p.Light}}, align: "ctr", spaceBefore: 6})
+		if item.Description != "" {
+			paras = append(paras, lines(item.Description, run{size: 14, color: "D1D5DB"}, "ctr")...)
+		}
+		d.textBox(b.inset(14.4), paras, "ctr")
+	}
+}
+
+// grid splits the content area into cells for n items, columns wide
+// (default 3) and at most maxHeight high, centering the grid vertically.
+func grid(n int, columns int, maxHeight float64) []box {
+	if n == 0 {
+		return nil
+	}
+	if columns <= 0 {
+		columns = 3
+	}
+	columns = min(columns, n)
+	rows := (n + columns - 1) / columns
+	w := (content.w - gap*float64(columns-1)) / float64(columns)
+	h := min(maxHeight, (content.h-gap*float64(rows-1))/float64(rows))
+	top := content.y + (content.h-(h*float64(rows)+gap*float64(rows-1)))/2
+	cells := make([]box, n)
+	for i := range cells {
+		row, col := i/columns, i%columns
+		cells[i] = box{content.x + float64(col)*(w+gap), top + float64(row)*(h+gap), w, h}
+	}
+	return cells
+}
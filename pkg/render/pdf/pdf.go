@@ -0,0 +1,386 @@
+// Package pdf exports a session as a PDF document, one slide-sized page
+// per page, or as handouts with several pages on each sheet.
+//
+// Pages are drawn as vector graphics in the layout of the PowerPoint
+// export: code as highlighted monospaced text with its focus config
+// applied, chat threads as speech bubbles next to the avatars, charts from
+// their data, and the card kinds as grids of shapes.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	// the avatar formats that can be converted for the document
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+// ContentType is the media type of a PDF document.
+const ContentType = "application/pdf"
+
+// Options control the layout of the document.
+type Options struct {
+	// Handout puts that many pages on each A4 sheet, 0 writes one page per
+	// sheet.
+	Handout int
+	// Notes adds ruled lines for notes next to each page of a handout.
+	Notes bool
+	// Font is a TrueType font file for the text the built-in fonts cannot
+	// show; they only cover Western European scripts.
+	Font string
+}
+
+// The slide size in points, that of the PowerPoint export.
+const (
+	slideWidth  = 960.0
+	slideHeight = 540.0
+)
+
+// The handout sheet, A4 portrait.
+const (
+	sheetWidth   = 595.28
+	sheetHeight  = 841.89
+	sheetMargin  = 36.0
+	sheetGap     = 18.0
+	sheetHeader  = 28.0
+	noteSpacing  = 18.0
+	noteFraction = 0.55 // of the sheet width taken by the page next to notes
+)
+
+// MaxHandout is the most pages a handout puts on one sheet.
+const MaxHandout = 16
+
+const (
+	sansFont = "Helvetica"
+	monoFont = "Courier"
+	// userFont is the family of Options.Font
+	userFont = "user"
+)
+
+// doc draws the pages of a deck into a document.
+type doc struct {
+	pdf  *fpdf.Fpdf
+	deck *render.Deck
+	// cp1252 encodes text for the built-in fonts
+	cp1252 func(string) string
+	// hasFont is set when Options.Font was given
+	hasFont bool
+	// font is the font last set, to set it only when it changes
+	font string
+	// avatars maps avatar names to their registered image, nil if they
+	// cannot be shown
+	avatars map[string]*avatarImage
+}
+
+type avatarImage struct {
+	name string
+	w, h float64
+}
+
+// Validate checks the options before anything is written.
+func (o Options) Validate() error {
+	if o.Handout < 0 || o.Handout > MaxHandout {
+		return fmt.Errorf("handout: pages per sheet must be between 1 and %d", MaxHandout)
+	}
+	if o.Notes && o.Handout == 0 {
+		return fmt.Errorf("notes: only handouts have notes")
+	}
+	return nil
+}
+
+// Write writes the deck as a PDF document.
+func Write(out io.Writer, deck *render.Deck, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "pt",
+		Size:    fpdf.SizeType{Wd: slideWidth, Ht: slideHeight},
+	})
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetTitle(deck.Session.Name, true)
+	pdf.SetCreator("presentationer", true)
+	modified := deck.Session.LastModified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	pdf.SetCreationDate(modified)
+	pdf.SetModificationDate(modified)
+
+	d := &doc{
+		pdf:     pdf,
+		deck:    deck,
+		cp1252:  pdf.UnicodeTranslatorFromDescriptor(""),
+		avatars: make(map[string]*avatarImage),
+	}
+	if opts.Font != "" {
+		font, err := os.ReadFile(opts.Font)
+		if err != nil {
+			return err
+		}
+		pdf.AddUTF8FontFromBytes(userFont, "", font)
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("font %s: %w", opts.Font, err)
+		}
+		d.hasFont = true
+	}
+
+	var err error
+	if opts.Handout > 0 {
+		err = d.handout(opts.Handout, opts.Notes)
+	} else {
+		err = d.slides()
+	}
+	if err != nil {
+		return err
+	}
+	return pdf.Output(out)
+}
+
+// slides writes each page on a sheet of its own.
+func (d *doc) slides() error {
+	pages := d.deck.Session.Pages
+	for i := range pages {
+		d.pdf.AddPage()
+		if err := d.page(&pages[i]); err != nil {
+			return fmt.Errorf("page %q: %w", pages[i].Title, err)
+		}
+	}
+	if len(pages) == 0 {
+		// a document needs a page
+		d.pdf.AddPage()
+	}
+	return d.pdf.Error()
+}
+
+// handout writes perSheet pages on each A4 sheet under the session name,
+// in a grid or, with notes, in a column with ruled lines beside it.
+func (d *doc) handout(perSheet int, notes bool) error {
+	pages := d.deck.Session.Pages
+	columns, rows := handoutGrid(perSheet)
+	if notes {
+		columns, rows = 1, perSheet
+	}
+	area := box{sheetMargin, sheetMargin + sheetHeader, sheetWidth - 2*sheetMargin, sheetHeight - 2*sheetMargin - 2*sheetHeader}
+	cellW := (area.w - sheetGap*float64(columns-1)) / float64(columns)
+	cellH := (area.h - sheetGap*float64(rows-1)) / float64(rows)
+	slideW := cellW
+	if notes {
+		slideW = cellW * noteFraction
+	}
+	scale := min(slideW/slideWidth, cellH/slideHeight)
+
+	sheets := max(1, (len(pages)+perSheet-1)/perSheet)
+	for sheet := 0; sheet < sheets; sheet++ {
+		d.pdf.AddPageFormat("P", fpdf.SizeType{Wd: sheetWidth, Ht: sheetHeight})
+		d.text(run{size: 12, bold: true, color: textColor}, d.deck.Session.Name, sheetMargin, sheetMargin+14)
+		footer := strconv.Itoa(sheet+1) + " / " + strconv.Itoa(sheets)
+		footerRun := run{size: 9, color: mutedColor}
+		d.text(footerRun, footer, (sheetWidth-d.width(footerRun, footer))/2, sheetHeight-sheetMargin)
+
+		for cell := 0; cell < perSheet; cell++ {
+			i := sheet*perSheet + cell
+			if i >= len(pages) {
+				break
+			}
+			row, col := cell/columns, cell%columns
+			x := area.x + float64(col)*(cellW+sheetGap)
+			y := area.y + float64(row)*(cellH+sheetGap)
+			w, h := slideWidth*scale, slideHeight*scale
+			if !notes {
+				x += (cellW - w) / 2
+			}
+			y += (cellH - h) / 2
+
+			d.pdf.TransformBegin()
+			d.pdf.TransformTranslate(x, y)
+			d.pdf.TransformScale(scale*100, scale*100, 0, 0)
+			err := d.page(&pages[i])
+			d.pdf.TransformEnd()
+			if err != nil {
+				return fmt.Errorf("page %q: %w", pages[i].Title, err)
+			}
+			d.setDraw("D1D5DB", 0.75)
+			d.pdf.Rect(x, y, w, h, "D")
+
+			if notes {
+				left := x + w + sheetGap
+				d.setDraw("D1D5DB", 0.5)
+				for ly := y + noteSpacing; ly <= y+h+0.01; ly += noteSpacing {
+					d.pdf.Line(left, ly, area.x+area.w, ly)
+				}
+			}
+		}
+	}
+	return d.pdf.Error()
+}
+
+// handoutGrid returns the columns and rows of a sheet of n pages: one
+// column up to three pages, then as square as the portrait sheet allows.
+func handoutGrid(n int) (int, int) {
+	if n <= 3 {
+		return 1, n
+	}
+	columns := int(math.Ceil(math.Sqrt(float64(n))))
+	if n <= 8 {
+		columns = 2
+	}
+	return columns, (n + columns - 1) / columns
+}
+
+// image returns the registered image of an avatar, registering it on
+// first use, or nil when there is no such avatar or it is not an image.
+// Avatars are converted to PNG first, a format the library fully supports.
+func (d *doc) image(name string) *avatarImage {
+	if img, ok := d.avatars[name]; ok {
+		return img
+	}
+	d.avatars[name] = nil
+	data := d.deck.Avatars[name]
+	if data == nil {
+		return nil
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, decoded); err != nil {
+		return nil
+	}
+	img := &avatarImage{name: "avatar:" + name}
+	info := d.pdf.RegisterImageOptionsReader(img.name, fpdf.ImageOptions{ImageType: "PNG"}, &buf)
+	if info == nil || d.pdf.Err() {
+		return nil
+	}
+	img.w, img.h = info.Width(), info.Height()
+	d.avatars[name] = img
+	return img
+}
+
+// use sets the font for r and returns text encoded for it: a built-in
+// font when it can show the text, else the user's font if there is one.
+func (d *doc) use(r run, text string) string {
+	if d.hasFont && !d.builtin(text) {
+		d.setFont(userFont, "", r.size)
+		return dropEmoji(text)
+	}
+	family := sansFont
+	if r.mono {
+		family = monoFont
+	}
+	style := ""
+	if r.bold {
+		style += "B"
+	}
+	if r.italic {
+		style += "I"
+	}
+	d.setFont(family, style, r.size)
+	return d.encode(text)
+}
+
+func (d *doc) setFont(family string, style string, size float64) {
+	font := family + ":" + style + ":" + strconv.FormatFloat(size, 'f', -1, 64)
+	if font != d.font {
+		d.pdf.SetFont(family, style, size)
+		d.font = font
+	}
+}
+
+// builtin reports whether the built-in fonts can show text.
+func (d *doc) builtin(text string) bool {
+	for _, r := range text {
+		if r >= 0x80 && d.cp1252(string(r)) == "." {
+			return false
+		}
+	}
+	return true
+}
+
+// encode encodes text for the built-in fonts, dropping the symbols they
+// cannot show and replacing other characters with a question mark.
+func (d *doc) encode(text string) string {
+	if d.builtin(text) {
+		return d.cp1252(text)
+	}
+	var b []rune
+	for _, r := range text {
+		switch {
+		case r < 0x80 || d.cp1252(string(r)) != ".":
+			b = append(b, r)
+		case isEmoji(r):
+		default:
+			b = append(b, '?')
+		}
+	}
+	return d.cp1252(string(b))
+}
+
+// dropEmoji drops the emoji from text: the library only embeds glyphs of
+// the Basic Multilingual Plane, and few text fonts have the rest.
+func dropEmoji(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xFFFF || r == 0xFE0F || r == 0x200D {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// isEmoji reports whether r is a symbol like an emoji or one of their
+// modifiers.
+func isEmoji(r rune) bool {
+	return r > 0xFFFF || unicode.Is(unicode.So, r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r)
+}
+
+// visible reports whether any of text can be shown.
+func (d *doc) visible(text string) bool {
+	if d.hasFont && !d.builtin(text) {
+		return strings.TrimSpace(dropEmoji(text)) != ""
+	}
+	return strings.TrimSpace(d.encode(text)) != ""
+}
+
+func (d *doc) width(r run, text string) float64 {
+	return d.pdf.GetStringWidth(d.use(r, text))
+}
+
+// text draws text in the style of r with its baseline at y.
+func (d *doc) text(r run, text string, x, y float64) {
+	encoded := d.use(r, text)
+	d.pdf.SetTextColor(rgb(r.color))
+	d.pdf.Text(x, y, encoded)
+}
+
+func (d *doc) setFill(color string) {
+	d.pdf.SetFillColor(rgb(color))
+}
+
+func (d *doc) setDraw(color string, width float64) {
+	d.pdf.SetDrawColor(rgb(color))
+	d.pdf.SetLineWidth(width)
+}
+
+// rgb splits an RRGGBB colour, black when it is not one.
+func rgb(color string) (int, int, int) {
+	c, err := strconv.ParseUint(color, 16, 32)
+	if err != nil || len(color) != 6 {
+		return 0, 0, 0
+	}
+	return int(c >> 16 & 0xff), int(c >> 8 & 0xff), int(c & 0xff)
+}
@@ -0,0 +1,112 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+// samplePages returns a page of every kind, charts of every type.
+func samplePages(t *testing.T) []model.Page {
+	contents := []model.Content{
+		&model.CodeContent{Code: "package main\n\nfunc main() {\n\tprintln(\"<&>\")\n}\n", Language: "go",
+			ConfigList: []model.FocusConfig{{ID: "f", Lines: "3-4"}}, SelectedConfigID: "f"},
+		&model.CodeDiffContent{OldCode: "a\nb := 1\nc\n", NewCode: "a\nb := 2\nc\nd\n", Language: "go", Path: "main.go"},
+		&model.ChatThreadContent{Messages: []model.Message{
+			{Sender: "Alice", Content: "hi there", SendTime: "10:02", Avatar: "alice.png"},
+			{Sender: "Bob", Content: "héllo & welcome", SendTime: "10:03", IsMe: true},
+		}},
+		&model.ChartContent{ChartType: model.ChartTypeBar, Items: []model.ChartItem{{Name: "a", Value: 1}, {Name: "b", Value: 2}}},
+		&model.ChartContent{ChartType: model.ChartTypeLine, Items: []model.ChartItem{{Name: "a", Value: 1}, {Name: "b", Value: 2}}},
+		&model.ChartContent{ChartType: model.ChartTypePie, Items: []model.ChartItem{{Name: "a", Value: 1}, {Name: "b", Value: 2}}},
+		&model.RectangleContent{Text: "Title", Subtext: "sub", Items: []model.RectangleItem{{Value: "item"}}},
+		&model.ConnectedRectanglesContent{
+			Nodes: []model.CRNode{{ID: "a", Text: "A"}, {ID: "b", Text: "B"}},
+			Edges: []model.CREdge{{From: "a", To: "b", Label: "calls"}},
+		},
+		&model.UserFeedbackContent{Items: []model.UserFeedbackItem{{Quote: "Great", Author: "Carol"}}},
+		&model.StructureBreakdownContent{Items: []model.StructureItem{{Title: "Layer", Description: "d", Content: "c"}}},
+		&model.StatsContent{Items: []model.StatItem{{Value: "99%", Label: "uptime"}}},
+		&model.NumberedListContent{Items: []model.NumberedItem{{Number: "1", Title: "First", Description: "d"}}},
+		&model.ConceptCardContent{Items: []model.ConceptItem{{Icon: "💡", Title: "Idea", Description: "d"}}},
+	}
+	kinds := make(map[model.PageKind]bool)
+	var pages []model.Page
+	for i, c := range contents {
+		page := model.Page{ID: fmt.Sprintf("p%d", i), Title: string(c.Kind())}
+		if err := page.EncodeContent(c); err != nil {
+			t.Fatal(err)
+		}
+		kinds[c.Kind()] = true
+		pages = append(pages, page)
+	}
+	for _, kind := range model.PageKinds {
+		if !kinds[kind] {
+			t.Fatalf("no sample page of kind %s", kind)
+		}
+	}
+	return pages
+}
+
+func samplePNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestWrite writes a deck of every page kind as slides and as handouts
+// and checks that a PDF document with the expected number of sheets
+// comes out.
+func TestWrite(t *testing.T) {
+	deck := &render.Deck{
+		Session: &model.Session{Name: "deck", Pages: samplePages(t)},
+		Avatars: map[string][]byte{"alice.png": samplePNG(t)},
+	}
+	n := len(deck.Session.Pages)
+	tests := []struct {
+		name   string
+		opts   Options
+		sheets int
+	}{
+		{"slides", Options{}, n},
+		{"handout", Options{Handout: 6}, (n + 5) / 6},
+		{"notes", Options{Handout: 3, Notes: true}, (n + 2) / 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, deck, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.Bytes()
+			if !bytes.HasPrefix(out, []byte("%PDF-")) {
+				t.Fatalf("output starts with %q, want %%PDF-", out[:min(len(out), 8)])
+			}
+			if got := bytes.Count(out, []byte("/Type /Page\n")); got != tt.sheets {
+				t.Errorf("got %d sheets, want %d", got, tt.sheets)
+			}
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	for _, opts := range []Options{
+		{Handout: -1},
+		{Handout: MaxHandout + 1},
+		{Notes: true},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%+v: want error", opts)
+		}
+		if err := Write(new(bytes.Buffer), &render.Deck{Session: &model.Session{}}, opts); err == nil {
+			t.Errorf("%+v: Write wants error", opts)
+		}
+	}
+}
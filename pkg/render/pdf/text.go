@@ -0,0 +1,229 @@
+package pdf
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// box is a rectangle in points from the top left.
+type box struct {
+	x, y, w, h float64
+}
+
+func (b box) inset(d float64) box {
+	return box{b.x + d, b.y + d, b.w - 2*d, b.h - 2*d}
+}
+
+// center returns a box of w by h centered in b.
+func (b box) center(w, h float64) box {
+	return box{b.x + (b.w-w)/2, b.y + (b.h-h)/2, w, h}
+}
+
+// run is text in one style.
+type run struct {
+	text   string
+	size   float64 // points
+	bold   bool
+	italic bool
+	mono   bool
+	color  string // RRGGBB
+}
+
+// para is a paragraph of runs, aligned "l", "ctr" or "r" ("" is left).
+type para struct {
+	runs        []run
+	align       string
+	spaceBefore float64 // points
+}
+
+// lines returns a paragraph per line of text, all in the style of r.
+func lines(text string, r run, align string) []para {
+	var paras []para
+	for _, line := range strings.Split(text, "\n") {
+		lr := r
+		lr.text = line
+		paras = append(paras, para{runs: []run{lr}, align: align})
+	}
+	return paras
+}
+
+// lineHeight is the height of a line of text relative to its size.
+const lineHeight = 1.25
+
+// textLine is a laid out line of text.
+type textLine struct {
+	pieces      []piece
+	width       float64
+	size        float64
+	align       string
+	spaceBefore float64
+}
+
+type piece struct {
+	run   run
+	text  string
+	width float64
+}
+
+// layout wraps the paragraphs to width, breaking lines between words, or
+// anywhere in words longer than a line.
+func (d *doc) layout(paras []para, width float64) []textLine {
+	var out []textLine
+	for _, p := range paras {
+		cur := textLine{align: p.align, spaceBefore: p.spaceBefore}
+		flush := func() {
+			// trailing spaces take no room
+			if n := len(cur.pieces); n > 0 {
+				last := &cur.pieces[n-1]
+				trimmed := strings.TrimRight(last.text, " ")
+				if trimmed != last.text {
+					cur.width -= last.width
+					last.text = trimmed
+					last.width = d.width(last.run, trimmed)
+					cur.width += last.width
+				}
+			}
+			out = append(out, cur)
+			cur = textLine{align: p.align, size: cur.size}
+		}
+		for _, r := range p.runs {
+			cur.size = max(cur.size, r.size)
+			for _, word := range words(r.text) {
+				w := d.width(r, word)
+				fits := cur.width+d.width(r, strings.TrimRight(word, " ")) <= width
+				if !fits && len(cur.pieces) > 0 {
+					flush()
+					cur.size = r.size
+				}
+				for w > width && utf8.RuneCountInString(word) > 1 {
+					// break the word where the line is full
+					head := breakWord(word, func(s string) bool { return d.width(r, s) <= width })
+					cur.pieces = append(cur.pieces, piece{run: r, text: head, width: d.width(r, head)})
+					cur.width += cur.pieces[len(cur.pieces)-1].width
+					flush()
+					cur.size = r.size
+					word = word[len(head):]
+					w = d.width(r, word)
+				}
+				cur.pieces = append(cur.pieces, piece{run: r, text: word, width: w})
+				cur.width += w
+			}
+		}
+		flush()
+	}
+	return out
+}
+
+// words splits s after runs of spaces, and around wide characters, which
+// lines may break between.
+func words(s string) []string {
+	var out []string
+	start := 0
+	for i, r := range s {
+		switch {
+		case r >= 0x2E80:
+			if i > start {
+				out = append(out, s[start:i])
+			}
+			out = append(out, s[i:i+utf8.RuneLen(r)])
+			start = i + utf8.RuneLen(r)
+		case r != ' ' && i > start && s[i-1] == ' ':
+			out = append(out, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) || len(out) == 0 {
+		out = append(out, s[start:])
+	}
+	return out
+}
+
+// breakWord returns the longest prefix of word, at least one character,
+// for which fits holds.
+func breakWord(word string, fits func(string) bool) string {
+	_, end := utf8.DecodeRuneInString(word)
+	for i := range word {
+		if i <= end {
+			continue
+		}
+		if !fits(word[:i]) {
+			break
+		}
+		end = i
+	}
+	return word[:end]
+}
+
+func textHeight(lines []textLine) float64 {
+	var h float64
+	for _, l := range lines {
+		h += l.spaceBefore + l.size*lineHeight
+	}
+	return h
+}
+
+// fit lays out the paragraphs in a box of w by h, scaling the text down
+// until it fits, but not below minScale.
+func (d *doc) fit(paras []para, w, h float64, minScale float64) []textLine {
+	for scale := 1.0; ; scale -= 0.05 {
+		if scale < minScale {
+			scale = minScale
+		}
+		scaled := make([]para, len(paras))
+		for i, p := range paras {
+			p.spaceBefore *= scale
+			p.runs = append([]run(nil), p.runs...)
+			for j := range p.runs {
+				p.runs[j].size *= scale
+			}
+			scaled[i] = p
+		}
+		lines := d.layout(scaled, w)
+		if textHeight(lines) <= h || scale <= minScale {
+			return lines
+		}
+	}
+}
+
+// textBox fits the paragraphs in b and draws them anchored at the top
+// ("t"), middle ("ctr") or bottom ("b").
+func (d *doc) textBox(b box, paras []para, anchor string) {
+	lines := d.fit(paras, b.w, b.h, 0.4)
+	y := b.y
+	switch anchor {
+	case "ctr":
+		y += (b.h - textHeight(lines)) / 2
+	case "b":
+		y += b.h - textHeight(lines)
+	}
+	d.drawLines(lines, b.x, y, b.w)
+}
+
+// drawLines draws laid out lines from y down, aligned in a column of
+// width w at x.
+func (d *doc) drawLines(lines []textLine, x, y, w float64) {
+	for _, l := range lines {
+		y += l.spaceBefore
+		lx := x
+		switch l.align {
+		case "ctr":
+			lx += (w - l.width) / 2
+		case "r":
+			lx += w - l.width
+		}
+		// the baseline, leaving room for descenders below
+		base := y + l.size*(lineHeight-0.3)
+		for _, p := range l.pieces {
+			if p.text != "" {
+				d.text(p.run, p.text, lx, base)
+			}
+			lx += p.width
+		}
+		y += l.size * lineHeight
+	}
+}
+
+// oneLine joins the lines of s for places that must stay on one line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
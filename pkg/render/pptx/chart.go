@@ -0,0 +1,165 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+// addChart adds the chart part for c, with the data in an embedded
+// workbook so it can be edited in PowerPoint, and returns its target
+// relative to a slide.
+func (w *writer) addChart(c *model.ChartContent) (string, error) {
+	items := c.Items
+	chartType := c.ChartType
+	if chartType == "" {
+		chartType = model.ChartTypeBar
+	}
+	if chartType == model.ChartTypePie {
+		// largest slice first, as in the frontend
+		items = append([]model.ChartItem(nil), items...)
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Value > items[j].Value })
+	}
+
+	workbook, err := chartWorkbook(items)
+	if err != nil {
+		return "", err
+	}
+	w.charts++
+	n := w.charts
+	w.defaults["xlsx"] = ctXLSX
+	w.add(fmt.Sprintf("ppt/embeddings/Workbook%d.xlsx", n), "", workbook)
+	w.add(fmt.Sprintf("ppt/charts/chart%d.xml", n), ctChart, chartXML(chartType, items))
+	w.add(fmt.Sprintf("ppt/charts/_rels/chart%d.xml.rels", n), "", relsXML(
+		relationship{id: "rId1", typ: relPackage, target: fmt.Sprintf("../embeddings/Workbook%d.xlsx", n)},
+	))
+	return fmt.Sprintf("../charts/chart%d.xml", n), nil
+}
+
+func chartXML(chartType model.ChartType, items []model.ChartItem) []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<c:chartSpace xmlns:c="%s" xmlns:a="%s" xmlns:r="%s">`, nsChart, nsMain, nsRel)
+	b.WriteString(`<c:roundedCorners val="0"/><c:chart><c:autoTitleDeleted val="1"/><c:plotArea><c:layout/>`)
+
+	switch chartType {
+	case model.ChartTypePie:
+		b.WriteString(`<c:pieChart><c:varyColors val="1"/>`)
+		writeSeries(&b, items, func() {
+			for i, item := range items {
+				color := render.ChartColors[i%len(render.ChartColors)]
+				if hex, ok := render.ParseHex(item.Color); ok {
+					color = hex
+				}
+				fmt.Fprintf(&b, `<c:dPt><c:idx val="%d"/><c:bubble3D val="0"/><c:spPr>`, i)
+				writeFill(&b, color)
+				b.WriteString(`</c:spPr></c:dPt>`)
+			}
+			b.WriteString(`<c:dLbls><c:showLegendKey val="0"/><c:showVal val="0"/><c:showCatName val="1"/><c:showSerName val="0"/><c:showPercent val="1"/><c:showBubbleSize val="0"/><c:separator>: </c:separator><c:showLeaderLines val="1"/></c:dLbls>`)
+		}, "")
+		b.WriteString(`<c:firstSliceAng val="0"/></c:pieChart>`)
+	case model.ChartTypeLine:
+		b.WriteString(`<c:lineChart><c:grouping val="standard"/><c:varyColors val="0"/>`)
+		writeSeries(&b, items, func() {
+			fmt.Fprintf(&b, `<c:spPr><a:ln w="28575"><a:solidFill><a:srgbClr val="%s"/></a:solidFill></a:ln></c:spPr>`, render.ChartColors[0])
+			b.WriteString(`<c:marker><c:symbol val="circle"/><c:size val="6"/></c:marker>`)
+		}, `<c:smooth val="1"/>`)
+		b.WriteString(`<c:marker val="1"/><c:axId val="1"/><c:axId val="2"/></c:lineChart>`)
+		b.WriteString(axesXML)
+	default:
+		b.WriteString(`<c:barChart><c:barDir val="col"/><c:grouping val="clustered"/><c:varyColors val="0"/>`)
+		writeSeries(&b, items, func() {
+			b.WriteString(`<c:spPr>`)
+			writeFill(&b, render.ChartColors[0])
+			b.WriteString(`</c:spPr><c:invertIfNegative val="0"/>`)
+		}, "")
+		b.WriteString(`<c:gapWidth val="80"/><c:axId val="1"/><c:axId val="2"/></c:barChart>`)
+		b.WriteString(axesXML)
+	}
+
+	b.WriteString(`</c:plotArea>`)
+	b.WriteString(`<c:legend><c:legendPos val="b"/><c:overlay val="0"/></c:legend>`)
+	b.WriteString(`<c:plotVisOnly val="1"/><c:dispBlanksAs val="gap"/></c:chart>`)
+	b.WriteString(`<c:txPr><a:bodyPr/><a:lstStyle/><a:p><a:pPr><a:defRPr sz="1400"/></a:pPr><a:endParaRPr lang="en-US"/></a:p></c:txPr>`)
+	b.WriteString(`<c:externalData r:id="rId1"><c:autoUpdate val="0"/></c:externalData>`)
+	b.WriteString(`</c:chartSpace>`)
+	return b.Bytes()
+}
+
+// writeSeries writes the single series of a chart, referring to the
+// embedded workbook and caching its values. style writes the elements
+// between the series name and its categories; tail those after the values.
+func writeSeries(b *bytes.Buffer, items []model.ChartItem, style func(), tail string) {
+	last := len(items) + 1
+	b.WriteString(`<c:ser><c:idx val="0"/><c:order val="0"/>`)
+	b.WriteString(`<c:tx><c:strRef><c:f>Sheet1!$B$1</c:f><c:strCache><c:ptCount val="1"/><c:pt idx="0"><c:v>Value</c:v></c:pt></c:strCache></c:strRef></c:tx>`)
+	style()
+	fmt.Fprintf(b, `<c:cat><c:strRef><c:f>Sheet1!$A$2:$A$%d</c:f><c:strCache><c:ptCount val="%d"/>`, last, len(items))
+	for i, item := range items {
+		fmt.Fprintf(b, `<c:pt idx="%d"><c:v>%s</c:v></c:pt>`, i, escape(item.Name))
+	}
+	b.WriteString(`</c:strCache></c:strRef></c:cat>`)
+	fmt.Fprintf(b, `<c:val><c:numRef><c:f>Sheet1!$B$2:$B$%d</c:f><c:numCache><c:formatCode>General</c:formatCode><c:ptCount val="%d"/>`, last, len(items))
+	for i, item := range items {
+		fmt.Fprintf(b, `<c:pt idx="%d"><c:v>%s</c:v></c:pt>`, i, formatNumber(item.Value))
+	}
+	b.WriteString(`</c:numCache></c:numRef></c:val>`)
+	b.WriteString(tail)
+	b.WriteString(`</c:ser>`)
+}
+
+const axesXML = `<c:catAx><c:axId val="1"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="b"/>` +
+	`<c:numFmt formatCode="General" sourceLinked="0"/><c:majorTickMark val="out"/><c:minorTickMark val="none"/><c:tickLblPos val="nextTo"/>` +
+	`<c:crossAx val="2"/><c:crosses val="autoZero"/><c:auto val="1"/><c:lblAlgn val="ctr"/><c:lblOffset val="100"/><c:noMultiLvlLbl val="0"/></c:catAx>` +
+	`<c:valAx><c:axId val="2"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="l"/>` +
+	`<c:majorGridlines><c:spPr><a:ln w="9525"><a:solidFill><a:srgbClr val="E5E7EB"/></a:solidFill><a:prstDash val="dash"/></a:ln></c:spPr></c:majorGridlines>` +
+	`<c:numFmt formatCode="General" sourceLinked="0"/><c:majorTickMark val="out"/><c:minorTickMark val="none"/><c:tickLblPos val="nextTo"/>` +
+	`<c:crossAx val="1"/><c:crosses val="autoZero"/><c:crossBetween val="between"/></c:valAx>`
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// chartWorkbook returns an .xlsx file with the names in column A and the
+// values in column B, under a header row.
+func chartWorkbook(items []model.ChartItem) ([]byte, error) {
+	var sheet bytes.Buffer
+	sheet.WriteString(xmlHeader)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	sheet.WriteString(`<row r="1"><c r="A1" t="inlineStr"><is><t>Name</t></is></c><c r="B1" t="inlineStr"><is><t>Value</t></is></c></row>`)
+	for i, item := range items {
+		r := i + 2
+		fmt.Fprintf(&sheet, `<row r="%d"><c r="A%d" t="inlineStr"><is><t>%s</t></is></c><c r="B%d"><v>%s</v></c></row>`, r, r, escape(item.Name), r, formatNumber(item.Value))
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []part{
+		{name: "[Content_Types].xml", data: []byte(xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`)},
+		{name: "_rels/.rels", data: relsXML(relationship{id: "rId1", typ: nsRel + "/officeDocument", target: "xl/workbook.xml"})},
+		{name: "xl/workbook.xml", data: []byte(xmlHeader + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="` + nsRel + `">` +
+			`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`)},
+		{name: "xl/_rels/workbook.xml.rels", data: relsXML(relationship{id: "rId1", typ: nsRel + "/worksheet", target: "worksheets/sheet1.xml"})},
+		{name: "xl/worksheets/sheet1.xml", data: sheet.Bytes()},
+	}
+	for _, f := range files {
+		if err := writeZipEntry(zw, f.name, f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,540 @@
+package pptx
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/highlight"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+var (
+	margin = inches(0.5)
+	// content is the area below the page title
+	content = box{margin, inches(1.3), slideWidth - 2*margin, slideHeight - inches(1.3) - inches(0.4)}
+	gap     = inches(0.3)
+)
+
+const (
+	textColor  = "1F2937"
+	mutedColor = "6B7280"
+	codeFont   = "Consolas"
+)
+
+// page lays out a page on the slide: its title and the content below.
+func (s *slide) page(page *model.Page) error {
+	if page.Title != "" {
+		title := para{runs: []run{{text: oneLine(page.Title), size: 28, bold: true, color: textColor}}}
+		s.text("Title", box{margin, inches(0.35), content.w, inches(0.8)}, shapeStyle{anchor: "b"}, []para{title})
+	}
+	c, err := page.DecodeContent()
+	if err != nil {
+		return err
+	}
+	switch c := c.(type) {
+	case *model.CodeContent:
+		if err := s.code(c); err != nil {
+			return err
+		}
+	case *model.CodeDiffContent:
+		if err := s.codeDiff(c); err != nil {
+			return err
+		}
+	case *model.ChatThreadContent:
+		s.chatThread(c)
+	case *model.ChartContent:
+		target, err := s.w.addChart(c)
+		if err != nil {
+			return err
+		}
+		s.chart("Chart", content, s.addRel(relChart, target))
+	case *model.RectangleContent:
+		s.rectangle(c)
+	case *model.ConnectedRectanglesContent:
+		s.connectedRectangles(c)
+	case *model.UserFeedbackContent:
+		s.userFeedback(c)
+	case *model.StructureBreakdownContent:
+		s.structureBreakdown(c)
+	case *model.StatsContent:
+		s.stats(c)
+	case *model.NumberedListContent:
+		s.numberedList(c)
+	case *model.ConceptCardContent:
+		s.conceptCards(c)
+	}
+	return nil
+}
+
+// code sets the code in a monospaced font, coloured by token, on a panel
+// in the style's background; lines outside the selected focus are faded
+// and the text matches of the focus shown in bold white.
+func (s *slide) code(c *model.CodeContent) error {
+	code, err := highlight.Highlight(c.Code, c.Language, "")
+	if err != nil {
+		return err
+	}
+	if cfg := c.SelectedConfig(); cfg != nil {
+		code.Focus(highlight.ParseFocus(cfg.Lines))
+	}
+
+	// size the font so the longest line and all lines fit
+	pad := inches(0.25)
+	inner := content.inset(pad)
+	size := min(20,
+		float64(inner.h)/emuPerPt/(1.2*float64(max(len(code.Lines), 1))),
+		float64(inner.w)/emuPerPt/(0.6*float64(code.Width())))
+	size = max(6, math.Floor(size*2)/2)
+
+	paras := make([]para, 0, len(code.Lines))
+	for _, line := range code.Lines {
+		var runs []run
+		for _, tok := range line.Tokens {
+			r := run{text: tok.Text, size: size, bold: tok.Bold, italic: tok.Italic, color: tok.Color, font: codeFont}
+			if tok.Marked {
+				r.bold, r.color = true, "FFFFFF"
+			}
+			if !line.Focused {
+				r.color = render.Mix(r.color, code.Background, 0.6)
+			}
+			runs = append(runs, r)
+		}
+		if len(runs) == 0 {
+			runs = []run{{size: size, font: codeFont}}
+		}
+		paras = append(paras, para{runs: runs})
+	}
+	s.text("Code", content, shapeStyle{geom: "roundRect", adjust: map[string]int64{"adj": 2500}, fill: code.Background, pad: pad}, paras)
+	return nil
+}
+
+// codeDiff sets the hunks of a code diff like code, each line after a
+// "-" or "+" in red or green for the lines deleted and inserted, with the
+// text of changed lines on a tint of that colour and the text that changed
+// within them on a stronger one.
+func (s *slide) codeDiff(c *model.CodeDiffContent) error {
+	d, err := render.HighlightDiff(c)
+	if err != nil {
+		return err
+	}
+	var header []para
+	if c.Path != "" {
+		header = append(header, para{runs: []run{{text: c.Path, size: 12, color: mutedColor, font: codeFont}}})
+	}
+	if len(d.Hunks) == 0 {
+		header = append(header, para{runs: []run{{text: "No changes.", size: 16, color: mutedColor}}})
+		s.text("Code Diff", content, shapeStyle{}, header)
+		return nil
+	}
+
+	// the path goes above the panel
+	panel := content
+	if c.Path != "" {
+		pathH := inches(0.35)
+		s.text("Path", box{content.x, content.y, content.w, pathH}, shapeStyle{pad: 1, anchor: "ctr"}, header)
+		panel = box{content.x, content.y + pathH, content.w, content.h - pathH}
+	}
+
+	// hunk headers take a line each, except a single hunk's
+	n := d.Lines()
+	if len(d.Hunks) > 1 {
+		n += len(d.Hunks)
+	}
+	pad := inches(0.25)
+	inner := panel.inset(pad)
+	size := min(20,
+		float64(inner.h)/emuPerPt/(1.2*float64(max(n, 1))),
+		float64(inner.w)/emuPerPt/(0.6*float64(d.Width()+2)))
+	size = max(6, math.Floor(size*2)/2)
+
+	paras := make([]para, 0, n)
+	for _, h := range d.Hunks {
+		if len(d.Hunks) > 1 {
+			paras = append(paras, para{runs: []run{{text: h.Header, size: size, color: render.Mix(d.Foreground, d.Background, 0.5), font: codeFont}}})
+		}
+		for _, line := range h.Lines {
+			sign, signColor, tint, strong := "  ", d.Foreground, "", ""
+			switch line.Kind {
+			case diff.Delete:
+				sign, signColor = "- ", render.DeleteColor
+				tint, strong = render.Mix(d.Background, render.DeleteColor, 0.25), render.Mix(d.Background, render.DeleteColor, 0.55)
+			case diff.Insert:
+				sign, signColor = "+ ", render.InsertColor
+				tint, strong = render.Mix(d.Background, render.InsertColor, 0.25), render.Mix(d.Background, render.InsertColor, 0.55)
+			}
+			runs := []run{{text: sign, size: size, bold: true, color: signColor, font: codeFont}}
+			for _, tok := range line.Tokens {
+				r := run{text: tok.Text, size: size, bold: tok.Bold, italic: tok.Italic, color: tok.Color, font: codeFont, highlight: tint}
+				if tok.Marked {
+					r.highlight = strong
+				}
+				runs = append(runs, r)
+			}
+			paras = append(paras, para{runs: runs})
+		}
+	}
+	s.text("Code Diff", panel, shapeStyle{geom: "roundRect", adjust: map[string]int64{"adj": 2500}, fill: d.Background, pad: pad}, paras)
+	return nil
+}
+
+// chatThread draws the messages top to bottom as speech bubbles next to
+// the sender's avatar, on the right for the viewer's own messages.
+func (s *slide) chatThread(c *model.ChatThreadContent) {
+	if len(c.Messages) == 0 {
+		return
+	}
+	avatarSize := inches(0.55)
+	bubbleMax := inches(8.5)
+	pad := inches(0.12)
+	tail := inches(0.15)
+	rowGap := inches(0.15)
+
+	bubbleParas := func(msg model.Message, scale float64) []para {
+		header := []run{{text: msg.Sender, size: 14 * scale, bold: true, color: textColor}}
+		if msg.IsBot {
+			header = append(header, run{text: "  BOT", size: 10 * scale, bold: true, color: mutedColor})
+		}
+		if msg.SendTime != "" {
+			header = append(header, run{text: "  " + msg.SendTime, size: 11 * scale, color: mutedColor})
+		}
+		paras := []para{{runs: header}}
+		return append(paras, lines(msg.Content, run{size: 16 * scale, color: textColor}, "")...)
+	}
+	// the bubble of a message at a scale: as wide as its longest line
+	measure := func(msg model.Message, scale float64) (int64, int64) {
+		paras := bubbleParas(msg, scale)
+		var width float64
+		for _, p := range paras {
+			var w float64
+			for _, r := range p.runs {
+				w += textWidth(r.text, r.size)
+			}
+			width = max(width, w)
+		}
+		w := min(bubbleMax, int64(width)+2*pad+inches(0.1))
+		h := max(avatarSize, textHeight(paras, w-2*pad, 1)+2*pad+inches(0.05))
+		return w, h
+	}
+	scale := 1.0
+	for ; scale > 0.5; scale -= 0.05 {
+		var total int64
+		for _, msg := range c.Messages {
+			_, h := measure(msg, scale)
+			total += h + rowGap
+		}
+		if total-rowGap <= content.h {
+			break
+		}
+	}
+
+	y := content.y
+	for _, msg := range c.Messages {
+		w, h := measure(msg, scale)
+		size := int64(float64(avatarSize) * max(scale, 0.7))
+		avatar := box{content.x, y, size, size}
+		bubble := box{content.x + size + tail + inches(0.1), y, w, h}
+		fill := "F3F4F6"
+		if msg.IsMe {
+			avatar.x = content.x + content.w - size
+			bubble.x = avatar.x - tail - inches(0.1) - w
+			fill = "DBEAFE"
+		}
+		s.avatar(msg, avatar)
+
+		// the tail points at the side of the avatar
+		tipX := avatar.x + size
+		if msg.IsMe {
+			tipX = avatar.x
+		}
+		tipY := avatar.y + size/2
+		adjust := map[string]int64{
+			"adj1": (tipX - bubble.x - w/2) * 100000 / w,
+			"adj2": (tipY - bubble.y - h/2) * 100000 / h,
+			"adj3": 16667,
+		}
+		s.text("Message", bubble, shapeStyle{geom: "wedgeRoundRectCallout", adjust: adjust, fill: fill, pad: pad}, bubbleParas(msg, scale))
+		y += h + rowGap
+	}
+}
+
+// avatar draws the sender's avatar image, or their initial on a circle
+// when the avatar is not in the session or not an image PowerPoint shows.
+func (s *slide) avatar(msg model.Message, b box) {
+	if msg.Avatar != "" {
+		if part := s.w.avatar(msg.Avatar); part != "" {
+			s.picture("Avatar", b, s.addRel(relImage, "../"+strings.TrimPrefix(part, "ppt/")), "ellipse")
+			return
+		}
+	}
+	initial := "?"
+	for _, r := range msg.Sender {
+		initial = strings.ToUpper(string(r))
+		break
+	}
+	s.text("Avatar", b, shapeStyle{geom: "ellipse", fill: "9CA3AF", anchor: "ctr", pad: 1}, []para{{
+		runs:  []run{{text: initial, size: float64(b.h) / emuPerPt * 0.45, bold: true, color: "FFFFFF"}},
+		align: "ctr",
+	}})
+}
+
+// pxEMU converts CSS pixels to EMU.
+const pxEMU = 9525
+
+func (s *slide) rectangle(c *model.RectangleContent) {
+	w, h := inches(6), inches(3.2)
+	if c.Width > 0 {
+		w = int64(c.Width * pxEMU)
+	}
+	if c.Height > 0 {
+		h = int64(c.Height * pxEMU)
+	}
+	b := content.center(min(w, content.w), min(h, content.h))
+
+	style := shapeStyle{geom: "roundRect", adjust: map[string]int64{"adj": 8000}, anchor: "ctr", lineW: 2, pad: inches(0.2)}
+	p := render.PaletteOf(c.Color)
+	style.fill = p.Tint
+	style.line = p.Accent
+	if hex, ok := render.ParseHex(c.BackgroundColor); ok {
+		style.fill = hex
+	}
+	if hex, ok := render.ParseHex(c.BorderColor); ok {
+		style.line = hex
+	}
+	color := textColor
+	if hex, ok := render.ParseHex(c.TextColor); ok {
+		color = hex
+	}
+
+	title := c.Text
+	if c.Icon != "" {
+		title = c.Icon + " " + title
+	}
+	var paras []para
+	if strings.TrimSpace(title) != "" {
+		paras = append(paras, lines(title, run{size: 28, bold: true, color: color}, "ctr")...)
+	}
+	if c.Subtext != "" {
+		sub := lines(c.Subtext, run{size: 18, color: render.Mix(color, style.fill, 0.3)}, "ctr")
+		sub[0].spaceBefore = 6
+		paras = append(paras, sub...)
+	}
+	for i, item := range c.Items {
+		r := run{text: item.Value, size: 16, bold: item.Bold, color: color}
+		if hex, ok := render.ParseHex(item.Color); ok {
+			r.color = hex
+		}
+		if px, err := strconv.ParseFloat(strings.TrimSuffix(item.Size, "px"), 64); err == nil && px > 0 {
+			// CSS pixels are three quarters of a point
+			r.size = px * 0.75
+		}
+		p := para{runs: []run{r}, align: "ctr"}
+		if i == 0 {
+			p.spaceBefore = 12
+		}
+		paras = append(paras, p)
+	}
+	inner := b.inset(style.pad)
+	s.text("Rectangle", b, style, fit(paras, inner.w, inner.h, 0.4))
+}
+
+// connectedRectangles draws the nodes in a row, or a column, with arrows
+// for the edges.
+func (s *slide) connectedRectangles(c *model.ConnectedRectanglesContent) {
+	n := len(c.Nodes)
+	if n == 0 {
+		return
+	}
+	column := c.Layout == "column"
+	arrowGap := inches(0.9)
+	var w, h int64
+	if column {
+		w = inches(4.5)
+		h = min(inches(1.3), (content.h-arrowGap*int64(n-1))/int64(n))
+	} else {
+		w = min(inches(3), (content.w-arrowGap*int64(n-1))/int64(n))
+		h = inches(1.6)
+	}
+	total := w*int64(n) + arrowGap*int64(n-1)
+	if column {
+		total = h*int64(n) + arrowGap*int64(n-1)
+	}
+
+	boxes := make(map[string]box, n)
+	order := make(map[string]int, n)
+	for i, node := range c.Nodes {
+		var b box
+		if column {
+			b = box{content.x + (content.w-w)/2, content.y + (content.h-total)/2 + int64(i)*(h+arrowGap), w, h}
+		} else {
+			b = box{content.x + (content.w-total)/2 + int64(i)*(w+arrowGap), content.y + (content.h-h)/2, w, h}
+		}
+		boxes[node.ID] = b
+		order[node.ID] = i
+
+		p := render.PaletteOf(node.Color)
+		text := node.Text
+		if node.Icon != "" {
+			text = node.Icon + " " + text
+		}
+		paras := lines(text, run{size: 18, bold: true, color: textColor}, "ctr")
+		if node.Subtext != "" {
+			paras = append(paras, lines(node.Subtext, run{size: 12, color: mutedColor}, "ctr")...)
+		}
+		style := shapeStyle{geom: "roundRect", fill: p.Tint, line: p.Accent, lineW: 2, anchor: "ctr"}
+		s.text("Node", b, style, fit(paras, b.w-inches(0.2), b.h-inches(0.2), 0.4))
+	}
+
+	for _, edge := range c.Edges {
+		from, ok1 := boxes[edge.From]
+		to, ok2 := boxes[edge.To]
+		if !ok1 || !ok2 || edge.From == edge.To {
+			continue
+		}
+		forward := order[edge.From] < order[edge.To]
+		var x1, y1, x2, y2 int64
+		switch {
+		case column && forward:
+			x1, y1, x2, y2 = from.x+from.w/2, from.y+from.h, to.x+to.w/2, to.y
+		case column:
+			x1, y1, x2, y2 = from.x+from.w/2, from.y, to.x+to.w/2, to.y+to.h
+		case forward:
+			x1, y1, x2, y2 = from.x+from.w, from.y+from.h/2, to.x, to.y+to.h/2
+		default:
+			x1, y1, x2, y2 = from.x, from.y+from.h/2, to.x+to.w, to.y+to.h/2
+		}
+		s.arrow("Edge", x1, y1, x2, y2, "9CA3AF")
+		if edge.Label != "" {
+			midX, midY := (x1+x2)/2, (y1+y2)/2
+			label := box{midX - inches(1), midY - inches(0.45), inches(2), inches(0.4)}
+			if column {
+				label = box{midX + inches(0.1), midY - inches(0.2), inches(2), inches(0.4)}
+			}
+			align := "ctr"
+			if column {
+				align = "l"
+			}
+			s.text("Label", label, shapeStyle{anchor: "b", pad: 1}, []para{{runs: []run{{text: oneLine(edge.Label), size: 11, color: mutedColor}}, align: align}})
+		}
+	}
+}
+
+func (s *slide) userFeedback(c *model.UserFeedbackContent) {
+	columns := 2
+	if len(c.Items) == 1 {
+		columns = 1
+	}
+	scale := 1.0
+	if c.FontSizeMultiplier > 0 {
+		scale = c.FontSizeMultiplier
+	}
+	for i, b := range grid(len(c.Items), columns, inches(2.6)) {
+		item := c.Items[i]
+		paras := lines("“"+item.Quote+"”", run{size: 18 * scale, italic: true, color: textColor}, "")
+		if item.Author != "" {
+			paras = append(paras, para{runs: []run{{text: "— " + item.Author, size: 14 * scale, color: mutedColor}}, align: "r", spaceBefore: 8})
+		}
+		style := shapeStyle{geom: "roundRect", adjust: map[string]int64{"adj": 6000}, fill: "F9FAFB", line: "E5E7EB", anchor: "ctr", pad: inches(0.2)}
+		inner := b.inset(style.pad)
+		s.text("Feedback", b, style, fit(paras, inner.w, inner.h, 0.4))
+	}
+}
+
+// structureBreakdown draws each item with a coloured bar on its left.
+func (s *slide) structureBreakdown(c *model.StructureBreakdownContent) {
+	for i, b := range grid(len(c.Items), c.Columns, inches(2.8)) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		s.text("Bar", box{b.x, b.y, inches(0.06), b.h}, shapeStyle{fill: p.Accent}, nil)
+
+		paras := lines(item.Title, run{size: 20, bold: true, color: textColor}, "")
+		if item.Description != "" {
+			paras = append(paras, lines(item.Description, run{size: 14, italic: true, color: mutedColor}, "")...)
+		}
+		if item.Content != "" {
+			code := lines(item.Content, run{size: 13, color: p.Accent, font: codeFont}, "")
+			code[0].spaceBefore = 8
+			paras = append(paras, code...)
+		}
+		text := box{b.x + inches(0.2), b.y, b.w - inches(0.2), b.h}
+		s.text("Item", text, shapeStyle{}, fit(paras, text.w-inches(0.2), text.h-inches(0.2), 0.4))
+	}
+}
+
+func (s *slide) stats(c *model.StatsContent) {
+	for i, b := range grid(len(c.Items), c.Columns, inches(2)) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		paras := []para{
+			{runs: []run{{text: oneLine(item.Value), size: 40, bold: true, color: p.Accent}}, align: "ctr"},
+			{runs: []run{{text: oneLine(item.Label), size: 14, color: mutedColor}}, align: "ctr", spaceBefore: 4},
+		}
+		style := shapeStyle{geom: "roundRect", adjust: map[string]int64{"adj": 8000}, fill: p.Tint, anchor: "ctr", pad: inches(0.15)}
+		inner := b.inset(style.pad)
+		s.text("Stat", b, style, fit(paras, inner.w, inner.h, 0.4))
+	}
+}
+
+func (s *slide) numberedList(c *model.NumberedListContent) {
+	for i, b := range grid(len(c.Items), c.Columns, inches(2.6)) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		paras := []para{
+			{runs: []run{{text: oneLine(item.Number), size: 36, color: "B3B3B3"}}},
+			{runs: []run{{text: oneLine(item.Title), size: 20, bold: true, color: p.Accent}}, spaceBefore: 8},
+		}
+		if item.Description != "" {
+			paras = append(paras, lines(item.Description, run{size: 14, color: "4B5563"}, "")...)
+		}
+		s.text("Item", b, shapeStyle{pad: inches(0.15)}, fit(paras, b.w-inches(0.3), b.h-inches(0.3), 0.4))
+	}
+}
+
+// conceptCards draws dark cards with a coloured border, as the frontend
+// does.
+func (s *slide) conceptCards(c *model.ConceptCardContent) {
+	for i, b := range grid(len(c.Items), c.Columns, inches(3)) {
+		item := c.Items[i]
+		p := render.PaletteOf(item.Color)
+		var paras []para
+		if item.Icon != "" {
+			paras = append(paras, para{runs: []run{{text: item.Icon, size: 40}}, align: "ctr"})
+		}
+		paras = append(paras, para{runs: []run{{text: oneLine(item.Title), size: 22, bold: true, color: p.Light}}, align: "ctr", spaceBefore: 6})
+		if item.Description != "" {
+			paras = append(paras, lines(item.Description, run{size: 14, color: "D1D5DB"}, "ctr")...)
+		}
+		style := shapeStyle{geom: "roundRect", adjust: map[string]int64{"adj": 8000}, fill: "1F2937", line: p.Accent, lineW: 2, anchor: "ctr", pad: inches(0.2)}
+		inner := b.inset(style.pad)
+		s.text("Card", b, style, fit(paras, inner.w, inner.h, 0.4))
+	}
+}
+
+// grid splits the content area into cells for n items, columns wide
+// (default 3) and at most maxHeight high, centering the grid vertically.
+func grid(n int, columns int, maxHeight int64) []box {
+	if n == 0 {
+		return nil
+	}
+	if columns <= 0 {
+		columns = 3
+	}
+	columns = min(columns, n)
+	rows := (n + columns - 1) / columns
+	w := (content.w - gap*int64(columns-1)) / int64(columns)
+	h := min(maxHeight, (content.h-gap*int64(rows-1))/int64(rows))
+	top := content.y + (content.h-(h*int64(rows)+gap*int64(rows-1)))/2
+	cells := make([]box, n)
+	for i := range cells {
+		row, col := i/columns, i%columns
+		cells[i] = box{content.x + int64(col)*(w+gap), top + int64(row)*(h+gap), w, h}
+	}
+	return cells
+}
+
+// oneLine joins the lines of s for places that must stay on one line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
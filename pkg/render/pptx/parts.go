@@ -0,0 +1,76 @@
+package pptx
+
+// The parts every deck shares. There is a single master with a single
+// blank layout: slides position all their shapes themselves.
+
+const presentationXML = xmlHeader + `<p:presentation xmlns:a="` + nsMain + `" xmlns:r="` + nsRel + `" xmlns:p="` + nsPres + `" saveSubsetFonts="1">` +
+	`<p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst>` +
+	`%s` +
+	`<p:sldSz cx="%d" cy="%d"/>` +
+	`<p:notesSz cx="6858000" cy="9144000"/>` +
+	`</p:presentation>`
+
+const slideMasterXML = xmlHeader + `<p:sldMaster xmlns:a="` + nsMain + `" xmlns:r="` + nsRel + `" xmlns:p="` + nsPres + `">` +
+	`<p:cSld><p:bg><p:bgRef idx="1001"><a:schemeClr val="bg1"/></p:bgRef></p:bg><p:spTree>` + emptyTree + `</p:spTree></p:cSld>` +
+	`<p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/>` +
+	`<p:sldLayoutIdLst><p:sldLayoutId id="2147483649" r:id="rId1"/></p:sldLayoutIdLst>` +
+	`<p:txStyles>` +
+	`<p:titleStyle><a:lvl1pPr><a:defRPr sz="2800"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mj-lt"/></a:defRPr></a:lvl1pPr></p:titleStyle>` +
+	`<p:bodyStyle><a:lvl1pPr><a:defRPr sz="1800"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mn-lt"/></a:defRPr></a:lvl1pPr></p:bodyStyle>` +
+	`<p:otherStyle><a:lvl1pPr><a:defRPr sz="1800"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mn-lt"/></a:defRPr></a:lvl1pPr></p:otherStyle>` +
+	`</p:txStyles>` +
+	`</p:sldMaster>`
+
+const slideLayoutXML = xmlHeader + `<p:sldLayout xmlns:a="` + nsMain + `" xmlns:r="` + nsRel + `" xmlns:p="` + nsPres + `" type="blank" preserve="1">` +
+	`<p:cSld name="Blank"><p:spTree>` + emptyTree + `</p:spTree></p:cSld>` +
+	`<p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr>` +
+	`</p:sldLayout>`
+
+const emptyTree = `<p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr>` +
+	`<p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr>`
+
+// themeXML uses the chart colours of the frontend as accents.
+const themeXML = xmlHeader + `<a:theme xmlns:a="` + nsMain + `" name="Presentationer"><a:themeElements>` +
+	`<a:clrScheme name="Presentationer">` +
+	`<a:dk1><a:srgbClr val="1F2937"/></a:dk1><a:lt1><a:srgbClr val="FFFFFF"/></a:lt1>` +
+	`<a:dk2><a:srgbClr val="374151"/></a:dk2><a:lt2><a:srgbClr val="F3F4F6"/></a:lt2>` +
+	`<a:accent1><a:srgbClr val="0088FE"/></a:accent1><a:accent2><a:srgbClr val="00C49F"/></a:accent2>` +
+	`<a:accent3><a:srgbClr val="FFBB28"/></a:accent3><a:accent4><a:srgbClr val="FF8042"/></a:accent4>` +
+	`<a:accent5><a:srgbClr val="8884D8"/></a:accent5><a:accent6><a:srgbClr val="82CA9D"/></a:accent6>` +
+	`<a:hlink><a:srgbClr val="2563EB"/></a:hlink><a:folHlink><a:srgbClr val="7C3AED"/></a:folHlink>` +
+	`</a:clrScheme>` +
+	`<a:fontScheme name="Presentationer">` +
+	`<a:majorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:majorFont>` +
+	`<a:minorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:minorFont>` +
+	`</a:fontScheme>` +
+	`<a:fmtScheme name="Presentationer">` +
+	`<a:fillStyleLst>` + themeFill + themeFill + themeFill + `</a:fillStyleLst>` +
+	`<a:lnStyleLst>` +
+	`<a:ln w="6350"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`<a:ln w="12700"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`<a:ln w="19050"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln>` +
+	`</a:lnStyleLst>` +
+	`<a:effectStyleLst>` + themeEffect + themeEffect + themeEffect + `</a:effectStyleLst>` +
+	`<a:bgFillStyleLst>` + themeFill + themeFill + themeFill + `</a:bgFillStyleLst>` +
+	`</a:fmtScheme>` +
+	`</a:themeElements><a:objectDefaults/><a:extraClrSchemeLst/></a:theme>`
+
+const (
+	themeFill   = `<a:solidFill><a:schemeClr val="phClr"/></a:solidFill>`
+	themeEffect = `<a:effectStyle><a:effectLst/></a:effectStyle>`
+)
+
+const presPropsXML = xmlHeader + `<p:presentationPr xmlns:a="` + nsMain + `" xmlns:r="` + nsRel + `" xmlns:p="` + nsPres + `"/>`
+
+const viewPropsXML = xmlHeader + `<p:viewPr xmlns:a="` + nsMain + `" xmlns:r="` + nsRel + `" xmlns:p="` + nsPres + `"/>`
+
+const tableStylesXML = xmlHeader + `<a:tblStyleLst xmlns:a="` + nsMain + `" def="{5C22544A-7EE6-4342-B048-85BDC9FD1C3A}"/>`
+
+const coreXML = xmlHeader + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+	`<dc:title>%s</dc:title><dc:creator>presentationer</dc:creator>` +
+	`<dcterms:modified xsi:type="dcterms:W3CDTF">%s</dcterms:modified>` +
+	`</cp:coreProperties>`
+
+const appXML = xmlHeader + `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties">` +
+	`<Application>presentationer</Application><Slides>%d</Slides>` +
+	`</Properties>`
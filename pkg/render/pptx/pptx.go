@@ -0,0 +1,245 @@
+// Package pptx exports a session as a PowerPoint deck, one slide per page,
+// writing the Office Open XML parts itself.
+//
+// Pages are laid out as native shapes so the deck stays editable: code as
+// coloured monospaced text, chat threads as speech bubbles next to the
+// avatars, charts as PowerPoint charts with their data in an embedded
+// workbook, and the card kinds as grids of shapes.
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+// ContentType is the media type of a .pptx file.
+const ContentType = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+
+const (
+	nsMain  = "http://schemas.openxmlformats.org/drawingml/2006/main"
+	nsPres  = "http://schemas.openxmlformats.org/presentationml/2006/main"
+	nsChart = "http://schemas.openxmlformats.org/drawingml/2006/chart"
+	nsRel   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsPkg   = "http://schemas.openxmlformats.org/package/2006/relationships"
+
+	relSlide       = nsRel + "/slide"
+	relSlideLayout = nsRel + "/slideLayout"
+	relSlideMaster = nsRel + "/slideMaster"
+	relTheme       = nsRel + "/theme"
+	relImage       = nsRel + "/image"
+	relChart       = nsRel + "/chart"
+	relPackage     = nsRel + "/package"
+
+	ctPresentation = "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"
+	ctSlide        = "application/vnd.openxmlformats-officedocument.presentationml.slide+xml"
+	ctSlideLayout  = "application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"
+	ctSlideMaster  = "application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"
+	ctTheme        = "application/vnd.openxmlformats-officedocument.theme+xml"
+	ctChart        = "application/vnd.openxmlformats-officedocument.drawingml.chart+xml"
+	ctCore         = "application/vnd.openxmlformats-package.core-properties+xml"
+	ctApp          = "application/vnd.openxmlformats-officedocument.extended-properties+xml"
+	ctPresProps    = "application/vnd.openxmlformats-officedocument.presentationml.presProps+xml"
+	ctViewProps    = "application/vnd.openxmlformats-officedocument.presentationml.viewProps+xml"
+	ctTableStyles  = "application/vnd.openxmlformats-officedocument.presentationml.tableStyles+xml"
+	ctXLSX         = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// imageExts maps the image types PowerPoint displays to file extensions.
+var imageExts = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+	"image/bmp":  "bmp",
+}
+
+// writer assembles the parts of the package.
+type writer struct {
+	deck  *render.Deck
+	parts []part
+	// overrides maps part names to content types
+	overrides map[string]string
+	// defaults maps extensions to content types
+	defaults map[string]string
+	// media maps avatar names to their part, "" if they cannot be shown
+	media  map[string]string
+	images int
+	charts int
+}
+
+type part struct {
+	name string
+	data []byte
+}
+
+func (w *writer) add(name string, contentType string, data []byte) {
+	w.parts = append(w.parts, part{name: name, data: data})
+	if contentType != "" {
+		w.overrides["/"+name] = contentType
+	}
+}
+
+// Write writes the deck as a .pptx file.
+func Write(out io.Writer, deck *render.Deck) error {
+	w := &writer{
+		deck:      deck,
+		overrides: make(map[string]string),
+		defaults: map[string]string{
+			"rels": "application/vnd.openxmlformats-package.relationships+xml",
+			"xml":  "application/xml",
+		},
+		media: make(map[string]string),
+	}
+
+	pages := deck.Session.Pages
+	for i := range pages {
+		s := &slide{w: w}
+		if err := s.page(&pages[i]); err != nil {
+			return fmt.Errorf("page %q: %w", pages[i].Title, err)
+		}
+		name := fmt.Sprintf("ppt/slides/slide%d.xml", i+1)
+		w.add(name, ctSlide, s.xml())
+		rels := append([]relationship{{id: "rId1", typ: relSlideLayout, target: "../slideLayouts/slideLayout1.xml"}}, s.rels...)
+		w.add(fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1), "", relsXML(rels...))
+	}
+
+	presRels := []relationship{{id: "rId1", typ: relSlideMaster, target: "slideMasters/slideMaster1.xml"}}
+	var slideIDs bytes.Buffer
+	for i := range pages {
+		id := "rId" + strconv.Itoa(i+2)
+		presRels = append(presRels, relationship{id: id, typ: relSlide, target: fmt.Sprintf("slides/slide%d.xml", i+1)})
+		fmt.Fprintf(&slideIDs, `<p:sldId id="%d" r:id="%s"/>`, 256+i, id)
+	}
+	n := len(pages) + 2
+	presRels = append(presRels,
+		relationship{id: "rId" + strconv.Itoa(n), typ: relTheme, target: "theme/theme1.xml"},
+		relationship{id: "rId" + strconv.Itoa(n+1), typ: nsRel + "/presProps", target: "presProps.xml"},
+		relationship{id: "rId" + strconv.Itoa(n+2), typ: nsRel + "/viewProps", target: "viewProps.xml"},
+		relationship{id: "rId" + strconv.Itoa(n+3), typ: nsRel + "/tableStyles", target: "tableStyles.xml"},
+	)
+	sldIDList := ""
+	if slideIDs.Len() > 0 {
+		sldIDList = "<p:sldIdLst>" + slideIDs.String() + "</p:sldIdLst>"
+	}
+	w.add("ppt/presentation.xml", ctPresentation, []byte(fmt.Sprintf(presentationXML, sldIDList, slideWidth, slideHeight)))
+	w.add("ppt/_rels/presentation.xml.rels", "", relsXML(presRels...))
+	w.add("ppt/slideMasters/slideMaster1.xml", ctSlideMaster, []byte(slideMasterXML))
+	w.add("ppt/slideMasters/_rels/slideMaster1.xml.rels", "", relsXML(
+		relationship{id: "rId1", typ: relSlideLayout, target: "../slideLayouts/slideLayout1.xml"},
+		relationship{id: "rId2", typ: relTheme, target: "../theme/theme1.xml"},
+	))
+	w.add("ppt/slideLayouts/slideLayout1.xml", ctSlideLayout, []byte(slideLayoutXML))
+	w.add("ppt/slideLayouts/_rels/slideLayout1.xml.rels", "", relsXML(
+		relationship{id: "rId1", typ: relSlideMaster, target: "../slideMasters/slideMaster1.xml"},
+	))
+	w.add("ppt/theme/theme1.xml", ctTheme, []byte(themeXML))
+	w.add("ppt/presProps.xml", ctPresProps, []byte(presPropsXML))
+	w.add("ppt/viewProps.xml", ctViewProps, []byte(viewPropsXML))
+	w.add("ppt/tableStyles.xml", ctTableStyles, []byte(tableStylesXML))
+
+	modified := deck.Session.LastModified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	w.add("docProps/core.xml", ctCore, []byte(fmt.Sprintf(coreXML, escape(deck.Session.Name), modified.UTC().Format(time.RFC3339))))
+	w.add("docProps/app.xml", ctApp, []byte(fmt.Sprintf(appXML, len(pages))))
+	w.add("_rels/.rels", "", relsXML(
+		relationship{id: "rId1", typ: nsRel + "/officeDocument", target: "ppt/presentation.xml"},
+		relationship{id: "rId2", typ: nsPkg + "/metadata/core-properties", target: "docProps/core.xml"},
+		relationship{id: "rId3", typ: nsRel + "/extended-properties", target: "docProps/app.xml"},
+	))
+
+	zw := zip.NewWriter(out)
+	// [Content_Types].xml goes first by convention
+	if err := writeZipEntry(zw, "[Content_Types].xml", w.contentTypes()); err != nil {
+		return err
+	}
+	for _, p := range w.parts {
+		if err := writeZipEntry(zw, p.name, p.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeZipEntry(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// avatar returns the media part of the avatar name, adding it on first
+// use, or "" when there is no such avatar or PowerPoint cannot show it.
+func (w *writer) avatar(name string) string {
+	if part, ok := w.media[name]; ok {
+		return part
+	}
+	data := w.deck.Avatars[name]
+	ext := imageExts[http.DetectContentType(data)]
+	if data == nil || ext == "" {
+		w.media[name] = ""
+		return ""
+	}
+	w.images++
+	part := fmt.Sprintf("ppt/media/image%d.%s", w.images, ext)
+	w.defaults[ext] = "image/" + ext
+	w.add(part, "", data)
+	w.media[name] = part
+	return part
+}
+
+func (w *writer) contentTypes() []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	for _, ext := range sortedKeys(w.defaults) {
+		fmt.Fprintf(&b, `<Default Extension="%s" ContentType="%s"/>`, ext, w.defaults[ext])
+	}
+	for _, name := range sortedKeys(w.overrides) {
+		fmt.Fprintf(&b, `<Override PartName="%s" ContentType="%s"/>`, escape(name), w.overrides[name])
+	}
+	b.WriteString(`</Types>`)
+	return b.Bytes()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func relsXML(rels ...relationship) []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<Relationships xmlns="%s">`, nsPkg)
+	for _, r := range rels {
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="%s"/>`, r.id, r.typ, escape(r.target))
+	}
+	b.WriteString(`</Relationships>`)
+	return b.Bytes()
+}
+
+func (s *slide) xml() []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<p:sld xmlns:a="%s" xmlns:r="%s" xmlns:p="%s"><p:cSld><p:spTree>`, nsMain, nsRel, nsPres)
+	b.WriteString(emptyTree)
+	b.Write(s.shapes.Bytes())
+	b.WriteString(`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`)
+	return b.Bytes()
+}
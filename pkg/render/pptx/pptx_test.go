@@ -0,0 +1,216 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/render"
+)
+
+// samplePages returns a page of every kind, charts of every type.
+func samplePages(t *testing.T) []model.Page {
+	contents := []model.Content{
+		&model.CodeContent{Code: "package main\n\nfunc main() {\n\tprintln(\"<&>\")\n}\n", Language: "go",
+			ConfigList: []model.FocusConfig{{ID: "f", Lines: "3-4"}}, SelectedConfigID: "f"},
+		&model.CodeDiffContent{OldCode: "a\nb := 1\nc\n", NewCode: "a\nb := 2\nc\nd\n", Language: "go", Path: "main.go"},
+		&model.ChatThreadContent{Messages: []model.Message{
+			{Sender: "Alice", Content: "hi <there>", SendTime: "10:02", Avatar: "alice.png"},
+			{Sender: "Bob", Content: "hello & welcome", SendTime: "10:03", IsMe: true},
+		}},
+		&model.ChartContent{ChartType: model.ChartTypeBar, Items: []model.ChartItem{{Name: "a", Value: 1}, {Name: "b", Value: 2}}},
+		&model.ChartContent{ChartType: model.ChartTypeLine, Items: []model.ChartItem{{Name: "a", Value: 1}, {Name: "b", Value: 2}}},
+		&model.ChartContent{ChartType: model.ChartTypePie, Items: []model.ChartItem{{Name: "a", Value: 1}, {Name: "b", Value: 2}}},
+		&model.RectangleContent{Text: "Title", Subtext: "sub", Items: []model.RectangleItem{{Value: "item"}}},
+		&model.ConnectedRectanglesContent{
+			Nodes: []model.CRNode{{ID: "a", Text: "A"}, {ID: "b", Text: "B"}},
+			Edges: []model.CREdge{{From: "a", To: "b", Label: "calls"}},
+		},
+		&model.UserFeedbackContent{Items: []model.UserFeedbackItem{{Quote: "Great", Author: "Carol"}}},
+		&model.StructureBreakdownContent{Items: []model.StructureItem{{Title: "Layer", Description: "d", Content: "c"}}},
+		&model.StatsContent{Items: []model.StatItem{{Value: "99%", Label: "uptime"}}},
+		&model.NumberedListContent{Items: []model.NumberedItem{{Number: "1", Title: "First", Description: "d"}}},
+		&model.ConceptCardContent{Items: []model.ConceptItem{{Icon: "💡", Title: "Idea", Description: "d"}}},
+	}
+	kinds := make(map[model.PageKind]bool)
+	var pages []model.Page
+	for i, c := range contents {
+		page := model.Page{ID: fmt.Sprintf("p%d", i), Title: string(c.Kind())}
+		if err := page.EncodeContent(c); err != nil {
+			t.Fatal(err)
+		}
+		kinds[c.Kind()] = true
+		pages = append(pages, page)
+	}
+	for _, kind := range model.PageKinds {
+		if !kinds[kind] {
+			t.Fatalf("no sample page of kind %s", kind)
+		}
+	}
+	return pages
+}
+
+func samplePNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestWriteValidPackage writes a deck of every page kind and checks the
+// package: every XML part parses, every part has a content type and
+// every internal relationship points at a part. The workbooks embedded
+// in the charts are checked the same way.
+func TestWriteValidPackage(t *testing.T) {
+	deck := &render.Deck{
+		Session: &model.Session{Name: "deck", Pages: samplePages(t)},
+		Avatars: map[string][]byte{"alice.png": samplePNG(t)},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, deck); err != nil {
+		t.Fatal(err)
+	}
+	parts := checkPackage(t, "deck.pptx", buf.Bytes())
+	for i := range deck.Session.Pages {
+		if name := fmt.Sprintf("ppt/slides/slide%d.xml", i+1); parts[name] == nil {
+			t.Errorf("missing %s", name)
+		}
+	}
+	workbooks := 0
+	for name, data := range parts {
+		if strings.HasSuffix(name, ".xlsx") {
+			workbooks++
+			checkPackage(t, name, data)
+		}
+	}
+	if workbooks != 3 {
+		t.Errorf("got %d embedded workbooks, want one per chart", workbooks)
+	}
+}
+
+type contentTypes struct {
+	Defaults []struct {
+		Extension   string `xml:",attr"`
+		ContentType string `xml:",attr"`
+	} `xml:"Default"`
+	Overrides []struct {
+		PartName    string `xml:",attr"`
+		ContentType string `xml:",attr"`
+	} `xml:"Override"`
+}
+
+type relationships struct {
+	Relationships []struct {
+		ID         string `xml:"Id,attr"`
+		Target     string `xml:",attr"`
+		TargetMode string `xml:",attr"`
+	} `xml:"Relationship"`
+}
+
+// checkPackage checks an Open Packaging Conventions zip and returns its
+// parts by name.
+func checkPackage(t *testing.T, pkg string, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s: %v", pkg, err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("%s: %s: %v", pkg, f.Name, err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %s: %v", pkg, f.Name, err)
+		}
+		if parts[f.Name] != nil {
+			t.Errorf("%s: duplicate part %s", pkg, f.Name)
+		}
+		parts[f.Name] = b
+	}
+
+	for name, b := range parts {
+		if !strings.HasSuffix(name, ".xml") && !strings.HasSuffix(name, ".rels") {
+			continue
+		}
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s: %s: %v", pkg, name, err)
+				break
+			}
+		}
+	}
+
+	var types contentTypes
+	if err := xml.Unmarshal(parts["[Content_Types].xml"], &types); err != nil {
+		t.Fatalf("%s: [Content_Types].xml: %v", pkg, err)
+	}
+	defaults := make(map[string]bool)
+	for _, d := range types.Defaults {
+		defaults[strings.ToLower(d.Extension)] = true
+	}
+	overrides := make(map[string]bool)
+	for _, o := range types.Overrides {
+		name := strings.TrimPrefix(o.PartName, "/")
+		overrides[name] = true
+		if parts[name] == nil {
+			t.Errorf("%s: content type for missing part %s", pkg, o.PartName)
+		}
+	}
+	for name := range parts {
+		if name == "[Content_Types].xml" {
+			continue
+		}
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+		if !overrides[name] && !defaults[ext] {
+			t.Errorf("%s: no content type for %s", pkg, name)
+		}
+	}
+
+	for name, b := range parts {
+		if !strings.HasSuffix(name, ".rels") {
+			continue
+		}
+		var rels relationships
+		if err := xml.Unmarshal(b, &rels); err != nil {
+			t.Errorf("%s: %s: %v", pkg, name, err)
+			continue
+		}
+		// _rels/x.xml.rels describes x.xml; targets are relative to its
+		// directory
+		dir := path.Dir(path.Dir(name))
+		ids := make(map[string]bool)
+		for _, r := range rels.Relationships {
+			if ids[r.ID] {
+				t.Errorf("%s: %s: duplicate id %s", pkg, name, r.ID)
+			}
+			ids[r.ID] = true
+			if r.TargetMode == "External" {
+				continue
+			}
+			target := path.Join(dir, r.Target)
+			if strings.HasPrefix(r.Target, "/") {
+				target = strings.TrimPrefix(r.Target, "/")
+			}
+			if parts[target] == nil {
+				t.Errorf("%s: %s: %s points at missing part %s", pkg, name, r.ID, target)
+			}
+		}
+	}
+	return parts
+}
@@ -0,0 +1,346 @@
+package pptx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Lengths are in EMU, English Metric Units, as in the XML.
+const (
+	emuPerInch = 914400
+	emuPerPt   = 12700
+	// 16:9, 13.333in by 7.5in
+	slideWidth  = 12192000
+	slideHeight = 6858000
+)
+
+func inches(f float64) int64 { return int64(f * emuPerInch) }
+
+type box struct{ x, y, w, h int64 }
+
+// inset returns b shrunk by d on every side.
+func (b box) inset(d int64) box {
+	return box{b.x + d, b.y + d, b.w - 2*d, b.h - 2*d}
+}
+
+// center returns a box of w by h centered in b.
+func (b box) center(w, h int64) box {
+	return box{b.x + (b.w-w)/2, b.y + (b.h-h)/2, w, h}
+}
+
+type run struct {
+	text string
+	// size is in points
+	size   float64
+	bold   bool
+	italic bool
+	// color is RRGGBB, empty for the default
+	color string
+	// highlight is the RRGGBB background of the text, empty for none
+	highlight string
+	font      string
+}
+
+type para struct {
+	runs []run
+	// align is l, ctr or r
+	align string
+	// spaceBefore is in points
+	spaceBefore float64
+}
+
+// lines splits text into one paragraph per line, every run styled like r.
+func lines(text string, r run, align string) []para {
+	var paras []para
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		r.text = line
+		paras = append(paras, para{runs: []run{r}, align: align})
+	}
+	return paras
+}
+
+type shapeStyle struct {
+	// geom is the preset geometry, default rect
+	geom string
+	// adjust holds the geometry's adjust values, e.g. "adj1" -> 16667
+	adjust map[string]int64
+	// fill and line are RRGGBB, empty for none
+	fill  string
+	line  string
+	lineW float64
+	// anchor is the vertical text anchor, t, ctr or b; default t
+	anchor string
+	// pad is the text inset, default 0.1in
+	pad int64
+}
+
+// slide collects the shapes of one slide and the relationships they need.
+type slide struct {
+	w      *writer
+	shapes bytes.Buffer
+	lastID int
+	// rels are the relationships after rId1, the slide layout
+	rels []relationship
+}
+
+type relationship struct {
+	id     string
+	typ    string
+	target string
+}
+
+func (s *slide) nextID() int {
+	if s.lastID == 0 {
+		// 1 is the shape tree itself
+		s.lastID = 1
+	}
+	s.lastID++
+	return s.lastID
+}
+
+func (s *slide) addRel(typ string, target string) string {
+	id := "rId" + strconv.Itoa(len(s.rels)+2)
+	s.rels = append(s.rels, relationship{id: id, typ: typ, target: target})
+	return id
+}
+
+// text adds a shape with text; paras may be empty for a plain shape.
+func (s *slide) text(name string, b box, style shapeStyle, paras []para) {
+	id := s.nextID()
+	geom := style.geom
+	if geom == "" {
+		geom = "rect"
+	}
+	buf := &s.shapes
+	fmt.Fprintf(buf, `<p:sp><p:nvSpPr><p:cNvPr id="%d" name="%s %d"/><p:cNvSpPr/><p:nvPr/></p:nvSpPr>`, id, escape(name), id)
+	buf.WriteString(`<p:spPr>`)
+	writeXfrm(buf, "a:xfrm", "", b)
+	writeGeom(buf, geom, style.adjust)
+	writeFill(buf, style.fill)
+	writeLine(buf, style.line, style.lineW, false)
+	buf.WriteString(`</p:spPr>`)
+	if len(paras) > 0 {
+		pad := style.pad
+		if pad == 0 {
+			pad = inches(0.1)
+		}
+		anchor := style.anchor
+		if anchor == "" {
+			anchor = "t"
+		}
+		fmt.Fprintf(buf, `<p:txBody><a:bodyPr wrap="square" lIns="%d" tIns="%d" rIns="%d" bIns="%d" anchor="%s" rtlCol="0"><a:normAutofit/></a:bodyPr><a:lstStyle/>`, pad, pad, pad, pad, anchor)
+		for _, p := range paras {
+			writePara(buf, p)
+		}
+		buf.WriteString(`</p:txBody>`)
+	}
+	buf.WriteString(`</p:sp>`)
+}
+
+// picture adds the image of relationship relID, clipped to geom.
+func (s *slide) picture(name string, b box, relID string, geom string) {
+	id := s.nextID()
+	buf := &s.shapes
+	fmt.Fprintf(buf, `<p:pic><p:nvPicPr><p:cNvPr id="%d" name="%s %d"/><p:cNvPicPr><a:picLocks noChangeAspect="1"/></p:cNvPicPr><p:nvPr/></p:nvPicPr>`, id, escape(name), id)
+	fmt.Fprintf(buf, `<p:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></p:blipFill>`, relID)
+	buf.WriteString(`<p:spPr>`)
+	writeXfrm(buf, "a:xfrm", "", b)
+	writeGeom(buf, geom, nil)
+	buf.WriteString(`</p:spPr></p:pic>`)
+}
+
+// chart adds the chart of relationship relID.
+func (s *slide) chart(name string, b box, relID string) {
+	id := s.nextID()
+	buf := &s.shapes
+	fmt.Fprintf(buf, `<p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="%d" name="%s %d"/><p:cNvGraphicFramePr/><p:nvPr/></p:nvGraphicFramePr>`, id, escape(name), id)
+	writeXfrm(buf, "p:xfrm", "", b)
+	fmt.Fprintf(buf, `<a:graphic><a:graphicData uri="%s"><c:chart xmlns:c="%s" r:id="%s"/></a:graphicData></a:graphic></p:graphicFrame>`, nsChart, nsChart, relID)
+}
+
+// arrow adds a straight arrow from (x1, y1) to (x2, y2).
+func (s *slide) arrow(name string, x1, y1, x2, y2 int64, color string) {
+	id := s.nextID()
+	buf := &s.shapes
+	var flip string
+	if x2 < x1 {
+		flip += ` flipH="1"`
+	}
+	if y2 < y1 {
+		flip += ` flipV="1"`
+	}
+	b := box{min(x1, x2), min(y1, y2), abs(x2 - x1), abs(y2 - y1)}
+	fmt.Fprintf(buf, `<p:cxnSp><p:nvCxnSpPr><p:cNvPr id="%d" name="%s %d"/><p:cNvCxnSpPr/><p:nvPr/></p:nvCxnSpPr>`, id, escape(name), id)
+	buf.WriteString(`<p:spPr>`)
+	writeXfrm(buf, "a:xfrm", flip, b)
+	writeGeom(buf, "straightConnector1", nil)
+	writeLine(buf, color, 2, true)
+	buf.WriteString(`</p:spPr></p:cxnSp>`)
+}
+
+func writeXfrm(buf *bytes.Buffer, tag string, attrs string, b box) {
+	fmt.Fprintf(buf, `<%s%s><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></%s>`, tag, attrs, b.x, b.y, max(b.w, 0), max(b.h, 0), tag)
+}
+
+func writeGeom(buf *bytes.Buffer, geom string, adjust map[string]int64) {
+	fmt.Fprintf(buf, `<a:prstGeom prst="%s"><a:avLst>`, geom)
+	for _, name := range []string{"adj", "adj1", "adj2", "adj3"} {
+		if v, ok := adjust[name]; ok {
+			fmt.Fprintf(buf, `<a:gd name="%s" fmla="val %d"/>`, name, v)
+		}
+	}
+	buf.WriteString(`</a:avLst></a:prstGeom>`)
+}
+
+func writeFill(buf *bytes.Buffer, color string) {
+	if color == "" {
+		buf.WriteString(`<a:noFill/>`)
+		return
+	}
+	fmt.Fprintf(buf, `<a:solidFill><a:srgbClr val="%s"/></a:solidFill>`, color)
+}
+
+func writeLine(buf *bytes.Buffer, color string, width float64, arrow bool) {
+	if color == "" {
+		buf.WriteString(`<a:ln><a:noFill/></a:ln>`)
+		return
+	}
+	if width == 0 {
+		width = 1
+	}
+	fmt.Fprintf(buf, `<a:ln w="%d"><a:solidFill><a:srgbClr val="%s"/></a:solidFill>`, int64(width*emuPerPt), color)
+	if arrow {
+		buf.WriteString(`<a:tailEnd type="triangle"/>`)
+	}
+	buf.WriteString(`</a:ln>`)
+}
+
+func writePara(buf *bytes.Buffer, p para) {
+	buf.WriteString(`<a:p>`)
+	if p.align != "" || p.spaceBefore > 0 {
+		buf.WriteString(`<a:pPr`)
+		if p.align != "" {
+			fmt.Fprintf(buf, ` algn="%s"`, p.align)
+		}
+		buf.WriteString(`>`)
+		if p.spaceBefore > 0 {
+			fmt.Fprintf(buf, `<a:spcBef><a:spcPts val="%d"/></a:spcBef>`, int(p.spaceBefore*100))
+		}
+		buf.WriteString(`</a:pPr>`)
+	}
+	size := 18.0
+	for _, r := range p.runs {
+		size = r.size
+		if r.text == "" {
+			continue
+		}
+		buf.WriteString(`<a:r>`)
+		writeRunProps(buf, "a:rPr", r)
+		fmt.Fprintf(buf, `<a:t>%s</a:t></a:r>`, escape(r.text))
+	}
+	fmt.Fprintf(buf, `<a:endParaRPr lang="en-US" sz="%d" dirty="0"/></a:p>`, fontSize(size))
+}
+
+func writeRunProps(buf *bytes.Buffer, tag string, r run) {
+	fmt.Fprintf(buf, `<%s lang="en-US" sz="%d"`, tag, fontSize(r.size))
+	if r.bold {
+		buf.WriteString(` b="1"`)
+	}
+	if r.italic {
+		buf.WriteString(` i="1"`)
+	}
+	buf.WriteString(` dirty="0">`)
+	if r.color != "" {
+		fmt.Fprintf(buf, `<a:solidFill><a:srgbClr val="%s"/></a:solidFill>`, r.color)
+	}
+	if r.highlight != "" {
+		fmt.Fprintf(buf, `<a:highlight><a:srgbClr val="%s"/></a:highlight>`, r.highlight)
+	}
+	if r.font != "" {
+		fmt.Fprintf(buf, `<a:latin typeface="%s"/><a:cs typeface="%s"/>`, escape(r.font), escape(r.font))
+	}
+	fmt.Fprintf(buf, `</%s>`, tag)
+}
+
+// fontSize converts points to the hundredths the XML wants, within the
+// range it allows.
+func fontSize(pt float64) int {
+	return min(max(int(math.Round(pt*100)), 100), 400000)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Text fitting
+//
+// Slides are laid out once, so text is sized up front from a rough guess
+// of its extent: glyphs half as wide as the font size, wide scripts and
+// emoji a full width, lines 1.2 times the font size high.
+
+// fit scales the runs of paras down, to no less than minScale, until they
+// roughly fit in w by h.
+func fit(paras []para, w int64, h int64, minScale float64) []para {
+	scale := 1.0
+	for scale > minScale && textHeight(paras, w, scale) > h {
+		scale -= 0.05
+	}
+	if scale >= 1 {
+		return paras
+	}
+	scaled := make([]para, len(paras))
+	for i, p := range paras {
+		p.runs = append([]run(nil), p.runs...)
+		for j := range p.runs {
+			p.runs[j].size *= scale
+		}
+		p.spaceBefore *= scale
+		scaled[i] = p
+	}
+	return scaled
+}
+
+// textHeight guesses the height of paras set in width w, with every size
+// multiplied by scale.
+func textHeight(paras []para, w int64, scale float64) int64 {
+	var h float64
+	for _, p := range paras {
+		var width, size float64
+		for _, r := range p.runs {
+			width += textWidth(r.text, r.size*scale)
+			size = max(size, r.size*scale)
+		}
+		if size == 0 {
+			size = 18 * scale
+		}
+		n := math.Max(1, math.Ceil(width/float64(w)))
+		h += n*size*1.2*emuPerPt + p.spaceBefore*scale*emuPerPt
+	}
+	return int64(h)
+}
+
+// textWidth guesses the width of text on one line at size points.
+func textWidth(text string, size float64) float64 {
+	var em float64
+	for _, r := range text {
+		if r >= 0x2E80 {
+			em += 1
+		} else {
+			em += 0.5
+		}
+	}
+	return em * size * emuPerPt
+}
@@ -0,0 +1,58 @@
+package goextract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/highlight"
+)
+
+// Line returns the line of the snippet that line of the file is at, or 0
+// when it is not in the snippet or was elided.
+func (s *Snippet) Line(line int) int {
+	for i, l := range s.Lines {
+		if l == line {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Focus rewrites a focus config written with the lines of the file, like
+// "120-124,130{err}", into one with the lines of the snippet, as code
+// pages count them. Lines outside of the snippet are left out.
+func (s *Snippet) Focus(spec string) string {
+	var parts []string
+	start, end := 0, 0
+	flush := func() {
+		switch {
+		case start == 0:
+		case start == end:
+			parts = append(parts, fmt.Sprint(start))
+		default:
+			parts = append(parts, fmt.Sprintf("%d-%d", start, end))
+		}
+		start, end = 0, 0
+	}
+	for _, fl := range highlight.ParseFocus(spec) {
+		n := s.Line(fl.Line)
+		if n == 0 {
+			continue
+		}
+		if fl.Text != "" {
+			flush()
+			text, _ := json.Marshal(fl.Text)
+			parts = append(parts, fmt.Sprintf("%d{%s}", n, text))
+			continue
+		}
+		if start != 0 && n == end+1 {
+			end = n
+			continue
+		}
+		flush()
+		start, end = n, n
+	}
+	flush()
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,347 @@
+// Package goextract extracts the declaration of a Go function, method,
+// type, const or var block from a module by its qualified name, such as
+// server.Serve or pkg/store.Revision, for code pages that show a symbol
+// instead of a copy of it.
+//
+// The bodies of the functions nested in a declaration can be elided to
+// keep a snippet short; snippets carry the lines of the file each of
+// their lines is from, so focus configs written against the file still
+// point at the right lines.
+package goextract
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned for a package or symbol that does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Options control what is extracted.
+type Options struct {
+	// Elide replaces the bodies of the function literals in the
+	// declaration with "...".
+	Elide bool
+}
+
+// Snippet is the source of a declaration.
+type Snippet struct {
+	// Package is the import path of the package, or its directory
+	// relative to the module when there is no go.mod.
+	Package string `json:"package"`
+	// File is the file of the declaration, relative to the module
+	// directory, with slashes.
+	File string `json:"file,omitempty"`
+	// Symbol is the name in the package, Name or Type.Method.
+	Symbol string `json:"symbol"`
+	Code   string `json:"code"`
+	// Start and End are the lines of the file the declaration spans, with
+	// its doc comment.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Lines are the lines of the file the lines of Code are from, which
+	// skip the lines of elided bodies.
+	Lines []int `json:"lines"`
+}
+
+// Extract finds the declaration of a qualified name in the module in dir.
+// The package is named by its import path, its directory in the module
+// or, when that is unique, by its name or the last element of its path:
+//
+//	server.Serve
+//	pkg/store.SessionStore
+//	github.com/xhd2015/presentationer/pkg/model.Page.Validate
+//
+// Only the files go build would compile on this platform are searched,
+// and the package must be in the module.
+func Extract(dir string, name string, opts Options) (*Snippet, error) {
+	slash := strings.LastIndex(name, "/")
+	pkgName, symbol, ok := strings.Cut(name[slash+1:], ".")
+	if !ok || pkgName == "" || symbol == "" {
+		return nil, fmt.Errorf("%s: want a qualified name like pkg.Name or pkg.Type.Method", name)
+	}
+	pkgPath := name[:slash+1] + pkgName
+
+	modPath, err := modulePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	var rel string
+	switch {
+	case modPath != "" && (pkgPath == modPath || strings.HasPrefix(pkgPath, modPath+"/")):
+		rel = strings.TrimPrefix(strings.TrimPrefix(pkgPath, modPath), "/")
+	case slash >= 0:
+		rel = pkgPath
+	default:
+		if rel, err = findPackage(dir, pkgName); err != nil {
+			return nil, err
+		}
+	}
+
+	if rel != "" && !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return nil, fmt.Errorf("package %s is outside of the module", pkgPath)
+	}
+
+	pkgDir := filepath.Join(dir, filepath.FromSlash(rel))
+	entries, err := os.ReadDir(pkgDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("package %s %w", pkgPath, ErrNotFound)
+		}
+		return nil, fmt.Errorf("package %s: %w", pkgPath, err)
+	}
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || !goFile(pkgDir, file) {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(pkgDir, file))
+		if err != nil {
+			return nil, err
+		}
+		snippet, err := ExtractFile(src, symbol, opts)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", path.Join(rel, file), err)
+		}
+		snippet.Package = rel
+		if modPath != "" {
+			snippet.Package = strings.TrimSuffix(path.Join(modPath, rel), "/")
+		}
+		snippet.File = path.Join(rel, file)
+		return snippet, nil
+	}
+	return nil, fmt.Errorf("%s %w in package %s", symbol, ErrNotFound, pkgPath)
+}
+
+// ExtractFile finds the declaration of symbol, Name or Type.Method, in
+// the Go source of one file. A const, var or type in a group is extracted
+// with its group.
+func ExtractFile(src []byte, symbol string, opts Options) (*Snippet, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	decl, doc := find(f, symbol)
+	if decl == nil {
+		return nil, fmt.Errorf("symbol %s %w", symbol, ErrNotFound)
+	}
+	start := decl.Pos()
+	if doc != nil {
+		start = doc.Pos()
+	}
+	tf := fset.File(decl.Pos())
+	from := tf.Offset(tf.LineStart(tf.Line(start)))
+	to := tf.Offset(decl.End())
+
+	// the bodies to elide, as offsets of what is between their braces
+	var cuts [][2]int
+	if opts.Elide {
+		ast.Inspect(decl, func(n ast.Node) bool {
+			lit, ok := n.(*ast.FuncLit)
+			if !ok || tf.Line(lit.Body.Lbrace) == tf.Line(lit.Body.Rbrace) {
+				return true
+			}
+			cuts = append(cuts, [2]int{tf.Offset(lit.Body.Lbrace) + 1, tf.Offset(lit.Body.Rbrace)})
+			return false
+		})
+		sort.Slice(cuts, func(i, j int) bool { return cuts[i][0] < cuts[j][0] })
+	}
+
+	snippet := &Snippet{Symbol: symbol, Start: tf.Line(start), End: tf.Line(decl.End())}
+	var b strings.Builder
+	line := snippet.Start
+	snippet.Lines = []int{line}
+	write := func(text []byte) {
+		for _, c := range text {
+			b.WriteByte(c)
+			if c == '\n' {
+				line++
+				snippet.Lines = append(snippet.Lines, line)
+			}
+		}
+	}
+	pos := from
+	for _, cut := range cuts {
+		write(src[pos:cut[0]])
+		b.WriteString(" ... ")
+		line += bytes.Count(src[cut[0]:cut[1]], []byte("\n"))
+		pos = cut[1]
+	}
+	write(src[pos:to])
+	snippet.Code = b.String()
+	return snippet, nil
+}
+
+// find returns the declaration of symbol in f and its doc comment.
+func find(f *ast.File, symbol string) (ast.Node, *ast.CommentGroup) {
+	recv, name, isMethod := strings.Cut(symbol, ".")
+	if !isMethod {
+		recv, name = "", recv
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Name == name && receiverType(d) == recv {
+				return d, d.Doc
+			}
+		case *ast.GenDecl:
+			if recv != "" {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if declares(spec, name) {
+					return d, d.Doc
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// receiverType returns the name of the type of a method, "" for a
+// function.
+func receiverType(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return ""
+	}
+	typ := d.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func declares(spec ast.Spec, name string) bool {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.Name == name
+	case *ast.ValueSpec:
+		for _, n := range s.Names {
+			if n.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// goFile reports whether file in dir is a Go file of the package as
+// go build would compile it for this platform, leaving out tests and the
+// files whose build constraints don't match.
+func goFile(dir string, file string) bool {
+	if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+		return false
+	}
+	match, err := build.Default.MatchFile(dir, file)
+	return err == nil && match
+}
+
+// modulePath returns the module path in the go.mod of dir, "" when there
+// is none.
+func modulePath(dir string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if mod, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(mod), `"`), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// findPackage returns the directory, relative to dir, of the one package
+// in the module that is called name or is in a directory called name.
+func findPackage(dir string, name string) (string, error) {
+	var found []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		base := d.Name()
+		if p != dir && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") || base == "testdata" || base == "vendor" || base == "node_modules") {
+			return filepath.SkipDir
+		}
+		if p != dir {
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				// another module
+				return filepath.SkipDir
+			}
+		}
+		if base == name || packageName(p) == name {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			if rel == "." {
+				rel = ""
+			}
+			found = append(found, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("package %s %w", name, ErrNotFound)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("package %s is ambiguous, use its path: %s", name, strings.Join(found, ", "))
+}
+
+// packageName returns the name of the package in dir, from the package
+// clause of its first Go file.
+func packageName(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || !goFile(dir, file) {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, file), nil, parser.PackageClauseOnly)
+		if err == nil {
+			return f.Name.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package goextract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExtract(t *testing.T) {
+	otherOS := "plan9"
+	if runtime.GOOS == otherOS {
+		otherOS = "windows"
+	}
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.24\n",
+		"pkg/store/store.go": `package store
+
+// Store keeps things.
+type Store struct{}
+
+// Get returns a thing.
+func (s *Store) Get() int { return 1 }
+
+func Get() int { return 2 }
+
+type List[T any] []T
+
+func (l List[T]) Len() int { return len(l) }
+
+const (
+	A = 1
+	B = 2
+)
+`,
+		"pkg/store/other_" + otherOS + ".go": "package store\n\nfunc Platform() {}\n",
+		"pkg/store/ignored.go":               "//go:build ignore\n\npackage store\n\nfunc Ignored() {}\n",
+		"pkg/store/store_test.go":            "package store\n\nfunc Helper() {}\n",
+		"server/server.go":                   "package server\n\nfunc Serve() {}\n",
+		"cmd/server/main.go":                 "package main\n\nfunc main() {}\n",
+	})
+	tests := []struct {
+		name   string
+		pkg    string
+		file   string
+		symbol string
+		start  int
+		err    string
+	}{
+		{name: "store.Store.Get", pkg: "example.com/m/pkg/store", file: "pkg/store/store.go", symbol: "Store.Get", start: 6},
+		{name: "pkg/store.Get", pkg: "example.com/m/pkg/store", file: "pkg/store/store.go", symbol: "Get", start: 9},
+		{name: "example.com/m/pkg/store.List.Len", pkg: "example.com/m/pkg/store", file: "pkg/store/store.go", symbol: "List.Len", start: 13},
+		{name: "store.B", pkg: "example.com/m/pkg/store", file: "pkg/store/store.go", symbol: "B", start: 15},
+		{name: "store.Store", pkg: "example.com/m/pkg/store", file: "pkg/store/store.go", symbol: "Store", start: 3},
+		{name: "store.Platform", err: "Platform not found"},
+		{name: "store.Ignored", err: "Ignored not found"},
+		{name: "store.Helper", err: "Helper not found"},
+		{name: "store.Store.Put", err: "Store.Put not found"},
+		{name: "server.Serve", err: "package server is ambiguous"},
+		{name: "missing.F", err: "package missing not found"},
+		{name: "pkg/missing.F", err: "package pkg/missing not found"},
+		{name: "Serve", err: "want a qualified name"},
+		{name: "../m/server.Serve", err: "outside of the module"},
+		{name: "pkg/../../x.F", err: "outside of the module"},
+		{name: "/etc.F", err: "outside of the module"},
+		{name: "example.com/m/../x.F", err: "outside of the module"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Extract(dir, tt.name, Options{})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, want error %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Package != tt.pkg || s.File != tt.file || s.Symbol != tt.symbol || s.Start != tt.start {
+				t.Errorf("got %s %s %s at %d", s.Package, s.File, s.Symbol, s.Start)
+			}
+		})
+	}
+	if _, err := Extract(dir, "store.Nope", Options{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing symbol: %v, want ErrNotFound", err)
+	}
+}
+
+const handlerSrc = `package server
+
+// Handle registers the routes.
+func Handle(mux *Mux) {
+	mux.Get("/a", func(w Writer) {
+		w.Write("a")
+		w.Write("b")
+	})
+	mux.Get("/b", func(w Writer) { w.Write("c") })
+	mux.Get("/c", func(w Writer) {
+		w.Write("d")
+	})
+}
+`
+
+func TestExtractFileElide(t *testing.T) {
+	s, err := ExtractFile([]byte(handlerSrc), "Handle", Options{Elide: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `// Handle registers the routes.
+func Handle(mux *Mux) {
+	mux.Get("/a", func(w Writer) { ... })
+	mux.Get("/b", func(w Writer) { w.Write("c") })
+	mux.Get("/c", func(w Writer) { ... })
+}`
+	if s.Code != want {
+		t.Errorf("got code\n%s\nwant\n%s", s.Code, want)
+	}
+	if s.Start != 3 || s.End != 13 {
+		t.Errorf("spans %d-%d, want 3-13", s.Start, s.End)
+	}
+	if wantLines := []int{3, 4, 5, 9, 10, 13}; !reflect.DeepEqual(s.Lines, wantLines) {
+		t.Errorf("lines %v, want %v", s.Lines, wantLines)
+	}
+	for fileLine, snippetLine := range map[int]int{3: 1, 5: 3, 6: 0, 9: 4, 13: 6, 14: 0} {
+		if got := s.Line(fileLine); got != snippetLine {
+			t.Errorf("Line(%d) = %d, want %d", fileLine, got, snippetLine)
+		}
+	}
+	for spec, want := range map[string]string{
+		"9":           "4",
+		"4-10":        "2-5",
+		"5-7,13":      "3,6",
+		`9{"Write"}`:  `4{"Write"}`,
+		"6,7":         "",
+		"1-3,9{c},10": `1,4{"c"},5`,
+	} {
+		if got := s.Focus(spec); got != want {
+			t.Errorf("Focus(%q) = %q, want %q", spec, got, want)
+		}
+	}
+
+	// without eliding the snippet is the declaration as it is
+	s, err = ExtractFile([]byte(handlerSrc), "Handle", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(handlerSrc, "\n"); s.Code != strings.Join(lines[2:13], "\n") || len(s.Lines) != 11 || s.Lines[10] != 13 {
+		t.Errorf("got %q with lines %v", s.Code, s.Lines)
+	}
+}
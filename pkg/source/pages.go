@@ -0,0 +1,98 @@
+package source
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+// PageStatus is the status of a code page linked to a source.
+type PageStatus struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Code is the code of the page as it is now.
+	Code string `json:"code"`
+	Status
+	// Refreshed is set by RefreshPages on the pages it took a new snapshot
+	// of.
+	Refreshed bool `json:"refreshed,omitempty"`
+}
+
+// linked calls fn with the content of each code page with a source.
+// Pages whose content does not decode are skipped.
+func linked(pages []model.Page, fn func(p *model.Page, c *model.CodeContent)) {
+	for i := range pages {
+		p := &pages[i]
+		if p.Kind != model.PageKindCode {
+			continue
+		}
+		content, err := p.DecodeContent()
+		if err != nil {
+			continue
+		}
+		if c := content.(*model.CodeContent); c.Source != nil && c.Source.Path != "" {
+			fn(p, c)
+		}
+	}
+}
+
+// ResolvePages resolves the code pages with a source and returns their
+// status, with the code as it is now. The pages keep their snapshots: only
+// RefreshPages replaces them, together with the line they start at.
+func ResolvePages(pages []model.Page) []PageStatus {
+	var statuses []PageStatus
+	linked(pages, func(p *model.Page, c *model.CodeContent) {
+		code, status := Resolve(c)
+		statuses = append(statuses, PageStatus{ID: p.ID, Title: p.Title, Code: code, Status: status})
+	})
+	return statuses
+}
+
+// RefreshPages takes a new snapshot of the code pages with a source, see
+// Refresh, and returns their status.
+func RefreshPages(pages []model.Page) ([]PageStatus, error) {
+	var statuses []PageStatus
+	var err error
+	linked(pages, func(p *model.Page, c *model.CodeContent) {
+		status, changed := Refresh(c)
+		if changed && err == nil {
+			p.Content, err = setFields(p.Content, map[string]interface{}{"code": c.Code, "source": c.Source})
+		}
+		statuses = append(statuses, PageStatus{ID: p.ID, Title: p.Title, Code: c.Code, Status: status, Refreshed: changed})
+	})
+	return statuses, err
+}
+
+// Files returns the absolute paths, with symlinks resolved, of the files the code pages are
+// linked to, sorted.
+func Files(pages []model.Page) []string {
+	seen := make(map[string]bool)
+	var files []string
+	linked(pages, func(p *model.Page, c *model.CodeContent) {
+		file, err := File(c.Source)
+		if err != nil || seen[file] {
+			return
+		}
+		seen[file] = true
+		files = append(files, file)
+	})
+	sort.Strings(files)
+	return files
+}
+
+// setFields sets fields of a JSON object, keeping the others as they are.
+func setFields(raw json.RawMessage, fields map[string]interface{}) (json.RawMessage, error) {
+	obj := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = data
+	}
+	return json.Marshal(obj)
+}
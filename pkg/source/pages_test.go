@@ -0,0 +1,63 @@
+package source
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+func codePage(t *testing.T, id string, c *model.CodeContent) model.Page {
+	t.Helper()
+	page := model.Page{ID: id, Title: id}
+	if err := page.EncodeContent(c); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+// TestResolvePagesKeepsSnapshots checks resolving reports the current
+// code without touching the pages, so saving them back keeps the drift.
+func TestResolvePagesKeepsSnapshots(t *testing.T) {
+	dir := allowTempDir(t)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pages := []model.Page{
+		codePage(t, "p", &model.CodeContent{Code: "old", Source: &model.SourceRef{Root: dir, Path: "a.txt", Lines: "2-3", Line: 2}}),
+		codePage(t, "plain", &model.CodeContent{Code: "x"}),
+	}
+	before := append([]byte(nil), pages[0].Content...)
+
+	statuses := ResolvePages(pages)
+	if len(statuses) != 1 {
+		t.Fatalf("got %d statuses, want 1", len(statuses))
+	}
+	st := statuses[0]
+	if st.ID != "p" || st.Code != "two\nthree" || st.State != StateChanged || st.Start != 2 || st.End != 3 {
+		t.Errorf("got %+v", st)
+	}
+	if !bytes.Equal(pages[0].Content, before) {
+		t.Errorf("ResolvePages changed the page to %s", pages[0].Content)
+	}
+
+	statuses, err := RefreshPages(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Refreshed {
+		t.Error("RefreshPages did not take a new snapshot")
+	}
+	content, err := pages[0].DecodeContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := content.(*model.CodeContent); c.Code != "two\nthree" || c.Source.Line != 2 {
+		t.Errorf("snapshot %q at line %d", c.Code, c.Source.Line)
+	}
+	if st := ResolvePages(pages)[0]; st.State != StateOK {
+		t.Errorf("state after refresh = %s, want ok", st.State)
+	}
+}
@@ -0,0 +1,263 @@
+// Package source links code pages to the files their code comes from. It
+// reads the lines a model.SourceRef points at, tells how the snapshot on
+// the page has drifted from them, takes new snapshots and watches the
+// files for changes.
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/xhd2015/presentationer/pkg/config"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/source/goextract"
+)
+
+// State is how the snapshot of a code page compares with its source.
+type State string
+
+const (
+	// StateOK is a snapshot matching its source.
+	StateOK State = "ok"
+	// StateMoved is a snapshot whose lines are unchanged but elsewhere in
+	// the file.
+	StateMoved State = "moved"
+	// StateChanged is a snapshot that differs from the lines referenced.
+	StateChanged State = "changed"
+	// StateMissing is a source whose file or symbol cannot be found.
+	StateMissing State = "missing"
+)
+
+// Status is the drift of a code page from its source.
+type Status struct {
+	State State `json:"state"`
+	// Start and End are the lines of the file the code is at now, zero
+	// when the source is missing.
+	Start int `json:"start,omitempty"`
+	End   int `json:"end,omitempty"`
+	// Line is the line the snapshot was taken at, zero if unknown.
+	Line int `json:"line,omitempty"`
+	// Error tells why the source is missing.
+	Error string `json:"error,omitempty"`
+}
+
+// allowed holds the directories set by AllowRoots.
+var allowed struct {
+	sync.RWMutex
+	roots []string
+}
+
+// AllowRoots sets the directories, besides the working directory, whose
+// files code pages may link to: the root of a source must be one of them
+// or inside one. Page content comes from bundles and the API, so it can't
+// choose the directories itself. Directories that don't exist are left
+// out.
+func AllowRoots(roots []string) {
+	var real []string
+	for _, root := range roots {
+		if dir, err := realPath(root); err == nil {
+			real = append(real, dir)
+		}
+	}
+	allowed.Lock()
+	allowed.roots = real
+	allowed.Unlock()
+}
+
+// Root returns the directory root names, with ~ expanded and symlinks
+// resolved, if it is an allowed one; empty names the working directory.
+func Root(root string) (string, error) {
+	root, err := config.ExpandHome(root)
+	if err != nil {
+		return "", err
+	}
+	if root == "" {
+		root = "."
+	}
+	dir, err := realPath(root)
+	if err != nil {
+		return "", err
+	}
+	if wd, err := realPath("."); err == nil && within(wd, dir) {
+		return dir, nil
+	}
+	allowed.RLock()
+	defer allowed.RUnlock()
+	for _, r := range allowed.roots {
+		if within(r, dir) {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("source root %s is not allowed, add it to sourceRoots in %s", root, config.FileName)
+}
+
+// File returns the path of the file ref points at, with symlinks
+// resolved. Page content can come from anywhere, so the root must be an
+// allowed one, see Root, and the file must stay within it.
+func File(ref *model.SourceRef) (string, error) {
+	root, err := Root(ref.Root)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.FromSlash(ref.Path)
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("source path %s is not within the root", ref.Path)
+	}
+	file, err := filepath.EvalSymlinks(filepath.Join(root, path))
+	if err != nil {
+		return "", err
+	}
+	if !within(root, file) {
+		return "", fmt.Errorf("source path %s is not within the root", ref.Path)
+	}
+	return file, nil
+}
+
+func realPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// within reports whether path is dir or inside it; both are absolute.
+func within(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func readLines(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	return strings.Split(text, "\n"), nil
+}
+
+// locate returns the code ref points at in the lines of its file, and
+// the lines of the file it spans.
+func locate(ref *model.SourceRef, lines []string) (string, int, int, error) {
+	start, end := 1, len(lines)
+	switch {
+	case ref.Symbol != "":
+		snippet, err := goextract.ExtractFile([]byte(strings.Join(lines, "\n")), ref.Symbol, goextract.Options{Elide: ref.Elide})
+		if err != nil {
+			return "", 0, 0, err
+		}
+		return snippet.Code, snippet.Start, snippet.End, nil
+	case ref.Lines != "":
+		var err error
+		start, end, err = model.ParseLineRange(ref.Lines)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		if start > len(lines) {
+			return "", 0, 0, fmt.Errorf("%s has %d lines", ref.Path, len(lines))
+		}
+		end = min(end, len(lines))
+	}
+	return strings.Join(lines[start-1:end], "\n"), start, end, nil
+}
+
+// Resolve reads the source of a code page and compares it with the
+// snapshot in its code. It returns the code as it is now, which for lines
+// that moved is where they moved to, or the snapshot when the source is
+// missing.
+func Resolve(c *model.CodeContent) (string, Status) {
+	ref := c.Source
+	file, err := File(ref)
+	var lines []string
+	if err == nil {
+		lines, err = readLines(file)
+	}
+	var current string
+	var start, end int
+	if err == nil {
+		current, start, end, err = locate(ref, lines)
+	}
+	if err != nil {
+		return c.Code, Status{State: StateMissing, Line: ref.Line, Error: err.Error()}
+	}
+	status := Status{State: StateChanged, Start: start, End: end, Line: ref.Line}
+	snapshot := strings.TrimRight(strings.ReplaceAll(c.Code, "\r\n", "\n"), "\n")
+	switch {
+	case current == snapshot:
+		status.State = StateOK
+		if ref.Line != 0 && ref.Line != start {
+			status.State = StateMoved
+		}
+	case ref.Symbol == "" && ref.Lines != "" && ref.Line == start && snapshot != "":
+		// the range is where the snapshot was taken: follow the lines if
+		// they are still in the file
+		if at := find(lines, strings.Split(snapshot, "\n"), start); at > 0 {
+			status.State = StateMoved
+			status.Start, status.End = at, at+strings.Count(snapshot, "\n")
+			current = snapshot
+		}
+	}
+	return current, status
+}
+
+// find returns the line lines has want at, the closest to near when there
+// are several, or 0.
+func find(lines []string, want []string, near int) int {
+	best := 0
+	for i := 0; i+len(want) <= len(lines); i++ {
+		match := true
+		for j := range want {
+			if lines[i+j] != want[j] {
+				match = false
+				break
+			}
+		}
+		if match && (best == 0 || abs(i+1-near) < abs(best-near)) {
+			best = i + 1
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Refresh takes a new snapshot of the source of a code page: the code
+// becomes the source as it is now and the range follows lines that moved.
+// It reports whether the content changed; a missing source leaves it as
+// it is.
+func Refresh(c *model.CodeContent) (Status, bool) {
+	code, status := Resolve(c)
+	if status.State == StateMissing {
+		return status, false
+	}
+	ref := c.Source
+	changed := false
+	if c.Code != code {
+		c.Code = code
+		changed = true
+	}
+	if ref.Lines != "" && status.State == StateMoved {
+		lines := fmt.Sprintf("%d-%d", status.Start, status.End)
+		if status.Start == status.End {
+			lines = fmt.Sprint(status.Start)
+		}
+		if lines != ref.Lines {
+			ref.Lines = lines
+			changed = true
+		}
+	}
+	if ref.Line != status.Start {
+		ref.Line = status.Start
+		changed = true
+	}
+	status.State, status.Line = StateOK, status.Start
+	return status, changed
+}
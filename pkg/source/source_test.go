@@ -0,0 +1,136 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+// allowTempDir returns a temporary directory that sources may be read
+// from, with symlinks resolved.
+func allowTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	AllowRoots([]string{dir})
+	t.Cleanup(func() { AllowRoots(nil) })
+	return dir
+}
+
+func writeFile(t *testing.T, path string, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFile(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	writeFile(t, filepath.Join(root, "pkg", "a.go"), "package pkg\n")
+	writeFile(t, filepath.Join(outside, "secret"), "secret\n")
+	symlink := func(target string, link string) {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	symlink(filepath.Join(outside, "secret"), filepath.Join(root, "escape"))
+	symlink(outside, filepath.Join(root, "escapedir"))
+	symlink(filepath.Join(root, "pkg", "a.go"), filepath.Join(root, "inside"))
+	symlink(root, filepath.Join(base, "link-to-root"))
+	AllowRoots([]string{root})
+	t.Cleanup(func() { AllowRoots(nil) })
+
+	tests := []struct {
+		name string
+		ref  model.SourceRef
+		want string // the file, or the start of the error with "error: "
+	}{
+		{"file in the root", model.SourceRef{Root: root, Path: "pkg/a.go"}, filepath.Join(root, "pkg", "a.go")},
+		{"sub directory as root", model.SourceRef{Root: filepath.Join(root, "pkg"), Path: "a.go"}, filepath.Join(root, "pkg", "a.go")},
+		{"symlinked root", model.SourceRef{Root: filepath.Join(base, "link-to-root"), Path: "pkg/a.go"}, filepath.Join(root, "pkg", "a.go")},
+		{"symlink within the root", model.SourceRef{Root: root, Path: "inside"}, filepath.Join(root, "pkg", "a.go")},
+		{"root not allowed", model.SourceRef{Root: outside, Path: "secret"}, "error: source root"},
+		{"filesystem root", model.SourceRef{Root: "/", Path: "etc/passwd"}, "error: source root"},
+		{"parent of the root", model.SourceRef{Root: base, Path: "outside/secret"}, "error: source root"},
+		{"dot dot", model.SourceRef{Root: root, Path: "../outside/secret"}, "error: source path"},
+		{"absolute path", model.SourceRef{Root: root, Path: filepath.Join(outside, "secret")}, "error: source path"},
+		{"symlink out of the root", model.SourceRef{Root: root, Path: "escape"}, "error: source path"},
+		{"through a symlinked directory", model.SourceRef{Root: root, Path: "escapedir/secret"}, "error: source path"},
+		{"missing file", model.SourceRef{Root: root, Path: "missing.go"}, "error: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := File(&tt.ref)
+			if want, ok := strings.CutPrefix(tt.want, "error: "); ok {
+				if err == nil || !strings.HasPrefix(err.Error(), want) {
+					t.Errorf("File() = %q, %v, want error %q", got, err, want)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("File() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileWorkingDirectory(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "a.go"), "package a\n")
+	t.Chdir(dir)
+	got, err := File(&model.SourceRef{Path: "a.go"})
+	if err != nil || got != filepath.Join(dir, "a.go") {
+		t.Errorf("File() = %q, %v", got, err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := allowTempDir(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "zero\none\ntwo\nthree\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package a\n\n// F is f.\nfunc F() int {\n\treturn 1\n}\n")
+
+	tests := []struct {
+		name  string
+		code  string
+		ref   model.SourceRef
+		state State
+		start int
+		end   int
+		want  string
+	}{
+		{"lines unchanged", "one\ntwo", model.SourceRef{Path: "a.txt", Lines: "2-3", Line: 2}, StateOK, 2, 3, "one\ntwo"},
+		{"lines changed", "uno\ndos", model.SourceRef{Path: "a.txt", Lines: "2-3", Line: 2}, StateChanged, 2, 3, "one\ntwo"},
+		{"lines moved", "one\ntwo", model.SourceRef{Path: "a.txt", Lines: "1-2", Line: 1}, StateMoved, 2, 3, "one\ntwo"},
+		{"whole file", "zero\none\ntwo\nthree\n", model.SourceRef{Path: "a.txt"}, StateOK, 1, 4, "zero\none\ntwo\nthree"},
+		{"symbol", "old", model.SourceRef{Path: "a.go", Symbol: "F"}, StateChanged, 3, 6, "// F is f.\nfunc F() int {\n\treturn 1\n}"},
+		{"missing symbol", "old", model.SourceRef{Path: "a.go", Symbol: "G"}, StateMissing, 0, 0, "old"},
+		{"missing file", "old", model.SourceRef{Path: "b.txt"}, StateMissing, 0, 0, "old"},
+		{"range past the end", "old", model.SourceRef{Path: "a.txt", Lines: "9-10"}, StateMissing, 0, 0, "old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := tt.ref
+			ref.Root = dir
+			code, status := Resolve(&model.CodeContent{Code: tt.code, Source: &ref})
+			if code != tt.want || status.State != tt.state || status.Start != tt.start || status.End != tt.end {
+				t.Errorf("Resolve() = %q, %+v, want %q, %s %d-%d", code, status, tt.want, tt.state, tt.start, tt.end)
+			}
+		})
+	}
+}
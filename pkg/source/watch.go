@@ -0,0 +1,132 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settle is how long a watcher waits for more changes before it reports
+// them, as saving a file is often several writes.
+const settle = 100 * time.Millisecond
+
+// Watcher reports changes to a set of files. It watches the directories
+// they are in, since editors often save a file by replacing it.
+type Watcher struct {
+	w       *fsnotify.Watcher
+	changes chan []string
+	done    chan struct{}
+
+	mu    sync.Mutex
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// NewWatcher returns a watcher of no files.
+func NewWatcher() (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		w:       fw,
+		changes: make(chan []string),
+		done:    make(chan struct{}),
+		files:   make(map[string]bool),
+		dirs:    make(map[string]bool),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Set replaces the files watched, given as absolute paths. Files in
+// directories that do not exist are not watched.
+func (w *Watcher) Set(files []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files = make(map[string]bool, len(files))
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		w.files[filepath.Clean(file)] = true
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			w.w.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+	for dir := range dirs {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.w.Add(dir); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		w.dirs[dir] = true
+	}
+	return nil
+}
+
+// Changes returns the channel the paths of the files that changed are
+// sent on, sorted. It is closed when the watcher is.
+func (w *Watcher) Changes() <-chan []string {
+	return w.changes
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.w.Close()
+}
+
+func (w *Watcher) run() {
+	defer close(w.changes)
+	pending := make(map[string]bool)
+	timer := time.NewTimer(settle)
+	timer.Stop()
+	for {
+		select {
+		case ev, ok := <-w.w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			file := filepath.Clean(ev.Name)
+			w.mu.Lock()
+			watched := w.files[file]
+			w.mu.Unlock()
+			if watched {
+				pending[file] = true
+				timer.Reset(settle)
+			}
+		case _, ok := <-w.w.Errors:
+			if !ok {
+				return
+			}
+		case <-timer.C:
+			changed := make([]string, 0, len(pending))
+			for file := range pending {
+				changed = append(changed, file)
+			}
+			sort.Strings(changed)
+			pending = make(map[string]bool)
+			select {
+			case w.changes <- changed:
+			case <-w.done:
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
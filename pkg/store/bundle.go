@@ -0,0 +1,288 @@
+package store
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+// Bundles
+//
+// A bundle is a zip archive holding one session, conventionally named
+// <session>.presentationer:
+//
+//	manifest.json      the BundleManifest
+//	pages/0001.json    one model.Page per file, in the order of the manifest
+//	avatars/<name>     the avatar images
+//
+// BundleVersion is bumped on incompatible changes; Import refuses bundles
+// newer than it understands.
+
+const (
+	BundleFormat  = "presentationer"
+	BundleVersion = 1
+	// BundleExt is the file extension of bundles.
+	BundleExt = ".presentationer"
+
+	maxBundleEntry = 64 << 20
+	// maxBundleSize caps the entries together, so that many entries each
+	// under maxBundleEntry can't expand a small archive without bound.
+	maxBundleSize = 256 << 20
+)
+
+type BundleManifest struct {
+	Format       string    `json:"format"`
+	Version      int       `json:"version"`
+	Name         string    `json:"name"`
+	LastModified time.Time `json:"lastModified"`
+	// Pages lists the page files in session order.
+	Pages []string `json:"pages"`
+	// Avatars lists the avatar names; each is stored as avatars/<name>.
+	Avatars []string `json:"avatars"`
+}
+
+// OnConflict says what Import and ImportSession do when the session
+// already exists.
+type OnConflict string
+
+const (
+	// ConflictFail fails the import with ErrConflict.
+	ConflictFail OnConflict = "fail"
+	// ConflictRename imports under a free name made by model.CopyTitle.
+	ConflictRename OnConflict = "rename"
+	// ConflictOverwrite replaces the pages and avatars of the session; the
+	// replaced pages stay in its history.
+	ConflictOverwrite OnConflict = "overwrite"
+)
+
+// ParseOnConflict parses an OnConflict, defaulting to ConflictFail.
+func ParseOnConflict(s string) (OnConflict, error) {
+	switch OnConflict(s) {
+	case "", ConflictFail:
+		return ConflictFail, nil
+	case ConflictRename, ConflictOverwrite:
+		return OnConflict(s), nil
+	}
+	return "", Errorf(ErrInvalid, "invalid conflict mode %q, expect fail, rename or overwrite", s)
+}
+
+// Export writes session name of s, with its pages and avatars, to w as a
+// bundle. Revision history is not included.
+func Export(ctx context.Context, s SessionStore, name string, w io.Writer) error {
+	session, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	avatars, err := s.ListAvatars(ctx, name)
+	if err != nil {
+		return err
+	}
+	manifest := BundleManifest{
+		Format:       BundleFormat,
+		Version:      BundleVersion,
+		Name:         session.Name,
+		LastModified: session.LastModified,
+		Pages:        make([]string, 0, len(session.Pages)),
+		Avatars:      avatars,
+	}
+
+	zw := zip.NewWriter(w)
+	writeJSON := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeZipEntry(zw, name, data)
+	}
+	for i, page := range session.Pages {
+		file := fmt.Sprintf("pages/%04d.json", i+1)
+		manifest.Pages = append(manifest.Pages, file)
+		if err := writeJSON(file, page); err != nil {
+			return err
+		}
+	}
+	for _, avatar := range avatars {
+		data, err := s.GetAvatar(ctx, name, avatar)
+		if err != nil {
+			return fmt.Errorf("read avatar %s: %w", avatar, err)
+		}
+		if err := writeZipEntry(zw, "avatars/"+avatar, data); err != nil {
+			return err
+		}
+	}
+	if err := writeJSON("manifest.json", manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZipEntry(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// Import reads a bundle from r into s as session name, or under the name
+// in its manifest when name is empty, and returns the name it was imported
+// as.
+func Import(ctx context.Context, s SessionStore, r io.Reader, name string, onConflict OnConflict) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", Errorf(ErrInvalid, "not a session bundle: %v", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	var total int64
+	readEntry := func(name string) ([]byte, error) {
+		f := files[name]
+		if f == nil {
+			return nil, Errorf(ErrInvalid, "bundle has no %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, Errorf(ErrInvalid, "read %s: %v", name, err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxBundleEntry+1))
+		if err != nil {
+			return nil, Errorf(ErrInvalid, "read %s: %v", name, err)
+		}
+		if len(data) > maxBundleEntry {
+			return nil, Errorf(ErrInvalid, "%s is larger than %d MB", name, maxBundleEntry>>20)
+		}
+		total += int64(len(data))
+		if total > maxBundleSize {
+			return nil, Errorf(ErrInvalid, "bundle is larger than %d MB", maxBundleSize>>20)
+		}
+		return data, nil
+	}
+
+	manifestData, err := readEntry("manifest.json")
+	if err != nil {
+		return "", err
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return "", Errorf(ErrInvalid, "read manifest.json: %v", err)
+	}
+	if manifest.Format != BundleFormat {
+		return "", Errorf(ErrInvalid, "not a session bundle: format %q", manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > BundleVersion {
+		return "", Errorf(ErrInvalid, "unsupported bundle version %d, expect up to %d", manifest.Version, BundleVersion)
+	}
+
+	pages := make([]model.Page, 0, len(manifest.Pages))
+	for _, file := range manifest.Pages {
+		data, err := readEntry(file)
+		if err != nil {
+			return "", err
+		}
+		var page model.Page
+		if err := json.Unmarshal(data, &page); err != nil {
+			return "", Errorf(ErrInvalid, "read %s: %v", file, err)
+		}
+		pages = append(pages, page)
+	}
+	if err := model.ValidatePages(pages); err != nil {
+		return "", err
+	}
+	avatars := make(map[string][]byte, len(manifest.Avatars))
+	for _, avatar := range manifest.Avatars {
+		if path.Base(avatar) != avatar {
+			return "", Errorf(ErrInvalid, "invalid avatar name %q", avatar)
+		}
+		data, err := readEntry("avatars/" + avatar)
+		if err != nil {
+			return "", err
+		}
+		avatars[avatar] = data
+	}
+
+	if name == "" {
+		name = manifest.Name
+	}
+	session := &model.Session{Name: name, Pages: pages}
+	return ImportSession(ctx, s, session, avatars, onConflict)
+}
+
+// ImportSession creates session, with avatars, in s and returns the name
+// it was created as, which differs from session.Name after a rename on
+// conflict. The pages should have been validated. A session it creates
+// is deleted again when its avatars can't be saved.
+func ImportSession(ctx context.Context, s SessionStore, session *model.Session, avatars map[string][]byte, onConflict OnConflict) (string, error) {
+	if session.Name == "" {
+		return "", Errorf(ErrInvalid, "session name required")
+	}
+	name := session.Name
+	taken := func(name string) bool {
+		_, err := s.Get(ctx, name)
+		return err == nil
+	}
+	overwrite := false
+	if taken(name) {
+		switch onConflict {
+		case ConflictRename:
+			session.Name = model.CopyTitle(name, taken)
+		case ConflictOverwrite:
+			overwrite = true
+		default:
+			return "", Errorf(ErrConflict, "session %s already exists", name)
+		}
+	}
+
+	if overwrite {
+		if err := s.Update(ctx, session); err != nil {
+			return "", err
+		}
+		existing, err := s.ListAvatars(ctx, session.Name)
+		if err != nil {
+			return "", err
+		}
+		for _, avatar := range existing {
+			if _, ok := avatars[avatar]; ok {
+				continue
+			}
+			if err := s.DeleteAvatar(ctx, session.Name, avatar); err != nil {
+				return "", err
+			}
+		}
+	} else if err := s.Create(ctx, session); err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(avatars))
+	for avatar := range avatars {
+		names = append(names, avatar)
+	}
+	sort.Strings(names)
+	for _, avatar := range names {
+		if err := s.SaveAvatar(ctx, session.Name, avatar, avatars[avatar]); err != nil {
+			err = fmt.Errorf("save avatar %s: %w", avatar, err)
+			if !overwrite {
+				if deleteErr := s.Delete(ctx, session.Name); deleteErr != nil {
+					err = errors.Join(err, deleteErr)
+				}
+			}
+			return "", err
+		}
+	}
+	return session.Name, nil
+}
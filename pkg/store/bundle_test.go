@@ -0,0 +1,200 @@
+package store_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/memory"
+)
+
+func page(t *testing.T, id string, title string, c model.Content) model.Page {
+	t.Helper()
+	p := model.Page{ID: id, Title: title}
+	if err := p.EncodeContent(c); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func exportDeck(t *testing.T) (*model.Session, map[string][]byte, []byte) {
+	t.Helper()
+	ctx := context.Background()
+	s := memory.New()
+	session := &model.Session{Name: "deck", Pages: []model.Page{
+		page(t, "1", "Intro", &model.RectangleContent{Text: "hi"}),
+		page(t, "2", "Code", &model.CodeContent{Code: "package main", Language: "go"}),
+		page(t, "3", "Chat", &model.ChatThreadContent{Messages: []model.Message{{Sender: "alice", Content: "hello"}}}),
+	}}
+	if err := s.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	avatars := map[string][]byte{"alice.png": []byte("alice"), "bob.png": []byte("bob")}
+	for name, data := range avatars {
+		if err := s.SaveAvatar(ctx, "deck", name, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := store.Export(ctx, s, "deck", &buf); err != nil {
+		t.Fatal(err)
+	}
+	return session, avatars, buf.Bytes()
+}
+
+func TestBundleRoundtrip(t *testing.T) {
+	ctx := context.Background()
+	want, avatars, bundle := exportDeck(t)
+
+	s := memory.New()
+	name, err := store.Import(ctx, s, bytes.NewReader(bundle), "", store.ConflictFail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "deck" {
+		t.Errorf("imported as %s, want deck", name)
+	}
+	got, err := s.Get(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Pages) != len(want.Pages) {
+		t.Fatalf("got %d pages, want %d", len(got.Pages), len(want.Pages))
+	}
+	for i, p := range got.Pages {
+		w := want.Pages[i]
+		gotContent, err := p.DecodeContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantContent, err := w.DecodeContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ID != w.ID || p.Title != w.Title || p.Kind != w.Kind || !reflect.DeepEqual(gotContent, wantContent) {
+			t.Errorf("page %d: got %+v, want %+v", i, p, w)
+		}
+	}
+	for avatar, data := range avatars {
+		if got, err := s.GetAvatar(ctx, name, avatar); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("avatar %s: %q, %v", avatar, got, err)
+		}
+	}
+
+	// importing again conflicts, unless renamed or overwritten
+	if _, err := store.Import(ctx, s, bytes.NewReader(bundle), "", store.ConflictFail); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("second import: %v, want ErrConflict", err)
+	}
+	renamed, err := store.Import(ctx, s, bytes.NewReader(bundle), "", store.ConflictRename)
+	if err != nil || renamed == "deck" {
+		t.Errorf("renamed import: %q, %v", renamed, err)
+	}
+	if err := s.SaveAvatar(ctx, "deck", "carol.png", []byte("carol")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Import(ctx, s, bytes.NewReader(bundle), "", store.ConflictOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := s.ListAvatars(ctx, "deck"); err != nil || !reflect.DeepEqual(names, []string{"alice.png", "bob.png"}) {
+		t.Errorf("avatars after overwrite: %q, %v", names, err)
+	}
+}
+
+// bundle writes a zip of the entries, with the manifest marshaled.
+func bundle(t *testing.T, manifest *store.BundleManifest, entries map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name string, data []byte) {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, data := range entries {
+		write(name, data)
+	}
+	if manifest != nil {
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		write("manifest.json", data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportInvalid(t *testing.T) {
+	manifest := func(version int, avatars ...string) *store.BundleManifest {
+		return &store.BundleManifest{Format: store.BundleFormat, Version: version, Name: "deck", Pages: []string{}, Avatars: avatars}
+	}
+	// avatars of zeros that compress well but together pass the size cap
+	large := make(map[string][]byte)
+	zeros := make([]byte, 60<<20)
+	var names []string
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("a%d.png", i)
+		large["avatars/"+name] = zeros
+		names = append(names, name)
+	}
+	tests := []struct {
+		name   string
+		bundle []byte
+		err    string
+	}{
+		{"not a zip", []byte("hello"), "not a session bundle"},
+		{"no manifest", bundle(t, nil, nil), "bundle has no manifest.json"},
+		{"other format", bundle(t, &store.BundleManifest{Format: "other", Version: 1}, nil), "not a session bundle"},
+		{"newer version", bundle(t, manifest(store.BundleVersion+1), nil), "unsupported bundle version"},
+		{"missing page", bundle(t, &store.BundleManifest{Format: store.BundleFormat, Version: 1, Name: "deck", Pages: []string{"pages/0001.json"}}, nil), "bundle has no pages/0001.json"},
+		{"avatar path", bundle(t, manifest(1, "../x.png"), nil), "invalid avatar name"},
+		{"too large", bundle(t, manifest(1, names...), large), "bundle is larger than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := memory.New()
+			_, err := store.Import(context.Background(), s, bytes.NewReader(tt.bundle), "", store.ConflictFail)
+			if !errors.Is(err, store.ErrInvalid) || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want ErrInvalid %q", err, tt.err)
+			}
+			if _, err := s.Get(context.Background(), "deck"); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("session created: %v", err)
+			}
+		})
+	}
+}
+
+// failingAvatars is a store that can't save avatars.
+type failingAvatars struct {
+	store.SessionStore
+}
+
+func (failingAvatars) SaveAvatar(ctx context.Context, sessionName string, avatarName string, data []byte) error {
+	return errors.New("disk full")
+}
+
+func TestImportCleansUp(t *testing.T) {
+	ctx := context.Background()
+	_, _, data := exportDeck(t)
+	s := failingAvatars{memory.New()}
+	if _, err := store.Import(ctx, s, bytes.NewReader(data), "", store.ConflictFail); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("got %v, want the avatar error", err)
+	}
+	if _, err := s.Get(ctx, "deck"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("the failed import left the session: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors
+//
+// Implementations wrap one of these sentinels in every error caused by
+// the request rather than by the backend, so callers can branch with
+// errors.Is instead of matching messages. *RevisionConflictError wraps
+// ErrConflict.
+var (
+	// ErrNotFound means a session, page, revision or avatar does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the change clashes with existing data, such as a
+	// taken session name or page title.
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid means the request itself is malformed, such as a page
+	// index out of range.
+	ErrInvalid = errors.New("invalid")
+)
+
+// Errorf returns an error with the formatted message that wraps kind, one
+// of the sentinels above, as well as any error wrapped with %w.
+func Errorf(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
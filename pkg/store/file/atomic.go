@@ -0,0 +1,222 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Crash safety
+//
+// Single files (avatars, the session marker) are written to a hidden temp
+// file next to the target and renamed over it. The pages directory is
+// rebuilt as a whole in a hidden ".pages.new" sibling and swapped in by
+// renaming the live directory to ".pages.old" and the staged one into
+// place. A new session is assembled in a hidden staging directory under
+// RootDir and renamed to its final name. Recover repairs whatever an
+// interrupted swap left behind.
+
+const (
+	tmpFileMarker        = ".tmp-"
+	stagedDirSuffix      = ".new"
+	previousDirSuffix    = ".old"
+	stagingSessionPrefix = ".presentationer-new-"
+)
+
+func stagedDir(dir string) string {
+	return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+stagedDirSuffix)
+}
+
+func previousDir(dir string) string {
+	return filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+previousDirSuffix)
+}
+
+// writeFileAtomic replaces path with data so that readers see either the
+// old or the new content, never a partial write.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+tmpFileMarker+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// writeFileSync writes a file that nobody else can see yet, e.g. one
+// inside a staged directory, and flushes it to disk.
+func writeFileSync(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// some platforms cannot fsync a directory; the rename is still atomic
+	d.Sync()
+	return nil
+}
+
+// swapDir replaces dir with its fully written staged sibling. If keepAs
+// is not empty the replaced directory is moved there instead of deleted.
+func swapDir(dir string, keepAs string) error {
+	staged := stagedDir(dir)
+	previous := previousDir(dir)
+
+	if err := os.RemoveAll(previous); err != nil {
+		return err
+	}
+	if err := os.Rename(dir, previous); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(staged, dir); err != nil {
+		// put the live directory back
+		if rerr := os.Rename(previous, dir); rerr != nil && !os.IsNotExist(rerr) {
+			return fmt.Errorf("swap %s: %v (restore: %v)", dir, err, rerr)
+		}
+		return err
+	}
+	if err := syncDir(filepath.Dir(dir)); err != nil {
+		return err
+	}
+	if keepAs != "" {
+		if err := os.MkdirAll(filepath.Dir(keepAs), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(previous, keepAs); err == nil {
+			return nil
+		}
+	}
+	return os.RemoveAll(previous)
+}
+
+// recoverDir finishes or rolls back a swapDir that was interrupted.
+//
+// The live directory is only moved aside once the staged one is complete,
+// so a missing live directory with a staged sibling means the swap should
+// be completed; a live directory with a staged sibling means staging never
+// finished and the staged copy is discarded.
+func recoverDir(dir string) error {
+	staged := stagedDir(dir)
+	previous := previousDir(dir)
+
+	_, dirErr := os.Stat(dir)
+	_, stagedErr := os.Stat(staged)
+
+	switch {
+	case os.IsNotExist(dirErr) && stagedErr == nil:
+		if err := os.Rename(staged, dir); err != nil {
+			return err
+		}
+	case os.IsNotExist(dirErr):
+		if _, err := os.Stat(previous); err == nil {
+			if err := os.Rename(previous, dir); err != nil {
+				return err
+			}
+		}
+	case stagedErr == nil:
+		if err := os.RemoveAll(staged); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(previous)
+}
+
+// removeTempFiles deletes temp files left by an interrupted writeFileAtomic.
+func removeTempFiles(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if isTempFile(e.Name()) {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tmpFileMarker)
+}
+
+// Recover repairs state left behind by a process killed in the middle of
+// a write: half-created sessions are removed, interrupted page swaps are
+// completed or rolled back and temp files are deleted.
+func (s *FileSessionStore) Recover() error {
+	unlock, err := s.lockRoot(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(s.RootDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if strings.HasPrefix(name, stagingSessionPrefix) {
+			if err := os.RemoveAll(filepath.Join(s.RootDir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !s.isSession(name) {
+			continue
+		}
+		if err := removeTempFiles(s.getSessionDir(name)); err != nil {
+			return err
+		}
+		if err := recoverDir(s.getPagesDir(name)); err != nil {
+			return fmt.Errorf("recover session %s: %w", name, err)
+		}
+		if err := removeTempFiles(s.getAvatarsDir(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
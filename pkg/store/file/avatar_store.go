@@ -4,11 +4,22 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+
+	"github.com/xhd2015/presentationer/pkg/store"
 )
 
 // Avatar Operations
 
 func (s *FileSessionStore) ListAvatars(ctx context.Context, sessionName string) ([]string, error) {
+	if err := CheckSessionName(sessionName); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSession(sessionName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	avatarsDir := s.getAvatarsDir(sessionName)
 	entries, err := os.ReadDir(avatarsDir)
 	if err != nil {
@@ -20,7 +31,7 @@ func (s *FileSessionStore) ListAvatars(ctx context.Context, sessionName string)
 
 	avatars := []string{}
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() && !isTempFile(entry.Name()) {
 			avatars = append(avatars, entry.Name())
 		}
 	}
@@ -28,27 +39,88 @@ func (s *FileSessionStore) ListAvatars(ctx context.Context, sessionName string)
 }
 
 func (s *FileSessionStore) SaveAvatar(ctx context.Context, sessionName string, avatarName string, data []byte) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	if err := checkAvatarName(avatarName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !s.isSession(sessionName) {
+		return store.Errorf(store.ErrNotFound, "session %s not found", sessionName)
+	}
 	avatarsDir := s.getAvatarsDir(sessionName)
 	if err := os.MkdirAll(avatarsDir, 0755); err != nil {
 		return err
 	}
 
 	filePath := filepath.Join(avatarsDir, avatarName)
-	return os.WriteFile(filePath, data, 0644)
+	return writeFileAtomic(filePath, data, 0644)
 }
 
 func (s *FileSessionStore) DeleteAvatar(ctx context.Context, sessionName string, avatarName string) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	if err := checkAvatarName(avatarName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	filePath := s.getAvatarPath(sessionName, avatarName)
-	return os.Remove(filePath)
+	if err := os.Remove(filePath); err != nil {
+		return notFound(err, "avatar %s not found in session %s", avatarName, sessionName)
+	}
+	return nil
 }
 
 func (s *FileSessionStore) RenameAvatar(ctx context.Context, sessionName string, oldName string, newName string) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	if err := checkAvatarName(oldName, newName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	oldPath := s.getAvatarPath(sessionName, oldName)
 	newPath := s.getAvatarPath(sessionName, newName)
-	return os.Rename(oldPath, newPath)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return notFound(err, "avatar %s not found in session %s", oldName, sessionName)
+	}
+	return nil
 }
 
 func (s *FileSessionStore) GetAvatar(ctx context.Context, sessionName string, avatarName string) ([]byte, error) {
+	if err := CheckSessionName(sessionName); err != nil {
+		return nil, err
+	}
+	if err := checkAvatarName(avatarName); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSession(sessionName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	filePath := s.getAvatarPath(sessionName, avatarName)
-	return os.ReadFile(filePath)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, notFound(err, "avatar %s not found in session %s", avatarName, sessionName)
+	}
+	return data, nil
 }
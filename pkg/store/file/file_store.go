@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
 )
 
 const ConfigDirName = ".presentationer"
@@ -24,12 +25,30 @@ type SessionContent struct {
 // FileSessionStore implements store.SessionStore using the file system
 type FileSessionStore struct {
 	RootDir string
+
+	// HistoryLimit is the number of past revisions kept per session.
+	// Zero means DefaultHistoryLimit; a negative value disables history.
+	HistoryLimit int
+	// HistoryMaxAge, when set, also drops snapshots older than this.
+	HistoryMaxAge time.Duration
+
+	locks locker
 }
 
 func New(rootDir string) *FileSessionStore {
 	return &FileSessionStore{RootDir: rootDir}
 }
 
+// Open returns a store rooted at rootDir, first recovering any write
+// that was interrupted by a crash.
+func Open(rootDir string) (*FileSessionStore, error) {
+	s := New(rootDir)
+	if err := s.Recover(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 func (s *FileSessionStore) getSessionDir(name string) string {
 	return filepath.Join(s.RootDir, name)
 }
@@ -46,7 +65,20 @@ func (s *FileSessionStore) getAvatarPath(sessionName, avatarName string) string
 	return filepath.Join(s.getAvatarsDir(sessionName), avatarName)
 }
 
+// isSession reports whether name is a session directory, i.e. contains
+// the .presentationer marker.
+func (s *FileSessionStore) isSession(name string) bool {
+	_, err := os.Stat(filepath.Join(s.getSessionDir(name), ConfigDirName))
+	return err == nil
+}
+
 func (s *FileSessionStore) List(ctx context.Context) ([]model.Session, error) {
+	unlock, err := s.lockRoot(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	entries, err := os.ReadDir(s.RootDir)
 	if err != nil {
 		return nil, err
@@ -59,10 +91,12 @@ func (s *FileSessionStore) List(ctx context.Context) ([]model.Session, error) {
 		}
 
 		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
 
 		// Check if .presentationer exists
-		configPath := filepath.Join(s.RootDir, name, ConfigDirName)
-		if _, err := os.Stat(configPath); err != nil {
+		if !s.isSession(name) {
 			continue
 		}
 
@@ -74,13 +108,22 @@ func (s *FileSessionStore) List(ctx context.Context) ([]model.Session, error) {
 			modTime = info.ModTime()
 		} else {
 			// Fallback to session dir
-			info, _ := os.Stat(s.getSessionDir(name))
+			info, err := os.Stat(s.getSessionDir(name))
+			if err != nil {
+				continue
+			}
 			modTime = info.ModTime()
 		}
 
+		var revision int64
+		if meta, err := s.readMeta(name); err == nil {
+			revision = meta.Revision
+		}
+
 		sessions = append(sessions, model.Session{
 			Name:         name,
 			LastModified: modTime,
+			Revision:     revision,
 		})
 	}
 
@@ -92,6 +135,15 @@ func (s *FileSessionStore) List(ctx context.Context) ([]model.Session, error) {
 }
 
 func (s *FileSessionStore) Get(ctx context.Context, name string) (*model.Session, error) {
+	if err := CheckSessionName(name); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSession(name, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	pages, err := s.readPagesFromDir(name)
 	if err != nil {
 		return nil, err
@@ -100,75 +152,216 @@ func (s *FileSessionStore) Get(ctx context.Context, name string) (*model.Session
 	pagesDir := s.getPagesDir(name)
 	info, err := os.Stat(pagesDir)
 	if err != nil {
-		info, _ = os.Stat(s.getSessionDir(name))
+		info, err = os.Stat(s.getSessionDir(name))
+		if err != nil {
+			return nil, notFound(err, "session %s not found", name)
+		}
+	}
+
+	meta, err := s.readMeta(name)
+	if err != nil {
+		return nil, err
 	}
 
 	return &model.Session{
 		Name:         name,
 		Pages:        pages,
 		LastModified: info.ModTime(),
+		Revision:     meta.Revision,
 	}, nil
 }
 
 func (s *FileSessionStore) Create(ctx context.Context, session *model.Session) error {
+	if err := CheckSessionName(session.Name); err != nil {
+		return err
+	}
+	unlock, err := s.lockRoot(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.createSession(ctx, session, "")
+}
+
+// createSession writes a new session. If avatarsDir is not empty the
+// files in it are copied into the new session's avatars. The caller must
+// hold the root lock exclusively.
+func (s *FileSessionStore) createSession(ctx context.Context, session *model.Session, avatarsDir string) error {
 	sessionDir := s.getSessionDir(session.Name)
 	if _, err := os.Stat(sessionDir); err == nil {
-		return fmt.Errorf("session already exists")
+		return store.Errorf(store.ErrConflict, "session already exists")
 	}
 
-	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+	// Assemble the session in a hidden directory and rename it into place
+	// so a crash never leaves a half-created session behind.
+	stagingDir, err := os.MkdirTemp(s.RootDir, stagingSessionPrefix+session.Name+"-")
+	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(stagingDir)
 
 	// Create marker file
-	markerPath := filepath.Join(sessionDir, ConfigDirName)
-	if err := os.WriteFile(markerPath, []byte{}, 0644); err != nil {
+	meta := &sessionMeta{Revision: 1}
+	metaData, err := marshalMeta(meta)
+	if err != nil {
+		return err
+	}
+	markerPath := filepath.Join(stagingDir, ConfigDirName)
+	if err := writeFileSync(markerPath, metaData, 0644); err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(s.getAvatarsDir(session.Name), 0755); err != nil {
+	if err := os.Mkdir(filepath.Join(stagingDir, "avatars"), 0755); err != nil {
 		return err
 	}
+	if avatarsDir != "" {
+		if err := copyFiles(avatarsDir, filepath.Join(stagingDir, "avatars")); err != nil {
+			return err
+		}
+	}
 
-	if err := os.MkdirAll(s.getPagesDir(session.Name), 0755); err != nil {
+	pagesDir := filepath.Join(stagingDir, "pages")
+	if err := os.Mkdir(pagesDir, 0755); err != nil {
+		return err
+	}
+	if err := writePageFiles(pagesDir, session.Pages); err != nil {
+		return err
+	}
+	if err := os.Chmod(stagingDir, 0755); err != nil {
 		return err
 	}
 
-	return s.writePagesToDir(session.Name, session.Pages)
+	if _, err := os.Stat(sessionDir); err == nil {
+		return store.Errorf(store.ErrConflict, "session already exists")
+	}
+	if err := os.Rename(stagingDir, sessionDir); err != nil {
+		return err
+	}
+	if err := syncDir(s.RootDir); err != nil {
+		return err
+	}
+	session.Revision = meta.Revision
+	store.SetRevision(ctx, meta.Revision)
+	return nil
+}
+
+func (s *FileSessionStore) Duplicate(ctx context.Context, src, dst string) error {
+	if err := CheckSessionName(src, dst); err != nil {
+		return err
+	}
+	unlock, err := s.lockRoot(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !s.isSession(src) {
+		return store.Errorf(store.ErrNotFound, "session %s not found", src)
+	}
+	pages, err := s.readPagesFromDir(src)
+	if err != nil {
+		return err
+	}
+	for i := range pages {
+		pages[i].ID = model.NewPageID()
+	}
+	return s.createSession(ctx, &model.Session{Name: dst, Pages: pages}, s.getAvatarsDir(src))
 }
 
 func (s *FileSessionStore) Update(ctx context.Context, session *model.Session) error {
+	if err := CheckSessionName(session.Name); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(session.Name, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	sessionDir := s.getSessionDir(session.Name)
 	if err := os.MkdirAll(sessionDir, 0755); err != nil {
 		return err
 	}
-	if err := os.MkdirAll(s.getPagesDir(session.Name), 0755); err != nil {
+
+	// Update also creates the session, so it checks the revision without
+	// requiring the marker to exist.
+	meta, err := s.readMeta(session.Name)
+	if err != nil {
 		return err
 	}
-
-	return s.writePagesToDir(session.Name, session.Pages)
+	if err := store.CheckRevision(ctx, meta.Revision); err != nil {
+		return err
+	}
+	if err := s.commitPages(ctx, session.Name, meta, session.Pages); err != nil {
+		return err
+	}
+	session.Revision = meta.Revision
+	return nil
 }
 
 func (s *FileSessionStore) Rename(ctx context.Context, oldName, newName string) error {
+	if err := CheckSessionName(oldName, newName); err != nil {
+		return err
+	}
+	unlock, err := s.lockRoot(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// only session directories are moved, not other files under the root
+	if !s.isSession(oldName) {
+		return store.Errorf(store.ErrNotFound, "session %s not found", oldName)
+	}
 	oldPath := s.getSessionDir(oldName)
 	newPath := s.getSessionDir(newName)
 
 	if _, err := os.Stat(newPath); err == nil {
-		return fmt.Errorf("session %s already exists", newName)
+		return store.Errorf(store.ErrConflict, "session %s already exists", newName)
 	}
 
-	return os.Rename(oldPath, newPath)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return notFound(err, "session %s not found", oldName)
+	}
+	return nil
 }
 
 func (s *FileSessionStore) Delete(ctx context.Context, name string) error {
+	if err := CheckSessionName(name); err != nil {
+		return err
+	}
+	unlock, err := s.lockRoot(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// only session directories are removed, not other files under the root
+	if !s.isSession(name) {
+		return store.Errorf(store.ErrNotFound, "session %s not found", name)
+	}
 	sessionDir := s.getSessionDir(name)
 	return os.RemoveAll(sessionDir)
 }
 
 // --- Helper Logic ---
 
+// notFound turns a file system not-exist error into store.ErrNotFound
+// with the given message; other errors are returned unchanged.
+func notFound(err error, format string, args ...interface{}) error {
+	if os.IsNotExist(err) {
+		return store.Errorf(store.ErrNotFound, format, args...)
+	}
+	return err
+}
+
 func (s *FileSessionStore) readPagesFromDir(name string) ([]model.Page, error) {
-	pagesDir := s.getPagesDir(name)
+	return readPageFiles(s.getPagesDir(name))
+}
+
+// readPageFiles reads the N.Title.json files in pagesDir in index order.
+func readPageFiles(pagesDir string) ([]model.Page, error) {
 	if _, err := os.Stat(pagesDir); os.IsNotExist(err) {
 		return []model.Page{}, nil
 	}
@@ -180,7 +373,7 @@ func (s *FileSessionStore) readPagesFromDir(name string) ([]model.Page, error) {
 
 	var files []string
 	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") && strings.HasSuffix(e.Name(), ".json") {
 			files = append(files, e.Name())
 		}
 	}
@@ -195,37 +388,87 @@ func (s *FileSessionStore) readPagesFromDir(name string) ([]model.Page, error) {
 	for _, f := range files {
 		data, err := os.ReadFile(filepath.Join(pagesDir, f))
 		if err != nil {
-			continue
+			return nil, err
 		}
 		var p model.Page
-		if err := json.Unmarshal(data, &p); err == nil {
-			pages = append(pages, p)
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("page file %s: %w", f, err)
 		}
+		pages = append(pages, p)
 	}
 	return pages, nil
 }
 
-func (s *FileSessionStore) writePagesToDir(sessionName string, pages []model.Page) error {
+// writePagesToDir replaces the session's pages with pages. The new files
+// are written to a staged directory which is then swapped in, so a failure
+// at any point leaves either the old or the new deck, never a mix. The
+// replaced directory is moved to keepAs when that is not empty.
+func (s *FileSessionStore) writePagesToDir(sessionName string, pages []model.Page, keepAs string) error {
 	pagesDir := s.getPagesDir(sessionName)
+	staged := stagedDir(pagesDir)
 
-	os.RemoveAll(pagesDir)
-	os.MkdirAll(pagesDir, 0755)
+	if err := os.RemoveAll(staged); err != nil {
+		return err
+	}
+	if err := os.Mkdir(staged, 0755); err != nil {
+		return err
+	}
+	if err := writePageFiles(staged, pages); err != nil {
+		os.RemoveAll(staged)
+		return err
+	}
+	if err := syncDir(staged); err != nil {
+		os.RemoveAll(staged)
+		return err
+	}
+	return swapDir(pagesDir, keepAs)
+}
 
+// writePageFiles writes pages as N.Title.json files into an empty,
+// not yet visible directory.
+func writePageFiles(dir string, pages []model.Page) error {
 	for i, p := range pages {
-		filename := fmt.Sprintf("%d.%s.json", i+1, sanitizeTitle(p.Title))
-		path := filepath.Join(pagesDir, filename)
-
 		data, err := json.MarshalIndent(p, "", "  ")
 		if err != nil {
 			return err
 		}
-		if err := os.WriteFile(path, data, 0644); err != nil {
+		if err := writeFileSync(filepath.Join(dir, pageFileName(i, p.Title)), data, 0644); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// copyFiles copies the regular files in srcDir into the new, not yet
+// visible directory dstDir.
+func copyFiles(srcDir, dstDir string) error {
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || isTempFile(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(srcDir, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := writeFileSync(filepath.Join(dstDir, e.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageFileName returns the file name of the page at zero-based index i.
+func pageFileName(i int, title string) string {
+	return fmt.Sprintf("%d.%s.json", i+1, sanitizeTitle(title))
+}
+
 func getIndexFromFileName(name string) int {
 	parts := strings.SplitN(name, ".", 2)
 	if len(parts) > 0 {
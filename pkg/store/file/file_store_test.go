@@ -0,0 +1,48 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.SessionStore {
+		s, err := Open(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+// TestDeleteOnlySessions checks that Delete and Rename leave alone a
+// directory under the root that is not a session.
+func TestDeleteOnlySessions(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(root)
+	ctx := context.Background()
+	for _, name := range []string{"src", "missing"} {
+		if err := s.Delete(ctx, name); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Delete(%s) = %v, want ErrNotFound", name, err)
+		}
+		if err := s.Rename(ctx, name, "moved"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("Rename(%s) = %v, want ErrNotFound", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(src, "main.go")); err != nil {
+		t.Errorf("src was touched: %v", err)
+	}
+}
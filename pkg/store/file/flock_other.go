@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package file
+
+// flock is a no-op where flock(2) is unavailable; only goroutines in
+// the same process are serialized.
+func flock(path string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
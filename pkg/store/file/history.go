@@ -0,0 +1,189 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// History
+//
+// When a page write replaces revision N, the replaced pages directory is
+// moved to .history/N inside the session instead of being deleted. Only
+// pages are kept; avatars are not versioned.
+
+const (
+	HistoryDirName      = ".history"
+	DefaultHistoryLimit = 50
+)
+
+func (s *FileSessionStore) historyEnabled() bool {
+	return s.HistoryLimit >= 0
+}
+
+func (s *FileSessionStore) getHistoryDir(name string) string {
+	return filepath.Join(s.getSessionDir(name), HistoryDirName)
+}
+
+func (s *FileSessionStore) getSnapshotDir(name string, revision int64) string {
+	return filepath.Join(s.getHistoryDir(name), strconv.FormatInt(revision, 10))
+}
+
+// listSnapshots returns the revisions kept for a session, newest first.
+func (s *FileSessionStore) listSnapshots(name string) ([]int64, error) {
+	entries, err := os.ReadDir(s.getHistoryDir(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var revisions []int64
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		rev, err := strconv.ParseInt(e.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, rev)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i] > revisions[j]
+	})
+	return revisions, nil
+}
+
+// pruneHistory drops snapshots beyond HistoryLimit or older than
+// HistoryMaxAge.
+func (s *FileSessionStore) pruneHistory(name string) error {
+	limit := s.HistoryLimit
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	revisions, err := s.listSnapshots(name)
+	if err != nil {
+		return err
+	}
+	for i, rev := range revisions {
+		dir := s.getSnapshotDir(name, rev)
+		drop := i >= limit
+		if !drop && s.HistoryMaxAge > 0 {
+			if info, err := os.Stat(dir); err == nil && time.Since(info.ModTime()) > s.HistoryMaxAge {
+				drop = true
+			}
+		}
+		if drop {
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileSessionStore) ListRevisions(ctx context.Context, sessionName string) ([]model.RevisionInfo, error) {
+	if err := CheckSessionName(sessionName); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSession(sessionName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if !s.isSession(sessionName) {
+		return nil, store.Errorf(store.ErrNotFound, "session %s not found", sessionName)
+	}
+	revisions, err := s.listSnapshots(sessionName)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]model.RevisionInfo, 0, len(revisions))
+	for _, rev := range revisions {
+		dir := s.getSnapshotDir(sessionName, rev)
+		info, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		pages := 0
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+				pages++
+			}
+		}
+		infos = append(infos, model.RevisionInfo{
+			Revision: rev,
+			Time:     info.ModTime(),
+			Pages:    pages,
+		})
+	}
+	return infos, nil
+}
+
+func (s *FileSessionStore) GetRevision(ctx context.Context, sessionName string, revision int64) (*model.Session, error) {
+	if err := CheckSessionName(sessionName); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSession(sessionName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return s.readSnapshot(sessionName, revision)
+}
+
+func (s *FileSessionStore) readSnapshot(sessionName string, revision int64) (*model.Session, error) {
+	dir := s.getSnapshotDir(sessionName, revision)
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, store.Errorf(store.ErrNotFound, "revision %d not found", revision)
+		}
+		return nil, err
+	}
+	pages, err := readPageFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Session{
+		Name:         sessionName,
+		LastModified: info.ModTime(),
+		Revision:     revision,
+		Pages:        pages,
+	}, nil
+}
+
+func (s *FileSessionStore) RestoreRevision(ctx context.Context, sessionName string, revision int64) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	meta, err := s.checkRevision(ctx, sessionName)
+	if err != nil {
+		return err
+	}
+	snapshot, err := s.readSnapshot(sessionName, revision)
+	if err != nil {
+		return err
+	}
+	return s.commitPages(ctx, sessionName, meta, snapshot.Pages)
+}
@@ -0,0 +1,115 @@
+package file
+
+import (
+	"os"
+	"sort"
+	"sync"
+)
+
+// Locking
+//
+// Operations that add, remove or rename whole sessions take the root lock
+// exclusively. Everything else takes the root lock shared and then the
+// session's own lock, shared for reads and exclusive for writes. Each level
+// is a sync.RWMutex for goroutines in this process plus an advisory flock
+// on the directory for other processes (e.g. a CLI script running next to
+// the server). Locks are always taken root first, then session.
+
+type locker struct {
+	root     sync.RWMutex
+	mu       sync.Mutex
+	sessions map[string]*sync.RWMutex
+}
+
+func (l *locker) session(name string) *sync.RWMutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sessions == nil {
+		l.sessions = make(map[string]*sync.RWMutex)
+	}
+	m, ok := l.sessions[name]
+	if !ok {
+		m = &sync.RWMutex{}
+		l.sessions[name] = m
+	}
+	return m
+}
+
+func lockRW(m *sync.RWMutex, exclusive bool) func() {
+	if exclusive {
+		m.Lock()
+		return m.Unlock
+	}
+	m.RLock()
+	return m.RUnlock
+}
+
+// lockDir takes an advisory lock on dir. A missing dir is not an error:
+// there is nothing on disk to protect yet and the operation itself will
+// report the missing session.
+func lockDir(dir string, exclusive bool) (func(), error) {
+	release, err := flock(dir, exclusive)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return func() {}, nil
+		}
+		return nil, err
+	}
+	return release, nil
+}
+
+// lockRoot locks the set of sessions.
+func (s *FileSessionStore) lockRoot(exclusive bool) (func(), error) {
+	unlockMem := lockRW(&s.locks.root, exclusive)
+	release, err := lockDir(s.RootDir, exclusive)
+	if err != nil {
+		unlockMem()
+		return nil, err
+	}
+	return func() {
+		release()
+		unlockMem()
+	}, nil
+}
+
+// lockSession locks a single session, holding the root lock shared so the
+// session cannot be renamed or deleted underneath the caller.
+func (s *FileSessionStore) lockSession(name string, exclusive bool) (func(), error) {
+	return s.lockSessions(exclusive, name)
+}
+
+// lockSessions locks several sessions at once, in name order so that two
+// callers locking the same pair cannot deadlock.
+func (s *FileSessionStore) lockSessions(exclusive bool, names ...string) (func(), error) {
+	unlockRoot, err := s.lockRoot(false)
+	if err != nil {
+		return nil, err
+	}
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	var unlocks []func()
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+		unlockRoot()
+	}
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		unlockMem := lockRW(s.locks.session(name), exclusive)
+		release, err := lockDir(s.getSessionDir(name), exclusive)
+		if err != nil {
+			unlockMem()
+			unlockAll()
+			return nil, err
+		}
+		unlocks = append(unlocks, func() {
+			release()
+			unlockMem()
+		})
+	}
+	return unlockAll, nil
+}
@@ -0,0 +1,117 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+// TestConcurrentPageWrites races page writers on one session, through two
+// stores on the same directory so the flocks are exercised as well as the
+// in-process locks, and checks no write is lost and the page files stay
+// numbered 1..n.
+func TestConcurrentPageWrites(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow: every write syncs the page files")
+	}
+	const (
+		writers = 8
+		created = 16
+		deleted = 3
+	)
+	dir := t.TempDir()
+	stores := []*FileSessionStore{New(dir), New(dir)}
+	ctx := context.Background()
+	if err := stores[0].Create(ctx, &model.Session{Name: "deck"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			s := stores[w%len(stores)]
+			for i := 0; i < created; i++ {
+				id := fmt.Sprintf("%d-%d", w, i)
+				page := &model.Page{ID: id, Title: "Page " + id, Kind: model.PageKindCode, Content: []byte(`{"code":""}`)}
+				if err := s.CreatePage(ctx, "deck", page); err != nil {
+					errs <- fmt.Errorf("create %s: %w", id, err)
+					return
+				}
+				if err := s.MovePage(ctx, "deck", id, 0); err != nil {
+					errs <- fmt.Errorf("move %s: %w", id, err)
+					return
+				}
+				if i < deleted {
+					if err := s.DeletePage(ctx, "deck", id); err != nil {
+						errs <- fmt.Errorf("delete %s: %w", id, err)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	session, err := New(dir).Get(ctx, "deck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := writers * (created - deleted); len(session.Pages) != want {
+		t.Fatalf("got %d pages, want %d", len(session.Pages), want)
+	}
+	seen := make(map[string]bool)
+	for _, p := range session.Pages {
+		if seen[p.ID] {
+			t.Errorf("page %s appears twice", p.ID)
+		}
+		seen[p.ID] = true
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < created; i++ {
+			id := fmt.Sprintf("%d-%d", w, i)
+			if seen[id] != (i >= deleted) {
+				t.Errorf("page %s: present %v, want %v", id, seen[id], i >= deleted)
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(stores[0].getPagesDir("deck"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indexes []int
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.SplitN(e.Name(), ".", 2)[0])
+		if err != nil {
+			t.Errorf("unexpected file %s", e.Name())
+			continue
+		}
+		indexes = append(indexes, n)
+	}
+	sort.Ints(indexes)
+	for i, n := range indexes {
+		if n != i+1 {
+			t.Fatalf("page files numbered %v, want 1..%d", indexes, len(session.Pages))
+		}
+	}
+	if len(indexes) != len(session.Pages) {
+		t.Fatalf("%d page files for %d pages", len(indexes), len(session.Pages))
+	}
+}
@@ -0,0 +1,94 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// sessionMeta is the content of the .presentationer marker file. Sessions
+// created before the marker had content have an empty file, read as
+// revision 0.
+type sessionMeta struct {
+	Revision int64 `json:"revision"`
+}
+
+func (s *FileSessionStore) getMetaPath(name string) string {
+	return filepath.Join(s.getSessionDir(name), ConfigDirName)
+}
+
+func (s *FileSessionStore) readMeta(name string) (*sessionMeta, error) {
+	meta := &sessionMeta{}
+	data, err := os.ReadFile(s.getMetaPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return meta, nil
+	}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func marshalMeta(meta *sessionMeta) ([]byte, error) {
+	return json.MarshalIndent(meta, "", "  ")
+}
+
+func (s *FileSessionStore) writeMeta(name string, meta *sessionMeta) error {
+	data, err := marshalMeta(meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.getMetaPath(name), data, 0644)
+}
+
+// checkRevision reads the session's meta and fails if the session does
+// not exist or the caller expects a different revision.
+func (s *FileSessionStore) checkRevision(ctx context.Context, name string) (*sessionMeta, error) {
+	if !s.isSession(name) {
+		return nil, store.Errorf(store.ErrNotFound, "session %s not found", name)
+	}
+	meta, err := s.readMeta(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.CheckRevision(ctx, meta.Revision); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// commitPages bumps the revision and replaces the session's pages, keeping
+// the replaced pages as a history snapshot. The revision is written first
+// so an interrupted write can only cause a spurious conflict, never hide a
+// change.
+func (s *FileSessionStore) commitPages(ctx context.Context, name string, meta *sessionMeta, pages []model.Page) error {
+	var snapshot string
+	if s.historyEnabled() {
+		snapshot = s.getSnapshotDir(name, meta.Revision)
+	}
+	meta.Revision++
+	if err := s.writeMeta(name, meta); err != nil {
+		return err
+	}
+	if err := s.writePagesToDir(name, pages, snapshot); err != nil {
+		return err
+	}
+	if snapshot != "" {
+		if err := s.pruneHistory(name); err != nil {
+			return err
+		}
+	}
+	store.SetRevision(ctx, meta.Revision)
+	return nil
+}
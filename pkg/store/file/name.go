@@ -0,0 +1,80 @@
+package file
+
+import (
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Names
+//
+// Session and avatar names become path elements under RootDir, so every
+// store method checks them before touching the file system. A valid name
+// is a single visible path element: no separators, no "." or "..", no
+// leading dot (which would also hide it among the store's own files) and
+// nothing Windows would treat as a device.
+
+const maxNameLength = 255
+
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// checkName returns a store.ErrInvalid error if name cannot be used as a
+// session or avatar name. kind ("session", "avatar") names it in the
+// message.
+func checkName(kind string, name string) error {
+	if reason := nameProblem(name); reason != "" {
+		return store.Errorf(store.ErrInvalid, "invalid %s name %q: %s", kind, name, reason)
+	}
+	return nil
+}
+
+func nameProblem(name string) string {
+	switch {
+	case name == "":
+		return "empty"
+	case len(name) > maxNameLength:
+		return "too long"
+	case name == "." || name == "..":
+		return "not a name"
+	case strings.ContainsAny(name, `/\`):
+		return "contains a path separator"
+	case strings.HasPrefix(name, "."):
+		return "starts with a dot"
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return "contains a control character"
+		}
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(base)] {
+		return "reserved name"
+	}
+	return ""
+}
+
+// CheckSessionName checks session names, for stores built on this one
+// that pass them on to other tools.
+func CheckSessionName(names ...string) error {
+	for _, name := range names {
+		if err := checkName("session", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkAvatarName(names ...string) error {
+	for _, name := range names {
+		if err := checkName("avatar", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
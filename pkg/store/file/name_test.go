@@ -0,0 +1,125 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+func TestNameProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"deck", ""},
+		{"my deck", ""},
+		{"v1.2", ""},
+		{"会议", ""},
+		{"CONTEXT", ""},
+		{"", "empty"},
+		{strings.Repeat("a", maxNameLength+1), "too long"},
+		{".", "not a name"},
+		{"..", "not a name"},
+		{"../deck", "contains a path separator"},
+		{"a/b", "contains a path separator"},
+		{`a\b`, "contains a path separator"},
+		{`..\..`, "contains a path separator"},
+		{".hidden", "starts with a dot"},
+		{".presentationer", "starts with a dot"},
+		{"CON", "reserved name"},
+		{"con", "reserved name"},
+		{"nul.txt", "reserved name"},
+		{"LPT1", "reserved name"},
+		{"a\x00b", "contains a control character"},
+		{"a\nb", "contains a control character"},
+		{"a\x7f", "contains a control character"},
+	}
+	for _, tt := range tests {
+		if got := nameProblem(tt.name); got != tt.want {
+			t.Errorf("nameProblem(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestStoreRejectsBadNames passes bad names to every method taking a
+// session or avatar name and checks each fails with store.ErrInvalid
+// without touching a session-like directory next to RootDir.
+func TestStoreRejectsBadNames(t *testing.T) {
+	base := t.TempDir()
+	outside := filepath.Join(base, "victim")
+	if err := os.MkdirAll(filepath.Join(outside, ConfigDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "me.png"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := New(root)
+	ctx := context.Background()
+	if err := s.Create(ctx, &model.Session{Name: "deck"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveAvatar(ctx, "deck", "me.png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+
+	methods := []struct {
+		name string
+		call func(name string) error
+	}{
+		{"Get", func(name string) error { _, err := s.Get(ctx, name); return err }},
+		{"Create", func(name string) error { return s.Create(ctx, &model.Session{Name: name}) }},
+		{"Update", func(name string) error { return s.Update(ctx, &model.Session{Name: name}) }},
+		{"Rename from", func(name string) error { return s.Rename(ctx, name, "renamed") }},
+		{"Rename to", func(name string) error { return s.Rename(ctx, "deck", name) }},
+		{"Delete", func(name string) error { return s.Delete(ctx, name) }},
+		{"Duplicate from", func(name string) error { return s.Duplicate(ctx, name, "copy") }},
+		{"Duplicate to", func(name string) error { return s.Duplicate(ctx, "deck", name) }},
+		{"CreatePage", func(name string) error {
+			return s.CreatePage(ctx, name, &model.Page{ID: "1", Title: "A", Kind: model.PageKindCode, Content: []byte(`{"code":""}`)})
+		}},
+		{"MovePageToSession", func(name string) error { return s.MovePageToSession(ctx, "deck", "1", name, 0) }},
+		{"ListRevisions", func(name string) error { _, err := s.ListRevisions(ctx, name); return err }},
+		{"ListAvatars", func(name string) error { _, err := s.ListAvatars(ctx, name); return err }},
+		{"GetAvatar session", func(name string) error { _, err := s.GetAvatar(ctx, name, "me.png"); return err }},
+		{"GetAvatar", func(name string) error { _, err := s.GetAvatar(ctx, "deck", name); return err }},
+		{"SaveAvatar session", func(name string) error { return s.SaveAvatar(ctx, name, "me.png", []byte("x")) }},
+		{"SaveAvatar", func(name string) error { return s.SaveAvatar(ctx, "deck", name, []byte("x")) }},
+		{"DeleteAvatar", func(name string) error { return s.DeleteAvatar(ctx, "deck", name) }},
+		{"RenameAvatar from", func(name string) error { return s.RenameAvatar(ctx, "deck", name, "other.png") }},
+		{"RenameAvatar to", func(name string) error { return s.RenameAvatar(ctx, "deck", "me.png", name) }},
+	}
+	badNames := []string{"..", "../victim", "../victim/me.png", "a/b", ".hidden", "CON", "bad\x01name"}
+	for _, m := range methods {
+		for _, name := range badNames {
+			if err := m.call(name); !errors.Is(err, store.ErrInvalid) {
+				t.Errorf("%s(%q) = %v, want ErrInvalid", m.name, name, err)
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(outside, "me.png")); err != nil || string(data) != "keep" {
+		t.Errorf("file outside the root changed: %q, %v", data, err)
+	}
+	if data, err := s.GetAvatar(ctx, "deck", "me.png"); err != nil || string(data) != "png" {
+		t.Errorf("avatar of deck changed: %q, %v", data, err)
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "root" && e.Name() != "victim" {
+			t.Errorf("unexpected %s created next to the root", e.Name())
+		}
+	}
+}
@@ -3,127 +3,269 @@ package file
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
 )
 
+// Page operations rewrite the whole pages directory through
+// commitPages, so renumbering after an insert or delete is atomic.
+
 func (s *FileSessionStore) CreatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	meta, err := s.checkRevision(ctx, sessionName)
+	if err != nil {
+		return err
+	}
 	pages, err := s.readPagesFromDir(sessionName)
 	if err != nil {
 		return err
 	}
 	for _, p := range pages {
 		if p.ID == page.ID {
-			return fmt.Errorf("page ID already exists")
+			return store.Errorf(store.ErrConflict, "page ID already exists")
 		}
 		if p.Title == page.Title {
-			return fmt.Errorf("page title already exists")
+			return store.Errorf(store.ErrConflict, "page title already exists")
 		}
 	}
 
 	// Append
-	index := len(pages) + 1
-	filename := fmt.Sprintf("%d.%s.json", index, sanitizeTitle(page.Title))
-	path := filepath.Join(s.getPagesDir(sessionName), filename)
+	pages = append(pages, *page)
+	return s.commitPages(ctx, sessionName, meta, pages)
+}
 
-	data, err := json.MarshalIndent(page, "", "  ")
+func (s *FileSessionStore) UpdatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
-}
+	defer unlock()
 
-func (s *FileSessionStore) UpdatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	meta, err := s.checkRevision(ctx, sessionName)
+	if err != nil {
+		return err
+	}
 	pages, err := s.readPagesFromDir(sessionName)
 	if err != nil {
 		return err
 	}
 
-	var oldPage *model.Page
-	var index int = -1
-
-	for i, p := range pages {
-		if p.ID == page.ID {
-			oldPage = &pages[i]
-			index = i
-			break
-		}
-	}
-	if oldPage == nil {
-		return fmt.Errorf("page not found")
+	index := findPage(pages, page.ID)
+	if index == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
 	}
 
 	// Check title uniqueness if changed
-	if oldPage.Title != page.Title {
+	if pages[index].Title != page.Title {
 		for _, p := range pages {
 			if p.ID != page.ID && p.Title == page.Title {
-				return fmt.Errorf("page title already exists")
+				return store.Errorf(store.ErrConflict, "page title already exists")
 			}
 		}
 	}
 
-	pagesDir := s.getPagesDir(sessionName)
-	oldFilename := fmt.Sprintf("%d.%s.json", index+1, sanitizeTitle(oldPage.Title))
-	newFilename := fmt.Sprintf("%d.%s.json", index+1, sanitizeTitle(page.Title))
-
-	oldPath := filepath.Join(pagesDir, oldFilename)
-	newPath := filepath.Join(pagesDir, newFilename)
+	pages[index] = *page
+	return s.commitPages(ctx, sessionName, meta, pages)
+}
 
-	data, err := json.MarshalIndent(page, "", "  ")
+func (s *FileSessionStore) DeletePage(ctx context.Context, sessionName string, pageID string) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	if oldFilename != newFilename {
-		// Rename (write new, delete old)
-		// Or just write new, remove old
-		if err := os.WriteFile(newPath, data, 0644); err != nil {
-			return err
-		}
-		os.Remove(oldPath)
-	} else {
-		// Overwrite
-		if err := os.WriteFile(newPath, data, 0644); err != nil {
-			return err
-		}
+	meta, err := s.checkRevision(ctx, sessionName)
+	if err != nil {
+		return err
 	}
-	return nil
-}
-
-func (s *FileSessionStore) DeletePage(ctx context.Context, sessionName string, pageID string) error {
 	pages, err := s.readPagesFromDir(sessionName)
 	if err != nil {
 		return err
 	}
 
-	index := -1
+	index := findPage(pages, pageID)
+	if index == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+
+	// Subsequent pages shift down one index
+	pages = append(pages[:index], pages[index+1:]...)
+	return s.commitPages(ctx, sessionName, meta, pages)
+}
+
+func findPage(pages []model.Page, pageID string) int {
 	for i, p := range pages {
 		if p.ID == pageID {
-			index = i
-			break
+			return i
 		}
 	}
+	return -1
+}
+
+func (s *FileSessionStore) MovePage(ctx context.Context, sessionName string, pageID string, newIndex int) error {
+	if err := CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	unlock, err := s.lockSession(sessionName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.movePage(ctx, sessionName, pageID, newIndex)
+}
+
+func (s *FileSessionStore) movePage(ctx context.Context, sessionName string, pageID string, newIndex int) error {
+	meta, err := s.checkRevision(ctx, sessionName)
+	if err != nil {
+		return err
+	}
+	pages, err := s.readPagesFromDir(sessionName)
+	if err != nil {
+		return err
+	}
+
+	index := findPage(pages, pageID)
 	if index == -1 {
-		return fmt.Errorf("page not found")
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+	if newIndex < 0 || newIndex >= len(pages) {
+		return store.Errorf(store.ErrInvalid, "page index %d out of range", newIndex)
+	}
+	if newIndex == index {
+		store.SetRevision(ctx, meta.Revision)
+		return nil
+	}
+
+	page := pages[index]
+	pages = append(pages[:index], pages[index+1:]...)
+	pages = insertPage(pages, newIndex, page)
+	return s.commitPages(ctx, sessionName, meta, pages)
+}
+
+func (s *FileSessionStore) MovePageToSession(ctx context.Context, fromSession string, pageID string, toSession string, index int) error {
+	if err := CheckSessionName(fromSession, toSession); err != nil {
+		return err
+	}
+	unlock, err := s.lockSessions(true, fromSession, toSession)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if fromSession == toSession {
+		return s.movePage(ctx, fromSession, pageID, index)
+	}
+
+	// The revision precondition applies to the source session.
+	fromMeta, err := s.checkRevision(ctx, fromSession)
+	if err != nil {
+		return err
+	}
+	fromPages, err := s.readPagesFromDir(fromSession)
+	if err != nil {
+		return err
+	}
+	pos := findPage(fromPages, pageID)
+	if pos == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+	page := fromPages[pos]
+
+	if !s.isSession(toSession) {
+		return store.Errorf(store.ErrNotFound, "session %s not found", toSession)
+	}
+	toMeta, err := s.readMeta(toSession)
+	if err != nil {
+		return err
+	}
+	toPages, err := s.readPagesFromDir(toSession)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index > len(toPages) {
+		return store.Errorf(store.ErrInvalid, "page index %d out of range", index)
+	}
+	for _, p := range toPages {
+		if p.ID == page.ID {
+			return store.Errorf(store.ErrConflict, "page ID already exists")
+		}
+		if p.Title == page.Title {
+			return store.Errorf(store.ErrConflict, "page title already exists")
+		}
 	}
 
-	pagesDir := s.getPagesDir(sessionName)
+	// Write the target first: if the second write fails the page exists
+	// in both sessions rather than in neither.
+	toPages = insertPage(toPages, index, page)
+	if err := s.commitPages(context.Background(), toSession, toMeta, toPages); err != nil {
+		return err
+	}
+	fromPages = append(fromPages[:pos], fromPages[pos+1:]...)
+	return s.commitPages(ctx, fromSession, fromMeta, fromPages)
+}
 
-	// Delete target
-	targetFilename := fmt.Sprintf("%d.%s.json", index+1, sanitizeTitle(pages[index].Title))
-	os.Remove(filepath.Join(pagesDir, targetFilename))
+func insertPage(pages []model.Page, index int, page model.Page) []model.Page {
+	pages = append(pages, model.Page{})
+	copy(pages[index+1:], pages[index:])
+	pages[index] = page
+	return pages
+}
 
-	// Renumber subsequent pages
-	for i := index + 1; i < len(pages); i++ {
-		p := pages[i]
-		oldName := fmt.Sprintf("%d.%s.json", i+1, sanitizeTitle(p.Title))
-		newName := fmt.Sprintf("%d.%s.json", i, sanitizeTitle(p.Title)) // Shift down
+func (s *FileSessionStore) DuplicatePage(ctx context.Context, sessionName string, pageID string) (*model.Page, error) {
+	if err := CheckSessionName(sessionName); err != nil {
+		return nil, err
+	}
+	unlock, err := s.lockSession(sessionName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
-		os.Rename(filepath.Join(pagesDir, oldName), filepath.Join(pagesDir, newName))
+	meta, err := s.checkRevision(ctx, sessionName)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := s.readPagesFromDir(sessionName)
+	if err != nil {
+		return nil, err
+	}
+	index := findPage(pages, pageID)
+	if index == -1 {
+		return nil, store.Errorf(store.ErrNotFound, "page not found")
 	}
 
-	return nil
+	page := pages[index]
+	page.ID = model.NewPageID()
+	page.Title = model.CopyTitle(page.Title, func(title string) bool {
+		for _, p := range pages {
+			if p.Title == title {
+				return true
+			}
+		}
+		return false
+	})
+	page.Content = append(json.RawMessage(nil), page.Content...)
+
+	pages = insertPage(pages, index+1, page)
+	if err := s.commitPages(ctx, sessionName, meta, pages); err != nil {
+		return nil, err
+	}
+	return &page, nil
 }
@@ -0,0 +1,482 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/file"
+)
+
+// GitSessionStore implements store.VersionedStore by wrapping a
+// FileSessionStore and committing every change to the git repository that
+// contains its RootDir, using the local git binary. Only the changed
+// session's files are committed, so unrelated work in the same repository
+// is left alone.
+type GitSessionStore struct {
+	*file.FileSessionStore
+
+	repoDir string
+	rootRel string
+
+	// mu serializes mutations with their commits so one commit never
+	// picks up another operation's half-written files.
+	mu sync.Mutex
+}
+
+var _ store.VersionedStore = (*GitSessionStore)(nil)
+
+// New wraps files, initializing a git repository at its RootDir if the
+// directory is not already inside one.
+func New(files *file.FileSessionStore) (*GitSessionStore, error) {
+	root, err := filepath.Abs(files.RootDir)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	top, err := runGit(ctx, root, "rev-parse", "--show-toplevel")
+	if err != nil {
+		if _, err := runGit(ctx, root, "init", "-q"); err != nil {
+			return nil, err
+		}
+		top = root
+	}
+	top, err = filepath.EvalSymlinks(strings.TrimSpace(top))
+	if err != nil {
+		return nil, err
+	}
+	rootRel, err := filepath.Rel(top, root)
+	if err != nil {
+		return nil, err
+	}
+	s := &GitSessionStore{
+		FileSessionStore: files,
+		repoDir:          top,
+		rootRel:          filepath.ToSlash(rootRel),
+	}
+	if err := s.writeGitignore(ctx, root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ignored are the file store's history snapshots and in-flight staging
+// files, which are kept out of the repository by a .gitignore in RootDir.
+var ignored = []string{
+	file.HistoryDirName + "/",
+	".pages.new/",
+	".pages.old/",
+	".*.tmp-*",
+	".presentationer-new-*/",
+}
+
+// writeGitignore adds the ignored patterns missing from the .gitignore of
+// root, creating it if needed, and commits it.
+func (s *GitSessionStore) writeGitignore(ctx context.Context, root string) error {
+	path := filepath.Join(root, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	have := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		have[strings.TrimSpace(line)] = true
+	}
+	var add bytes.Buffer
+	for _, pattern := range ignored {
+		if !have[pattern] {
+			add.WriteString(pattern + "\n")
+		}
+	}
+	if add.Len() == 0 {
+		return nil
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, add.Bytes()...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	return s.commitPaths(ctx, "ignore history and staging files", []string{":(literal)" + pathJoin(s.rootRel, ".gitignore")})
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+func (s *GitSessionStore) git(ctx context.Context, args ...string) (string, error) {
+	return runGit(ctx, s.repoDir, args...)
+}
+
+// sessionPath returns the session directory relative to the repository.
+func (s *GitSessionStore) sessionPath(name string) string {
+	return pathJoin(s.rootRel, name)
+}
+
+func pathJoin(dir, name string) string {
+	if dir == "" || dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// pathspecs selects the given sessions; the .gitignore written by New
+// leaves out the file store's own files. The names are matched literally,
+// so that one like "*" or ":/" selects no other files; callers check them
+// with file.CheckSessionName first.
+func (s *GitSessionStore) pathspecs(sessions ...string) []string {
+	var specs []string
+	for _, name := range sessions {
+		specs = append(specs, ":(literal)"+s.sessionPath(name))
+	}
+	return specs
+}
+
+// commit records the current state of sessions. The store operation has
+// already been applied when it fails, so the error says the change is
+// saved; the next commit of the session picks it up.
+func (s *GitSessionStore) commit(ctx context.Context, message string, sessions ...string) error {
+	if err := s.commitPaths(ctx, message, s.pathspecs(sessions...)); err != nil {
+		return fmt.Errorf("saved, but not committed: %w", err)
+	}
+	return nil
+}
+
+func (s *GitSessionStore) commitPaths(ctx context.Context, message string, specs []string) error {
+	status, err := s.git(ctx, append([]string{"status", "--porcelain", "--"}, specs...)...)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) == "" {
+		return nil
+	}
+	if _, err := s.git(ctx, append([]string{"add", "-A", "--"}, specs...)...); err != nil {
+		return err
+	}
+	args := []string{
+		"-c", "user.name=" + s.configOr(ctx, "user.name", "presentationer"),
+		"-c", "user.email=" + s.configOr(ctx, "user.email", "presentationer@localhost"),
+		"-c", "commit.gpgsign=false",
+		"commit", "-q", "--no-verify", "-m", message, "--only", "--",
+	}
+	_, err = s.git(ctx, append(args, specs...)...)
+	return err
+}
+
+func (s *GitSessionStore) configOr(ctx context.Context, key string, fallback string) string {
+	value, err := s.git(ctx, "config", "--get", key)
+	if err != nil || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return strings.TrimSpace(value)
+}
+
+// pageRef describes a page for a commit message, with its 1-based
+// position when it can be found.
+func (s *GitSessionStore) pageRef(ctx context.Context, sessionName string, pageID string, title string) string {
+	session, err := s.FileSessionStore.Get(ctx, sessionName)
+	if err == nil {
+		for i, p := range session.Pages {
+			if p.ID == pageID {
+				return fmt.Sprintf("page %d '%s'", i+1, p.Title)
+			}
+		}
+	}
+	return fmt.Sprintf("page '%s'", title)
+}
+
+func (s *GitSessionStore) Create(ctx context.Context, session *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.Create(ctx, session); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("create session '%s'", session.Name), session.Name)
+}
+
+func (s *GitSessionStore) Update(ctx context.Context, session *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.Update(ctx, session); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] update %d pages", session.Name, len(session.Pages)), session.Name)
+}
+
+func (s *GitSessionStore) Rename(ctx context.Context, oldName string, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.Rename(ctx, oldName, newName); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("rename session '%s' to '%s'", oldName, newName), oldName, newName)
+}
+
+func (s *GitSessionStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.Delete(ctx, name); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("delete session '%s'", name), name)
+}
+
+func (s *GitSessionStore) Duplicate(ctx context.Context, src string, dst string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.Duplicate(ctx, src, dst); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("duplicate session '%s' as '%s'", src, dst), dst)
+}
+
+func (s *GitSessionStore) CreatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.CreatePage(ctx, sessionName, page); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] add %s", sessionName, s.pageRef(ctx, sessionName, page.ID, page.Title)), sessionName)
+}
+
+func (s *GitSessionStore) UpdatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.UpdatePage(ctx, sessionName, page); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] update %s", sessionName, s.pageRef(ctx, sessionName, page.ID, page.Title)), sessionName)
+}
+
+func (s *GitSessionStore) DeletePage(ctx context.Context, sessionName string, pageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := s.pageRef(ctx, sessionName, pageID, pageID)
+	if err := s.FileSessionStore.DeletePage(ctx, sessionName, pageID); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] delete %s", sessionName, ref), sessionName)
+}
+
+func (s *GitSessionStore) MovePage(ctx context.Context, sessionName string, pageID string, newIndex int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := s.pageRef(ctx, sessionName, pageID, pageID)
+	if err := s.FileSessionStore.MovePage(ctx, sessionName, pageID, newIndex); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] move %s to position %d", sessionName, ref, newIndex+1), sessionName)
+}
+
+func (s *GitSessionStore) MovePageToSession(ctx context.Context, fromSession string, pageID string, toSession string, index int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := s.pageRef(ctx, fromSession, pageID, pageID)
+	if err := s.FileSessionStore.MovePageToSession(ctx, fromSession, pageID, toSession, index); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] move %s to session '%s'", fromSession, ref, toSession), fromSession, toSession)
+}
+
+func (s *GitSessionStore) DuplicatePage(ctx context.Context, sessionName string, pageID string) (*model.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := s.pageRef(ctx, sessionName, pageID, pageID)
+	page, err := s.FileSessionStore.DuplicatePage(ctx, sessionName, pageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commit(ctx, fmt.Sprintf("[%s] duplicate %s as '%s'", sessionName, ref, page.Title), sessionName); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (s *GitSessionStore) RestoreRevision(ctx context.Context, sessionName string, revision int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.RestoreRevision(ctx, sessionName, revision); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] restore revision %d", sessionName, revision), sessionName)
+}
+
+func (s *GitSessionStore) SaveAvatar(ctx context.Context, sessionName string, avatarName string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.SaveAvatar(ctx, sessionName, avatarName, data); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] save avatar '%s'", sessionName, avatarName), sessionName)
+}
+
+func (s *GitSessionStore) DeleteAvatar(ctx context.Context, sessionName string, avatarName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.DeleteAvatar(ctx, sessionName, avatarName); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] delete avatar '%s'", sessionName, avatarName), sessionName)
+}
+
+func (s *GitSessionStore) RenameAvatar(ctx context.Context, sessionName string, oldName string, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.FileSessionStore.RenameAvatar(ctx, sessionName, oldName, newName); err != nil {
+		return err
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] rename avatar '%s' to '%s'", sessionName, oldName, newName), sessionName)
+}
+
+// --- Version control ---
+
+// checkRev rejects revisions git would parse as options.
+func checkRev(rev string) error {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return store.Errorf(store.ErrInvalid, "invalid commit %q", rev)
+	}
+	return nil
+}
+
+func (s *GitSessionStore) Log(ctx context.Context, sessionName string, limit int) ([]model.Commit, error) {
+	if err := file.CheckSessionName(sessionName); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	// a repository without commits has no log
+	if _, err := s.git(ctx, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return []model.Commit{}, nil
+	}
+	args := []string{"log", "-n", strconv.Itoa(limit), "--format=%H%x1f%an%x1f%aI%x1f%s", "--"}
+	out, err := s.git(ctx, append(args, s.pathspecs(sessionName)...)...)
+	if err != nil {
+		return nil, err
+	}
+	commits := []model.Commit{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, fields[2])
+		commits = append(commits, model.Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Time:    t,
+			Message: fields[3],
+		})
+	}
+	return commits, nil
+}
+
+func (s *GitSessionStore) Diff(ctx context.Context, sessionName string, from string, to string) (string, error) {
+	if err := file.CheckSessionName(sessionName); err != nil {
+		return "", err
+	}
+	if from == "" {
+		from = "HEAD"
+	}
+	args := []string{"diff"}
+	for _, rev := range []string{from, to} {
+		if rev == "" {
+			continue
+		}
+		if err := checkRev(rev); err != nil {
+			return "", err
+		}
+		args = append(args, rev)
+	}
+	args = append(args, "--")
+	return s.git(ctx, append(args, s.pathspecs(sessionName)...)...)
+}
+
+func (s *GitSessionStore) Checkout(ctx context.Context, sessionName string, commit string) error {
+	if err := file.CheckSessionName(sessionName); err != nil {
+		return err
+	}
+	if err := checkRev(commit); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pagesPath := s.sessionPath(sessionName) + "/pages/"
+	out, err := s.git(ctx, "ls-tree", "-z", "--name-only", commit, "--", pagesPath)
+	if err != nil {
+		return err
+	}
+	type pageFile struct {
+		index int
+		path  string
+	}
+	var files []pageFile
+	for _, p := range strings.Split(out, "\x00") {
+		if !strings.HasSuffix(p, ".json") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.SplitN(filepath.Base(p), ".", 2)[0])
+		if err != nil {
+			continue
+		}
+		files = append(files, pageFile{index: index, path: p})
+	}
+	if len(files) == 0 {
+		return store.Errorf(store.ErrNotFound, "session %s has no pages at %s", sessionName, commit)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].index < files[j].index
+	})
+
+	pages := make([]model.Page, 0, len(files))
+	for _, f := range files {
+		data, err := s.git(ctx, "show", commit+":"+f.path)
+		if err != nil {
+			return err
+		}
+		var page model.Page
+		if err := json.Unmarshal([]byte(data), &page); err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		pages = append(pages, page)
+	}
+
+	if err := s.FileSessionStore.Update(ctx, &model.Session{Name: sessionName, Pages: pages}); err != nil {
+		return err
+	}
+	short := commit
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	return s.commit(ctx, fmt.Sprintf("[%s] checkout pages at %s", sessionName, short), sessionName)
+}
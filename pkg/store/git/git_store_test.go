@@ -0,0 +1,269 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/file"
+	"github.com/xhd2015/presentationer/pkg/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	storetest.RunConformance(t, func(t *testing.T) store.SessionStore {
+		// the temporary directory is outside any repository, so New
+		// initializes one there
+		files, err := file.Open(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := New(files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+func newStore(t *testing.T) *GitSessionStore {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	files, err := file.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func textPage(t *testing.T, id string, title string, text string) *model.Page {
+	t.Helper()
+	page := &model.Page{ID: id, Title: title}
+	if err := page.EncodeContent(&model.RectangleContent{Text: text}); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestGitignore(t *testing.T) {
+	s := newStore(t)
+	data, err := os.ReadFile(filepath.Join(s.RootDir, ".gitignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(ignored, "\n") + "\n"; string(data) != want {
+		t.Errorf(".gitignore is %q, want %q", data, want)
+	}
+	// committed, and ignoring the staging files of the file store
+	if status, err := s.git(context.Background(), "status", "--porcelain"); err != nil || status != "" {
+		t.Errorf("status %q, %v", status, err)
+	}
+	for _, name := range []string{"deck/.history/1.json", "deck/.pages.new/0.json", "deck/.pages.old/0.json", "deck/pages/.0.json.tmp-123", ".presentationer-new-1/x"} {
+		if _, err := s.git(context.Background(), "check-ignore", "-q", name); err != nil {
+			t.Errorf("%s is not ignored: %v", name, err)
+		}
+	}
+
+	// an existing .gitignore gets the missing patterns
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("mine\n.history/"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := file.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = New(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "mine\n" + strings.Join(ignored, "\n") + "\n"; !fileIs(t, filepath.Join(dir, ".gitignore"), want) {
+		t.Errorf(".gitignore is not %q", want)
+	}
+	if _, err := New(files); err != nil {
+		t.Fatal(err)
+	}
+	if commits, err := s.git(context.Background(), "rev-list", "--count", "HEAD"); err != nil || strings.TrimSpace(commits) != "1" {
+		t.Errorf("%s commits, %v; a complete .gitignore is committed once", commits, err)
+	}
+
+	// a complete .gitignore is left alone, and without commits there is
+	// no log
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(strings.Join(ignored, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if files, err = file.Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = New(files); err != nil {
+		t.Fatal(err)
+	}
+	commits, err := s.Log(context.Background(), "deck", 0)
+	if err != nil || len(commits) != 0 {
+		t.Errorf("Log() = %v, %v on a repository without commits", commits, err)
+	}
+}
+
+func fileIs(t *testing.T, path string, want string) bool {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data) == want
+}
+
+func TestLog(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+	if err := s.Create(ctx, &model.Session{Name: "deck", Pages: []model.Page{*textPage(t, "1", "Intro", "hi")}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePage(ctx, "deck", textPage(t, "2", "Next", "more")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdatePage(ctx, "deck", textPage(t, "1", "Intro", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MovePage(ctx, "deck", "2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DuplicatePage(ctx, "deck", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeletePage(ctx, "deck", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(ctx, &model.Session{Name: "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := s.Log(ctx, "deck", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, c := range commits {
+		messages = append(messages, c.Message)
+		if c.Hash == "" || c.Author == "" || c.Time.IsZero() {
+			t.Errorf("commit %+v", c)
+		}
+	}
+	want := []string{
+		"[deck] delete page 1 'Next'",
+		"[deck] duplicate page 2 'Intro' as 'Intro Copy'",
+		"[deck] move page 2 'Next' to position 1",
+		"[deck] update page 1 'Intro'",
+		"[deck] add page 2 'Next'",
+		"create session 'deck'",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("got log\n%q\nwant\n%q", messages, want)
+	}
+	if commits, err := s.Log(ctx, "deck", 2); err != nil || len(commits) != 2 {
+		t.Errorf("Log(limit 2) = %d commits, %v", len(commits), err)
+	}
+	if _, err := s.Log(ctx, "../deck", 0); !errors.Is(err, store.ErrInvalid) {
+		t.Errorf("Log(../deck) = %v, want ErrInvalid", err)
+	}
+}
+
+func TestDiffAndCheckout(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+	if err := s.Create(ctx, &model.Session{Name: "deck", Pages: []model.Page{*textPage(t, "1", "Intro", "first")}}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Log(ctx, "deck", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdatePage(ctx, "deck", textPage(t, "1", "Intro", "second")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePage(ctx, "deck", textPage(t, "2", "More", "added")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := s.Diff(ctx, "deck", first[0].Hash, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`first`, `second`, "+++ b/deck/pages/2.More.json"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("diff lacks %q:\n%s", want, out)
+		}
+	}
+	// without revisions it compares the files to HEAD: nothing changed
+	if out, err := s.Diff(ctx, "deck", "", ""); err != nil || out != "" {
+		t.Errorf("Diff() = %q, %v", out, err)
+	}
+	if _, err := s.Diff(ctx, "deck", "--output=/tmp/x", ""); !errors.Is(err, store.ErrInvalid) {
+		t.Errorf("Diff(option) = %v, want ErrInvalid", err)
+	}
+
+	if err := s.Checkout(ctx, "deck", first[0].Hash); err != nil {
+		t.Fatal(err)
+	}
+	session, err := s.Get(ctx, "deck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Pages) != 1 || !strings.Contains(string(session.Pages[0].Content), "first") {
+		t.Errorf("pages after checkout: %+v", session.Pages)
+	}
+	commits, err := s.Log(ctx, "deck", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[deck] checkout pages at " + first[0].Hash[:7]; commits[0].Message != want {
+		t.Errorf("message %q, want %q", commits[0].Message, want)
+	}
+	if err := s.Checkout(ctx, "other", first[0].Hash); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Checkout(other) = %v, want ErrNotFound", err)
+	}
+}
+
+// TestCommitError checks a change that can't be committed reports it.
+func TestCommitError(t *testing.T) {
+	s := newStore(t)
+	ctx := context.Background()
+	if err := s.Create(ctx, &model.Session{Name: "deck"}); err != nil {
+		t.Fatal(err)
+	}
+	// a held index lock makes git add fail
+	lock := filepath.Join(s.repoDir, ".git", "index.lock")
+	if err := os.WriteFile(lock, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := s.CreatePage(ctx, "deck", textPage(t, "1", "Intro", "hi"))
+	if err == nil || !strings.Contains(err.Error(), "not committed") {
+		t.Fatalf("CreatePage() = %v, want a commit error", err)
+	}
+	// the page is saved and goes into the next commit
+	if err := os.Remove(lock); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rename(ctx, "deck", "talk"); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := s.git(ctx, "status", "--porcelain"); err != nil || status != "" {
+		t.Errorf("status %q, %v", status, err)
+	}
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Avatar Operations
+
+func avatarNotFound(sessionName string, avatarName string) error {
+	return store.Errorf(store.ErrNotFound, "avatar %s not found in session %s", avatarName, sessionName)
+}
+
+func (s *MemorySessionStore) ListAvatars(ctx context.Context, sessionName string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	avatars := []string{}
+	if sess, ok := s.sessions[sessionName]; ok {
+		for name := range sess.avatars {
+			avatars = append(avatars, name)
+		}
+	}
+	sort.Strings(avatars)
+	return avatars, nil
+}
+
+func (s *MemorySessionStore) SaveAvatar(ctx context.Context, sessionName string, avatarName string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[sessionName]
+	if !ok {
+		return notFound(sessionName)
+	}
+	sess.avatars[avatarName] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemorySessionStore) DeleteAvatar(ctx context.Context, sessionName string, avatarName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[sessionName]
+	if !ok {
+		return notFound(sessionName)
+	}
+	if _, ok := sess.avatars[avatarName]; !ok {
+		return avatarNotFound(sessionName, avatarName)
+	}
+	delete(sess.avatars, avatarName)
+	return nil
+}
+
+// RenameAvatar renames an avatar, replacing any avatar already called
+// newName as a file rename would.
+func (s *MemorySessionStore) RenameAvatar(ctx context.Context, sessionName string, oldName string, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[sessionName]
+	if !ok {
+		return notFound(sessionName)
+	}
+	data, ok := sess.avatars[oldName]
+	if !ok {
+		return avatarNotFound(sessionName, oldName)
+	}
+	delete(sess.avatars, oldName)
+	sess.avatars[newName] = data
+	return nil
+}
+
+func (s *MemorySessionStore) GetAvatar(ctx context.Context, sessionName string, avatarName string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[sessionName]
+	if !ok {
+		return nil, notFound(sessionName)
+	}
+	data, ok := sess.avatars[avatarName]
+	if !ok {
+		return nil, avatarNotFound(sessionName, avatarName)
+	}
+	return append([]byte(nil), data...), nil
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+func (s *MemorySessionStore) ListRevisions(ctx context.Context, sessionName string) ([]model.RevisionInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[sessionName]
+	if !ok {
+		return nil, notFound(sessionName)
+	}
+	infos := make([]model.RevisionInfo, 0, len(sess.history))
+	for i := len(sess.history) - 1; i >= 0; i-- {
+		snap := sess.history[i]
+		infos = append(infos, model.RevisionInfo{
+			Revision: snap.revision,
+			Time:     snap.time,
+			Pages:    len(snap.pages),
+		})
+	}
+	return infos, nil
+}
+
+func (s *MemorySessionStore) GetRevision(ctx context.Context, sessionName string, revision int64) (*model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[sessionName]
+	if !ok {
+		return nil, notFound(sessionName)
+	}
+	snap, err := findSnapshot(sess, revision)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Session{
+		Name:         sessionName,
+		LastModified: snap.time,
+		Revision:     snap.revision,
+		Pages:        copyPages(snap.pages),
+	}, nil
+}
+
+func findSnapshot(sess *entry, revision int64) (*snapshot, error) {
+	for i := range sess.history {
+		if sess.history[i].revision == revision {
+			return &sess.history[i], nil
+		}
+	}
+	return nil, store.Errorf(store.ErrNotFound, "revision %d not found", revision)
+}
+
+func (s *MemorySessionStore) RestoreRevision(ctx context.Context, sessionName string, revision int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.lookup(ctx, sessionName)
+	if err != nil {
+		return err
+	}
+	snap, err := findSnapshot(sess, revision)
+	if err != nil {
+		return err
+	}
+	s.commitPages(ctx, sess, snap.pages)
+	return nil
+}
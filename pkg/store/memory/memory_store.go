@@ -0,0 +1,231 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// DefaultHistoryLimit is the number of past revisions kept per session
+// when HistoryLimit is zero.
+const DefaultHistoryLimit = 50
+
+// MemorySessionStore implements store.SessionStore in memory. It is meant
+// for tests and for trying the server out without touching the disk;
+// everything is lost when the process exits.
+type MemorySessionStore struct {
+	// HistoryLimit is the number of past revisions kept per session.
+	// Zero means DefaultHistoryLimit; a negative value disables history.
+	HistoryLimit int
+
+	mu       sync.RWMutex
+	sessions map[string]*entry
+}
+
+// entry is the stored state of one session.
+type entry struct {
+	revision     int64
+	lastModified time.Time
+	pages        []model.Page
+	avatars      map[string][]byte
+	// history holds replaced page lists, oldest first
+	history []snapshot
+}
+
+type snapshot struct {
+	revision int64
+	time     time.Time
+	pages    []model.Page
+}
+
+var _ store.SessionStore = (*MemorySessionStore)(nil)
+
+func New() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*entry)}
+}
+
+// copyPages returns a deep copy so callers never share content buffers
+// with the store.
+func copyPages(pages []model.Page) []model.Page {
+	out := make([]model.Page, len(pages))
+	for i, p := range pages {
+		out[i] = p
+		out[i].Content = append(json.RawMessage(nil), p.Content...)
+	}
+	return out
+}
+
+func notFound(name string) error {
+	return store.Errorf(store.ErrNotFound, "session %s not found", name)
+}
+
+// lookup returns the named session, failing if the caller expects a
+// different revision. The caller must hold mu.
+func (s *MemorySessionStore) lookup(ctx context.Context, name string) (*entry, error) {
+	sess, ok := s.sessions[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	if err := store.CheckRevision(ctx, sess.revision); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// commitPages replaces the session's pages and bumps its revision,
+// keeping the replaced pages as a history snapshot. The caller must
+// hold mu.
+func (s *MemorySessionStore) commitPages(ctx context.Context, sess *entry, pages []model.Page) {
+	if s.HistoryLimit >= 0 && sess.revision > 0 {
+		limit := s.HistoryLimit
+		if limit == 0 {
+			limit = DefaultHistoryLimit
+		}
+		sess.history = append(sess.history, snapshot{
+			revision: sess.revision,
+			time:     time.Now(),
+			pages:    sess.pages,
+		})
+		if len(sess.history) > limit {
+			sess.history = sess.history[len(sess.history)-limit:]
+		}
+	}
+	sess.pages = copyPages(pages)
+	sess.revision++
+	sess.lastModified = time.Now()
+	store.SetRevision(ctx, sess.revision)
+}
+
+func (s *MemorySessionStore) List(ctx context.Context) ([]model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []model.Session
+	for name, sess := range s.sessions {
+		sessions = append(sessions, model.Session{
+			Name:         name,
+			LastModified: sess.lastModified,
+			Revision:     sess.revision,
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastModified.After(sessions[j].LastModified)
+	})
+	return sessions, nil
+}
+
+func (s *MemorySessionStore) Get(ctx context.Context, name string) (*model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	return &model.Session{
+		Name:         name,
+		LastModified: sess.lastModified,
+		Revision:     sess.revision,
+		Pages:        copyPages(sess.pages),
+	}, nil
+}
+
+func (s *MemorySessionStore) Create(ctx context.Context, session *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.create(session.Name, session.Pages, nil); err != nil {
+		return err
+	}
+	session.Revision = 1
+	store.SetRevision(ctx, 1)
+	return nil
+}
+
+// create adds a session at revision 1. The caller must hold mu.
+func (s *MemorySessionStore) create(name string, pages []model.Page, avatars map[string][]byte) error {
+	if _, ok := s.sessions[name]; ok {
+		return store.Errorf(store.ErrConflict, "session already exists")
+	}
+	sess := &entry{
+		revision:     1,
+		lastModified: time.Now(),
+		pages:        copyPages(pages),
+		avatars:      make(map[string][]byte, len(avatars)),
+	}
+	for k, v := range avatars {
+		sess.avatars[k] = append([]byte(nil), v...)
+	}
+	s.sessions[name] = sess
+	return nil
+}
+
+func (s *MemorySessionStore) Duplicate(ctx context.Context, src, dst string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, ok := s.sessions[src]
+	if !ok {
+		return store.Errorf(store.ErrNotFound, "session %s not found", src)
+	}
+	pages := copyPages(from.pages)
+	for i := range pages {
+		pages[i].ID = model.NewPageID()
+	}
+	if err := s.create(dst, pages, from.avatars); err != nil {
+		return err
+	}
+	store.SetRevision(ctx, 1)
+	return nil
+}
+
+// Update replaces the session's pages, creating the session if it does
+// not exist yet.
+func (s *MemorySessionStore) Update(ctx context.Context, session *model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[session.Name]
+	if !ok {
+		sess = &entry{avatars: make(map[string][]byte)}
+	}
+	if err := store.CheckRevision(ctx, sess.revision); err != nil {
+		return err
+	}
+	s.sessions[session.Name] = sess
+	s.commitPages(ctx, sess, session.Pages)
+	session.Revision = sess.revision
+	return nil
+}
+
+func (s *MemorySessionStore) Rename(ctx context.Context, oldName, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[newName]; ok {
+		return store.Errorf(store.ErrConflict, "session %s already exists", newName)
+	}
+	sess, ok := s.sessions[oldName]
+	if !ok {
+		return notFound(oldName)
+	}
+	delete(s.sessions, oldName)
+	s.sessions[newName] = sess
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[name]; !ok {
+		return notFound(name)
+	}
+	delete(s.sessions, name)
+	return nil
+}
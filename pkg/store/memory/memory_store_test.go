@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.SessionStore {
+		return New()
+	})
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Page operations edit a copy of the page list and swap it in through
+// commitPages.
+
+func (s *MemorySessionStore) CreatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.lookup(ctx, sessionName)
+	if err != nil {
+		return err
+	}
+	for _, p := range sess.pages {
+		if p.ID == page.ID {
+			return store.Errorf(store.ErrConflict, "page ID already exists")
+		}
+		if p.Title == page.Title {
+			return store.Errorf(store.ErrConflict, "page title already exists")
+		}
+	}
+	pages := append(copyPages(sess.pages), *page)
+	s.commitPages(ctx, sess, pages)
+	return nil
+}
+
+func (s *MemorySessionStore) UpdatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.lookup(ctx, sessionName)
+	if err != nil {
+		return err
+	}
+	index := findPage(sess.pages, page.ID)
+	if index == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+	for _, p := range sess.pages {
+		if p.ID != page.ID && p.Title == page.Title {
+			return store.Errorf(store.ErrConflict, "page title already exists")
+		}
+	}
+	pages := copyPages(sess.pages)
+	pages[index] = *page
+	s.commitPages(ctx, sess, pages)
+	return nil
+}
+
+func (s *MemorySessionStore) DeletePage(ctx context.Context, sessionName string, pageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.lookup(ctx, sessionName)
+	if err != nil {
+		return err
+	}
+	index := findPage(sess.pages, pageID)
+	if index == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+	pages := copyPages(sess.pages)
+	pages = append(pages[:index], pages[index+1:]...)
+	s.commitPages(ctx, sess, pages)
+	return nil
+}
+
+func findPage(pages []model.Page, pageID string) int {
+	for i, p := range pages {
+		if p.ID == pageID {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertPage(pages []model.Page, index int, page model.Page) []model.Page {
+	pages = append(pages, model.Page{})
+	copy(pages[index+1:], pages[index:])
+	pages[index] = page
+	return pages
+}
+
+func (s *MemorySessionStore) MovePage(ctx context.Context, sessionName string, pageID string, newIndex int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.movePage(ctx, sessionName, pageID, newIndex)
+}
+
+func (s *MemorySessionStore) movePage(ctx context.Context, sessionName string, pageID string, newIndex int) error {
+	sess, err := s.lookup(ctx, sessionName)
+	if err != nil {
+		return err
+	}
+	index := findPage(sess.pages, pageID)
+	if index == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+	if newIndex < 0 || newIndex >= len(sess.pages) {
+		return store.Errorf(store.ErrInvalid, "page index %d out of range", newIndex)
+	}
+	if newIndex == index {
+		store.SetRevision(ctx, sess.revision)
+		return nil
+	}
+	pages := copyPages(sess.pages)
+	page := pages[index]
+	pages = append(pages[:index], pages[index+1:]...)
+	pages = insertPage(pages, newIndex, page)
+	s.commitPages(ctx, sess, pages)
+	return nil
+}
+
+func (s *MemorySessionStore) MovePageToSession(ctx context.Context, fromSession string, pageID string, toSession string, index int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if fromSession == toSession {
+		return s.movePage(ctx, fromSession, pageID, index)
+	}
+
+	// The revision precondition applies to the source session.
+	from, err := s.lookup(ctx, fromSession)
+	if err != nil {
+		return err
+	}
+	pos := findPage(from.pages, pageID)
+	if pos == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+	page := from.pages[pos]
+
+	to, ok := s.sessions[toSession]
+	if !ok {
+		return store.Errorf(store.ErrNotFound, "session %s not found", toSession)
+	}
+	if index < 0 || index > len(to.pages) {
+		return store.Errorf(store.ErrInvalid, "page index %d out of range", index)
+	}
+	for _, p := range to.pages {
+		if p.ID == page.ID {
+			return store.Errorf(store.ErrConflict, "page ID already exists")
+		}
+		if p.Title == page.Title {
+			return store.Errorf(store.ErrConflict, "page title already exists")
+		}
+	}
+
+	s.commitPages(context.Background(), to, insertPage(copyPages(to.pages), index, page))
+	fromPages := copyPages(from.pages)
+	s.commitPages(ctx, from, append(fromPages[:pos], fromPages[pos+1:]...))
+	return nil
+}
+
+func (s *MemorySessionStore) DuplicatePage(ctx context.Context, sessionName string, pageID string) (*model.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, err := s.lookup(ctx, sessionName)
+	if err != nil {
+		return nil, err
+	}
+	index := findPage(sess.pages, pageID)
+	if index == -1 {
+		return nil, store.Errorf(store.ErrNotFound, "page not found")
+	}
+
+	pages := copyPages(sess.pages)
+	page := pages[index]
+	page.ID = model.NewPageID()
+	page.Title = model.CopyTitle(page.Title, func(title string) bool {
+		return findTitle(pages, title)
+	})
+	s.commitPages(ctx, sess, insertPage(pages, index+1, page))
+	return &page, nil
+}
+
+func findTitle(pages []model.Page, title string) bool {
+	for _, p := range pages {
+		if p.Title == title {
+			return true
+		}
+	}
+	return false
+}
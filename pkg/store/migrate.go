@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// Copy copies every session in src, with its pages and avatars, into dst.
+// Sessions that already exist in dst are skipped and reported in skipped.
+// Revision history is not copied; each session starts over at revision 1.
+func Copy(ctx context.Context, dst SessionStore, src SessionStore) (copied []string, skipped []string, err error) {
+	sessions, err := src.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, info := range sessions {
+		session, err := src.Get(ctx, info.Name)
+		if err != nil {
+			return copied, skipped, fmt.Errorf("read session %s: %w", info.Name, err)
+		}
+		if _, err := dst.Get(ctx, info.Name); err == nil {
+			skipped = append(skipped, info.Name)
+			continue
+		}
+		if err := dst.Create(ctx, session); err != nil {
+			return copied, skipped, fmt.Errorf("create session %s: %w", info.Name, err)
+		}
+		avatars, err := src.ListAvatars(ctx, info.Name)
+		if err != nil {
+			return copied, skipped, fmt.Errorf("list avatars of %s: %w", info.Name, err)
+		}
+		for _, name := range avatars {
+			data, err := src.GetAvatar(ctx, info.Name, name)
+			if err != nil {
+				return copied, skipped, fmt.Errorf("read avatar %s/%s: %w", info.Name, name, err)
+			}
+			if err := dst.SaveAvatar(ctx, info.Name, name, data); err != nil {
+				return copied, skipped, fmt.Errorf("save avatar %s/%s: %w", info.Name, name, err)
+			}
+		}
+		copied = append(copied, info.Name)
+	}
+	return copied, skipped, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// Revisions
+//
+// Every session carries a revision that increases by one on each change to
+// its pages. A caller that wants optimistic concurrency attaches a *Revision
+// to the context of a mutating call: the store fails with a
+// *RevisionConflictError if the session has moved past IfMatch, and fills in
+// Current with the revision the call produced.
+
+type Revision struct {
+	// IfMatch is the revision the caller last saw; 0 skips the check.
+	IfMatch int64
+	// Current is set by the store to the session's revision after the call.
+	Current int64
+}
+
+type revisionKey struct{}
+
+func WithRevision(ctx context.Context, rev *Revision) context.Context {
+	return context.WithValue(ctx, revisionKey{}, rev)
+}
+
+// RevisionFrom returns the *Revision attached by WithRevision, or nil.
+func RevisionFrom(ctx context.Context) *Revision {
+	rev, _ := ctx.Value(revisionKey{}).(*Revision)
+	return rev
+}
+
+// CheckRevision fails if ctx expects a revision other than current.
+func CheckRevision(ctx context.Context, current int64) error {
+	rev := RevisionFrom(ctx)
+	if rev == nil || rev.IfMatch == 0 || rev.IfMatch == current {
+		return nil
+	}
+	return &RevisionConflictError{Expected: rev.IfMatch, Current: current}
+}
+
+// SetRevision reports the revision a mutating call produced.
+func SetRevision(ctx context.Context, current int64) {
+	if rev := RevisionFrom(ctx); rev != nil {
+		rev.Current = current
+	}
+}
+
+type RevisionConflictError struct {
+	Expected int64
+	Current  int64
+}
+
+func (e *RevisionConflictError) Error() string {
+	return fmt.Sprintf("session was modified: expected revision %d, current revision %d", e.Expected, e.Current)
+}
+
+// Unwrap makes a revision conflict match ErrConflict.
+func (e *RevisionConflictError) Unwrap() error {
+	return ErrConflict
+}
@@ -13,11 +13,28 @@ type SessionStore interface {
 	Update(ctx context.Context, session *model.Session) error
 	Rename(ctx context.Context, oldName string, newName string) error
 	Delete(ctx context.Context, name string) error
+	// Duplicate copies session src, with its pages and avatars, to a new
+	// session dst. The copied pages get new IDs.
+	Duplicate(ctx context.Context, src string, dst string) error
 
 	// Page operations
 	CreatePage(ctx context.Context, sessionName string, page *model.Page) error
 	UpdatePage(ctx context.Context, sessionName string, page *model.Page) error
 	DeletePage(ctx context.Context, sessionName string, pageID string) error
+	// MovePage moves a page to newIndex (zero-based) within its session.
+	MovePage(ctx context.Context, sessionName string, pageID string, newIndex int) error
+	// MovePageToSession removes a page from one session and inserts it into
+	// another at index; index may equal the target's page count to append.
+	MovePageToSession(ctx context.Context, fromSession string, pageID string, toSession string, index int) error
+	// DuplicatePage inserts a copy of a page right after it, with a new ID
+	// and a title made unique by model.CopyTitle, and returns the copy.
+	DuplicatePage(ctx context.Context, sessionName string, pageID string) (*model.Page, error)
+
+	// History operations. Stores keep snapshots of past page lists;
+	// restoring one is itself recorded as a new revision.
+	ListRevisions(ctx context.Context, sessionName string) ([]model.RevisionInfo, error)
+	GetRevision(ctx context.Context, sessionName string, revision int64) (*model.Session, error)
+	RestoreRevision(ctx context.Context, sessionName string, revision int64) error
 
 	// Avatar operations
 	ListAvatars(ctx context.Context, sessionName string) ([]string, error)
@@ -26,3 +43,17 @@ type SessionStore interface {
 	RenameAvatar(ctx context.Context, sessionName string, oldName string, newName string) error
 	GetAvatar(ctx context.Context, sessionName string, avatarName string) ([]byte, error)
 }
+
+// VersionedStore is implemented by stores that record every change in a
+// version control system.
+type VersionedStore interface {
+	SessionStore
+
+	// Log returns up to limit commits touching the session, newest first.
+	Log(ctx context.Context, sessionName string, limit int) ([]model.Commit, error)
+	// Diff returns a unified diff of the session between two commits; an
+	// empty to compares against the current files.
+	Diff(ctx context.Context, sessionName string, from string, to string) (string, error)
+	// Checkout replaces the session's pages with those at commit.
+	Checkout(ctx context.Context, sessionName string, commit string) error
+}
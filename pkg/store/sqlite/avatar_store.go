@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Avatar Operations
+
+func avatarNotFound(sessionName string, avatarName string) error {
+	return store.Errorf(store.ErrNotFound, "avatar %s not found in session %s", avatarName, sessionName)
+}
+
+func (s *SQLiteSessionStore) ListAvatars(ctx context.Context, sessionName string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM avatars WHERE session = ? ORDER BY name`, sessionName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	avatars := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		avatars = append(avatars, name)
+	}
+	return avatars, rows.Err()
+}
+
+func (s *SQLiteSessionStore) SaveAvatar(ctx context.Context, sessionName string, avatarName string, data []byte) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		exists, err := sessionExists(tx, sessionName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return notFound(sessionName)
+		}
+		_, err = tx.Exec(`INSERT OR REPLACE INTO avatars (session, name, data) VALUES (?, ?, ?)`, sessionName, avatarName, data)
+		return err
+	})
+}
+
+func (s *SQLiteSessionStore) DeleteAvatar(ctx context.Context, sessionName string, avatarName string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM avatars WHERE session = ? AND name = ?`, sessionName, avatarName)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return avatarNotFound(sessionName, avatarName)
+	}
+	return nil
+}
+
+// RenameAvatar renames an avatar, replacing any avatar already called
+// newName as a file rename would.
+func (s *SQLiteSessionStore) RenameAvatar(ctx context.Context, sessionName string, oldName string, newName string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if oldName == newName {
+			return nil
+		}
+		if _, err := tx.Exec(`DELETE FROM avatars WHERE session = ? AND name = ? AND EXISTS
+			(SELECT 1 FROM avatars WHERE session = ? AND name = ?)`, sessionName, newName, sessionName, oldName); err != nil {
+			return err
+		}
+		res, err := tx.Exec(`UPDATE avatars SET name = ? WHERE session = ? AND name = ?`, newName, sessionName, oldName)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return avatarNotFound(sessionName, oldName)
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteSessionStore) GetAvatar(ctx context.Context, sessionName string, avatarName string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM avatars WHERE session = ? AND name = ?`, sessionName, avatarName).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, avatarNotFound(sessionName, avatarName)
+	}
+	return data, err
+}
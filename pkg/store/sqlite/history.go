@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// History
+//
+// When a page write replaces revision N, the replaced pages are stored as
+// a JSON array in the revisions table under N. Only pages are kept;
+// avatars are not versioned.
+
+func (s *SQLiteSessionStore) historyEnabled() bool {
+	return s.HistoryLimit >= 0
+}
+
+// pruneHistory drops snapshots beyond HistoryLimit or older than
+// HistoryMaxAge.
+func (s *SQLiteSessionStore) pruneHistory(tx *sql.Tx, name string) error {
+	limit := s.HistoryLimit
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	if _, err := tx.Exec(`DELETE FROM revisions WHERE session = ? AND revision NOT IN
+		(SELECT revision FROM revisions WHERE session = ? ORDER BY revision DESC LIMIT ?)`, name, name, limit); err != nil {
+		return err
+	}
+	if s.HistoryMaxAge > 0 {
+		cutoff := time.Now().Add(-s.HistoryMaxAge).UnixMilli()
+		if _, err := tx.Exec(`DELETE FROM revisions WHERE session = ? AND time < ?`, name, cutoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteSessionStore) ListRevisions(ctx context.Context, sessionName string) ([]model.RevisionInfo, error) {
+	infos := []model.RevisionInfo{}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := sessionRevision(tx, sessionName); err != nil {
+			return err
+		}
+		rows, err := tx.Query(`SELECT revision, time, json_array_length(pages) FROM revisions
+			WHERE session = ? ORDER BY revision DESC`, sessionName)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var info model.RevisionInfo
+			var t int64
+			if err := rows.Scan(&info.Revision, &t, &info.Pages); err != nil {
+				return err
+			}
+			info.Time = time.UnixMilli(t)
+			infos = append(infos, info)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+func (s *SQLiteSessionStore) GetRevision(ctx context.Context, sessionName string, revision int64) (*model.Session, error) {
+	var session *model.Session
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		session, err = readSnapshot(tx, sessionName, revision)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func readSnapshot(tx *sql.Tx, sessionName string, revision int64) (*model.Session, error) {
+	var t int64
+	var data []byte
+	err := tx.QueryRow(`SELECT time, pages FROM revisions WHERE session = ? AND revision = ?`, sessionName, revision).Scan(&t, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.Errorf(store.ErrNotFound, "revision %d not found", revision)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pages []model.Page
+	if err := json.Unmarshal(data, &pages); err != nil {
+		return nil, fmt.Errorf("revision %d: %w", revision, err)
+	}
+	return &model.Session{
+		Name:         sessionName,
+		LastModified: time.UnixMilli(t),
+		Revision:     revision,
+		Pages:        pages,
+	}, nil
+}
+
+func (s *SQLiteSessionStore) RestoreRevision(ctx context.Context, sessionName string, revision int64) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		current, err := checkRevision(ctx, tx, sessionName)
+		if err != nil {
+			return err
+		}
+		snapshot, err := readSnapshot(tx, sessionName, revision)
+		if err != nil {
+			return err
+		}
+		return s.commitPages(ctx, tx, sessionName, current, snapshot.Pages)
+	})
+}
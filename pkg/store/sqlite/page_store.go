@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Page operations load the ordered page list, edit it and write it back
+// through commitPages in one transaction, which keeps indexes dense and
+// gives every change a history snapshot.
+
+func (s *SQLiteSessionStore) CreatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		revision, err := checkRevision(ctx, tx, sessionName)
+		if err != nil {
+			return err
+		}
+		pages, err := readPages(tx, sessionName)
+		if err != nil {
+			return err
+		}
+		for _, p := range pages {
+			if p.ID == page.ID {
+				return store.Errorf(store.ErrConflict, "page ID already exists")
+			}
+			if p.Title == page.Title {
+				return store.Errorf(store.ErrConflict, "page title already exists")
+			}
+		}
+		pages = append(pages, *page)
+		return s.commitPages(ctx, tx, sessionName, revision, pages)
+	})
+}
+
+func (s *SQLiteSessionStore) UpdatePage(ctx context.Context, sessionName string, page *model.Page) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		revision, err := checkRevision(ctx, tx, sessionName)
+		if err != nil {
+			return err
+		}
+		pages, err := readPages(tx, sessionName)
+		if err != nil {
+			return err
+		}
+		index := findPage(pages, page.ID)
+		if index == -1 {
+			return store.Errorf(store.ErrNotFound, "page not found")
+		}
+		for _, p := range pages {
+			if p.ID != page.ID && p.Title == page.Title {
+				return store.Errorf(store.ErrConflict, "page title already exists")
+			}
+		}
+		pages[index] = *page
+		return s.commitPages(ctx, tx, sessionName, revision, pages)
+	})
+}
+
+func (s *SQLiteSessionStore) DeletePage(ctx context.Context, sessionName string, pageID string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		revision, err := checkRevision(ctx, tx, sessionName)
+		if err != nil {
+			return err
+		}
+		pages, err := readPages(tx, sessionName)
+		if err != nil {
+			return err
+		}
+		index := findPage(pages, pageID)
+		if index == -1 {
+			return store.Errorf(store.ErrNotFound, "page not found")
+		}
+		pages = append(pages[:index], pages[index+1:]...)
+		return s.commitPages(ctx, tx, sessionName, revision, pages)
+	})
+}
+
+func findPage(pages []model.Page, pageID string) int {
+	for i, p := range pages {
+		if p.ID == pageID {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertPage(pages []model.Page, index int, page model.Page) []model.Page {
+	pages = append(pages, model.Page{})
+	copy(pages[index+1:], pages[index:])
+	pages[index] = page
+	return pages
+}
+
+func (s *SQLiteSessionStore) MovePage(ctx context.Context, sessionName string, pageID string, newIndex int) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		return s.movePage(ctx, tx, sessionName, pageID, newIndex)
+	})
+}
+
+func (s *SQLiteSessionStore) movePage(ctx context.Context, tx *sql.Tx, sessionName string, pageID string, newIndex int) error {
+	revision, err := checkRevision(ctx, tx, sessionName)
+	if err != nil {
+		return err
+	}
+	pages, err := readPages(tx, sessionName)
+	if err != nil {
+		return err
+	}
+	index := findPage(pages, pageID)
+	if index == -1 {
+		return store.Errorf(store.ErrNotFound, "page not found")
+	}
+	if newIndex < 0 || newIndex >= len(pages) {
+		return store.Errorf(store.ErrInvalid, "page index %d out of range", newIndex)
+	}
+	if newIndex == index {
+		store.SetRevision(ctx, revision)
+		return nil
+	}
+	page := pages[index]
+	pages = append(pages[:index], pages[index+1:]...)
+	pages = insertPage(pages, newIndex, page)
+	return s.commitPages(ctx, tx, sessionName, revision, pages)
+}
+
+func (s *SQLiteSessionStore) MovePageToSession(ctx context.Context, fromSession string, pageID string, toSession string, index int) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if fromSession == toSession {
+			return s.movePage(ctx, tx, fromSession, pageID, index)
+		}
+
+		// The revision precondition applies to the source session.
+		fromRevision, err := checkRevision(ctx, tx, fromSession)
+		if err != nil {
+			return err
+		}
+		fromPages, err := readPages(tx, fromSession)
+		if err != nil {
+			return err
+		}
+		pos := findPage(fromPages, pageID)
+		if pos == -1 {
+			return store.Errorf(store.ErrNotFound, "page not found")
+		}
+		page := fromPages[pos]
+
+		toRevision, err := sessionRevision(tx, toSession)
+		if err != nil {
+			return store.Errorf(store.ErrNotFound, "session %s not found", toSession)
+		}
+		toPages, err := readPages(tx, toSession)
+		if err != nil {
+			return err
+		}
+		if index < 0 || index > len(toPages) {
+			return store.Errorf(store.ErrInvalid, "page index %d out of range", index)
+		}
+		for _, p := range toPages {
+			if p.ID == page.ID {
+				return store.Errorf(store.ErrConflict, "page ID already exists")
+			}
+			if p.Title == page.Title {
+				return store.Errorf(store.ErrConflict, "page title already exists")
+			}
+		}
+
+		toPages = insertPage(toPages, index, page)
+		if err := s.commitPages(context.Background(), tx, toSession, toRevision, toPages); err != nil {
+			return err
+		}
+		fromPages = append(fromPages[:pos], fromPages[pos+1:]...)
+		return s.commitPages(ctx, tx, fromSession, fromRevision, fromPages)
+	})
+}
+
+func (s *SQLiteSessionStore) DuplicatePage(ctx context.Context, sessionName string, pageID string) (*model.Page, error) {
+	var page model.Page
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		revision, err := checkRevision(ctx, tx, sessionName)
+		if err != nil {
+			return err
+		}
+		pages, err := readPages(tx, sessionName)
+		if err != nil {
+			return err
+		}
+		index := findPage(pages, pageID)
+		if index == -1 {
+			return store.Errorf(store.ErrNotFound, "page not found")
+		}
+
+		page = pages[index]
+		page.ID = model.NewPageID()
+		page.Title = model.CopyTitle(page.Title, func(title string) bool {
+			for _, p := range pages {
+				if p.Title == title {
+					return true
+				}
+			}
+			return false
+		})
+		page.Content = append(json.RawMessage(nil), page.Content...)
+
+		pages = insertPage(pages, index+1, page)
+		return s.commitPages(ctx, tx, sessionName, revision, pages)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
@@ -0,0 +1,367 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	// a pure Go driver, so release builds need no cgo
+	_ "modernc.org/sqlite"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// DefaultHistoryLimit is the number of past revisions kept per session
+// when HistoryLimit is zero.
+const DefaultHistoryLimit = 50
+
+const schema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	name          TEXT PRIMARY KEY,
+	revision      INTEGER NOT NULL,
+	last_modified INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_last_modified ON sessions(last_modified);
+
+CREATE TABLE IF NOT EXISTS pages (
+	session TEXT NOT NULL REFERENCES sessions(name) ON UPDATE CASCADE ON DELETE CASCADE,
+	idx     INTEGER NOT NULL,
+	id      TEXT NOT NULL,
+	title   TEXT NOT NULL,
+	kind    TEXT NOT NULL,
+	content BLOB,
+	PRIMARY KEY (session, id)
+);
+CREATE INDEX IF NOT EXISTS pages_order ON pages(session, idx);
+
+CREATE TABLE IF NOT EXISTS avatars (
+	session TEXT NOT NULL REFERENCES sessions(name) ON UPDATE CASCADE ON DELETE CASCADE,
+	name    TEXT NOT NULL,
+	data    BLOB NOT NULL,
+	PRIMARY KEY (session, name)
+);
+
+CREATE TABLE IF NOT EXISTS revisions (
+	session  TEXT NOT NULL REFERENCES sessions(name) ON UPDATE CASCADE ON DELETE CASCADE,
+	revision INTEGER NOT NULL,
+	time     INTEGER NOT NULL,
+	pages    BLOB NOT NULL,
+	PRIMARY KEY (session, revision)
+);
+`
+
+// SQLiteSessionStore implements store.SessionStore in a single SQLite
+// database. Pages are rows ordered by their index, so listing sessions
+// and loading a deck are single queries regardless of how many decks
+// there are.
+type SQLiteSessionStore struct {
+	// HistoryLimit is the number of past revisions kept per session.
+	// Zero means DefaultHistoryLimit; a negative value disables history.
+	HistoryLimit int
+	// HistoryMaxAge, when set, also drops snapshots older than this.
+	HistoryMaxAge time.Duration
+
+	db *sql.DB
+}
+
+var _ store.SessionStore = (*SQLiteSessionStore)(nil)
+
+// Open opens the database at path, creating it and its tables if needed.
+func Open(path string) (*SQLiteSessionStore, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+		"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// One connection serializes writers inside the process; busy_timeout
+	// handles other processes.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init %s: %w", path, err)
+	}
+	return &SQLiteSessionStore{db: db}, nil
+}
+
+func (s *SQLiteSessionStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteSessionStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func now() int64 {
+	return time.Now().UnixMilli()
+}
+
+func notFound(name string) error {
+	return store.Errorf(store.ErrNotFound, "session %s not found", name)
+}
+
+// sessionRevision returns the current revision of a session.
+func sessionRevision(tx *sql.Tx, name string) (int64, error) {
+	var revision int64
+	err := tx.QueryRow(`SELECT revision FROM sessions WHERE name = ?`, name).Scan(&revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, notFound(name)
+	}
+	return revision, err
+}
+
+// checkRevision reads the session's revision and fails if the caller
+// expects a different one.
+func checkRevision(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	revision, err := sessionRevision(tx, name)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.CheckRevision(ctx, revision); err != nil {
+		return 0, err
+	}
+	return revision, nil
+}
+
+func sessionExists(tx *sql.Tx, name string) (bool, error) {
+	var n int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM sessions WHERE name = ?`, name).Scan(&n)
+	return n > 0, err
+}
+
+func readPages(tx *sql.Tx, name string) ([]model.Page, error) {
+	rows, err := tx.Query(`SELECT id, title, kind, content FROM pages WHERE session = ? ORDER BY idx`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []model.Page{}
+	for rows.Next() {
+		var p model.Page
+		var content []byte
+		if err := rows.Scan(&p.ID, &p.Title, &p.Kind, &content); err != nil {
+			return nil, err
+		}
+		if len(content) > 0 {
+			p.Content = json.RawMessage(content)
+		}
+		pages = append(pages, p)
+	}
+	return pages, rows.Err()
+}
+
+func writePages(tx *sql.Tx, name string, pages []model.Page) error {
+	if _, err := tx.Exec(`DELETE FROM pages WHERE session = ?`, name); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO pages (session, idx, id, title, kind, content) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, p := range pages {
+		if _, err := stmt.Exec(name, i, p.ID, p.Title, string(p.Kind), []byte(p.Content)); err != nil {
+			return fmt.Errorf("page %s: %w", p.ID, err)
+		}
+	}
+	return nil
+}
+
+// commitPages replaces the session's pages and bumps its revision,
+// keeping the replaced pages as a history snapshot.
+func (s *SQLiteSessionStore) commitPages(ctx context.Context, tx *sql.Tx, name string, revision int64, pages []model.Page) error {
+	if s.historyEnabled() && revision > 0 {
+		old, err := readPages(tx, name)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(old)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO revisions (session, revision, time, pages) VALUES (?, ?, ?, ?)`,
+			name, revision, now(), data); err != nil {
+			return err
+		}
+		if err := s.pruneHistory(tx, name); err != nil {
+			return err
+		}
+	}
+	if err := writePages(tx, name, pages); err != nil {
+		return err
+	}
+	revision++
+	if _, err := tx.Exec(`UPDATE sessions SET revision = ?, last_modified = ? WHERE name = ?`, revision, now(), name); err != nil {
+		return err
+	}
+	store.SetRevision(ctx, revision)
+	return nil
+}
+
+func (s *SQLiteSessionStore) List(ctx context.Context) ([]model.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, revision, last_modified FROM sessions ORDER BY last_modified DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		var session model.Session
+		var modified int64
+		if err := rows.Scan(&session.Name, &session.Revision, &modified); err != nil {
+			return nil, err
+		}
+		session.LastModified = time.UnixMilli(modified)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQLiteSessionStore) Get(ctx context.Context, name string) (*model.Session, error) {
+	var session *model.Session
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var revision, modified int64
+		err := tx.QueryRow(`SELECT revision, last_modified FROM sessions WHERE name = ?`, name).Scan(&revision, &modified)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFound(name)
+		}
+		if err != nil {
+			return err
+		}
+		pages, err := readPages(tx, name)
+		if err != nil {
+			return err
+		}
+		session = &model.Session{
+			Name:         name,
+			LastModified: time.UnixMilli(modified),
+			Revision:     revision,
+			Pages:        pages,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *SQLiteSessionStore) Create(ctx context.Context, session *model.Session) error {
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return createSession(tx, session.Name, session.Pages)
+	})
+	if err != nil {
+		return err
+	}
+	session.Revision = 1
+	store.SetRevision(ctx, 1)
+	return nil
+}
+
+func createSession(tx *sql.Tx, name string, pages []model.Page) error {
+	exists, err := sessionExists(tx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return store.Errorf(store.ErrConflict, "session already exists")
+	}
+	if _, err := tx.Exec(`INSERT INTO sessions (name, revision, last_modified) VALUES (?, 1, ?)`, name, now()); err != nil {
+		return err
+	}
+	return writePages(tx, name, pages)
+}
+
+func (s *SQLiteSessionStore) Duplicate(ctx context.Context, src, dst string) error {
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		exists, err := sessionExists(tx, src)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return store.Errorf(store.ErrNotFound, "session %s not found", src)
+		}
+		pages, err := readPages(tx, src)
+		if err != nil {
+			return err
+		}
+		for i := range pages {
+			pages[i].ID = model.NewPageID()
+		}
+		if err := createSession(tx, dst, pages); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO avatars (session, name, data) SELECT ?, name, data FROM avatars WHERE session = ?`, dst, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	store.SetRevision(ctx, 1)
+	return nil
+}
+
+// Update replaces the session's pages, creating the session if it does
+// not exist yet.
+func (s *SQLiteSessionStore) Update(ctx context.Context, session *model.Session) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO sessions (name, revision, last_modified) VALUES (?, 0, ?)`, session.Name, now()); err != nil {
+			return err
+		}
+		revision, err := checkRevision(ctx, tx, session.Name)
+		if err != nil {
+			return err
+		}
+		if err := s.commitPages(ctx, tx, session.Name, revision, session.Pages); err != nil {
+			return err
+		}
+		session.Revision = revision + 1
+		return nil
+	})
+}
+
+func (s *SQLiteSessionStore) Rename(ctx context.Context, oldName, newName string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		exists, err := sessionExists(tx, newName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return store.Errorf(store.ErrConflict, "session %s already exists", newName)
+		}
+		res, err := tx.Exec(`UPDATE sessions SET name = ? WHERE name = ?`, newName, oldName)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return notFound(oldName)
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteSessionStore) Delete(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound(name)
+	}
+	return nil
+}
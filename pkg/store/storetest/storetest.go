@@ -0,0 +1,483 @@
+// Package storetest checks that a store.SessionStore implementation
+// behaves like the others. A backend's tests call RunConformance with a
+// factory that returns a fresh, empty store:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.RunConformance(t, func(t *testing.T) store.SessionStore {
+//			return memory.New()
+//		})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Factory returns a new, empty store. It may use t.TempDir and t.Cleanup.
+type Factory func(t *testing.T) store.SessionStore
+
+// RunConformance runs the shared SessionStore contract against stores
+// made by newStore, one fresh store per subtest.
+func RunConformance(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.SessionStore)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"GetNotFound", testGetNotFound},
+		{"Update", testUpdate},
+		{"RevisionConflict", testRevisionConflict},
+		{"PageOrdering", testPageOrdering},
+		{"PageUniqueness", testPageUniqueness},
+		{"PageNotFound", testPageNotFound},
+		{"DuplicatePage", testDuplicatePage},
+		{"MovePageToSession", testMovePageToSession},
+		{"Rename", testRename},
+		{"RenameCollision", testRenameCollision},
+		{"Delete", testDelete},
+		{"Duplicate", testDuplicate},
+		{"Avatars", testAvatars},
+		{"History", testHistory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newPage(id string, title string) model.Page {
+	return model.Page{
+		ID:      id,
+		Title:   title,
+		Kind:    model.PageKindChart,
+		Content: json.RawMessage(`{"json":"[{\"name\":\"` + title + `\",\"value\":1}]","chartType":"bar"}`),
+	}
+}
+
+func create(t *testing.T, s store.SessionStore, name string, pages ...model.Page) {
+	t.Helper()
+	if err := s.Create(context.Background(), &model.Session{Name: name, Pages: pages}); err != nil {
+		t.Fatalf("Create(%s): %v", name, err)
+	}
+}
+
+func get(t *testing.T, s store.SessionStore, name string) *model.Session {
+	t.Helper()
+	session, err := s.Get(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", name, err)
+	}
+	return session
+}
+
+// titles returns the page titles of a session in order.
+func titles(t *testing.T, s store.SessionStore, name string) []string {
+	t.Helper()
+	list := []string{}
+	for _, p := range get(t, s, name).Pages {
+		list = append(list, p.Title)
+	}
+	return list
+}
+
+func expectTitles(t *testing.T, s store.SessionStore, name string, want ...string) {
+	t.Helper()
+	if got := titles(t, s, name); !reflect.DeepEqual(got, want) {
+		t.Fatalf("pages of %s: got %v, want %v", name, got, want)
+	}
+}
+
+// expectError fails unless err wraps kind, one of store.ErrNotFound,
+// store.ErrConflict and store.ErrInvalid.
+func expectError(t *testing.T, what string, err error, kind error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("%s: expected an error", what)
+	}
+	if !errors.Is(err, kind) {
+		t.Fatalf("%s: expected an error wrapping %q, got %v", what, kind, err)
+	}
+}
+
+func expectNoError(t *testing.T, what string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+// sameJSON reports whether a and b encode the same value, ignoring
+// formatting.
+func sameJSON(a, b []byte) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+func hasSession(t *testing.T, s store.SessionStore, name string) bool {
+	t.Helper()
+	sessions, err := s.List(context.Background())
+	expectNoError(t, "List", err)
+	for _, session := range sessions {
+		if session.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func testCreateAndGet(t *testing.T, s store.SessionStore) {
+	a, b := newPage("1", "Intro"), newPage("2", "Architecture")
+	create(t, s, "deck", a, b)
+
+	session := get(t, s, "deck")
+	if session.Name != "deck" {
+		t.Fatalf("name: got %q", session.Name)
+	}
+	if session.Revision != 1 {
+		t.Fatalf("revision of a new session: got %d, want 1", session.Revision)
+	}
+	if len(session.Pages) != 2 {
+		t.Fatalf("pages: got %d, want 2", len(session.Pages))
+	}
+	for i, want := range []model.Page{a, b} {
+		got := session.Pages[i]
+		if got.ID != want.ID || got.Title != want.Title || got.Kind != want.Kind {
+			t.Fatalf("page %d: got %s/%s/%s, want %s/%s/%s", i, got.ID, got.Title, got.Kind, want.ID, want.Title, want.Kind)
+		}
+		if !sameJSON(got.Content, want.Content) {
+			t.Fatalf("page %d content: got %s, want %s", i, got.Content, want.Content)
+		}
+	}
+	if !hasSession(t, s, "deck") {
+		t.Fatalf("List does not include deck")
+	}
+
+	err := s.Create(context.Background(), &model.Session{Name: "deck"})
+	expectError(t, "Create existing session", err, store.ErrConflict)
+	expectTitles(t, s, "deck", "Intro", "Architecture")
+}
+
+func testGetNotFound(t *testing.T, s store.SessionStore) {
+	_, err := s.Get(context.Background(), "missing")
+	expectError(t, "Get missing session", err, store.ErrNotFound)
+}
+
+func testUpdate(t *testing.T, s store.SessionStore) {
+	create(t, s, "deck", newPage("1", "A"))
+
+	rev := &store.Revision{}
+	ctx := store.WithRevision(context.Background(), rev)
+	session := &model.Session{Name: "deck", Pages: []model.Page{newPage("2", "B"), newPage("3", "C")}}
+	expectNoError(t, "Update", s.Update(ctx, session))
+	if session.Revision != 2 || rev.Current != 2 {
+		t.Fatalf("revision after update: got %d (context %d), want 2", session.Revision, rev.Current)
+	}
+	expectTitles(t, s, "deck", "B", "C")
+	if got := get(t, s, "deck").Revision; got != 2 {
+		t.Fatalf("stored revision: got %d, want 2", got)
+	}
+}
+
+func testRevisionConflict(t *testing.T, s store.SessionStore) {
+	create(t, s, "deck", newPage("1", "A"))
+
+	ctx := store.WithRevision(context.Background(), &store.Revision{IfMatch: 1})
+	page := newPage("2", "B")
+	expectNoError(t, "CreatePage at current revision", s.CreatePage(ctx, "deck", &page))
+
+	stale := newPage("3", "C")
+	err := s.CreatePage(ctx, "deck", &stale)
+	var conflict *store.RevisionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("CreatePage at stale revision: expected *store.RevisionConflictError, got %v", err)
+	}
+	if !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("revision conflict does not wrap store.ErrConflict")
+	}
+	if conflict.Current != 2 {
+		t.Fatalf("conflict current revision: got %d, want 2", conflict.Current)
+	}
+	expectTitles(t, s, "deck", "A", "B")
+}
+
+func testPageOrdering(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "deck", newPage("1", "A"), newPage("2", "B"))
+
+	c := newPage("3", "C")
+	expectNoError(t, "CreatePage", s.CreatePage(ctx, "deck", &c))
+	expectTitles(t, s, "deck", "A", "B", "C")
+
+	expectNoError(t, "MovePage to front", s.MovePage(ctx, "deck", "3", 0))
+	expectTitles(t, s, "deck", "C", "A", "B")
+
+	expectNoError(t, "MovePage to end", s.MovePage(ctx, "deck", "3", 2))
+	expectTitles(t, s, "deck", "A", "B", "C")
+
+	expectError(t, "MovePage out of range", s.MovePage(ctx, "deck", "3", 3), store.ErrInvalid)
+	expectError(t, "MovePage negative index", s.MovePage(ctx, "deck", "3", -1), store.ErrInvalid)
+
+	expectNoError(t, "DeletePage", s.DeletePage(ctx, "deck", "1"))
+	expectTitles(t, s, "deck", "B", "C")
+
+	d := newPage("4", "D")
+	expectNoError(t, "CreatePage after delete", s.CreatePage(ctx, "deck", &d))
+	expectTitles(t, s, "deck", "B", "C", "D")
+}
+
+func testPageUniqueness(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "deck", newPage("1", "A"), newPage("2", "B"))
+
+	sameID := newPage("1", "Other")
+	expectError(t, "CreatePage with existing ID", s.CreatePage(ctx, "deck", &sameID), store.ErrConflict)
+	sameTitle := newPage("3", "A")
+	expectError(t, "CreatePage with existing title", s.CreatePage(ctx, "deck", &sameTitle), store.ErrConflict)
+
+	renamed := newPage("2", "A")
+	expectError(t, "UpdatePage to a taken title", s.UpdatePage(ctx, "deck", &renamed), store.ErrConflict)
+
+	unchanged := newPage("2", "B")
+	unchanged.Content = json.RawMessage(`{"json":"[]","chartType":"pie"}`)
+	expectNoError(t, "UpdatePage keeping its title", s.UpdatePage(ctx, "deck", &unchanged))
+	if got := get(t, s, "deck").Pages[1].Content; !sameJSON(got, unchanged.Content) {
+		t.Fatalf("updated content: got %s, want %s", got, unchanged.Content)
+	}
+
+	renamed = newPage("2", "C")
+	expectNoError(t, "UpdatePage to a free title", s.UpdatePage(ctx, "deck", &renamed))
+	expectTitles(t, s, "deck", "A", "C")
+
+	// titles only need to be unique within a session
+	create(t, s, "other")
+	elsewhere := newPage("9", "A")
+	expectNoError(t, "CreatePage with a title used by another session", s.CreatePage(ctx, "other", &elsewhere))
+}
+
+func testPageNotFound(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "deck", newPage("1", "A"))
+
+	missing := newPage("404", "Missing")
+	expectError(t, "UpdatePage of a missing page", s.UpdatePage(ctx, "deck", &missing), store.ErrNotFound)
+	expectError(t, "DeletePage of a missing page", s.DeletePage(ctx, "deck", "404"), store.ErrNotFound)
+	expectError(t, "MovePage of a missing page", s.MovePage(ctx, "deck", "404", 0), store.ErrNotFound)
+	_, err := s.DuplicatePage(ctx, "deck", "404")
+	expectError(t, "DuplicatePage of a missing page", err, store.ErrNotFound)
+	expectTitles(t, s, "deck", "A")
+
+	page := newPage("2", "B")
+	expectError(t, "CreatePage in a missing session", s.CreatePage(ctx, "missing", &page), store.ErrNotFound)
+	expectError(t, "SaveAvatar in a missing session", s.SaveAvatar(ctx, "missing", "me.png", []byte("png")), store.ErrNotFound)
+}
+
+func testDuplicatePage(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "deck", newPage("1", "A"), newPage("2", "B"))
+
+	copy1, err := s.DuplicatePage(ctx, "deck", "1")
+	expectNoError(t, "DuplicatePage", err)
+	if copy1.ID == "1" || copy1.ID == "" {
+		t.Fatalf("copy ID: got %q, want a new ID", copy1.ID)
+	}
+	if copy1.Title != "A Copy" {
+		t.Fatalf("copy title: got %q, want %q", copy1.Title, "A Copy")
+	}
+	expectTitles(t, s, "deck", "A", "A Copy", "B")
+
+	copy2, err := s.DuplicatePage(ctx, "deck", "1")
+	expectNoError(t, "DuplicatePage again", err)
+	if copy2.Title != "A Copy 2" {
+		t.Fatalf("second copy title: got %q, want %q", copy2.Title, "A Copy 2")
+	}
+	expectTitles(t, s, "deck", "A", "A Copy 2", "A Copy", "B")
+
+	pages := get(t, s, "deck").Pages
+	if !sameJSON(pages[1].Content, pages[0].Content) {
+		t.Fatalf("copy content: got %s, want %s", pages[1].Content, pages[0].Content)
+	}
+}
+
+func testMovePageToSession(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "from", newPage("1", "A"), newPage("2", "B"))
+	create(t, s, "to", newPage("3", "C"), newPage("4", "B"))
+
+	expectNoError(t, "MovePageToSession", s.MovePageToSession(ctx, "from", "1", "to", 1))
+	expectTitles(t, s, "from", "B")
+	expectTitles(t, s, "to", "C", "A", "B")
+
+	expectError(t, "MovePageToSession with a taken title", s.MovePageToSession(ctx, "from", "2", "to", 0), store.ErrConflict)
+	expectError(t, "MovePageToSession out of range", s.MovePageToSession(ctx, "to", "1", "from", 5), store.ErrInvalid)
+	expectError(t, "MovePageToSession to a missing session", s.MovePageToSession(ctx, "from", "2", "missing", 0), store.ErrNotFound)
+	expectTitles(t, s, "from", "B")
+	expectTitles(t, s, "to", "C", "A", "B")
+
+	expectNoError(t, "MovePageToSession within a session", s.MovePageToSession(ctx, "to", "4", "to", 0))
+	expectTitles(t, s, "to", "B", "C", "A")
+}
+
+func testRename(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "old", newPage("1", "A"))
+	expectNoError(t, "SaveAvatar", s.SaveAvatar(ctx, "old", "me.png", []byte("png")))
+
+	expectNoError(t, "Rename", s.Rename(ctx, "old", "new"))
+	_, err := s.Get(ctx, "old")
+	expectError(t, "Get renamed session by its old name", err, store.ErrNotFound)
+	expectTitles(t, s, "new", "A")
+	data, err := s.GetAvatar(ctx, "new", "me.png")
+	expectNoError(t, "GetAvatar after rename", err)
+	if string(data) != "png" {
+		t.Fatalf("avatar after rename: got %q", data)
+	}
+	if hasSession(t, s, "old") || !hasSession(t, s, "new") {
+		t.Fatalf("List after rename does not show only the new name")
+	}
+
+	expectError(t, "Rename a missing session", s.Rename(ctx, "missing", "other"), store.ErrNotFound)
+}
+
+func testRenameCollision(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "a", newPage("1", "A"))
+	create(t, s, "b", newPage("2", "B"))
+
+	expectError(t, "Rename onto an existing session", s.Rename(ctx, "a", "b"), store.ErrConflict)
+	expectTitles(t, s, "a", "A")
+	expectTitles(t, s, "b", "B")
+}
+
+func testDelete(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "deck", newPage("1", "A"))
+
+	expectNoError(t, "Delete", s.Delete(ctx, "deck"))
+	_, err := s.Get(ctx, "deck")
+	expectError(t, "Get deleted session", err, store.ErrNotFound)
+	if hasSession(t, s, "deck") {
+		t.Fatalf("List still includes a deleted session")
+	}
+
+	// the name can be reused
+	create(t, s, "deck", newPage("2", "B"))
+	expectTitles(t, s, "deck", "B")
+
+	expectError(t, "Delete a missing session", s.Delete(ctx, "missing"), store.ErrNotFound)
+}
+
+func testDuplicate(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "src", newPage("1", "A"), newPage("2", "B"))
+	expectNoError(t, "SaveAvatar", s.SaveAvatar(ctx, "src", "me.png", []byte("png")))
+
+	expectNoError(t, "Duplicate", s.Duplicate(ctx, "src", "dst"))
+	expectTitles(t, s, "dst", "A", "B")
+	for _, p := range get(t, s, "dst").Pages {
+		if p.ID == "1" || p.ID == "2" {
+			t.Fatalf("duplicated page kept its ID %s", p.ID)
+		}
+	}
+	avatars, err := s.ListAvatars(ctx, "dst")
+	expectNoError(t, "ListAvatars of the copy", err)
+	if !reflect.DeepEqual(avatars, []string{"me.png"}) {
+		t.Fatalf("avatars of the copy: got %v", avatars)
+	}
+
+	expectError(t, "Duplicate onto an existing session", s.Duplicate(ctx, "src", "dst"), store.ErrConflict)
+	expectError(t, "Duplicate a missing session", s.Duplicate(ctx, "missing", "other"), store.ErrNotFound)
+	expectTitles(t, s, "src", "A", "B")
+}
+
+func testAvatars(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "deck")
+
+	list := func() []string {
+		t.Helper()
+		avatars, err := s.ListAvatars(ctx, "deck")
+		expectNoError(t, "ListAvatars", err)
+		return avatars
+	}
+	if got := list(); len(got) != 0 {
+		t.Fatalf("avatars of a new session: got %v", got)
+	}
+
+	expectNoError(t, "SaveAvatar", s.SaveAvatar(ctx, "deck", "alice.png", []byte("a1")))
+	expectNoError(t, "SaveAvatar", s.SaveAvatar(ctx, "deck", "bob.png", []byte("b")))
+	expectNoError(t, "SaveAvatar overwrite", s.SaveAvatar(ctx, "deck", "alice.png", []byte("a2")))
+	if got := list(); !reflect.DeepEqual(got, []string{"alice.png", "bob.png"}) {
+		t.Fatalf("avatars: got %v", got)
+	}
+	data, err := s.GetAvatar(ctx, "deck", "alice.png")
+	expectNoError(t, "GetAvatar", err)
+	if string(data) != "a2" {
+		t.Fatalf("avatar data: got %q, want %q", data, "a2")
+	}
+
+	expectNoError(t, "RenameAvatar", s.RenameAvatar(ctx, "deck", "alice.png", "carol.png"))
+	if got := list(); !reflect.DeepEqual(got, []string{"bob.png", "carol.png"}) {
+		t.Fatalf("avatars after rename: got %v", got)
+	}
+	_, err = s.GetAvatar(ctx, "deck", "alice.png")
+	expectError(t, "GetAvatar by its old name", err, store.ErrNotFound)
+	expectError(t, "RenameAvatar of a missing avatar", s.RenameAvatar(ctx, "deck", "missing.png", "x.png"), store.ErrNotFound)
+
+	expectNoError(t, "DeleteAvatar", s.DeleteAvatar(ctx, "deck", "bob.png"))
+	if got := list(); !reflect.DeepEqual(got, []string{"carol.png"}) {
+		t.Fatalf("avatars after delete: got %v", got)
+	}
+	expectError(t, "DeleteAvatar of a missing avatar", s.DeleteAvatar(ctx, "deck", "bob.png"), store.ErrNotFound)
+	_, err = s.GetAvatar(ctx, "deck", "bob.png")
+	expectError(t, "GetAvatar of a deleted avatar", err, store.ErrNotFound)
+}
+
+func testHistory(t *testing.T, s store.SessionStore) {
+	ctx := context.Background()
+	create(t, s, "deck", newPage("1", "A"))
+	b := newPage("2", "B")
+	expectNoError(t, "CreatePage", s.CreatePage(ctx, "deck", &b))
+
+	revisions, err := s.ListRevisions(ctx, "deck")
+	expectNoError(t, "ListRevisions", err)
+	if len(revisions) != 1 || revisions[0].Revision != 1 || revisions[0].Pages != 1 {
+		t.Fatalf("revisions: got %+v, want revision 1 with 1 page", revisions)
+	}
+
+	old, err := s.GetRevision(ctx, "deck", 1)
+	expectNoError(t, "GetRevision", err)
+	if len(old.Pages) != 1 || old.Pages[0].Title != "A" {
+		t.Fatalf("revision 1 pages: got %+v", old.Pages)
+	}
+	_, err = s.GetRevision(ctx, "deck", 99)
+	expectError(t, "GetRevision of a missing revision", err, store.ErrNotFound)
+	_, err = s.ListRevisions(ctx, "missing")
+	expectError(t, "ListRevisions of a missing session", err, store.ErrNotFound)
+	_, err = s.GetRevision(ctx, "missing", 1)
+	expectError(t, "GetRevision of a missing session", err, store.ErrNotFound)
+
+	rev := &store.Revision{IfMatch: 2}
+	expectNoError(t, "RestoreRevision", s.RestoreRevision(store.WithRevision(ctx, rev), "deck", 1))
+	if rev.Current != 3 {
+		t.Fatalf("revision after restore: got %d, want 3", rev.Current)
+	}
+	expectTitles(t, s, "deck", "A")
+
+	revisions, err = s.ListRevisions(ctx, "deck")
+	expectNoError(t, "ListRevisions after restore", err)
+	if len(revisions) != 2 || revisions[0].Revision != 2 {
+		t.Fatalf("revisions after restore: got %+v, want 2 then 1", revisions)
+	}
+}
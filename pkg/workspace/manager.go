@@ -0,0 +1,166 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Manager keeps the known workspaces and which one is selected. The
+// selected workspace is only the default for callers that don't name one.
+// Stores are opened on first use and kept open until Close.
+type Manager struct {
+	// Save, when set, is called after the list or the selection changes
+	// so the change survives a restart.
+	Save func(workspaces []Workspace, current string) error
+
+	mu         sync.RWMutex
+	workspaces []Workspace
+	stores     map[string]store.SessionStore
+	current    string
+}
+
+func NewManager() *Manager {
+	return &Manager{stores: make(map[string]store.SessionStore)}
+}
+
+func (m *Manager) find(name string) int {
+	for i, ws := range m.workspaces {
+		if ws.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add registers a workspace without opening it.
+func (m *Manager) Add(ws Workspace) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.add(ws)
+}
+
+func (m *Manager) add(ws Workspace) error {
+	if err := CheckName(ws.Name); err != nil {
+		return err
+	}
+	if m.find(ws.Name) != -1 {
+		return store.Errorf(store.ErrConflict, "workspace %s already exists", ws.Name)
+	}
+	m.workspaces = append(m.workspaces, ws)
+	return nil
+}
+
+// AddStore registers a workspace whose store is already open, and selects
+// it if nothing is selected yet.
+func (m *Manager) AddStore(ws Workspace, s store.SessionStore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.add(ws); err != nil {
+		return err
+	}
+	m.stores[ws.Name] = s
+	if m.current == "" {
+		m.current = ws.Name
+	}
+	return nil
+}
+
+// Create registers a new workspace, checks it can be opened and saves
+// the list.
+func (m *Manager) Create(ws Workspace) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.add(ws); err != nil {
+		return err
+	}
+	s, err := ws.Open()
+	if err != nil {
+		m.workspaces = m.workspaces[:len(m.workspaces)-1]
+		return err
+	}
+	m.stores[ws.Name] = s
+	return m.save()
+}
+
+// List returns the workspaces and the name of the selected one.
+func (m *Manager) List() ([]Workspace, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Workspace(nil), m.workspaces...), m.current
+}
+
+// Select opens the named workspace if needed and makes it current.
+func (m *Manager) Select(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.open(name); err != nil {
+		return err
+	}
+	if m.current == name {
+		return nil
+	}
+	m.current = name
+	return m.save()
+}
+
+// Store returns the store of the selected workspace, or nil.
+func (m *Manager) Store() store.SessionStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.stores[m.current]
+}
+
+// Open returns the store of the named workspace, or of the selected one
+// when name is empty, opening it if needed.
+func (m *Manager) Open(name string) (store.SessionStore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "" {
+		name = m.current
+	}
+	return m.open(name)
+}
+
+func (m *Manager) open(name string) (store.SessionStore, error) {
+	if s, ok := m.stores[name]; ok {
+		return s, nil
+	}
+	i := m.find(name)
+	if i == -1 {
+		return nil, store.Errorf(store.ErrNotFound, "workspace %s not found", name)
+	}
+	s, err := m.workspaces[i].Open()
+	if err != nil {
+		return nil, err
+	}
+	m.stores[name] = s
+	return s, nil
+}
+
+// Close closes the open stores that hold resources, like the database of
+// a SQLite store. Later calls open them again.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for name, s := range m.stores {
+		if c, ok := s.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close workspace %s: %w", name, err))
+			}
+			delete(m.stores, name)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) save() error {
+	if m.Save == nil {
+		return nil
+	}
+	return m.Save(append([]Workspace(nil), m.workspaces...), m.current)
+}
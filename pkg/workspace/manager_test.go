@@ -0,0 +1,64 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+func TestManagerOpen(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager()
+	if err := m.Add(Workspace{Name: DefaultName, Root: dir + "/default"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(Workspace{Name: "db", Root: dir + "/db", Store: "sqlite:sessions.db"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Select(DefaultName); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	db, err := m.Open("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(ctx, &model.Session{Name: "deck"}); err != nil {
+		t.Fatal(err)
+	}
+	// opening another workspace leaves the selection alone
+	if _, current := m.List(); current != DefaultName {
+		t.Errorf("selected %s, want %s", current, DefaultName)
+	}
+	def, err := m.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := def.Get(ctx, "deck"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("deck of db found in the default workspace: %v", err)
+	}
+	if _, err := m.Open("missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Open(missing) = %v, want ErrNotFound", err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get(ctx, "deck"); err == nil {
+		t.Error("the SQLite store is still open after Close")
+	}
+	db, err = m.Open("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get(ctx, "deck"); err != nil {
+		t.Errorf("reopened store: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/file"
+	"github.com/xhd2015/presentationer/pkg/store/git"
+	"github.com/xhd2015/presentationer/pkg/store/sqlite"
+)
+
+// DefaultName is the workspace configured by --root, --store and --git,
+// or the matching fields of the config file.
+const DefaultName = "default"
+
+// Workspace is a named place sessions are kept in.
+type Workspace struct {
+	Name string `json:"name"`
+	// Root is the workspace directory.
+	Root string `json:"root"`
+	// Store selects the backend:
+	//
+	//	file            sessions in Root (the default)
+	//	file:DIR        sessions in DIR
+	//	sqlite:PATH     sessions in the SQLite database at PATH
+	//
+	// Relative paths are relative to Root.
+	Store string `json:"store,omitempty"`
+	// Git commits every change to git; file stores only.
+	Git bool `json:"git,omitempty"`
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._ -]{0,63}$`)
+
+// CheckName reports whether name can name a workspace.
+func CheckName(name string) error {
+	if !namePattern.MatchString(name) {
+		return store.Errorf(store.ErrInvalid, "invalid workspace name %q: use up to 64 letters, digits, '.', '_', '-' or spaces", name)
+	}
+	return nil
+}
+
+func (w Workspace) path(p string) string {
+	if w.Root != "" && !filepath.IsAbs(p) {
+		return filepath.Join(w.Root, p)
+	}
+	return p
+}
+
+// Open opens the workspace's store, creating its directory if needed.
+func (w Workspace) Open() (store.SessionStore, error) {
+	kind, arg, _ := strings.Cut(w.Store, ":")
+	switch kind {
+	case "", "file":
+		dir := w.Root
+		if arg != "" {
+			dir = w.path(arg)
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("workspace %s has no root directory", w.Name)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		fileStore, err := file.Open(dir)
+		if err != nil {
+			return nil, err
+		}
+		if w.Git {
+			return git.New(fileStore)
+		}
+		return fileStore, nil
+	case "sqlite":
+		if arg == "" {
+			return nil, fmt.Errorf("store sqlite requires a database path, e.g. sqlite:sessions.db")
+		}
+		if w.Git {
+			return nil, fmt.Errorf("git only works with the file store")
+		}
+		path := w.path(arg)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		return sqlite.Open(path)
+	default:
+		return nil, fmt.Errorf("unknown store %q, expect file, file:DIR or sqlite:PATH", w.Store)
+	}
+}
@@ -9,6 +9,7 @@ export const PageKind = {
     Stats: 'stats',
     NumberedList: 'numbered_list',
     ConceptCard: 'concept_card',
+    CodeDiff: 'code_diff',
 } as const;
 
 export type PageKind = typeof PageKind[keyof typeof PageKind];
@@ -20,137 +21,611 @@ export interface Page {
     content: any;
 }
 
+// Links a code page to the file its code was taken from; the code is a snapshot of the lines
+export interface SourceRef {
+    // the repository path is relative to, default the directory presentationer runs in
+    root?: string;
+    path: string;
+    // a line range like "10-30"
+    lines?: string;
+    // a Go function, type, const or var, or Type.Method
+    symbol?: string;
+    // show the bodies of the functions nested in symbol as ...
+    elide?: boolean;
+    // the line the snapshot starts at, set by a refresh
+    line?: number;
+}
+
+export type SourceState = 'ok' | 'moved' | 'changed' | 'missing';
+
+// How the snapshot of a code page drifted from its source
+export interface SourceStatus {
+    state: SourceState;
+    start?: number;
+    end?: number;
+    line?: number;
+    error?: string;
+    // the code as it is in the file now; the page keeps its snapshot
+    code?: string;
+}
+
+export interface PageSourceStatus extends SourceStatus {
+    id: string;
+    title: string;
+    code: string;
+    refreshed?: boolean;
+}
+
 export interface Session {
     name: string;
     lastModified: string;
+    revision?: number;
     pages?: Page[];
+    // by page ID, for the code pages linked to source files
+    sources?: Record<string, SourceStatus>;
+}
+
+// Thrown when a write sent a stale revision: someone else changed the session.
+export class ConflictError extends Error {
+    revision: number;
+    constructor(message: string, revision: number) {
+        super(message);
+        this.revision = revision;
+    }
+}
+
+function revisionHeaders(revision?: number): Record<string, string> {
+    return revision ? { 'If-Match': `"${revision}"` } : {};
+}
+
+// An error reply from the API; code is machine readable, e.g. 'not_found',
+// 'conflict', 'invalid' or 'revision_conflict'.
+export class ApiError extends Error {
+    code: string;
+    status: number;
+    constructor(message: string, code: string, status: number) {
+        super(message);
+        this.code = code;
+        this.status = status;
+    }
+}
+
+async function readError(res: Response): Promise<ApiError> {
+    const text = await res.text();
+    try {
+        const body = JSON.parse(text);
+        return new ApiError(body.error || text, body.code || 'internal', res.status);
+    } catch {
+        return new ApiError(text, 'internal', res.status);
+    }
+}
+
+// Returns the session revision produced by a write, from its ETag.
+async function readRevision(res: Response): Promise<number | undefined> {
+    if (res.status === 409) {
+        const body = await res.json().catch(() => ({}));
+        if (body.code === 'revision_conflict') {
+            throw new ConflictError('Session was changed elsewhere, reload to get the latest version', body.revision || 0);
+        }
+        throw new ApiError(body.error || 'Conflict', body.code || 'conflict', res.status);
+    }
+    if (!res.ok) {
+        throw await readError(res);
+    }
+    const etag = res.headers.get('ETag');
+    if (!etag) return undefined;
+    const revision = parseInt(etag.replace(/"/g, ''), 10);
+    return isNaN(revision) ? undefined : revision;
+}
+
+// The workspace of this tab. Every session request names it, so switching
+// workspaces in another tab doesn't move where this one reads and saves.
+// It is kept in sessionStorage to survive a reload of the tab.
+const workspaceKey = 'presentationer.workspace';
+let tabWorkspace = sessionStorage.getItem(workspaceKey) || '';
+let pinning: Promise<void> | null = null;
+
+export function getTabWorkspace(): string {
+    return tabWorkspace;
+}
+
+export function setTabWorkspace(name: string) {
+    tabWorkspace = name;
+    sessionStorage.setItem(workspaceKey, name);
+}
+
+// Adds the workspace of this tab to a session API URL.
+function inWorkspace(url: string): string {
+    if (!tabWorkspace) return url;
+    return `${url}${url.includes('?') ? '&' : '?'}workspace=${encodeURIComponent(tabWorkspace)}`;
+}
+
+// Fetches a session API URL in the workspace of this tab. A new tab first
+// takes the workspace the server opens by default.
+async function sessionFetch(url: string, init?: RequestInit): Promise<Response> {
+    if (!tabWorkspace) {
+        pinning ??= listWorkspaces()
+            .then(data => {
+                if (!tabWorkspace) setTabWorkspace(data.current);
+            })
+            .catch(() => {
+                pinning = null;
+            });
+        await pinning;
+    }
+    return fetch(inWorkspace(url), init);
 }
 
 export async function listSessions(signal?: AbortSignal): Promise<Session[]> {
-    const res = await fetch('/api/sessions/list', { signal });
+    const res = await sessionFetch('/api/sessions/list', { signal });
     if (!res.ok) throw new Error('Failed to fetch sessions');
     return res.json();
 }
 
 export async function createSession(name: string, pages: Page[]): Promise<void> {
-    const res = await fetch('/api/sessions/create', {
+    const res = await sessionFetch('/api/sessions/create', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ name, pages }),
     });
     if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg);
+        throw await readError(res);
     }
 }
 
-export async function updateSession(name: string, pages: Page[]): Promise<void> {
-    const res = await fetch('/api/sessions/update', {
+export async function updateSession(name: string, pages: Page[], revision?: number): Promise<number | undefined> {
+    const res = await sessionFetch('/api/sessions/update', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', ...revisionHeaders(revision) },
         body: JSON.stringify({ name, pages }),
     });
-    if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg);
-    }
+    return readRevision(res);
 }
 
 export async function renameSession(oldName: string, newName: string): Promise<void> {
-    const res = await fetch('/api/sessions/rename', {
+    const res = await sessionFetch('/api/sessions/rename', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ old: oldName, new: newName }),
     });
     if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg);
+        throw await readError(res);
+    }
+}
+
+export async function duplicateSession(src: string, dst: string): Promise<void> {
+    const res = await sessionFetch('/api/sessions/duplicate', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ src, dst }),
+    });
+    if (!res.ok) {
+        throw await readError(res);
     }
 }
 
 export async function deleteSession(name: string): Promise<void> {
-    const res = await fetch(`/api/sessions/delete?name=${encodeURIComponent(name)}`, {
+    const res = await sessionFetch(`/api/sessions/delete?name=${encodeURIComponent(name)}`, {
         method: 'POST',
     });
     if (!res.ok) throw new Error('Failed to delete session');
 }
 
 export async function getSession(name: string): Promise<Session> {
-    const res = await fetch(`/api/sessions/get?name=${encodeURIComponent(name)}`);
+    const res = await sessionFetch(`/api/sessions/get?name=${encodeURIComponent(name)}`);
     if (!res.ok) throw new Error('Failed to load session');
     return res.json();
 }
 
-export async function createPage(sessionName: string, page: Page): Promise<void> {
-    const res = await fetch(`/api/sessions/page/create?session=${encodeURIComponent(sessionName)}`, {
+export async function createPage(sessionName: string, page: Page, revision?: number): Promise<number | undefined> {
+    const res = await sessionFetch(`/api/sessions/page/create?session=${encodeURIComponent(sessionName)}`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', ...revisionHeaders(revision) },
         body: JSON.stringify(page),
     });
-    if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg);
-    }
+    return readRevision(res);
 }
 
-export async function updatePage(sessionName: string, page: Page): Promise<void> {
-    const res = await fetch(`/api/sessions/page/update?session=${encodeURIComponent(sessionName)}`, {
+export async function updatePage(sessionName: string, page: Page, revision?: number): Promise<number | undefined> {
+    const res = await sessionFetch(`/api/sessions/page/update?session=${encodeURIComponent(sessionName)}`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', ...revisionHeaders(revision) },
         body: JSON.stringify(page),
     });
-    if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg);
+    return readRevision(res);
+}
+
+export async function deletePage(sessionName: string, pageId: string, revision?: number): Promise<number | undefined> {
+    const res = await sessionFetch(`/api/sessions/page/delete?session=${encodeURIComponent(sessionName)}&id=${encodeURIComponent(pageId)}`, {
+        method: 'POST',
+        headers: revisionHeaders(revision),
+    });
+    return readRevision(res);
+}
+
+// Inserts a copy of the page right after it and returns the copy.
+export async function duplicatePage(sessionName: string, pageId: string, revision?: number): Promise<{ page: Page; revision?: number }> {
+    const res = await sessionFetch(`/api/sessions/page/duplicate?session=${encodeURIComponent(sessionName)}&id=${encodeURIComponent(pageId)}`, {
+        method: 'POST',
+        headers: revisionHeaders(revision),
+    });
+    const newRevision = await readRevision(res);
+    return { page: await res.json(), revision: newRevision };
+}
+
+// Moves a page to index within its session, or into toSession when given.
+export async function movePage(sessionName: string, pageId: string, index: number, revision?: number, toSession?: string): Promise<number | undefined> {
+    const res = await sessionFetch(`/api/sessions/page/move?session=${encodeURIComponent(sessionName)}`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json', ...revisionHeaders(revision) },
+        body: JSON.stringify({ id: pageId, index, toSession }),
+    });
+    return readRevision(res);
+}
+
+// History APIs
+export interface RevisionInfo {
+    revision: number;
+    time: string;
+    pages: number;
+}
+
+export async function listRevisions(sessionName: string): Promise<RevisionInfo[]> {
+    const res = await sessionFetch(`/api/sessions/history/list?session=${encodeURIComponent(sessionName)}`);
+    if (!res.ok) throw new Error('Failed to list revisions');
+    return res.json();
+}
+
+export async function getRevision(sessionName: string, revision: number): Promise<Session> {
+    const res = await sessionFetch(`/api/sessions/history/get?session=${encodeURIComponent(sessionName)}&revision=${revision}`);
+    if (!res.ok) throw new Error('Failed to load revision');
+    return res.json();
+}
+
+export async function restoreRevision(sessionName: string, revision: number, currentRevision?: number): Promise<number | undefined> {
+    const res = await sessionFetch(`/api/sessions/history/restore?session=${encodeURIComponent(sessionName)}&revision=${revision}`, {
+        method: 'POST',
+        headers: revisionHeaders(currentRevision),
+    });
+    return readRevision(res);
+}
+
+// Version control APIs, available when the server runs with --git
+export interface Commit {
+    hash: string;
+    author: string;
+    time: string;
+    message: string;
+}
+
+export async function gitLog(sessionName: string, limit?: number): Promise<Commit[]> {
+    const params = limit ? `&limit=${limit}` : '';
+    const res = await sessionFetch(`/api/sessions/git/log?session=${encodeURIComponent(sessionName)}${params}`);
+    if (!res.ok) throw await readError(res);
+    return res.json();
+}
+
+export async function gitDiff(sessionName: string, from?: string, to?: string): Promise<string> {
+    const params = new URLSearchParams({ session: sessionName });
+    if (from) params.set('from', from);
+    if (to) params.set('to', to);
+    const res = await sessionFetch(`/api/sessions/git/diff?${params}`);
+    if (!res.ok) throw await readError(res);
+    return res.text();
+}
+
+export async function gitCheckout(sessionName: string, commit: string, currentRevision?: number): Promise<number | undefined> {
+    const res = await sessionFetch('/api/sessions/git/checkout', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json', ...revisionHeaders(currentRevision) },
+        body: JSON.stringify({ session: sessionName, commit }),
+    });
+    return readRevision(res);
+}
+
+// Export APIs
+// Updates the snapshots of the code pages linked to source files, or of one page
+export async function refreshSources(sessionName: string, pageId?: string, revision?: number): Promise<{ statuses: PageSourceStatus[]; revision?: number }> {
+    let url = `/api/sessions/source/refresh?session=${encodeURIComponent(sessionName)}`;
+    if (pageId) url += `&page=${encodeURIComponent(pageId)}`;
+    const res = await sessionFetch(url, {
+        method: 'POST',
+        headers: revisionHeaders(revision),
+    });
+    const newRevision = await readRevision(res);
+    return { statuses: await res.json(), revision: newRevision };
+}
+
+// Server-sent "source" events carry the PageSourceStatus of every linked page when their files change
+export function getSourceWatchUrl(sessionName: string): string {
+    return inWorkspace(`/api/sessions/source/watch?session=${encodeURIComponent(sessionName)}`);
+}
+
+export type ExportFormat = 'html' | 'md' | 'pptx' | 'pdf' | 'bundle';
+
+// Options of the pdf export: handout puts that many pages on each sheet,
+// notes adds lines for notes beside them.
+export interface PdfExportOptions {
+    handout?: number;
+    notes?: boolean;
+}
+
+export function getExportUrl(sessionName: string, format: ExportFormat, pdf?: PdfExportOptions): string {
+    let url = `/api/sessions/export?session=${encodeURIComponent(sessionName)}&format=${format}`;
+    if (pdf?.handout) {
+        url += `&handout=${pdf.handout}`;
+    }
+    if (pdf?.notes) {
+        url += '&notes=true';
     }
+    return inWorkspace(url);
+}
+
+// What an import does when the session exists: fail, import under a free
+// name, or replace the existing session's pages and avatars.
+export type ImportConflict = 'fail' | 'rename' | 'overwrite';
+
+export type ImportFormat = 'bundle' | 'md' | 'diff';
+
+// Picks the import format from the file name: Markdown for .md files, a
+// unified diff for .diff and .patch files, otherwise a bundle.
+export function getImportFormat(fileName: string): ImportFormat {
+    if (/\.(md|markdown)$/i.test(fileName)) return 'md';
+    if (/\.(diff|patch)$/i.test(fileName)) return 'diff';
+    return 'bundle';
+}
+
+// Imports a .presentationer bundle, a Markdown outline or a unified diff
+// and returns the name of the new session.
+export async function importSession(file: File, onConflict: ImportConflict = 'fail', name?: string): Promise<string> {
+    const formData = new FormData();
+    formData.append('file', file);
+    const params = new URLSearchParams({ format: getImportFormat(file.name), onConflict });
+    if (name) params.set('name', name);
+    const res = await sessionFetch(`/api/sessions/import?${params}`, {
+        method: 'POST',
+        body: formData,
+    });
+    if (!res.ok) throw await readError(res);
+    const body = await res.json();
+    return body.name;
+}
+
+// Creates a session of code_diff pages, one per hunk, from a unified diff
+// or git diff output, and returns its name.
+export async function importDiff(diff: string, name?: string, onConflict: ImportConflict = 'fail'): Promise<string> {
+    const params = new URLSearchParams({ onConflict });
+    if (name) params.set('name', name);
+    const res = await sessionFetch(`/api/import/diff?${params}`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'text/x-diff' },
+        body: diff,
+    });
+    if (!res.ok) throw await readError(res);
+    const body = await res.json();
+    return body.name;
+}
+
+// Workspace APIs; each workspace is a separate storage root with its own sessions
+export interface Workspace {
+    name: string;
+    root: string;
+    store?: string;
+    git?: boolean;
+}
+
+export interface WorkspaceList {
+    current: string;
+    workspaces: Workspace[];
+}
+
+export async function listWorkspaces(): Promise<WorkspaceList> {
+    const res = await fetch('/api/workspaces');
+    if (!res.ok) throw await readError(res);
+    return res.json();
+}
+
+export async function selectWorkspace(name: string): Promise<WorkspaceList> {
+    const res = await fetch('/api/workspaces/select', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name }),
+    });
+    if (!res.ok) throw await readError(res);
+    return res.json();
 }
 
-export async function deletePage(sessionName: string, pageId: string): Promise<void> {
-    const res = await fetch(`/api/sessions/page/delete?session=${encodeURIComponent(sessionName)}&id=${encodeURIComponent(pageId)}`, {
+// Creates a workspace in workspaces/<name> of the presentationer home
+// directory; workspaces elsewhere are set up in config.json.
+export async function createWorkspace(name: string): Promise<WorkspaceList> {
+    const res = await fetch('/api/workspaces/create', {
         method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name }),
     });
-    if (!res.ok) throw new Error('Failed to delete page');
+    if (!res.ok) throw await readError(res);
+    return res.json();
+}
+
+// Highlighting on the server, as the exports do
+export interface HighlightToken {
+    text: string;
+    type: string;
+    color: string;
+    bold?: boolean;
+    italic?: boolean;
+    marked?: boolean;
+}
+
+export interface HighlightLine {
+    number: number;
+    focused: boolean;
+    tokens: HighlightToken[];
+}
+
+export interface HighlightedCode {
+    language: string;
+    background: string;
+    foreground: string;
+    lines: HighlightLine[];
+}
+
+export interface HighlightRequest {
+    code: string;
+    language?: string;
+    style?: string;
+    // a focus config such as '3-8,10{"text"}'
+    lines?: string;
+}
+
+export async function highlightCode(req: HighlightRequest): Promise<HighlightedCode> {
+    const res = await fetch('/api/highlight', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(req),
+    });
+    if (!res.ok) throw await readError(res);
+    return res.json();
+}
+
+export async function highlightCodeHtml(req: HighlightRequest): Promise<string> {
+    const res = await fetch('/api/highlight', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ...req, format: 'html' }),
+    });
+    if (!res.ok) throw await readError(res);
+    return res.text();
+}
+
+export async function listHighlightStyles(): Promise<string[]> {
+    const res = await fetch('/api/highlight/styles');
+    if (!res.ok) throw await readError(res);
+    return res.json();
+}
+
+// The content of a code_diff page
+export interface CodeDiffContent {
+    oldCode?: string;
+    newCode?: string;
+    language?: string;
+    // the file the code is from
+    path?: string;
+    // unchanged lines around the changes, 3 when 0 or unset, all when negative
+    context?: number;
+    // the numbers of the first lines, for code from the middle of a file
+    oldStart?: number;
+    newStart?: number;
+    exportWidth?: string;
+    exportHeight?: string;
+}
+
+export interface DiffSpan {
+    text: string;
+    changed?: boolean;
+}
+
+export interface DiffLine {
+    kind: 'context' | 'delete' | 'insert';
+    old?: number;
+    new?: number;
+    text: string;
+    // the shared and changed text of a line paired with its other version
+    spans?: DiffSpan[];
+}
+
+export interface DiffHunk {
+    oldStart: number;
+    oldLines: number;
+    newStart: number;
+    newLines: number;
+    lines: DiffLine[];
+}
+
+// Compares the old and new code of a code_diff page on the server
+export async function diffCode(content: CodeDiffContent): Promise<DiffHunk[]> {
+    const res = await fetch('/api/diff', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(content),
+    });
+    if (!res.ok) throw await readError(res);
+    return res.json();
+}
+
+// A Go declaration extracted from a module
+export interface GoSnippet {
+    package: string;
+    // relative to the module directory
+    file: string;
+    // Name or Type.Method
+    symbol: string;
+    code: string;
+    start: number;
+    end: number;
+    // the line of the file each line of code is from
+    lines: number[];
+    // the focus config of the request, with the lines of code
+    focus?: string;
+}
+
+export interface ExtractRequest {
+    // the module directory, default the directory presentationer runs in
+    dir?: string;
+    // a qualified name like server.Serve or pkg/model.Page.Validate
+    name: string;
+    elide?: boolean;
+    // a focus config with the lines of the file
+    focus?: string;
+}
+
+export async function extractGoSymbol(req: ExtractRequest): Promise<GoSnippet> {
+    const res = await fetch('/api/source/extract', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(req),
+    });
+    if (!res.ok) throw await readError(res);
+    return res.json();
 }
 
 // Avatar APIs
 export async function uploadAvatar(sessionName: string, avatarName: string, file: File): Promise<void> {
     const formData = new FormData();
     formData.append('file', file);
-    const res = await fetch(`/api/sessions/avatar/upload?session=${encodeURIComponent(sessionName)}&name=${encodeURIComponent(avatarName)}`, {
+    const res = await sessionFetch(`/api/sessions/avatar/upload?session=${encodeURIComponent(sessionName)}&name=${encodeURIComponent(avatarName)}`, {
         method: 'POST',
         body: formData,
     });
     if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg);
+        throw await readError(res);
     }
 }
 
 export async function listAvatars(sessionName: string): Promise<string[]> {
-    const res = await fetch(`/api/sessions/avatar/list?session=${encodeURIComponent(sessionName)}`);
+    const res = await sessionFetch(`/api/sessions/avatar/list?session=${encodeURIComponent(sessionName)}`);
     if (!res.ok) throw new Error('Failed to list avatars');
     return res.json();
 }
 
 export async function deleteAvatar(sessionName: string, avatarName: string): Promise<void> {
-    const res = await fetch(`/api/sessions/avatar/delete?session=${encodeURIComponent(sessionName)}&name=${encodeURIComponent(avatarName)}`, {
+    const res = await sessionFetch(`/api/sessions/avatar/delete?session=${encodeURIComponent(sessionName)}&name=${encodeURIComponent(avatarName)}`, {
         method: 'DELETE'
     });
     if (!res.ok) throw new Error('Failed to delete avatar');
 }
 
 export async function renameAvatar(sessionName: string, oldName: string, newName: string): Promise<void> {
-    const res = await fetch(`/api/sessions/avatar/rename?session=${encodeURIComponent(sessionName)}`, {
+    const res = await sessionFetch(`/api/sessions/avatar/rename?session=${encodeURIComponent(sessionName)}`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ old: oldName, new: newName }),
     });
     if (!res.ok) {
-        const msg = await res.text();
-        throw new Error(msg);
+        throw await readError(res);
     }
 }
 
 export function getAvatarUrl(sessionName: string, avatarName: string): string {
-    return `/api/sessions/avatar/get?session=${encodeURIComponent(sessionName)}&name=${encodeURIComponent(avatarName)}`;
+    return inWorkspace(`/api/sessions/avatar/get?session=${encodeURIComponent(sessionName)}&name=${encodeURIComponent(avatarName)}`);
 }
@@ -0,0 +1,112 @@
+import React from 'react';
+import type { CodeDiffContent } from '../../api/session';
+import { SUPPORTED_LANGUAGES } from '../code-presenter/ConfigPanel';
+
+interface CodeDiffEditorCoreProps {
+    content: CodeDiffContent;
+    update: (partial: Partial<CodeDiffContent>) => void;
+}
+
+const textareaStyle: React.CSSProperties = {
+    width: '100%',
+    fontFamily: 'monospace',
+    padding: '10px',
+    borderRadius: '4px',
+    border: '1px solid #ccc',
+    boxSizing: 'border-box',
+};
+
+const labelStyle: React.CSSProperties = { display: 'block', fontSize: '12px', marginBottom: '2px' };
+
+// Edits the two versions of the code a code_diff page compares
+export const CodeDiffEditorCore: React.FC<CodeDiffEditorCoreProps> = ({ content, update }) => {
+    const number = (value: string) => {
+        const n = parseInt(value, 10);
+        return isNaN(n) ? undefined : n;
+    };
+    return (
+        <div style={{ display: 'flex', flexDirection: 'column', gap: '10px' }}>
+            <div style={{ display: 'flex', gap: '10px', alignItems: 'flex-end', flexWrap: 'wrap' }}>
+                <label>
+                    <strong>Language: </strong>
+                    <select
+                        value={content.language || 'go'}
+                        onChange={(e) => update({ language: e.target.value })}
+                        style={{ marginLeft: '5px', padding: '4px', borderRadius: '4px' }}
+                    >
+                        {SUPPORTED_LANGUAGES.map(lang => (
+                            <option key={lang} value={lang}>{lang}</option>
+                        ))}
+                    </select>
+                </label>
+                <div style={{ flex: 1, minWidth: '160px' }}>
+                    <label style={labelStyle}>File:</label>
+                    <input
+                        type="text"
+                        value={content.path || ''}
+                        onChange={(e) => update({ path: e.target.value || undefined })}
+                        placeholder="server/server.go"
+                        style={{ width: '100%', padding: '4px', boxSizing: 'border-box' }}
+                    />
+                </div>
+                <div>
+                    <label style={labelStyle} title="Unchanged lines around the changes, -1 for all">Context lines:</label>
+                    <input
+                        type="number"
+                        min={-1}
+                        value={content.context ?? ''}
+                        onChange={(e) => update({ context: number(e.target.value) })}
+                        placeholder="3"
+                        style={{ width: '70px', padding: '4px' }}
+                    />
+                </div>
+            </div>
+            <div style={{ display: 'flex', gap: '10px' }}>
+                <div style={{ flex: 1 }}>
+                    <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
+                        <strong>Before:</strong>
+                        <label style={{ fontSize: '12px' }}>
+                            first line{' '}
+                            <input
+                                type="number"
+                                min={1}
+                                value={content.oldStart ?? ''}
+                                onChange={(e) => update({ oldStart: number(e.target.value) })}
+                                placeholder="1"
+                                style={{ width: '60px', padding: '2px' }}
+                            />
+                        </label>
+                    </div>
+                    <textarea
+                        value={content.oldCode || ''}
+                        onChange={(e) => update({ oldCode: e.target.value })}
+                        rows={15}
+                        style={textareaStyle}
+                    />
+                </div>
+                <div style={{ flex: 1 }}>
+                    <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
+                        <strong>After:</strong>
+                        <label style={{ fontSize: '12px' }}>
+                            first line{' '}
+                            <input
+                                type="number"
+                                min={1}
+                                value={content.newStart ?? ''}
+                                onChange={(e) => update({ newStart: number(e.target.value) })}
+                                placeholder="1"
+                                style={{ width: '60px', padding: '2px' }}
+                            />
+                        </label>
+                    </div>
+                    <textarea
+                        value={content.newCode || ''}
+                        onChange={(e) => update({ newCode: e.target.value })}
+                        rows={15}
+                        style={textareaStyle}
+                    />
+                </div>
+            </div>
+        </div>
+    );
+};
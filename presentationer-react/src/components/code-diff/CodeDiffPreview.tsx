@@ -0,0 +1,96 @@
+import React, { useEffect, useState } from 'react';
+import hljs from 'highlight.js/lib/core';
+import { diffCode } from '../../api/session';
+import type { CodeDiffContent, DiffHunk, DiffLine } from '../../api/session';
+import { getExportedDeck } from '../viewer/DeckViewer';
+
+import 'highlight.js/styles/vs2015.css';
+
+interface CodeDiffPreviewProps {
+    pageId: string;
+    content: CodeDiffContent;
+}
+
+// The tints of deleted and inserted lines, and the stronger ones of the
+// text that changed within them, as the exports draw them.
+const lineColors = {
+    delete: { sign: '#F85149', line: 'rgba(248, 81, 73, 0.15)', changed: 'rgba(248, 81, 73, 0.4)' },
+    insert: { sign: '#3FB950', line: 'rgba(63, 185, 80, 0.15)', changed: 'rgba(63, 185, 80, 0.4)' },
+};
+
+const escapeHtml = (text: string) =>
+    text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+
+// Highlights a piece of a line on its own; the languages are the ones the
+// code presenter registers.
+const highlightText = (text: string, language: string): string => {
+    if (!hljs.getLanguage(language)) return escapeHtml(text);
+    return hljs.highlight(text, { language, ignoreIllegals: true }).value;
+};
+
+const hunkHeader = (h: DiffHunk) => {
+    const range = (start: number, lines: number) => lines === 1 ? `${start}` : `${start},${lines}`;
+    return `@@ -${range(h.oldStart, h.oldLines)} +${range(h.newStart, h.newLines)} @@`;
+};
+
+const DiffLineRow: React.FC<{ line: DiffLine; language: string }> = ({ line, language }) => {
+    const colors = line.kind === 'context' ? undefined : lineColors[line.kind];
+    const numberStyle: React.CSSProperties = { display: 'inline-block', width: '36px', color: '#666', textAlign: 'right', marginRight: '8px', userSelect: 'none' };
+    return (
+        <div style={{ backgroundColor: colors?.line, whiteSpace: 'pre' }}>
+            <span style={numberStyle}>{line.old || ''}</span>
+            <span style={numberStyle}>{line.new || ''}</span>
+            <span style={{ display: 'inline-block', width: '16px', color: colors?.sign, fontWeight: 'bold', userSelect: 'none' }}>
+                {line.kind === 'delete' ? '-' : line.kind === 'insert' ? '+' : ' '}
+            </span>
+            {line.spans ? (
+                line.spans.map((span, i) => (
+                    <span
+                        key={i}
+                        style={{ backgroundColor: span.changed ? colors?.changed : undefined, borderRadius: '2px' }}
+                        dangerouslySetInnerHTML={{ __html: highlightText(span.text, language) }}
+                    />
+                ))
+            ) : (
+                <span dangerouslySetInnerHTML={{ __html: highlightText(line.text, language) || ' ' }} />
+            )}
+        </div>
+    );
+};
+
+// Shows the changes from the old to the new code, hunk by hunk. The hunks
+// come from the server, or with a static export from the deck.
+export const CodeDiffPreview: React.FC<CodeDiffPreviewProps> = ({ pageId, content }) => {
+    const exported = getExportedDeck()?.diffs?.[pageId];
+    const [hunks, setHunks] = useState<DiffHunk[] | null>(exported || null);
+    const [error, setError] = useState<string | null>(null);
+    const { oldCode, newCode, context, oldStart, newStart } = content;
+
+    useEffect(() => {
+        if (exported) return;
+        let cancelled = false;
+        const timer = setTimeout(() => {
+            diffCode({ oldCode, newCode, context, oldStart, newStart })
+                .then(h => { if (!cancelled) { setHunks(h); setError(null); } })
+                .catch(e => { if (!cancelled) setError(e instanceof Error ? e.message : String(e)); });
+        }, 300);
+        return () => { cancelled = true; clearTimeout(timer); };
+    }, [exported, oldCode, newCode, context, oldStart, newStart]);
+
+    const language = content.language || 'go';
+    return (
+        <div style={{ backgroundColor: '#1e1e1e', color: '#d4d4d4', fontFamily: 'monospace', fontSize: '14px', lineHeight: 1.5, padding: '15px', minWidth: '400px' }}>
+            {content.path && <div style={{ color: '#888', marginBottom: '8px' }}>{content.path}</div>}
+            {error && <div style={{ color: '#F85149' }}>{error}</div>}
+            {hunks && hunks.length === 0 && <div style={{ color: '#888' }}>No changes.</div>}
+            {hunks && hunks.map((h, i) => (
+                <div key={i} className={`language-${language}`}>
+                    {hunks.length > 1 && (
+                        <div style={{ color: '#888', marginTop: i > 0 ? '8px' : 0 }}>{hunkHeader(h)}</div>
+                    )}
+                    {h.lines.map((line, j) => <DiffLineRow key={j} line={line} language={language} />)}
+                </div>
+            ))}
+        </div>
+    );
+};
@@ -0,0 +1,158 @@
+import React, { useState } from 'react';
+import { extractGoSymbol } from '../../api/session';
+import type { SourceRef, SourceStatus } from '../../api/session';
+
+interface SourcePanelProps {
+    source?: SourceRef;
+    setSource: (source: SourceRef | undefined) => void;
+    status?: SourceStatus;
+    onRefresh?: () => void;
+}
+
+const describeStatus = (status: SourceStatus): string => {
+    const lines = status.start ? `lines ${status.start}-${status.end}` : '';
+    switch (status.state) {
+        case 'ok':
+            return `In sync with ${lines}`;
+        case 'moved':
+            return `Moved${status.line ? ` from line ${status.line}` : ''} to ${lines}`;
+        case 'changed':
+            return `Changed since the snapshot, now ${lines}`;
+        case 'missing':
+            return `Missing: ${status.error || 'source not found'}`;
+    }
+};
+
+const statusColor: Record<SourceStatus['state'], string> = {
+    ok: '#2e7d32',
+    moved: '#b26a00',
+    changed: '#b26a00',
+    missing: '#c62828',
+};
+
+// Links the code to a source file, showing how the snapshot drifted from it
+export const SourcePanel: React.FC<SourcePanelProps> = ({ source, setSource, status, onRefresh }) => {
+    const [qualifiedName, setQualifiedName] = useState('');
+    const [findError, setFindError] = useState<string | null>(null);
+
+    if (!source) {
+        return (
+            <div>
+                <button onClick={() => setSource({ path: '' })} style={{ padding: '4px 8px', cursor: 'pointer', fontSize: '12px' }}>
+                    Link to Source File
+                </button>
+            </div>
+        );
+    }
+
+    const byLines = source.symbol === undefined;
+    const update = (partial: Partial<SourceRef>) => setSource({ ...source, ...partial });
+    const inputStyle: React.CSSProperties = { width: '100%', padding: '4px', boxSizing: 'border-box' };
+    const labelStyle: React.CSSProperties = { display: 'block', fontSize: '12px', marginBottom: '2px' };
+
+    // finds the file of a qualified name like server.Serve in the repository
+    const findSymbol = async () => {
+        setFindError(null);
+        try {
+            const snippet = await extractGoSymbol({ dir: source.root, name: qualifiedName.trim() });
+            update({ path: snippet.file, lines: undefined, symbol: snippet.symbol });
+        } catch (e) {
+            setFindError(e instanceof Error ? e.message : String(e));
+        }
+    };
+
+    return (
+        <div style={{ border: '1px solid #eee', padding: '10px', borderRadius: '4px', display: 'flex', flexDirection: 'column', gap: '8px' }}>
+            <div style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between' }}>
+                <strong>Source:</strong>
+                <button
+                    onClick={() => setSource(undefined)}
+                    style={{ color: 'red', fontSize: '12px', padding: '2px 6px', cursor: 'pointer', border: '1px solid #faa', background: '#fff0f0' }}
+                >
+                    Unlink
+                </button>
+            </div>
+            <div style={{ display: 'flex', gap: '8px' }}>
+                <div style={{ flex: 2 }}>
+                    <label style={labelStyle}>File (relative to the repository):</label>
+                    <input
+                        type="text"
+                        value={source.path}
+                        onChange={(e) => update({ path: e.target.value })}
+                        placeholder="server/server.go"
+                        style={inputStyle}
+                    />
+                </div>
+                <div style={{ flex: 1 }}>
+                    <label style={labelStyle}>
+                        <select
+                            value={byLines ? 'lines' : 'symbol'}
+                            onChange={(e) => update(e.target.value === 'lines' ? { lines: '', symbol: undefined, elide: undefined } : { lines: undefined, symbol: '' })}
+                            style={{ fontSize: '12px' }}
+                        >
+                            <option value="lines">Lines</option>
+                            <option value="symbol">Go symbol</option>
+                        </select>
+                    </label>
+                    <input
+                        type="text"
+                        value={(byLines ? source.lines : source.symbol) || ''}
+                        onChange={(e) => update(byLines ? { lines: e.target.value } : { symbol: e.target.value })}
+                        placeholder={byLines ? '10-30, empty for all' : 'Server.Serve'}
+                        style={inputStyle}
+                    />
+                </div>
+            </div>
+            {!byLines && (
+                <div style={{ display: 'flex', flexDirection: 'column', gap: '4px' }}>
+                    <div style={{ display: 'flex', gap: '8px', alignItems: 'center' }}>
+                        <input
+                            type="text"
+                            value={qualifiedName}
+                            onChange={(e) => setQualifiedName(e.target.value)}
+                            onKeyDown={(e) => e.key === 'Enter' && qualifiedName.trim() && findSymbol()}
+                            placeholder="Find in the module: server.Serve"
+                            style={{ ...inputStyle, flex: 1 }}
+                        />
+                        <button
+                            onClick={findSymbol}
+                            disabled={!qualifiedName.trim()}
+                            style={{ padding: '4px 8px', cursor: 'pointer', fontSize: '12px' }}
+                        >
+                            Find
+                        </button>
+                    </div>
+                    {findError && <span style={{ color: '#c62828', fontSize: '12px' }}>{findError}</span>}
+                    <label style={{ fontSize: '12px', display: 'flex', alignItems: 'center', gap: '4px' }}>
+                        <input
+                            type="checkbox"
+                            checked={!!source.elide}
+                            onChange={(e) => update({ elide: e.target.checked || undefined })}
+                        />
+                        Elide the bodies of nested functions
+                    </label>
+                </div>
+            )}
+            <div>
+                <label style={labelStyle}>Repository (default: where presentationer runs):</label>
+                <input
+                    type="text"
+                    value={source.root || ''}
+                    onChange={(e) => update({ root: e.target.value || undefined })}
+                    placeholder="/path/to/repo"
+                    style={inputStyle}
+                />
+            </div>
+            {status && (
+                <div style={{ display: 'flex', alignItems: 'center', justifyContent: 'space-between', fontSize: '13px' }}>
+                    <span style={{ color: statusColor[status.state] }}>{describeStatus(status)}</span>
+                    {status.state !== 'ok' && status.state !== 'missing' && onRefresh && (
+                        <button onClick={onRefresh} style={{ padding: '2px 8px', cursor: 'pointer', fontSize: '12px' }}>
+                            Update Snapshot
+                        </button>
+                    )}
+                </div>
+            )}
+        </div>
+    );
+};
@@ -11,6 +11,7 @@ interface PageListSidebarProps {
     onSettingsClick: () => void;
     onRenamePage?: (id: string, newTitle: string) => Promise<void>;
     onDuplicatePage?: (id: string) => Promise<void>;
+    onMovePage?: (id: string, newIndex: number) => Promise<void>;
 }
 
 export const PageListSidebar: React.FC<PageListSidebarProps> = ({
@@ -22,11 +23,14 @@ export const PageListSidebar: React.FC<PageListSidebarProps> = ({
     onSettingsClick,
     onRenamePage,
     onDuplicatePage,
+    onMovePage,
 }) => {
     const [deleteConfirmId, setDeleteConfirmId] = useState<string | null>(null);
     const [editingId, setEditingId] = useState<string | null>(null);
     const [editValue, setEditValue] = useState('');
     const inputRef = useRef<HTMLInputElement>(null);
+    const [dragId, setDragId] = useState<string | null>(null);
+    const [dropIndex, setDropIndex] = useState<number | null>(null);
 
     useEffect(() => {
         if (editingId && inputRef.current) {
@@ -60,6 +64,15 @@ export const PageListSidebar: React.FC<PageListSidebarProps> = ({
         }
     };
 
+    const handleDrop = (e: React.DragEvent, index: number) => {
+        e.preventDefault();
+        if (dragId && onMovePage) {
+            onMovePage(dragId, index);
+        }
+        setDragId(null);
+        setDropIndex(null);
+    };
+
     const handleKeyDown = (e: React.KeyboardEvent) => {
         if (e.key === 'Enter') {
             handleEditSave(e);
@@ -78,14 +91,21 @@ export const PageListSidebar: React.FC<PageListSidebarProps> = ({
                 </div>
             </div>
             <div style={{ flex: 1, overflowY: 'auto' }}>
-                {pages.map(p => (
+                {pages.map((p, index) => (
                     <div
                         key={p.id}
                         onClick={() => onSelectPage(p.id)}
+                        draggable={!!onMovePage && editingId !== p.id}
+                        onDragStart={() => setDragId(p.id)}
+                        onDragOver={(e) => { if (dragId) { e.preventDefault(); setDropIndex(index); } }}
+                        onDrop={(e) => handleDrop(e, index)}
+                        onDragEnd={() => { setDragId(null); setDropIndex(null); }}
                         style={{
                             padding: '8px 15px',
                             cursor: 'pointer',
                             backgroundColor: selectedPageId === p.id ? '#eef' : 'transparent',
+                            borderTop: dropIndex === index && dragId !== p.id ? '2px solid #88f' : '2px solid transparent',
+                            opacity: dragId === p.id ? 0.5 : 1,
                             display: 'flex',
                             alignItems: 'center',
                             justifyContent: 'space-between',
@@ -1,17 +1,19 @@
 import React, { useState, useRef, useCallback } from 'react';
+import { MdFileDownload } from 'react-icons/md';
 import { Outlet, useNavigate, useParams, useLocation } from 'react-router-dom';
 import { PageListSidebar } from './PageListSidebar';
 import { SessionDetailProvider, useSessionDetailContext } from '../../context/SessionDetailContext';
 import { CreatePageModal } from './CreatePageModal';
-import { getAvatarUrl, PageKind } from '../../api/session';
+import { getAvatarUrl, getExportUrl, PageKind } from '../../api/session';
 import { ResizableSplitPane } from '../common/ResizableSplitPane';
 import { PreviewControls } from '../common/PreviewControls';
 import { PreviewContainer } from '../common/PreviewContainer';
+import { Menu } from '../common/Menu';
 import { pageRegistry } from './PageRegistry';
 import './StandardPages';
 
 const SessionDetailContent: React.FC = () => {
-    const { pages, createPage, deletePage, sessionName, saveSession, renamePage, updatePageContent, duplicatePage } = useSessionDetailContext();
+    const { pages, createPage, deletePage, sessionName, saveSession, renamePage, updatePageContent, duplicatePage, movePage } = useSessionDetailContext();
     const { pageTitle } = useParams();
     const navigate = useNavigate();
     const location = useLocation();
@@ -109,6 +111,7 @@ const SessionDetailContent: React.FC = () => {
                 onSettingsClick={handleSettingsClick}
                 onRenamePage={handleRenamePage}
                 onDuplicatePage={duplicatePage}
+                onMovePage={movePage}
             />
             <div style={{ flex: 1, display: 'flex', flexDirection: 'column', overflow: 'hidden' }}>
                 <div style={{ padding: '10px 20px', borderBottom: '1px solid #eee', display: 'flex', justifyContent: 'space-between', alignItems: 'center', backgroundColor: 'white', height: '40px' }}>
@@ -126,19 +129,32 @@ const SessionDetailContent: React.FC = () => {
                             </>
                         )}
                     </div>
-                    <button
-                        onClick={saveSession}
-                        style={{
-                            padding: '6px 12px',
-                            backgroundColor: '#646cff',
-                            color: 'white',
-                            border: 'none',
-                            borderRadius: '4px',
-                            cursor: 'pointer'
-                        }}
-                    >
-                        Save Session
-                    </button>
+                    <div style={{ display: 'flex', alignItems: 'center', gap: '10px' }}>
+                        <Menu
+                            icon={<MdFileDownload size={20} title="Export" />}
+                            items={[
+                                { label: 'Export HTML', onClick: () => { window.location.href = getExportUrl(sessionName, 'html'); } },
+                                { label: 'Export Markdown', onClick: () => { window.location.href = getExportUrl(sessionName, 'md'); } },
+                                { label: 'Export PowerPoint', onClick: () => { window.location.href = getExportUrl(sessionName, 'pptx'); } },
+                                { label: 'Export PDF', onClick: () => { window.location.href = getExportUrl(sessionName, 'pdf'); } },
+                                { label: 'Export PDF Handout', onClick: () => { window.location.href = getExportUrl(sessionName, 'pdf', { handout: 3, notes: true }); } },
+                                { label: 'Export Bundle', onClick: () => { window.location.href = getExportUrl(sessionName, 'bundle'); } },
+                            ]}
+                        />
+                        <button
+                            onClick={saveSession}
+                            style={{
+                                padding: '6px 12px',
+                                backgroundColor: '#646cff',
+                                color: 'white',
+                                border: 'none',
+                                borderRadius: '4px',
+                                cursor: 'pointer'
+                            }}
+                        >
+                            Save Session
+                        </button>
+                    </div>
                 </div>
                 <ResizableSplitPane
                     left={<Outlet context={{ previewRef }} />}
@@ -0,0 +1,62 @@
+import React, { useState, useEffect, useCallback } from 'react';
+import { FiRotateCcw } from 'react-icons/fi';
+import toast from 'react-hot-toast';
+import { listRevisions, type RevisionInfo } from '../../api/session';
+import { useSessionDetailContext } from '../../context/SessionDetailContext';
+
+interface SessionHistoryProps {
+    sessionName: string;
+}
+
+export const SessionHistory: React.FC<SessionHistoryProps> = ({ sessionName }) => {
+    const { restoreRevision } = useSessionDetailContext();
+    const [revisions, setRevisions] = useState<RevisionInfo[]>([]);
+    const [loading, setLoading] = useState(false);
+
+    const loadRevisions = useCallback(async () => {
+        setLoading(true);
+        try {
+            setRevisions(await listRevisions(sessionName) || []);
+        } catch (e) {
+            toast.error('Failed to load history');
+            setRevisions([]);
+        } finally {
+            setLoading(false);
+        }
+    }, [sessionName]);
+
+    useEffect(() => {
+        loadRevisions();
+    }, [loadRevisions]);
+
+    const handleRestore = async (revision: number) => {
+        if (!confirm(`Restore revision ${revision}? The current pages are kept in history.`)) return;
+        await restoreRevision(revision);
+        loadRevisions();
+    };
+
+    return (
+        <div style={{ marginBottom: '20px' }}>
+            <h4 style={{ margin: '0 0 10px 0' }}>History</h4>
+            {loading ? <div>Loading...</div> : (
+                <div style={{ display: 'flex', flexDirection: 'column', gap: '4px', fontSize: '0.9em' }}>
+                    {revisions.map(r => (
+                        <div key={r.revision} style={{ display: 'flex', alignItems: 'center', gap: '10px', padding: '4px 8px', border: '1px solid #eee', borderRadius: '4px' }}>
+                            <span style={{ width: '50px', color: '#666' }}>#{r.revision}</span>
+                            <span style={{ flex: 1 }}>{new Date(r.time).toLocaleString()}</span>
+                            <span style={{ color: '#888' }}>{r.pages} pages</span>
+                            <button
+                                onClick={() => handleRestore(r.revision)}
+                                title="Restore"
+                                style={{ border: 'none', background: 'none', cursor: 'pointer', color: '#666' }}
+                            >
+                                <FiRotateCcw size={12} />
+                            </button>
+                        </div>
+                    ))}
+                    {revisions.length === 0 && <div style={{ color: '#888', padding: '10px 0' }}>No history yet</div>}
+                </div>
+            )}
+        </div>
+    );
+};
@@ -1,5 +1,5 @@
 import React, { useState, useEffect, useRef } from 'react';
-import { MdAdd, MdCheck, MdClose, MdDelete, MdEdit } from 'react-icons/md';
+import { MdAdd, MdCheck, MdClose, MdContentCopy, MdDelete, MdEdit, MdFileUpload } from 'react-icons/md';
 import { type Session } from '../../api/session';
 
 interface SessionListSidebarProps {
@@ -9,6 +9,9 @@ interface SessionListSidebarProps {
     onDeleteSession: (name: string) => void;
     onCreateClick: () => void;
     onRenameSession?: (oldName: string, newName: string) => Promise<void>;
+    onDuplicateSession?: (name: string) => Promise<void>;
+    onImportSession?: (file: File) => Promise<void>;
+    header?: React.ReactNode;
 }
 
 export const SessionListSidebar: React.FC<SessionListSidebarProps> = ({
@@ -18,11 +21,15 @@ export const SessionListSidebar: React.FC<SessionListSidebarProps> = ({
     onDeleteSession,
     onCreateClick,
     onRenameSession,
+    onDuplicateSession,
+    onImportSession,
+    header,
 }) => {
     const [deleteConfirmId, setDeleteConfirmId] = useState<string | null>(null);
     const [editingId, setEditingId] = useState<string | null>(null);
     const [editValue, setEditValue] = useState('');
     const inputRef = useRef<HTMLInputElement>(null);
+    const importRef = useRef<HTMLInputElement>(null);
 
     useEffect(() => {
         if (editingId && inputRef.current) {
@@ -80,9 +87,28 @@ export const SessionListSidebar: React.FC<SessionListSidebarProps> = ({
 
     return (
         <div style={{ width: '250px', borderRight: '1px solid #ddd', display: 'flex', flexDirection: 'column', backgroundColor: '#f0f0f0' }}>
+            {header}
             <div style={{ padding: '10px 15px', borderBottom: '1px solid #ddd', display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
                 <h4 style={{ margin: 0 }}>Sessions</h4>
-                <button onClick={onCreateClick} style={{ cursor: 'pointer', padding: '2px 6px' }}><MdAdd /></button>
+                <div style={{ display: 'flex', gap: '4px' }}>
+                    {onImportSession && (
+                        <>
+                            <button onClick={() => importRef.current?.click()} style={{ cursor: 'pointer', padding: '2px 6px' }} title="Import Session"><MdFileUpload /></button>
+                            <input
+                                ref={importRef}
+                                type="file"
+                                accept=".presentationer,.zip,.md,.markdown,.diff,.patch"
+                                style={{ display: 'none' }}
+                                onChange={(e) => {
+                                    const file = e.target.files?.[0];
+                                    e.target.value = '';
+                                    if (file) onImportSession(file).catch(() => { });
+                                }}
+                            />
+                        </>
+                    )}
+                    <button onClick={onCreateClick} style={{ cursor: 'pointer', padding: '2px 6px' }}><MdAdd /></button>
+                </div>
             </div>
             <div style={{ flex: 1, overflowY: 'auto' }}>
                 {sessions.map(s => (
@@ -136,6 +162,13 @@ export const SessionListSidebar: React.FC<SessionListSidebarProps> = ({
                                     >
                                         <MdEdit size={16} />
                                     </button>
+                                    <button
+                                        onClick={(e) => { e.stopPropagation(); onDuplicateSession?.(s.name).catch(() => { }); }}
+                                        style={{ border: 'none', background: 'none', cursor: 'pointer', color: '#999', padding: 0 }}
+                                        title="Duplicate"
+                                    >
+                                        <MdContentCopy size={16} />
+                                    </button>
                                     <button
                                         onClick={(e) => handleDeleteClick(s.name, e)}
                                         style={{ border: 'none', background: 'none', cursor: 'pointer', color: '#999', padding: 0 }}
@@ -2,6 +2,7 @@ import React, { useState, useEffect } from 'react';
 import { FiTrash, FiEdit2, FiUpload } from 'react-icons/fi';
 import toast from 'react-hot-toast';
 import { listAvatars, uploadAvatar, deleteAvatar, renameAvatar, getAvatarUrl } from '../../api/session';
+import { SessionHistory } from './SessionHistory';
 
 interface SessionSettingsProps {
     sessionName: string;
@@ -160,6 +161,8 @@ export const SessionSettings: React.FC<SessionSettingsProps> = ({ sessionName })
                     </div>
                 )}
             </div>
+
+            <SessionHistory sessionName={sessionName} />
         </div>
     );
 };
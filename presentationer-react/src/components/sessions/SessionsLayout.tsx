@@ -3,9 +3,10 @@ import { Outlet, useNavigate, useParams } from 'react-router-dom';
 import { SessionListSidebar } from './SessionListSidebar';
 import { useSessionContext } from '../../context/SessionContext';
 import { CreateSessionModal } from './CreateSessionModal';
+import { WorkspaceSelector } from './WorkspaceSelector';
 
 export const SessionsLayout: React.FC = () => {
-    const { sessions, createSession, deleteSession, renameSession } = useSessionContext();
+    const { sessions, createSession, deleteSession, renameSession, duplicateSession, importSession, workspaces, currentWorkspace, selectWorkspace, createWorkspace } = useSessionContext();
     const { sessionName } = useParams();
     const navigate = useNavigate();
     const [isCreateModalOpen, setCreateModalOpen] = useState(false);
@@ -37,6 +38,16 @@ export const SessionsLayout: React.FC = () => {
                 onDeleteSession={handleDeleteSession}
                 onCreateClick={() => setCreateModalOpen(true)}
                 onRenameSession={handleRenameSession}
+                onDuplicateSession={duplicateSession}
+                onImportSession={importSession}
+                header={workspaces.length > 0 && (
+                    <WorkspaceSelector
+                        workspaces={workspaces}
+                        currentWorkspace={currentWorkspace}
+                        onSelectWorkspace={selectWorkspace}
+                        onCreateWorkspace={(name) => createWorkspace(name)}
+                    />
+                )}
             />
             <div style={{ flex: 1, display: 'flex', flexDirection: 'column', overflow: 'hidden' }}>
                 <Outlet />
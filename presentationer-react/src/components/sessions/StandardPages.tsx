@@ -1,6 +1,6 @@
 import React from 'react';
 import { PageKind, getAvatarUrl, listAvatars } from '../../api/session';
-import type { Page } from '../../api/session';
+import type { CodeDiffContent, Page } from '../../api/session';
 import { pageRegistry } from './PageRegistry';
 import type { PageDefinition } from './PageRegistry';
 import { PreviewPanel } from '../code-presenter/PreviewPanel';
@@ -13,7 +13,10 @@ import { StructureBreakdownPreview } from '../structure/StructureBreakdownPrevie
 import { StatsPreview } from '../stats/StatsPreview';
 import { NumberedListPreview } from '../numbered-list/NumberedListPreview';
 import { ConceptCardPreview } from '../concept-card/ConceptCardPreview';
+import { CodeDiffPreview } from '../code-diff/CodeDiffPreview';
 import { parseLineConfig } from '../code-presenter/focus';
+import { SourcePanel } from '../code-presenter/SourcePanel';
+import { useSessionDetailContext, useSourceStatus } from '../../context/SessionDetailContext';
 
 import { CodePresenterCore } from '../CodePresenterCore';
 import { IMEditorCore } from '../im/IMEditorCore';
@@ -25,6 +28,7 @@ import { StructureBreakdownEditorCore } from '../structure/StructureBreakdownEdi
 import { StatsEditorCore } from '../stats/StatsEditorCore';
 import { NumberedListEditorCore } from '../numbered-list/NumberedListEditorCore';
 import { ConceptCardEditorCore } from '../concept-card/ConceptCardEditorCore';
+import { CodeDiffEditorCore } from '../code-diff/CodeDiffEditorCore';
 import type { CodePresenterState } from '../CodePresenterEditorPreview';
 
 // --- Helpers ---
@@ -70,6 +74,45 @@ const validateJsonContent = (page: Page, expectedType: 'array' | 'object') => {
     }
 };
 
+// The server rejects a source link without a file or with a malformed line range
+const validateSource = (page: Page) => {
+    const source = (page.content as any)?.source;
+    if (!source) return null;
+    if (!source.path?.trim()) return "Source file is required.";
+    if (/^([\\/]|[A-Za-z]:)/.test(source.path) || source.path.split(/[\\/]/).includes('..')) return "Source file must be relative to the root, without ..";
+    if (source.lines && !/^\s*\d+\s*(-\s*\d+\s*)?$/.test(source.lines)) return "Source lines must be like 10-30.";
+    return null;
+};
+
+const CodeSourcePanel: React.FC<{ page: Page; onPageUpdate: (id: string, content: any) => void }> = ({ page, onPageUpdate }) => {
+    const { sources, refreshSource } = useSessionDetailContext();
+    return (
+        <SourcePanel
+            source={(page.content as any)?.source}
+            setSource={source => onPageUpdate(page.id, (prevContent: any) => ({ ...(prevContent || {}), source }))}
+            status={sources[page.id]}
+            onRefresh={() => refreshSource(page.id)}
+        />
+    );
+};
+
+// A page linked to a source file previews the code as it is in the file now, while its
+// content keeps the snapshot the exports use until the snapshot is refreshed
+const CodePreview: React.FC<{ page: Page }> = ({ page }) => {
+    const status = useSourceStatus(page.id);
+    const content = page.content as any || {};
+    const configList = content.configList || [];
+    const selectedConfig = configList.find((c: any) => c.id === content.selectedConfigId);
+    return (
+        <PreviewPanel
+            code={(content.source && status?.code) ?? content.code ?? ''}
+            language={content.language}
+            isFocusMode={!!content.selectedConfigId}
+            focusedLines={parseLineConfig(selectedConfig?.lines || '')}
+        />
+    );
+};
+
 // --- Page Definitions ---
 
 // Code Page
@@ -79,19 +122,8 @@ const CodePage: PageDefinition = {
     getPreviewTitle: () => 'Preview:',
     getPreviewStyle: () => ({ backgroundColor: '#1e1e1e' }),
     getExportDimensions: getStandardExportDimensions,
-    renderPreview: ({ page }) => {
-        const content = page.content as any || {};
-        const configList = content.configList || [];
-        const selectedConfig = configList.find((c: any) => c.id === content.selectedConfigId);
-        return (
-            <PreviewPanel
-                code={content.code || ''}
-                language={content.language}
-                isFocusMode={!!content.selectedConfigId}
-                focusedLines={parseLineConfig(selectedConfig?.lines || '')}
-            />
-        );
-    },
+    validateContent: validateSource,
+    renderPreview: ({ page }) => <CodePreview page={page} />,
     renderEditor: ({ page, onPageUpdate }) => {
         const codeState = (page.content || {}) as CodePresenterState;
         const updateCodeState = (partial: Partial<CodePresenterState>) => {
@@ -101,18 +133,21 @@ const CodePage: PageDefinition = {
             });
         };
         return (
-            <CodePresenterCore
-                code={codeState.code || ''}
-                setCode={code => updateCodeState({ code })}
-                language={codeState.language || 'go'}
-                setLanguage={lang => updateCodeState({ language: lang })}
-                configList={codeState.configList || []}
-                setConfigList={list => updateCodeState({ configList: list })}
-                selectedConfigId={codeState.selectedConfigId || null}
-                setSelectedConfigId={id => updateCodeState({ selectedConfigId: id })}
-                showHtml={codeState.showHtml || false}
-                setShowHtml={show => updateCodeState({ showHtml: show })}
-            />
+            <div style={{ display: 'flex', flexDirection: 'column', gap: '10px' }}>
+                <CodeSourcePanel page={page} onPageUpdate={onPageUpdate} />
+                <CodePresenterCore
+                    code={codeState.code || ''}
+                    setCode={code => updateCodeState({ code })}
+                    language={codeState.language || 'go'}
+                    setLanguage={lang => updateCodeState({ language: lang })}
+                    configList={codeState.configList || []}
+                    setConfigList={list => updateCodeState({ configList: list })}
+                    selectedConfigId={codeState.selectedConfigId || null}
+                    setSelectedConfigId={id => updateCodeState({ selectedConfigId: id })}
+                    showHtml={codeState.showHtml || false}
+                    setShowHtml={show => updateCodeState({ showHtml: show })}
+                />
+            </div>
         );
     }
 };
@@ -413,6 +448,29 @@ const ConceptCardPage: PageDefinition = {
     )
 };
 
+// Code Diff Page
+const CodeDiffPage: PageDefinition = {
+    kind: PageKind.CodeDiff,
+    label: 'Code Diff',
+    getPreviewTitle: () => 'Preview:',
+    getPreviewStyle: () => ({ backgroundColor: '#1e1e1e' }),
+    getExportDimensions: getStandardExportDimensions,
+    validateContent: (page) => {
+        const content = (page.content || {}) as CodeDiffContent;
+        if ((content.oldStart ?? 0) < 0 || (content.newStart ?? 0) < 0) return "First lines must not be negative.";
+        return null;
+    },
+    renderPreview: ({ page }) => (
+        <CodeDiffPreview pageId={page.id} content={(page.content || {}) as CodeDiffContent} />
+    ),
+    renderEditor: ({ page, onPageUpdate }) => (
+        <CodeDiffEditorCore
+            content={(page.content || {}) as CodeDiffContent}
+            update={partial => onPageUpdate(page.id, (prevContent: any) => ({ ...(prevContent || {}), ...partial }))}
+        />
+    )
+};
+
 // Register all
 function registerStandardPages() {
     pageRegistry.register(CodePage);
@@ -425,6 +483,7 @@ function registerStandardPages() {
     pageRegistry.register(StatsPage);
     pageRegistry.register(NumberedListPage);
     pageRegistry.register(ConceptCardPage);
+    pageRegistry.register(CodeDiffPage);
 }
 
 // Execute registration
@@ -0,0 +1,68 @@
+import React, { useState } from 'react';
+import { MdAdd, MdCheck, MdClose } from 'react-icons/md';
+import { type Workspace } from '../../api/session';
+
+interface WorkspaceSelectorProps {
+    workspaces: Workspace[];
+    currentWorkspace: string;
+    onSelectWorkspace: (name: string) => Promise<void>;
+    onCreateWorkspace: (name: string) => Promise<void>;
+}
+
+export const WorkspaceSelector: React.FC<WorkspaceSelectorProps> = ({
+    workspaces,
+    currentWorkspace,
+    onSelectWorkspace,
+    onCreateWorkspace,
+}) => {
+    const [creating, setCreating] = useState(false);
+    const [name, setName] = useState('');
+
+    const handleCreate = async () => {
+        const newName = name.trim();
+        if (!newName) return;
+        try {
+            await onCreateWorkspace(newName);
+            setCreating(false);
+            setName('');
+        } catch {
+            // Error shown by parent, keep the input open
+        }
+    };
+
+    if (creating) {
+        return (
+            <div style={{ padding: '8px 15px', borderBottom: '1px solid #ddd', display: 'flex', alignItems: 'center', gap: '4px' }}>
+                <input
+                    value={name}
+                    onChange={(e) => setName(e.target.value)}
+                    placeholder="Workspace Name"
+                    autoFocus
+                    onKeyDown={(e) => {
+                        if (e.key === 'Enter') handleCreate();
+                        if (e.key === 'Escape') setCreating(false);
+                    }}
+                    style={{ flex: 1, padding: '2px', minWidth: 0 }}
+                />
+                <button onClick={handleCreate} disabled={!name.trim()} style={{ color: 'green', border: 'none', background: 'none', cursor: 'pointer', padding: 0 }}><MdCheck /></button>
+                <button onClick={() => setCreating(false)} style={{ color: '#666', border: 'none', background: 'none', cursor: 'pointer', padding: 0 }}><MdClose /></button>
+            </div>
+        );
+    }
+
+    return (
+        <div style={{ padding: '8px 15px', borderBottom: '1px solid #ddd', display: 'flex', alignItems: 'center', gap: '4px' }}>
+            <select
+                value={currentWorkspace}
+                onChange={(e) => onSelectWorkspace(e.target.value).catch(() => { })}
+                title={workspaces.find(w => w.name === currentWorkspace)?.root}
+                style={{ flex: 1, padding: '2px', minWidth: 0 }}
+            >
+                {workspaces.map(w => (
+                    <option key={w.name} value={w.name}>{w.name}</option>
+                ))}
+            </select>
+            <button onClick={() => setCreating(true)} style={{ cursor: 'pointer', padding: '2px 6px' }} title="New Workspace"><MdAdd /></button>
+        </div>
+    );
+};
@@ -0,0 +1,118 @@
+import React, { useCallback, useEffect, useState } from 'react';
+import type { DiffHunk, Session } from '../../api/session';
+import { pageRegistry } from '../sessions/PageRegistry';
+import '../sessions/StandardPages';
+
+// The deck of a static HTML export, set by the exporter in
+// pkg/render/html before the app loads.
+export interface ExportedDeck {
+    session: Session;
+    // Avatar names to URLs, relative to the page or data URIs.
+    avatars: Record<string, string>;
+    // The hunks of the code_diff pages by page ID, which the editor gets
+    // from the server.
+    diffs?: Record<string, DiffHunk[]>;
+}
+
+declare global {
+    interface Window {
+        __PRESENTATIONER_EXPORT__?: ExportedDeck;
+    }
+}
+
+export function getExportedDeck(): ExportedDeck | undefined {
+    return window.__PRESENTATIONER_EXPORT__;
+}
+
+// The page index lives in the URL hash (#1 is the first page) so a reload
+// keeps the position, also when opened from file://.
+function readHashIndex(count: number): number {
+    const n = parseInt(window.location.hash.replace('#', ''), 10);
+    if (isNaN(n) || n < 1) return 0;
+    return Math.min(n, count) - 1;
+}
+
+export const DeckViewer: React.FC<{ deck: ExportedDeck }> = ({ deck }) => {
+    const pages = deck.session.pages || [];
+    const [index, setIndex] = useState(() => readHashIndex(pages.length));
+
+    const go = useCallback((i: number) => {
+        const next = Math.max(0, Math.min(pages.length - 1, i));
+        setIndex(next);
+        window.history.replaceState(null, '', `#${next + 1}`);
+    }, [pages.length]);
+
+    useEffect(() => {
+        document.title = deck.session.name;
+        const onHashChange = () => setIndex(readHashIndex(pages.length));
+        window.addEventListener('hashchange', onHashChange);
+        return () => window.removeEventListener('hashchange', onHashChange);
+    }, [deck.session.name, pages.length]);
+
+    useEffect(() => {
+        const onKeyDown = (e: KeyboardEvent) => {
+            switch (e.key) {
+                case 'ArrowRight':
+                case 'ArrowDown':
+                case 'PageDown':
+                case ' ':
+                    go(index + 1);
+                    break;
+                case 'ArrowLeft':
+                case 'ArrowUp':
+                case 'PageUp':
+                    go(index - 1);
+                    break;
+                case 'Home':
+                    go(0);
+                    break;
+                case 'End':
+                    go(pages.length - 1);
+                    break;
+                default:
+                    return;
+            }
+            e.preventDefault();
+        };
+        window.addEventListener('keydown', onKeyDown);
+        return () => window.removeEventListener('keydown', onKeyDown);
+    }, [go, index, pages.length]);
+
+    const page = pages[index];
+    const pageDef = page ? pageRegistry.get(page.kind) : undefined;
+
+    return (
+        <div style={{ display: 'flex', flexDirection: 'column', height: '100vh', backgroundColor: '#f9f9f9' }}>
+            <div style={{ padding: '10px 20px', borderBottom: '1px solid #eee', display: 'flex', justifyContent: 'space-between', alignItems: 'center', backgroundColor: 'white' }}>
+                <div style={{ display: 'flex', alignItems: 'center', gap: '10px' }}>
+                    <strong>{deck.session.name}</strong>
+                    {page && (
+                        <>
+                            <span style={{ color: '#ccc' }}>/</span>
+                            <span>{page.title}</span>
+                        </>
+                    )}
+                </div>
+                <div style={{ display: 'flex', alignItems: 'center', gap: '10px' }}>
+                    <button onClick={() => go(index - 1)} disabled={index <= 0} style={{ cursor: 'pointer', padding: '2px 8px' }}>&larr;</button>
+                    <span style={{ color: '#666' }}>{pages.length > 0 ? `${index + 1} / ${pages.length}` : '0 / 0'}</span>
+                    <button onClick={() => go(index + 1)} disabled={index >= pages.length - 1} style={{ cursor: 'pointer', padding: '2px 8px' }}>&rarr;</button>
+                </div>
+            </div>
+            <div style={{ flex: 1, overflow: 'auto', padding: '20px', display: 'flex' }}>
+                {page && pageDef ? (
+                    <div key={page.id} style={{ margin: '0 auto', width: 'fit-content', maxWidth: '100%', ...pageDef.getPreviewStyle(page) }}>
+                        {pageDef.renderPreview({
+                            page,
+                            resolveAvatarUrl: (name) => deck.avatars[name] || '',
+                        })}
+                    </div>
+                ) : (
+                    <div style={{ margin: 'auto', color: '#888' }}>
+                        {page ? `Unknown page kind ${page.kind}` : 'This session has no pages'}
+                    </div>
+                )}
+            </div>
+        </div>
+    );
+};
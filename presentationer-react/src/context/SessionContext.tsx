@@ -1,5 +1,5 @@
 import React, { createContext, useContext, useState, useEffect, useCallback } from 'react';
-import { listSessions, createSession, deleteSession, renameSession, type Session, type Page } from '../api/session';
+import { listSessions, createSession, deleteSession, renameSession, duplicateSession, listWorkspaces, selectWorkspace, createWorkspace, getTabWorkspace, setTabWorkspace, importSession, ApiError, type Session, type Page, type Workspace } from '../api/session';
 import toast from 'react-hot-toast';
 import { useNavigate } from 'react-router-dom';
 
@@ -9,6 +9,12 @@ interface SessionContextType {
     createSession: (name: string) => Promise<void>;
     deleteSession: (name: string) => Promise<void>;
     renameSession: (oldName: string, newName: string) => Promise<void>;
+    duplicateSession: (name: string) => Promise<void>;
+    importSession: (file: File) => Promise<void>;
+    workspaces: Workspace[];
+    currentWorkspace: string;
+    selectWorkspace: (name: string) => Promise<void>;
+    createWorkspace: (name: string) => Promise<void>;
 }
 
 const SessionContext = createContext<SessionContextType | null>(null);
@@ -21,6 +27,8 @@ export const useSessionContext = () => {
 
 export const SessionProvider: React.FC<{ children: React.ReactNode }> = ({ children }) => {
     const [sessions, setSessions] = useState<Session[]>([]);
+    const [workspaces, setWorkspaces] = useState<Workspace[]>([]);
+    const [currentWorkspace, setCurrentWorkspace] = useState(getTabWorkspace());
     const navigate = useNavigate();
 
     const refreshSessions = useCallback(async () => {
@@ -37,6 +45,47 @@ export const SessionProvider: React.FC<{ children: React.ReactNode }> = ({ child
         refreshSessions();
     }, [refreshSessions]);
 
+    useEffect(() => {
+        listWorkspaces()
+            .then(data => {
+                setWorkspaces(data.workspaces);
+                // the workspace this tab was in may be gone from the config
+                if (!data.workspaces.some(w => w.name === getTabWorkspace())) {
+                    setTabWorkspace(data.current);
+                    refreshSessions();
+                }
+                setCurrentWorkspace(getTabWorkspace());
+            })
+            .catch(error => console.error(error));
+    }, [refreshSessions]);
+
+    // Switches this tab only; the server remembers the choice for new tabs.
+    const handleSelectWorkspace = async (name: string) => {
+        try {
+            const data = await selectWorkspace(name);
+            setWorkspaces(data.workspaces);
+            setTabWorkspace(name);
+            setCurrentWorkspace(name);
+            await refreshSessions();
+            navigate('/sessions');
+        } catch (error: any) {
+            toast.error(error.message || 'Failed to switch workspace');
+            throw error;
+        }
+    };
+
+    const handleCreateWorkspace = async (name: string) => {
+        try {
+            const data = await createWorkspace(name);
+            setWorkspaces(data.workspaces);
+            toast.success('Workspace created');
+        } catch (error: any) {
+            toast.error(error.message || 'Failed to create workspace');
+            throw error;
+        }
+        await handleSelectWorkspace(name);
+    };
+
     const handleCreateSession = async (name: string) => {
         try {
             const initialPages: Page[] = [
@@ -79,13 +128,56 @@ export const SessionProvider: React.FC<{ children: React.ReactNode }> = ({ child
         }
     };
 
+    const handleDuplicateSession = async (name: string) => {
+        let newName = `${name} Copy`;
+        let counter = 1;
+        while (sessions.some(s => s.name === newName)) {
+            counter++;
+            newName = `${name} Copy ${counter}`;
+        }
+        try {
+            await duplicateSession(name, newName);
+            toast.success('Duplicated');
+            await refreshSessions();
+            navigate(`/sessions/${newName}`);
+        } catch (error: any) {
+            toast.error(error.message || 'Failed to duplicate');
+            throw error;
+        }
+    };
+
+    const handleImportSession = async (file: File) => {
+        try {
+            let name: string;
+            try {
+                name = await importSession(file);
+            } catch (error) {
+                if (!(error instanceof ApiError) || error.code !== 'conflict') throw error;
+                if (!window.confirm(`${error.message}. Import it under a new name?`)) return;
+                name = await importSession(file, 'rename');
+            }
+            toast.success(`Imported ${name}`);
+            await refreshSessions();
+            navigate(`/sessions/${name}`);
+        } catch (error: any) {
+            toast.error(error.message || 'Failed to import');
+            throw error;
+        }
+    };
+
     return (
         <SessionContext.Provider value={{
             sessions,
             refreshSessions,
             createSession: handleCreateSession,
             deleteSession: handleDeleteSession,
-            renameSession: handleRenameSession
+            renameSession: handleRenameSession,
+            duplicateSession: handleDuplicateSession,
+            importSession: handleImportSession,
+            workspaces,
+            currentWorkspace,
+            selectWorkspace: handleSelectWorkspace,
+            createWorkspace: handleCreateWorkspace
         }}>
             {children}
         </SessionContext.Provider>
@@ -1,11 +1,14 @@
 import React, { createContext, useContext, useState, useEffect, useCallback, useRef } from 'react';
-import { getSession, updateSession, createPage as createPageApi, deletePage as deletePageApi, updatePage as updatePageApi, type Page, PageKind } from '../api/session';
+import { getSession, updateSession, createPage as createPageApi, deletePage as deletePageApi, updatePage as updatePageApi, movePage as movePageApi, duplicatePage as duplicatePageApi, restoreRevision as restoreRevisionApi, refreshSources, getSourceWatchUrl, type Page, type PageSourceStatus, type SourceStatus, PageKind, ConflictError } from '../api/session';
 import toast from 'react-hot-toast';
 import { useNavigate } from 'react-router-dom';
+import { pageRegistry } from '../components/sessions/PageRegistry';
 
 interface SessionDetailContextType {
     sessionName: string;
     pages: Page[];
+    // drift of the code pages linked to source files, by page ID
+    sources: Record<string, SourceStatus>;
     loading: boolean;
     refreshPages: () => Promise<void>;
     saveSession: () => Promise<void>;
@@ -14,6 +17,9 @@ interface SessionDetailContextType {
     deletePage: (pageId: string) => Promise<void>;
     renamePage: (pageId: string, newTitle: string) => Promise<void>;
     duplicatePage: (pageId: string) => Promise<void>;
+    movePage: (pageId: string, newIndex: number) => Promise<void>;
+    restoreRevision: (revision: number) => Promise<void>;
+    refreshSource: (pageId: string) => Promise<void>;
 }
 
 const SessionDetailContext = createContext<SessionDetailContextType | null>(null);
@@ -24,8 +30,15 @@ export const useSessionDetailContext = () => {
     return context;
 };
 
+// The drift and current code of a page linked to a source file, when
+// shown within a session.
+export const useSourceStatus = (pageId: string): SourceStatus | undefined => {
+    return useContext(SessionDetailContext)?.sources[pageId];
+};
+
 export const SessionDetailProvider: React.FC<{ sessionName: string; children: React.ReactNode }> = ({ sessionName, children }) => {
     const [pages, setPages] = useState<Page[]>([]);
+    const [sources, setSources] = useState<Record<string, SourceStatus>>({});
     const [loading, setLoading] = useState(false);
     const navigate = useNavigate();
 
@@ -35,11 +48,34 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
     // Debounce ref
     const debouncedSaveRef = useRef<{ [key: string]: ReturnType<typeof setTimeout> }>({});
 
+    // Revision of the session as last loaded or written by this tab
+    const revisionRef = useRef<number | undefined>(undefined);
+    const writeQueueRef = useRef<Promise<unknown>>(Promise.resolve());
+
+    // Writes run one at a time so each sends the revision produced by the one before it
+    const write = useCallback((fn: (revision?: number) => Promise<number | undefined>): Promise<void> => {
+        const next = writeQueueRef.current.catch(() => { }).then(async () => {
+            try {
+                const revision = await fn(revisionRef.current);
+                if (revision !== undefined) revisionRef.current = revision;
+            } catch (err) {
+                if (err instanceof ConflictError) {
+                    toast.error(err.message);
+                }
+                throw err;
+            }
+        });
+        writeQueueRef.current = next;
+        return next;
+    }, []);
+
     const refreshPages = useCallback(async () => {
         if (!sessionName) return;
         setLoading(true);
         try {
             const session = await getSession(sessionName);
+            revisionRef.current = session.revision;
+            setSources(session.sources || {});
             let loadedPages = session.pages || [];
             if (loadedPages.length === 0 && session.pages === undefined) {
                 loadedPages = [
@@ -64,6 +100,29 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
         refreshPages();
     }, [refreshPages]);
 
+    // Code pages linked to source files follow the files as they change. The server watches
+    // the links saved, so reconnect a moment after they are edited.
+    const sourceLinks = pages
+        .filter(p => p.kind === PageKind.Code && p.content?.source?.path)
+        .map(p => JSON.stringify([p.id, p.content.source]))
+        .join('\n');
+    useEffect(() => {
+        if (!sessionName || !sourceLinks) return;
+        let events: EventSource | undefined;
+        const timer = setTimeout(() => {
+            events = new EventSource(getSourceWatchUrl(sessionName));
+            events.addEventListener('source', (e) => {
+                const statuses: PageSourceStatus[] = JSON.parse((e as MessageEvent).data);
+                // only the status changes: the pages keep their snapshots until a refresh
+                setSources(Object.fromEntries(statuses.map(st => [st.id, st])));
+            });
+        }, 1500);
+        return () => {
+            clearTimeout(timer);
+            events?.close();
+        };
+    }, [sessionName, sourceLinks]);
+
     const updatePageContent = useCallback((pageId: string, content: any) => {
         setPages(prev => {
             const idx = prev.findIndex(p => p.id === pageId);
@@ -73,12 +132,17 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
             const newPage = { ...newPages[idx], content: resolvedContent };
             newPages[idx] = newPage;
 
+            // The server rejects malformed content, so hold off saving until the editor JSON parses
+            if (pageRegistry.get(newPage.kind)?.validateContent?.(newPage)) {
+                return newPages;
+            }
+
             // Trigger auto-save
             if (debouncedSaveRef.current[pageId]) {
                 clearTimeout(debouncedSaveRef.current[pageId]);
             }
             debouncedSaveRef.current[pageId] = setTimeout(() => {
-                updatePageApi(sessionName, newPage)
+                write(revision => updatePageApi(sessionName, newPage, revision))
                     .then(() => {
                         console.log("Auto-saved page", pageId);
                     })
@@ -90,7 +154,7 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
 
             return newPages;
         });
-    }, [sessionName]);
+    }, [sessionName, write]);
 
     const saveSession = useCallback(async () => {
         // Clear any pending auto-saves for current pages to avoid race conditions?
@@ -101,13 +165,13 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
         debouncedSaveRef.current = {};
 
         try {
-            await updateSession(sessionName, pagesRef.current);
+            await write(revision => updateSession(sessionName, pagesRef.current, revision));
             toast.success("Saved");
             refreshPages();
         } catch (error: any) {
             toast.error(error.message || "Failed to save");
         }
-    }, [sessionName, refreshPages]);
+    }, [sessionName, refreshPages, write]);
 
     const createPage = async (title: string, kind: PageKind) => {
         if (pages.some(p => p.title === title)) {
@@ -119,7 +183,7 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
             id: Date.now().toString(),
             title: title,
             kind: kind,
-            content: (kind === PageKind.Code || kind === PageKind.Chart || kind === PageKind.Rectangle || kind === PageKind.ConnectedRectangles) ? {} : undefined
+            content: (kind === PageKind.Code || kind === PageKind.CodeDiff || kind === PageKind.Chart || kind === PageKind.Rectangle || kind === PageKind.ConnectedRectangles) ? {} : undefined
         };
 
         const updatedPages = [...pages, newPage];
@@ -128,7 +192,7 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
         navigate(`/sessions/${sessionName}/pages/${encodeURIComponent(newPage.title)}`);
 
         try {
-            await createPageApi(sessionName, newPage);
+            await write(revision => createPageApi(sessionName, newPage, revision));
             toast.success("Page added");
         } catch (e) {
             toast.error("Failed to create page");
@@ -141,7 +205,7 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
         setPages(updatedPages);
 
         try {
-            await deletePageApi(sessionName, pageId);
+            await write(revision => deletePageApi(sessionName, pageId, revision));
             toast.success("Page deleted");
         } catch (e) {
             toast.error("Failed to delete page");
@@ -162,7 +226,7 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
         setPages(prev => prev.map(p => p.id === pageId ? updatedPage : p));
 
         try {
-            await updatePageApi(sessionName, updatedPage);
+            await write(revision => updatePageApi(sessionName, updatedPage, revision));
             toast.success("Renamed");
         } catch (e) {
             toast.error("Failed to rename");
@@ -172,46 +236,69 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
     };
 
     const duplicatePage = async (pageId: string) => {
-        const page = pages.find(p => p.id === pageId);
-        if (!page) return;
-
-        let newTitle = `${page.title} Copy`;
-        let counter = 1;
-        // Use pagesRef.current to get latest pages if closure is stale, but setPages updates state.
-        // We should use current pages.
-        const currentPages = pagesRef.current;
-        while (currentPages.some(p => p.title === newTitle)) {
-            counter++;
-            newTitle = `${page.title} Copy ${counter}`;
+        try {
+            let newPage: Page | undefined;
+            await write(async revision => {
+                const res = await duplicatePageApi(sessionName, pageId, revision);
+                newPage = res.page;
+                return res.revision;
+            });
+            await refreshPages();
+            if (newPage) {
+                navigate(`/sessions/${sessionName}/pages/${encodeURIComponent(newPage.title)}`);
+            }
+            toast.success("Page duplicated");
+        } catch (e) {
+            toast.error("Failed to duplicate page");
+            refreshPages();
         }
+    };
 
-        const newContent = page.content ? JSON.parse(JSON.stringify(page.content)) : undefined;
-
-        const newPage: Page = {
-            id: Date.now().toString(),
-            title: newTitle,
-            kind: page.kind,
-            content: newContent
-        };
+    const movePage = async (pageId: string, newIndex: number) => {
+        const currentPages = pagesRef.current;
+        const idx = currentPages.findIndex(p => p.id === pageId);
+        if (idx === -1 || idx === newIndex) return;
 
-        const updatedPages = [...currentPages, newPage];
+        const updatedPages = [...currentPages];
+        const [moved] = updatedPages.splice(idx, 1);
+        updatedPages.splice(newIndex, 0, moved);
         setPages(updatedPages);
 
-        navigate(`/sessions/${sessionName}/pages/${encodeURIComponent(newPage.title)}`);
-
         try {
-            await createPageApi(sessionName, newPage);
-            toast.success("Page duplicated");
+            await write(revision => movePageApi(sessionName, pageId, newIndex, revision));
         } catch (e) {
-            toast.error("Failed to duplicate page");
+            toast.error("Failed to move page");
             refreshPages();
         }
     };
 
+    const restoreRevision = async (revision: number) => {
+        Object.values(debouncedSaveRef.current).forEach(clearTimeout);
+        debouncedSaveRef.current = {};
+        try {
+            await write(current => restoreRevisionApi(sessionName, revision, current));
+            toast.success(`Restored revision ${revision}`);
+        } catch (e) {
+            toast.error("Failed to restore revision");
+        }
+        await refreshPages();
+    };
+
+    const refreshSource = async (pageId: string) => {
+        try {
+            await write(async revision => (await refreshSources(sessionName, pageId, revision)).revision);
+            toast.success("Snapshot updated");
+        } catch (e) {
+            toast.error("Failed to update the snapshot");
+        }
+        await refreshPages();
+    };
+
     return (
         <SessionDetailContext.Provider value={{
             sessionName,
             pages,
+            sources,
             loading,
             refreshPages,
             saveSession,
@@ -219,7 +306,10 @@ export const SessionDetailProvider: React.FC<{ sessionName: string; children: Re
             createPage,
             deletePage,
             renamePage,
-            duplicatePage
+            duplicatePage,
+            movePage,
+            restoreRevision,
+            refreshSource
         }}>
             {children}
         </SessionDetailContext.Provider>
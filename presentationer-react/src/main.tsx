@@ -2,9 +2,13 @@ import { StrictMode } from 'react'
 import { createRoot } from 'react-dom/client'
 import './index.css'
 import App from './App.tsx'
+import { DeckViewer, getExportedDeck } from './components/viewer/DeckViewer'
+
+// A static HTML export carries its deck and shows only the viewer
+const exportedDeck = getExportedDeck()
 
 createRoot(document.getElementById('root')!).render(
   <StrictMode>
-    <App />
+    {exportedDeck ? <DeckViewer deck={exportedDeck} /> : <App />}
   </StrictMode>,
 )
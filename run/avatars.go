@@ -0,0 +1,135 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const avatarHelp = `
+Usage: presentationer avatar <command> [OPTIONS]
+
+Commands:
+  add SESSION FILE   add an image as an avatar, named after the file
+                     unless --name is given
+  ls SESSION         list the avatars of a session
+  rm SESSION NAME    delete an avatar
+`
+
+func runAvatar(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Print(avatarHelp[1:])
+		return nil
+	}
+	switch args[0] {
+	case "add":
+		return runAvatarAdd(args[1:])
+	case "ls":
+		return runAvatarLs(args[1:])
+	case "rm":
+		return runAvatarRm(args[1:])
+	default:
+		return fmt.Errorf("unknown avatar command: %s", args[0])
+	}
+}
+
+const avatarAddHelp = `
+Usage: presentationer avatar add SESSION FILE [--name NAME] [OPTIONS]
+
+Add an image as an avatar, replacing one of the same name.
+
+Options:
+  --name NAME   avatar name, default the base name of FILE
+`
+
+func runAvatarAdd(args []string) error {
+	var opts cliOptions
+	var name string
+	args, err := opts.parse(flags.String("--name", &name), avatarAddHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 2, "presentationer avatar add SESSION FILE"); err != nil {
+		return err
+	}
+	if name == "" {
+		name = filepath.Base(args[1])
+	}
+	data, err := readInput(args[1])
+	if err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	if err := s.SaveAvatar(context.Background(), args[0], name, data); err != nil {
+		return err
+	}
+	return opts.output(map[string]string{"name": name}, func(w io.Writer) {
+		fmt.Fprintf(w, "added avatar %s to session %s\n", name, args[0])
+	})
+}
+
+const avatarLsHelp = `
+Usage: presentationer avatar ls SESSION [OPTIONS]
+
+List the avatars of a session.
+`
+
+func runAvatarLs(args []string) error {
+	var opts cliOptions
+	args, err := opts.parse(flags.New(), avatarLsHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 1, "presentationer avatar ls SESSION"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	avatars, err := s.ListAvatars(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	if avatars == nil {
+		avatars = []string{}
+	}
+	return opts.output(avatars, func(w io.Writer) {
+		for _, name := range avatars {
+			fmt.Fprintln(w, name)
+		}
+	})
+}
+
+const avatarRmHelp = `
+Usage: presentationer avatar rm SESSION NAME [OPTIONS]
+
+Delete an avatar.
+`
+
+func runAvatarRm(args []string) error {
+	var opts cliOptions
+	args, err := opts.parse(flags.New(), avatarRmHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 2, "presentationer avatar rm SESSION NAME"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	if err := s.DeleteAvatar(context.Background(), args[0], args[1]); err != nil {
+		return err
+	}
+	return opts.output(map[string]string{"deleted": args[1]}, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted avatar %s from session %s\n", args[1], args[0])
+	})
+}
@@ -0,0 +1,108 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/presentationer/pkg/config"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/workspace"
+)
+
+const storeOptionsHelp = `
+Store options:
+  --root DIR          directory of the default workspace
+  --store STORE       file, file:DIR or sqlite:PATH, see presentationer --help
+  --git               commit every change, file store only
+  --workspace NAME    use a workspace of config.json instead of the default
+  --json              print results as JSON
+`
+
+// cliOptions are the flags shared by the session management subcommands.
+type cliOptions struct {
+	root      string
+	store     string
+	git       bool
+	workspace string
+	json      bool
+}
+
+// parse registers the shared flags on b and parses args with it.
+func (o *cliOptions) parse(b *flags.Builder, help string, args []string) ([]string, error) {
+	return b.String("--root", &o.root).
+		String("--store", &o.store).
+		Bool("--git", &o.git).
+		String("--workspace", &o.workspace).
+		Bool("--json", &o.json).
+		Help("-h,--help", help+storeOptionsHelp).
+		Parse(args)
+}
+
+// open opens the store the options point to. Like the server, it uses the
+// workspace selected last unless a flag names one.
+func (o *cliOptions) open() (store.SessionStore, error) {
+	home, cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	def, err := defaultWorkspace(home, cfg, o.root, o.store, o.git)
+	if err != nil {
+		return nil, err
+	}
+	name := o.workspace
+	if name == "" && o.root == "" && o.store == "" && !o.git {
+		name = cfg.Workspace
+	}
+	if name == "" || name == workspace.DefaultName {
+		return def.Open()
+	}
+	for _, ws := range cfg.Workspaces {
+		if ws.Name != name {
+			continue
+		}
+		if ws.Root, err = config.ResolvePath(home, ws.Root); err != nil {
+			return nil, err
+		}
+		return ws.Open()
+	}
+	return nil, store.Errorf(store.ErrNotFound, "workspace %s not found", name)
+}
+
+// output prints v as indented JSON with --json, or calls text otherwise.
+func (o *cliOptions) output(v interface{}, text func(w io.Writer)) error {
+	if !o.json {
+		if text != nil {
+			text(os.Stdout)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Printf("%s\n", data)
+	return err
+}
+
+// needArgs checks that exactly n positional arguments were given.
+func needArgs(args []string, n int, usage string) error {
+	if len(args) < n {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	if len(args) > n {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[n:], " "))
+	}
+	return nil
+}
+
+// readInput reads a file, or stdin when path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
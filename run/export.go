@@ -0,0 +1,270 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	mdimport "github.com/xhd2015/presentationer/pkg/importer/markdown"
+	"github.com/xhd2015/presentationer/pkg/importer/patch"
+	"github.com/xhd2015/presentationer/pkg/render"
+	"github.com/xhd2015/presentationer/pkg/render/html"
+	"github.com/xhd2015/presentationer/pkg/render/markdown"
+	"github.com/xhd2015/presentationer/pkg/render/pdf"
+	"github.com/xhd2015/presentationer/pkg/render/pptx"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/server"
+)
+
+const exportHelp = `
+Usage: presentationer export FORMAT SESSION [-o OUT] [OPTIONS]
+
+Export a session.
+
+Formats:
+  html   a static deck, navigable with the arrow keys and openable from
+         file://. OUT is a directory, default SESSION; with --single-file
+         it is one HTML file, default SESSION.html, with the avatars
+         inlined.
+  md     a Markdown document. OUT is a file, default SESSION.md; -
+         writes stdout.
+  pptx   a PowerPoint deck, one slide per page. OUT is a file, default
+         SESSION.pptx; - writes stdout.
+  pdf    a PDF document, one slide-sized page per page, or with
+         --handout N pages on each A4 sheet. OUT is a file, default
+         SESSION.pdf; - writes stdout.
+  bundle a .presentationer zip bundle to move the session to another
+         machine with presentationer import. OUT is a file, default
+         SESSION.presentationer; - writes stdout.
+
+Options:
+  -o,--output OUT   where to write the export
+  --single-file     write the html export as one file
+  --handout N       put N pages on each sheet of the pdf export
+  --notes           add lines for notes beside the pages of a handout
+  --font FILE       a TrueType font for the pdf export to show text the
+                    built-in fonts cannot, such as CJK
+`
+
+func runExport(args []string) error {
+	var opts cliOptions
+	var output string
+	var singleFile bool
+	var pdfOpts pdf.Options
+	b := flags.String("-o,--output", &output).
+		Bool("--single-file", &singleFile).
+		Int("--handout", &pdfOpts.Handout).
+		Bool("--notes", &pdfOpts.Notes).
+		String("--font", &pdfOpts.Font)
+	args, err := opts.parse(b, exportHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 2, "presentationer export FORMAT SESSION"); err != nil {
+		return err
+	}
+	format, sessionName := args[0], args[1]
+
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch format {
+	case "html":
+		dist, err := server.DistFS()
+		if err != nil {
+			return err
+		}
+		deck, err := render.LoadDeck(ctx, s, sessionName)
+		if err != nil {
+			return err
+		}
+		if !singleFile {
+			if output == "" {
+				output = sessionName
+			}
+			err = html.WriteDir(output, dist, deck)
+		} else {
+			if output == "" {
+				output = sessionName + ".html"
+			}
+			err = writeOutput(output, func(w io.Writer) error {
+				return html.WriteFile(w, dist, deck)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	case "md":
+		deck, err := render.LoadDeck(ctx, s, sessionName)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			output = sessionName + ".md"
+		}
+		err = writeOutput(output, func(w io.Writer) error {
+			return markdown.Write(w, deck)
+		})
+		if err != nil {
+			return err
+		}
+	case "pptx":
+		deck, err := render.LoadDeck(ctx, s, sessionName)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			output = sessionName + ".pptx"
+		}
+		err = writeOutput(output, func(w io.Writer) error {
+			return pptx.Write(w, deck)
+		})
+		if err != nil {
+			return err
+		}
+	case "pdf":
+		if err := pdfOpts.Validate(); err != nil {
+			return err
+		}
+		deck, err := render.LoadDeck(ctx, s, sessionName)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			output = sessionName + ".pdf"
+		}
+		err = writeOutput(output, func(w io.Writer) error {
+			return pdf.Write(w, deck, pdfOpts)
+		})
+		if err != nil {
+			return err
+		}
+	case "bundle":
+		if output == "" {
+			output = sessionName + store.BundleExt
+		}
+		err := writeOutput(output, func(w io.Writer) error {
+			return store.Export(ctx, s, sessionName, w)
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+	if output == "-" {
+		// stdout carries the export itself
+		return nil
+	}
+	return opts.output(map[string]string{"output": output}, func(w io.Writer) {
+		fmt.Fprintf(w, "exported session %s to %s\n", sessionName, output)
+	})
+}
+
+// writeOutput creates the file path, or uses stdout for "-", and lets
+// write fill it.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+const importHelp = `
+Usage: presentationer import [FORMAT] FILE [--name NAME] [--on-conflict MODE] [OPTIONS]
+
+Import a session. To read stdin, end the options with -- and pass - as FILE.
+
+Formats:
+  bundle   a .presentationer bundle made by presentationer export bundle,
+           the default
+  md       a Markdown outline: each # or ## heading starts a page, and
+           code blocks, ordered lists, tables and quoted chats become
+           code, numbered list, chart or stats and chat pages
+  diff     a unified diff or git diff output: a code_diff page per hunk
+  git      commits of a local repository, see presentationer import git
+           --help
+
+Options:
+  --name NAME          session name, default the one in the bundle, or the
+                       document title or file name for Markdown and
+                       diffs
+  --on-conflict MODE   when the session exists: fail (default), rename to
+                       a free name, or overwrite its pages and avatars
+`
+
+func runImport(args []string) error {
+	if len(args) > 0 && args[0] == "git" {
+		return runImportGit(args[1:])
+	}
+	var opts cliOptions
+	var name string
+	var onConflictFlag string
+	b := flags.String("--name", &name).
+		String("--on-conflict", &onConflictFlag)
+	args, err := opts.parse(b, importHelp, args)
+	if err != nil {
+		return err
+	}
+	format := "bundle"
+	if len(args) > 1 {
+		format, args = args[0], args[1:]
+	}
+	if err := needArgs(args, 1, "presentationer import [FORMAT] FILE"); err != nil {
+		return err
+	}
+	onConflict, err := store.ParseOnConflict(onConflictFlag)
+	if err != nil {
+		return err
+	}
+	file := args[0]
+	if format != "bundle" && format != "md" && format != "diff" {
+		return fmt.Errorf("unsupported import format: %s, expect bundle, md or diff", format)
+	}
+	data, err := readInput(file)
+	if err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	defaultName := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if file == "-" {
+		defaultName = ""
+	}
+	var imported string
+	switch format {
+	case "md":
+		imported, err = mdimport.Import(context.Background(), s, data, name, defaultName, onConflict)
+	case "diff":
+		if defaultName == "" {
+			defaultName = "diff"
+		}
+		imported, err = patch.Import(context.Background(), s, data, name, defaultName, onConflict)
+	default:
+		imported, err = store.Import(context.Background(), s, bytes.NewReader(data), name, onConflict)
+	}
+	if err != nil {
+		return err
+	}
+	return opts.output(map[string]string{"name": imported}, func(w io.Writer) {
+		fmt.Fprintf(w, "imported session %s\n", imported)
+	})
+}
@@ -0,0 +1,113 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/presentationer/pkg/config"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/source/goextract"
+)
+
+const extractHelp = `
+Usage: presentationer extract NAME [OPTIONS]
+
+Print the declaration of a Go function, method, type, const or var from a
+module, with its doc comment. NAME is qualified by the package, as its
+name, its directory in the module or its import path:
+
+  presentationer extract server.Serve
+  presentationer extract pkg/store.SessionStore
+  presentationer extract model.Page.Validate --elide
+
+With --add, the declaration becomes a code page of a session linked to
+its source file, so presentationer refresh keeps it up to date.
+
+Options:
+  --dir DIR         module directory, default the working directory
+  --elide           replace the bodies of nested functions with ...
+  --focus LINES     focus config with the lines of the file, like 120-124,
+                    rewritten to the lines of the snippet
+  --add SESSION     add the declaration as a code page to SESSION
+  --title TITLE     title of the page, default NAME
+`
+
+func runExtract(args []string) error {
+	var opts cliOptions
+	var dir, focus, sessionName, title string
+	var elide bool
+	b := flags.String("--dir", &dir).
+		Bool("--elide", &elide).
+		String("--focus", &focus).
+		String("--add", &sessionName).
+		String("--title", &title)
+	args, err := opts.parse(b, extractHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 1, "presentationer extract NAME"); err != nil {
+		return err
+	}
+	dir, err = config.ExpandHome(dir)
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		dir = "."
+	}
+	snippet, err := goextract.Extract(dir, args[0], goextract.Options{Elide: elide})
+	if err != nil {
+		return err
+	}
+	if focus != "" {
+		focus = snippet.Focus(focus)
+	}
+	if sessionName == "" {
+		return opts.output(snippet, func(w io.Writer) {
+			fmt.Fprintf(w, "// %s:%d-%d\n%s\n", snippet.File, snippet.Start, snippet.End, snippet.Code)
+		})
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	code := &model.CodeContent{
+		Code:     snippet.Code,
+		Language: "go",
+		Source: &model.SourceRef{
+			Root:   root,
+			Path:   snippet.File,
+			Symbol: snippet.Symbol,
+			Elide:  elide,
+			Line:   snippet.Start,
+		},
+	}
+	if focus != "" {
+		code.ConfigList = []model.FocusConfig{{ID: "1", Name: "Focus", Lines: focus}}
+		code.SelectedConfigID = "1"
+	}
+	if title == "" {
+		title = args[0]
+	}
+	page := &model.Page{ID: model.NewPageID(), Title: title}
+	if err := page.EncodeContent(code); err != nil {
+		return err
+	}
+	if err := page.Validate(); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	if err := s.CreatePage(context.Background(), sessionName, page); err != nil {
+		return err
+	}
+	return opts.output(page, func(w io.Writer) {
+		fmt.Fprintf(w, "added page %s %q to session %s\n", page.ID, page.Title, sessionName)
+	})
+}
@@ -0,0 +1,83 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/presentationer/pkg/config"
+	"github.com/xhd2015/presentationer/pkg/importer/gitlog"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+const importGitHelp = `
+Usage: presentationer import git --range RANGE [--repo DIR] [--paths PATH]... [OPTIONS]
+
+Import a walkthrough of commits of a local git repository, oldest first.
+Each commit gets a chat thread page with its author and message, then a
+page per file it changed, titled by the commit subject: the changes for
+a changed file, the code for an added one. Binary files and files over
+1MB are left out.
+
+  presentationer import git --range v1.2..main --paths server/
+
+Options:
+  --range RANGE        revision range like A..B, or a single commit
+  --repo DIR           directory in the repository, default the working
+                       directory
+  --paths PATH         only commits and files under PATH, relative to
+                       --repo; repeat for more
+  --name NAME          session name, default the repository directory name
+  --on-conflict MODE   when the session exists: fail (default), rename to
+                       a free name, or overwrite its pages and avatars
+`
+
+func runImportGit(args []string) error {
+	var opts cliOptions
+	var gitOpts gitlog.Options
+	var name string
+	var onConflictFlag string
+	b := flags.String("--range", &gitOpts.Range).
+		String("--repo", &gitOpts.Repo).
+		StringSlice("--paths", &gitOpts.Paths).
+		String("--name", &name).
+		String("--on-conflict", &onConflictFlag)
+	args, err := opts.parse(b, importGitHelp, args)
+	if err != nil {
+		return err
+	}
+	if gitOpts.Range == "" {
+		return fmt.Errorf("usage: presentationer import git --range RANGE")
+	}
+	if err := needArgs(args, 0, "presentationer import git --range RANGE"); err != nil {
+		return err
+	}
+	onConflict, err := store.ParseOnConflict(onConflictFlag)
+	if err != nil {
+		return err
+	}
+	gitOpts.Repo, err = config.ExpandHome(gitOpts.Repo)
+	if err != nil {
+		return err
+	}
+	if gitOpts.Repo == "" {
+		gitOpts.Repo = "."
+	}
+	repo, err := filepath.Abs(gitOpts.Repo)
+	if err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	imported, err := gitlog.Import(context.Background(), s, gitOpts, name, filepath.Base(repo), onConflict)
+	if err != nil {
+		return err
+	}
+	return opts.output(map[string]string{"name": imported}, func(w io.Writer) {
+		fmt.Fprintf(w, "imported session %s\n", imported)
+	})
+}
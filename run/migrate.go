@@ -0,0 +1,81 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+const migrateHelp = `
+Usage: presentationer migrate [--root DIR] [--from STORE] --to STORE
+
+Copy every session, with its pages and avatars, from one store to
+another. Sessions that already exist in the target are skipped.
+Revision history is not copied.
+
+Options:
+  --root DIR     directory of the default workspace, see presentationer --help
+  --from STORE   source store, default the default workspace's store
+  --to STORE     target store, e.g. sqlite:sessions.db; relative paths
+                 are relative to the root
+
+Examples:
+  presentationer migrate --to sqlite:sessions.db
+  presentationer migrate --from file:decks --to sqlite:decks.db
+`
+
+func runMigrate(args []string) error {
+	var root string
+	var from string
+	var to string
+	args, err := flags.String("--root", &root).
+		String("--from", &from).
+		String("--to", &to).
+		Help("-h,--help", migrateHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	if to == "" {
+		return fmt.Errorf("requires --to")
+	}
+
+	home, cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	srcWorkspace, err := defaultWorkspace(home, cfg, root, from, false)
+	if err != nil {
+		return err
+	}
+	srcWorkspace.Git = false
+	dstWorkspace := srcWorkspace
+	dstWorkspace.Store = to
+
+	src, err := srcWorkspace.Open()
+	if err != nil {
+		return err
+	}
+	dst, err := dstWorkspace.Open()
+	if err != nil {
+		return err
+	}
+	copied, skipped, err := store.Copy(context.Background(), dst, src)
+	for _, name := range copied {
+		fmt.Printf("copied %s\n", name)
+	}
+	for _, name := range skipped {
+		fmt.Printf("skipped %s: already exists\n", name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("migrated %d sessions\n", len(copied))
+	return nil
+}
@@ -0,0 +1,287 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+const pageHelp = `
+Usage: presentationer page <command> [OPTIONS]
+
+Commands:
+  add SESSION      add a page
+  update SESSION ID
+                   change a page's title, kind or content
+  rm SESSION ID    delete a page
+  mv SESSION ID POSITION
+                   move a page
+
+Positions count from 1, as listed by presentationer show.
+Run presentationer page <command> --help for the options of a command.
+`
+
+func runPage(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Print(pageHelp[1:])
+		return nil
+	}
+	switch args[0] {
+	case "add":
+		return runPageAdd(args[1:])
+	case "update":
+		return runPageUpdate(args[1:])
+	case "rm":
+		return runPageRm(args[1:])
+	case "mv":
+		return runPageMv(args[1:])
+	default:
+		return fmt.Errorf("unknown page command: %s", args[0])
+	}
+}
+
+const pageAddHelp = `
+Usage: presentationer page add SESSION --kind KIND --title TITLE [OPTIONS]
+
+Add a page to the end of a session, or at --at.
+
+Options:
+  --kind KIND           page kind, e.g. code, chat_thread, stats
+  --title TITLE         page title, unique within the session
+  --content JSON        page content
+  --content-file FILE   read the page content from FILE, - for stdin
+  --id ID               page ID, default a new one
+  --at POSITION         position of the new page, from 1
+`
+
+// pageFlags are the page fields the add and update commands take.
+type pageFlags struct {
+	kind        string
+	title       string
+	content     string
+	contentFile string
+}
+
+func (f *pageFlags) register(b *flags.Builder) *flags.Builder {
+	return b.String("--kind", &f.kind).
+		String("--title", &f.title).
+		String("--content", &f.content).
+		String("--content-file", &f.contentFile)
+}
+
+// apply sets the fields given on the command line on page.
+func (f *pageFlags) apply(page *model.Page) error {
+	if f.kind != "" {
+		page.Kind = model.PageKind(f.kind)
+	}
+	if f.title != "" {
+		page.Title = f.title
+	}
+	if f.content != "" && f.contentFile != "" {
+		return fmt.Errorf("--content and --content-file are exclusive")
+	}
+	content := []byte(f.content)
+	if f.contentFile != "" {
+		var err error
+		content, err = readInput(f.contentFile)
+		if err != nil {
+			return err
+		}
+	}
+	if len(content) > 0 {
+		if !json.Valid(content) {
+			return fmt.Errorf("page content is not valid JSON")
+		}
+		page.Content = content
+	}
+	return page.Validate()
+}
+
+func runPageAdd(args []string) error {
+	var opts cliOptions
+	var pf pageFlags
+	var id string
+	var at int
+	b := pf.register(flags.String("--id", &id).Int("--at", &at))
+	args, err := opts.parse(b, pageAddHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 1, "presentationer page add SESSION --kind KIND --title TITLE"); err != nil {
+		return err
+	}
+	if id == "" {
+		id = model.NewPageID()
+	}
+	page := &model.Page{ID: id}
+	if err := pf.apply(page); err != nil {
+		return err
+	}
+
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if at != 0 {
+		// the page goes to the end first, so check the position before
+		// adding it
+		session, err := s.Get(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		if at < 1 || at > len(session.Pages)+1 {
+			return fmt.Errorf("--at %d out of range, expect 1 to %d", at, len(session.Pages)+1)
+		}
+	}
+	if err := s.CreatePage(ctx, args[0], page); err != nil {
+		return err
+	}
+	if at != 0 {
+		if err := s.MovePage(ctx, args[0], page.ID, at-1); err != nil {
+			// the session changed meanwhile; don't leave the page at the end
+			if deleteErr := s.DeletePage(ctx, args[0], page.ID); deleteErr != nil {
+				return errors.Join(err, deleteErr)
+			}
+			return err
+		}
+	}
+	return opts.output(page, func(w io.Writer) {
+		fmt.Fprintf(w, "added page %s %q to session %s\n", page.ID, page.Title, args[0])
+	})
+}
+
+const pageUpdateHelp = `
+Usage: presentationer page update SESSION ID [OPTIONS]
+
+Change a page. Fields that are not given are kept.
+
+Options:
+  --kind KIND           page kind
+  --title TITLE         page title
+  --content JSON        page content, replacing the old one
+  --content-file FILE   read the page content from FILE, - for stdin
+`
+
+func runPageUpdate(args []string) error {
+	var opts cliOptions
+	var pf pageFlags
+	args, err := opts.parse(pf.register(flags.New()), pageUpdateHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 2, "presentationer page update SESSION ID"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	page, err := getPage(ctx, s, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if err := pf.apply(page); err != nil {
+		return err
+	}
+	if err := s.UpdatePage(ctx, args[0], page); err != nil {
+		return err
+	}
+	return opts.output(page, func(w io.Writer) {
+		fmt.Fprintf(w, "updated page %s %q in session %s\n", page.ID, page.Title, args[0])
+	})
+}
+
+// getPage returns the page with the given ID.
+func getPage(ctx context.Context, s store.SessionStore, sessionName string, pageID string) (*model.Page, error) {
+	session, err := s.Get(ctx, sessionName)
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range session.Pages {
+		if page.ID == pageID {
+			return &page, nil
+		}
+	}
+	return nil, store.Errorf(store.ErrNotFound, "page not found")
+}
+
+const pageRmHelp = `
+Usage: presentationer page rm SESSION ID [OPTIONS]
+
+Delete a page.
+`
+
+func runPageRm(args []string) error {
+	var opts cliOptions
+	args, err := opts.parse(flags.New(), pageRmHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 2, "presentationer page rm SESSION ID"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	if err := s.DeletePage(context.Background(), args[0], args[1]); err != nil {
+		return err
+	}
+	return opts.output(map[string]string{"deleted": args[1]}, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted page %s from session %s\n", args[1], args[0])
+	})
+}
+
+const pageMvHelp = `
+Usage: presentationer page mv SESSION ID POSITION [--to SESSION] [OPTIONS]
+
+Move a page to POSITION, counting from 1, within its session or into
+the session given by --to.
+`
+
+func runPageMv(args []string) error {
+	var opts cliOptions
+	var to string
+	args, err := opts.parse(flags.String("--to", &to), pageMvHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 3, "presentationer page mv SESSION ID POSITION"); err != nil {
+		return err
+	}
+	pos, err := strconv.Atoi(args[2])
+	if err != nil || pos < 1 {
+		return fmt.Errorf("invalid position %s, expect a number from 1", args[2])
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	target := args[0]
+	if to != "" && to != args[0] {
+		target = to
+		err = s.MovePageToSession(ctx, args[0], args[1], to, pos-1)
+	} else {
+		err = s.MovePage(ctx, args[0], args[1], pos-1)
+	}
+	if err != nil {
+		return err
+	}
+	page, err := getPage(ctx, s, target, args[1])
+	if err != nil {
+		return err
+	}
+	return opts.output(page, func(w io.Writer) {
+		fmt.Fprintf(w, "moved page %s to position %d of session %s\n", page.ID, pos, target)
+	})
+}
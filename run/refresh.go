@@ -0,0 +1,94 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/presentationer/pkg/source"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+const refreshHelp = `
+Usage: presentationer refresh SESSION [OPTIONS]
+
+Update the code pages of a session linked to source files: their code
+becomes the referenced lines as they are now, and line ranges follow the
+lines when they moved. Pages whose source is missing are left as they are.
+
+Options:
+  --dry-run   only show how the pages drifted from their sources
+`
+
+func runRefresh(args []string) error {
+	var opts cliOptions
+	var dryRun bool
+	args, err := opts.parse(flags.Bool("--dry-run", &dryRun), refreshHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 1, "presentationer refresh SESSION"); err != nil {
+		return err
+	}
+	home, cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := allowSourceRoots(home, cfg); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	session, err := s.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	var statuses []source.PageStatus
+	if dryRun {
+		statuses = source.ResolvePages(session.Pages)
+	} else {
+		statuses, err = source.RefreshPages(session.Pages)
+		if err != nil {
+			return err
+		}
+	}
+	// fail rather than overwrite a change saved while the sources were read
+	ctx = store.WithRevision(ctx, &store.Revision{IfMatch: session.Revision})
+	for _, st := range statuses {
+		if st.Refreshed {
+			if err := s.Update(ctx, session); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if statuses == nil {
+		statuses = []source.PageStatus{}
+	}
+	return opts.output(statuses, func(w io.Writer) {
+		if len(statuses) == 0 {
+			fmt.Fprintf(w, "no code pages of %s are linked to source files\n", session.Name)
+			return
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "PAGE\tSTATE\tLINES\tERROR\n")
+		for _, st := range statuses {
+			state := string(st.State)
+			if st.Refreshed {
+				state = "refreshed"
+			}
+			lines := ""
+			if st.Start > 0 {
+				lines = fmt.Sprintf("%d-%d", st.Start, st.End)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", st.Title, state, lines, st.Error)
+		}
+		tw.Flush()
+	})
+}
@@ -2,7 +2,10 @@ package run
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/xhd2015/kool/pkgs/web"
 	"github.com/xhd2015/less-gen/flags"
@@ -10,15 +13,71 @@ import (
 )
 
 const help = `
-Usage: presentationer <subcommand>
+Usage: presentationer [OPTIONS]
+       presentationer <subcommand> [ARGS]
+
+Without a subcommand, start the web server.
 
 Subcommands:
-  create    Create a new presentation
+  list      List sessions
+  create    Create a new session
+  show      Show a session and its pages
+  rename    Rename a session
+  delete    Delete a session
+  page      Add, update, delete or move pages: page add|update|rm|mv
+  avatar    Manage avatars: avatar add|ls|rm
+  export    Export a session as HTML, Markdown, PowerPoint, PDF or a bundle
+  import    Import a session from a bundle, Markdown, a diff or commits
+  refresh   Update the code pages linked to source files
+  extract   Print a Go declaration, or add it as a code page
+  migrate   Copy sessions from one store to another
+
+The session subcommands take the --root, --store and --git options below,
+plus --workspace NAME and --json; see presentationer <subcommand> --help.
+
+Options:
+  --dev           run the frontend dev server
+  --root DIR      directory of the default workspace, default
+                  $PRESENTATIONER_HOME or ~/.presentationer
+  --store STORE   where sessions are kept: file (in the root, default),
+                  file:DIR or sqlite:PATH, relative to the root
+  --git           commit every change to the git repository holding the
+                  sessions, file store only
+
+Defaults for these options, the port and more workspaces can be set in
+config.json in the presentationer home directory.
 `
 
+var subcommands = map[string]func(args []string) error{
+	"list":    runList,
+	"create":  runCreate,
+	"show":    runShow,
+	"rename":  runRename,
+	"delete":  runDelete,
+	"page":    runPage,
+	"avatar":  runAvatar,
+	"export":  runExport,
+	"import":  runImport,
+	"refresh": runRefresh,
+	"extract": runExtract,
+	"migrate": runMigrate,
+}
+
 func Run(args []string) error {
+	if len(args) > 0 {
+		if cmd, ok := subcommands[args[0]]; ok {
+			return cmd(args[1:])
+		}
+	}
+
 	var devFlag bool
+	var gitFlag bool
+	var storeFlag string
+	var rootFlag string
 	args, err := flags.Bool("--dev", &devFlag).
+		Bool("--git", &gitFlag).
+		String("--store", &storeFlag).
+		String("--root", &rootFlag).
 		Help("-h,--help", help).
 		Parse(args)
 	if err != nil {
@@ -29,8 +88,43 @@ func Run(args []string) error {
 		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
 	}
 
+	home, cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := allowSourceRoots(home, cfg); err != nil {
+		return err
+	}
+	def, err := defaultWorkspace(home, cfg, rootFlag, storeFlag, gitFlag)
+	if err != nil {
+		return err
+	}
+	selectDefault := rootFlag != "" || storeFlag != "" || gitFlag
+	workspaces, err := openWorkspaces(home, cfg, def, selectDefault)
+	if err != nil {
+		return err
+	}
+	defer workspaces.Close()
+	if !devFlag {
+		// --dev stops the server itself on a signal, running the Close above
+		go func() {
+			c := make(chan os.Signal, 1)
+			signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+			<-c
+			if err := workspaces.Close(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(0)
+		}()
+	}
+	server.SetWorkspaces(workspaces)
+
+	startPort := cfg.Port
+	if startPort == 0 {
+		startPort = 8080
+	}
 	// next port
-	port, err := web.FindAvailablePort(8080, 100)
+	port, err := web.FindAvailablePort(startPort, 100)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,223 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/presentationer/pkg/model"
+)
+
+const listHelp = `
+Usage: presentationer list [OPTIONS]
+
+List the sessions of the workspace.
+`
+
+func runList(args []string) error {
+	var opts cliOptions
+	args, err := opts.parse(flags.New(), listHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 0, "presentationer list"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	sessions, err := s.List(context.Background())
+	if err != nil {
+		return err
+	}
+	if sessions == nil {
+		sessions = []model.Session{}
+	}
+	return opts.output(sessions, func(w io.Writer) {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "NAME\tREVISION\tMODIFIED\n")
+		for _, session := range sessions {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", session.Name, session.Revision, session.LastModified.Local().Format(time.DateTime))
+		}
+		tw.Flush()
+	})
+}
+
+const createHelp = `
+Usage: presentationer create NAME [--pages FILE] [OPTIONS]
+
+Create a new session.
+
+Options:
+  --pages FILE   initial pages, a JSON array of pages or a session object
+                 with a pages field; - reads stdin. Pages without an id
+                 get one.
+`
+
+func runCreate(args []string) error {
+	var opts cliOptions
+	var pagesFile string
+	args, err := opts.parse(flags.String("--pages", &pagesFile), createHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 1, "presentationer create NAME"); err != nil {
+		return err
+	}
+	session := &model.Session{Name: args[0], Pages: []model.Page{}}
+	if pagesFile != "" {
+		session.Pages, err = readPages(pagesFile)
+		if err != nil {
+			return err
+		}
+	}
+	if err := model.ValidatePages(session.Pages); err != nil {
+		return err
+	}
+
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := s.Create(ctx, session); err != nil {
+		return err
+	}
+	created, err := s.Get(ctx, session.Name)
+	if err != nil {
+		return err
+	}
+	return opts.output(created, func(w io.Writer) {
+		fmt.Fprintf(w, "created session %s with %d pages\n", created.Name, len(created.Pages))
+	})
+}
+
+// readPages reads the pages of a JSON array or session object, giving
+// new IDs to pages that have none.
+func readPages(path string) ([]model.Page, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	var pages []model.Page
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var session model.Session
+		err = json.Unmarshal(data, &session)
+		pages = session.Pages
+	} else {
+		err = json.Unmarshal(data, &pages)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if pages == nil {
+		pages = []model.Page{}
+	}
+	for i := range pages {
+		if pages[i].ID == "" {
+			pages[i].ID = model.NewPageID()
+		}
+	}
+	return pages, nil
+}
+
+const showHelp = `
+Usage: presentationer show NAME [OPTIONS]
+
+Show a session and its pages. With --json the page contents are
+included.
+`
+
+func runShow(args []string) error {
+	var opts cliOptions
+	args, err := opts.parse(flags.New(), showHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 1, "presentationer show NAME"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	session, err := s.Get(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	return opts.output(session, func(w io.Writer) {
+		fmt.Fprintf(w, "%s, revision %d, modified %s\n", session.Name, session.Revision, session.LastModified.Local().Format(time.DateTime))
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "#\tID\tKIND\tTITLE\n")
+		for i, page := range session.Pages {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", i+1, page.ID, page.Kind, page.Title)
+		}
+		tw.Flush()
+	})
+}
+
+const renameHelp = `
+Usage: presentationer rename OLD NEW [OPTIONS]
+
+Rename a session.
+`
+
+func runRename(args []string) error {
+	var opts cliOptions
+	args, err := opts.parse(flags.New(), renameHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 2, "presentationer rename OLD NEW"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := s.Rename(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+	session, err := s.Get(ctx, args[1])
+	if err != nil {
+		return err
+	}
+	return opts.output(session, func(w io.Writer) {
+		fmt.Fprintf(w, "renamed session %s to %s\n", args[0], args[1])
+	})
+}
+
+const deleteHelp = `
+Usage: presentationer delete NAME [OPTIONS]
+
+Delete a session with its pages and avatars.
+`
+
+func runDelete(args []string) error {
+	var opts cliOptions
+	args, err := opts.parse(flags.New(), deleteHelp, args)
+	if err != nil {
+		return err
+	}
+	if err := needArgs(args, 1, "presentationer delete NAME"); err != nil {
+		return err
+	}
+	s, err := opts.open()
+	if err != nil {
+		return err
+	}
+	if err := s.Delete(context.Background(), args[0]); err != nil {
+		return err
+	}
+	return opts.output(map[string]string{"deleted": args[0]}, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted session %s\n", args[0])
+	})
+}
@@ -0,0 +1,113 @@
+package run
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/xhd2015/presentationer/pkg/config"
+	"github.com/xhd2015/presentationer/pkg/source"
+	"github.com/xhd2015/presentationer/pkg/workspace"
+)
+
+// loadConfig returns the presentationer home directory and its config.
+func loadConfig() (string, *config.Config, error) {
+	home, err := config.Home()
+	if err != nil {
+		return "", nil, err
+	}
+	cfg, err := config.Load(home)
+	if err != nil {
+		return "", nil, err
+	}
+	return home, cfg, nil
+}
+
+// allowSourceRoots lets code pages link to files in the source roots of
+// the config; relative ones are taken from home.
+func allowSourceRoots(home string, cfg *config.Config) error {
+	var roots []string
+	for _, r := range cfg.SourceRoots {
+		root, err := config.ResolvePath(home, r)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, root)
+	}
+	source.AllowRoots(roots)
+	return nil
+}
+
+// defaultWorkspace describes the default workspace: flags first, then the
+// config file, then the home directory itself. A relative --root is taken
+// from the working directory, a relative root in the config from home.
+func defaultWorkspace(home string, cfg *config.Config, root string, storeSpec string, useGit bool) (workspace.Workspace, error) {
+	var err error
+	if root != "" {
+		root, err = config.ExpandHome(root)
+		if err == nil {
+			root, err = filepath.Abs(root)
+		}
+	} else {
+		root, err = config.ResolvePath(home, cfg.Root)
+	}
+	if err != nil {
+		return workspace.Workspace{}, err
+	}
+	if root == "" {
+		root = home
+	}
+	if storeSpec == "" {
+		storeSpec = cfg.Store
+	}
+	return workspace.Workspace{
+		Name:  workspace.DefaultName,
+		Root:  root,
+		Store: storeSpec,
+		Git:   useGit || cfg.Git,
+	}, nil
+}
+
+// openWorkspaces registers the default workspace and those of the config
+// file and opens the one to start with: the default one when selectDefault
+// is set, otherwise the one last selected.
+func openWorkspaces(home string, cfg *config.Config, def workspace.Workspace, selectDefault bool) (*workspace.Manager, error) {
+	m := workspace.NewManager()
+	if err := m.Add(def); err != nil {
+		return nil, err
+	}
+	for _, ws := range cfg.Workspaces {
+		root, err := config.ResolvePath(home, ws.Root)
+		if err != nil {
+			return nil, err
+		}
+		ws.Root = root
+		if err := m.Add(ws); err != nil {
+			return nil, fmt.Errorf("%s: %w", config.FileName, err)
+		}
+	}
+
+	current := cfg.Workspace
+	if selectDefault || current == "" {
+		current = workspace.DefaultName
+	}
+	if err := m.Select(current); err != nil {
+		return nil, fmt.Errorf("open workspace %s: %w", current, err)
+	}
+
+	m.Save = func(list []workspace.Workspace, current string) error {
+		// re-read so edits made while the server runs are kept
+		latest, err := config.Load(home)
+		if err != nil {
+			return err
+		}
+		latest.Workspaces = nil
+		for _, ws := range list {
+			if ws.Name != workspace.DefaultName {
+				latest.Workspaces = append(latest.Workspaces, ws)
+			}
+		}
+		latest.Workspace = current
+		return config.Save(home, latest)
+	}
+	return m, nil
+}
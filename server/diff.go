@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/importer/patch"
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// handleDiff compares the old and new code of a code_diff page, posted as
+// its content, and replies with the hunks.
+func handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Limit the body to 10MB
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	var c model.CodeDiffContent
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	hunks := c.Hunks()
+	if hunks == nil {
+		hunks = []diff.Hunk{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hunks)
+}
+
+// handleImportDiff creates a session of code_diff pages from a unified
+// diff or git diff output, posted as the request body or as the file of a
+// form. The session is named by ?name=, the file name or "diff".
+func handleImportDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	onConflict, err := store.ParseOnConflict(r.URL.Query().Get("onConflict"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Limit upload size to 100MB
+	r.Body = http.MaxBytesReader(w, r.Body, 100<<20)
+	defaultName := "diff"
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			httpError(w, "Error retrieving file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		defaultName = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		httpError(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+	name, err := patch.Import(r.Context(), sessionStore(r), data, r.URL.Query().Get("name"), defaultName, onConflict)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"name": name})
+}
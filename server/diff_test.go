@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/diff"
+	"github.com/xhd2015/presentationer/pkg/store/memory"
+)
+
+func TestHandleDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		status  int
+		headers []string
+	}{
+		{"hunks", http.MethodPost, `{"oldCode":"a\nb\nc","newCode":"a\nB\nc","context":1}`, http.StatusOK, []string{"@@ -1,3 +1,3 @@"}},
+		{"no changes", http.MethodPost, `{"oldCode":"a","newCode":"a"}`, http.StatusOK, []string{}},
+		{"invalid body", http.MethodPost, `{`, http.StatusBadRequest, nil},
+		{"body too large", http.MethodPost, `{"oldCode":"` + strings.Repeat("x", 10<<20) + `"}`, http.StatusBadRequest, nil},
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleDiff(w, httptest.NewRequest(tt.method, "/api/diff", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var hunks []diff.Hunk
+			if err := json.Unmarshal(w.Body.Bytes(), &hunks); err != nil {
+				t.Fatal(err)
+			}
+			headers := []string{}
+			for _, h := range hunks {
+				headers = append(headers, h.Header())
+			}
+			if strings.Join(headers, ",") != strings.Join(tt.headers, ",") {
+				t.Errorf("got hunks %q, want %q", headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestHandleImportDiff(t *testing.T) {
+	s := memory.New()
+	post := func(query string, body string) *httptest.ResponseRecorder {
+		return serve(handleImportDiff, s, http.MethodPost, "/api/import/diff"+query, body)
+	}
+	patch := "--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n"
+
+	w := post("?name=review", patch)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"review"`) {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	session, err := s.Get(context.Background(), "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Pages) != 1 || session.Pages[0].Title != "a.go" {
+		t.Errorf("got pages %+v", session.Pages)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		query  string
+		body   string
+		status int
+	}{
+		{"existing name", "?name=review", patch, http.StatusConflict},
+		{"not a diff", "?name=other", "hello\n", http.StatusBadRequest},
+		{"invalid onConflict", "?onConflict=maybe", patch, http.StatusBadRequest},
+	} {
+		if w := post(tt.query, tt.body); w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// Error responses
+//
+// API errors are JSON objects with a human readable "error" and a
+// machine readable "code". Validation errors add "fields" and revision
+// conflicts add the session's current "revision".
+
+const (
+	CodeInvalid          = "invalid"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeRevisionConflict = "revision_conflict"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal"
+)
+
+type errorResponse struct {
+	Error    string             `json:"error"`
+	Code     string             `json:"code"`
+	Fields   []model.FieldError `json:"fields,omitempty"`
+	Revision int64              `json:"revision,omitempty"`
+}
+
+// writeError replies with the status and code matching err: the store
+// sentinels map to 404, 409 and 400, anything else is a 500.
+func writeError(w http.ResponseWriter, err error) {
+	resp := errorResponse{Error: err.Error()}
+	var status int
+
+	var invalid *model.ValidationError
+	var conflict *store.RevisionConflictError
+	switch {
+	case errors.As(err, &invalid):
+		status, resp.Code = http.StatusBadRequest, CodeInvalid
+		resp.Error = "invalid content"
+		resp.Fields = invalid.Errors
+	case errors.As(err, &conflict):
+		status, resp.Code = http.StatusConflict, CodeRevisionConflict
+		resp.Revision = conflict.Current
+		setETag(w, conflict.Current)
+	case errors.Is(err, store.ErrNotFound):
+		status, resp.Code = http.StatusNotFound, CodeNotFound
+	case errors.Is(err, store.ErrConflict):
+		status, resp.Code = http.StatusConflict, CodeConflict
+	case errors.Is(err, store.ErrInvalid):
+		status, resp.Code = http.StatusBadRequest, CodeInvalid
+	default:
+		status, resp.Code = http.StatusInternalServerError, CodeInternal
+	}
+	writeErrorResponse(w, status, resp)
+}
+
+// httpError is http.Error with a JSON body, for errors a handler detects
+// itself such as a missing parameter.
+func httpError(w http.ResponseWriter, msg string, status int) {
+	code := CodeInternal
+	switch status {
+	case http.StatusBadRequest:
+		code = CodeInvalid
+	case http.StatusNotFound:
+		code = CodeNotFound
+	case http.StatusConflict:
+		code = CodeConflict
+	case http.StatusMethodNotAllowed:
+		code = CodeMethodNotAllowed
+	}
+	writeErrorResponse(w, status, errorResponse{Error: msg, Code: code})
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, resp errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	mdimport "github.com/xhd2015/presentationer/pkg/importer/markdown"
+	"github.com/xhd2015/presentationer/pkg/importer/patch"
+	"github.com/xhd2015/presentationer/pkg/render"
+	"github.com/xhd2015/presentationer/pkg/render/html"
+	"github.com/xhd2015/presentationer/pkg/render/markdown"
+	"github.com/xhd2015/presentationer/pkg/render/pdf"
+	"github.com/xhd2015/presentationer/pkg/render/pptx"
+	"github.com/xhd2015/presentationer/pkg/store"
+)
+
+// handleExport downloads a session as a file of the requested format:
+//
+//	html     a single self-contained HTML deck
+//	md       a Markdown document
+//	pptx     a PowerPoint deck
+//	pdf      a PDF document; handout=N puts N pages on each sheet and
+//	         notes=true adds lines for notes beside them
+//	bundle   a .presentationer zip bundle, see store.Export
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "html"
+	}
+
+	var buf bytes.Buffer
+	var contentType, ext string
+	switch format {
+	case "html":
+		dist, err := DistFS()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		deck, err := render.LoadDeck(r.Context(), sessionStore(r), sessionName)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := html.WriteFile(&buf, dist, deck); err != nil {
+			writeError(w, err)
+			return
+		}
+		contentType, ext = "text/html; charset=utf-8", ".html"
+	case "md":
+		deck, err := render.LoadDeck(r.Context(), sessionStore(r), sessionName)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := markdown.Write(&buf, deck); err != nil {
+			writeError(w, err)
+			return
+		}
+		contentType, ext = "text/markdown; charset=utf-8", ".md"
+	case "pptx":
+		deck, err := render.LoadDeck(r.Context(), sessionStore(r), sessionName)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := pptx.Write(&buf, deck); err != nil {
+			writeError(w, err)
+			return
+		}
+		contentType, ext = pptx.ContentType, ".pptx"
+	case "pdf":
+		var opts pdf.Options
+		if handout := r.URL.Query().Get("handout"); handout != "" {
+			n, err := strconv.Atoi(handout)
+			if err != nil {
+				httpError(w, "invalid handout: "+handout, http.StatusBadRequest)
+				return
+			}
+			opts.Handout = n
+		}
+		opts.Notes = r.URL.Query().Get("notes") == "true"
+		if err := opts.Validate(); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		deck, err := render.LoadDeck(r.Context(), sessionStore(r), sessionName)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := pdf.Write(&buf, deck, opts); err != nil {
+			writeError(w, err)
+			return
+		}
+		contentType, ext = pdf.ContentType, ".pdf"
+	case "bundle":
+		if err := store.Export(r.Context(), sessionStore(r), sessionName, &buf); err != nil {
+			writeError(w, err)
+			return
+		}
+		contentType, ext = "application/zip", store.BundleExt
+	default:
+		httpError(w, "unsupported export format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": sessionName + ext}))
+	w.Write(buf.Bytes())
+}
+
+// handleImport creates a session from a file uploaded as form file "file",
+// of the format given by the format query parameter:
+//
+//	bundle   a .presentationer bundle, the default
+//	md       a Markdown outline, see pkg/importer/markdown
+//
+// The optional name query parameter overrides the session name found in the
+// file and onConflict is one of fail (default), rename or overwrite.
+func handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	onConflict, err := store.ParseOnConflict(r.URL.Query().Get("onConflict"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Limit upload size to 100MB
+	r.Body = http.MaxBytesReader(w, r.Body, 100<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httpError(w, "Error retrieving file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	name := r.URL.Query().Get("name")
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "bundle":
+		name, err = store.Import(r.Context(), sessionStore(r), file, name, onConflict)
+	case "md":
+		var data []byte
+		data, err = io.ReadAll(file)
+		if err == nil {
+			defaultName := strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+			name, err = mdimport.Import(r.Context(), sessionStore(r), data, name, defaultName, onConflict)
+		}
+	case "diff":
+		var data []byte
+		data, err = io.ReadAll(file)
+		if err == nil {
+			defaultName := strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+			name, err = patch.Import(r.Context(), sessionStore(r), data, name, defaultName, onConflict)
+		}
+	default:
+		httpError(w, "unsupported import format: "+format, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"name": name})
+}
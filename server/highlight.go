@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/xhd2015/presentationer/pkg/highlight"
+)
+
+type highlightRequest struct {
+	Code     string `json:"code"`
+	Language string `json:"language"`
+	// Style is a chroma style, highlight.DefaultStyle when empty.
+	Style string `json:"style"`
+	// Lines is a focus config like "3-8,10{\"text\"}".
+	Lines string `json:"lines"`
+	// Format is "tokens" (the default) or "html".
+	Format string `json:"format"`
+}
+
+// handleHighlight highlights code with the focus config applied, replying
+// with the lines as token streams, or with format "html" with the HTML
+// the code presenter would show.
+func handleHighlight(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Limit the body to 10MB
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	var req highlightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Format != "" && req.Format != "tokens" && req.Format != "html" {
+		httpError(w, "unsupported format: "+req.Format, http.StatusBadRequest)
+		return
+	}
+	code, err := highlight.Highlight(req.Code, req.Language, req.Style)
+	if err != nil {
+		if errors.Is(err, highlight.ErrUnknownStyle) {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeError(w, err)
+		return
+	}
+	code.Focus(highlight.ParseFocus(req.Lines))
+
+	if req.Format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(code.HTML()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(code)
+}
+
+func handleHighlightStyles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(highlight.Styles())
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/highlight"
+)
+
+func TestHandleHighlight(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string // in the body
+	}{
+		{"tokens", `{"code":"a := 1\nb := 2","language":"go","lines":"2"}`, http.StatusOK, `"focused":true`},
+		{"html", `{"code":"x","format":"html"}`, http.StatusOK, `<div class="line-1"`},
+		{"unknown style", `{"code":"x","style":"nope"}`, http.StatusBadRequest, "unknown style"},
+		{"unknown format", `{"code":"x","format":"png"}`, http.StatusBadRequest, "unsupported format"},
+		{"invalid body", `{`, http.StatusBadRequest, "Invalid request body"},
+		{"body too large", `{"code":"` + strings.Repeat("x", 10<<20) + `"}`, http.StatusBadRequest, "Invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleHighlight(w, httptest.NewRequest(http.MethodPost, "/api/highlight", strings.NewReader(tt.body)))
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("status %d, want %d with %q: %.200s", w.Code, tt.status, tt.want, w.Body)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handleHighlight(w, httptest.NewRequest(http.MethodPost, "/api/highlight", strings.NewReader(`{"code":"a\nb\nc","lines":"2-3"}`)))
+	var code highlight.Code
+	if err := json.Unmarshal(w.Body.Bytes(), &code); err != nil {
+		t.Fatal(err)
+	}
+	var focused []int
+	for _, l := range code.Lines {
+		if l.Focused {
+			focused = append(focused, l.Number)
+		}
+	}
+	if len(focused) != 2 || focused[0] != 2 || focused[1] != 3 {
+		t.Errorf("focused lines %v, want [2 3]", focused)
+	}
+}
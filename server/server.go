@@ -155,11 +155,20 @@ func ProxyDev(mux *http.ServeMux) error {
 	return nil
 }
 
+// DistFS returns the embedded frontend build.
+func DistFS() (fs.FS, error) {
+	reactFileSystem, err := fs.Sub(distFS, "presentationer-react/dist")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create react file system: %v", err)
+	}
+	return reactFileSystem, nil
+}
+
 func Static(mux *http.ServeMux) error {
 	// Serve static files from the embedded React build
-	reactFileSystem, err := fs.Sub(distFS, "presentationer-react/dist")
+	reactFileSystem, err := DistFS()
 	if err != nil {
-		return fmt.Errorf("failed to create react file system: %v", err)
+		return err
 	}
 
 	// Create sub-filesystem for assets
@@ -1,35 +1,77 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/xhd2015/presentationer/pkg/config"
 	"github.com/xhd2015/presentationer/pkg/model"
 	"github.com/xhd2015/presentationer/pkg/store"
 	"github.com/xhd2015/presentationer/pkg/store/file"
+	"github.com/xhd2015/presentationer/pkg/workspace"
 )
 
-// Global store instance
-var sessionStore store.SessionStore
+// Global workspaces; each session route uses the store of the workspace
+// its request names, see inWorkspace.
+var workspaces = workspace.NewManager()
 
+// SetSessionStore serves s as the only, default workspace. It must be
+// called before the routes are registered.
+func SetSessionStore(s store.SessionStore) {
+	m := workspace.NewManager()
+	m.AddStore(workspace.Workspace{Name: workspace.DefaultName}, s)
+	workspaces = m
+}
+
+type storeKey struct{}
+
+// inWorkspace runs h with the store of the workspace named by
+// ?workspace=, or of the selected one when there is none. Every tab of
+// the editor names its own, so switching workspaces in one tab doesn't
+// move where the others read and save.
+func inWorkspace(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, err := workspaces.Open(r.URL.Query().Get("workspace"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), storeKey{}, s)))
+	}
+}
+
+// sessionStore returns the store inWorkspace resolved for r.
+func sessionStore(r *http.Request) store.SessionStore {
+	return r.Context().Value(storeKey{}).(store.SessionStore)
+}
+
+// InitSessionStore opens the default workspace in the presentationer
+// home directory unless one was set up already.
 func InitSessionStore() error {
-	wd, err := os.Getwd()
+	if workspaces.Store() != nil {
+		return nil
+	}
+	home, err := config.Home()
 	if err != nil {
 		return err
 	}
-	sessionStore = file.New(wd)
-	return nil
+	ws := workspace.Workspace{Name: workspace.DefaultName, Root: home}
+	s, err := ws.Open()
+	if err != nil {
+		return err
+	}
+	return workspaces.AddStore(ws, s)
 }
 
 func handleListSessions(w http.ResponseWriter, r *http.Request) {
-	sessions, err := sessionStore.List(r.Context())
+	sessions, err := sessionStore(r).List(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -39,43 +81,59 @@ func handleListSessions(w http.ResponseWriter, r *http.Request) {
 func handleCreateSession(w http.ResponseWriter, r *http.Request) {
 	var req model.Session
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 	if req.Name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		httpError(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	// names of every store must be usable as file names, so that
+	// sessions can move between workspaces and be exported
+	if err := file.CheckSessionName(req.Name); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := model.ValidatePages(req.Pages); err != nil {
+		writeError(w, err)
 		return
 	}
-	req.Name = filepath.Base(req.Name)
 
-	if err := sessionStore.Create(r.Context(), &req); err != nil {
-		if err.Error() == "session already exists" {
-			http.Error(w, err.Error(), http.StatusConflict)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	if err := sessionStore(r).Create(r.Context(), &req); err != nil {
+		writeError(w, err)
 		return
 	}
 
+	setETag(w, req.Revision)
 	w.WriteHeader(http.StatusCreated)
 }
 
 func handleUpdateSession(w http.ResponseWriter, r *http.Request) {
 	var req model.Session
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 	if req.Name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		httpError(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if err := model.ValidatePages(req.Pages); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	if err := sessionStore.Update(r.Context(), &req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sessionStore(r).Update(ctx, &req); err != nil {
+		writeError(w, err)
 		return
 	}
 
+	setETag(w, rev.Current)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -85,36 +143,65 @@ func handleRenameSession(w http.ResponseWriter, r *http.Request) {
 		New string `json:"new"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 	if req.Old == "" || req.New == "" {
-		http.Error(w, "old and new names required", http.StatusBadRequest)
+		httpError(w, "old and new names required", http.StatusBadRequest)
+		return
+	}
+	if err := file.CheckSessionName(req.New); err != nil {
+		writeError(w, err)
 		return
 	}
-	req.New = filepath.Base(req.New)
 
-	if err := sessionStore.Rename(r.Context(), req.Old, req.New); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := sessionStore(r).Rename(r.Context(), req.Old, req.New); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+func handleDuplicateSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Src string `json:"src"`
+		Dst string `json:"dst"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Src == "" || req.Dst == "" {
+		httpError(w, "src and dst names required", http.StatusBadRequest)
+		return
+	}
+	if err := file.CheckSessionName(req.Dst); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := sessionStore(r).Duplicate(r.Context(), req.Src, req.Dst); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
 func handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		httpError(w, "Name is required", http.StatusBadRequest)
 		return
 	}
 
-	if err := sessionStore.Delete(r.Context(), name); err != nil {
-		if os.IsNotExist(err) {
-			http.Error(w, "Session not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+	if err := sessionStore(r).Delete(r.Context(), name); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -124,89 +211,387 @@ func handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 func handleGetSession(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
-		http.Error(w, "Name is required", http.StatusBadRequest)
+		httpError(w, "Name is required", http.StatusBadRequest)
 		return
 	}
 
-	session, err := sessionStore.Get(r.Context(), name)
+	session, err := sessionStore(r).Get(r.Context(), name)
 	if err != nil {
-		if os.IsNotExist(err) {
-			http.Error(w, "Session not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
+	resp := resolveSources(session)
 
+	setETag(w, session.Revision)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(session)
+	json.NewEncoder(w).Encode(resp)
 }
 
 func handleCreatePage(w http.ResponseWriter, r *http.Request) {
 	sessionName := r.URL.Query().Get("session")
 	if sessionName == "" {
-		http.Error(w, "session name required", http.StatusBadRequest)
+		httpError(w, "session name required", http.StatusBadRequest)
 		return
 	}
 	var page model.Page
 	if err := json.NewDecoder(r.Body).Decode(&page); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	if err := sessionStore.CreatePage(r.Context(), sessionName, &page); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := page.Validate(); err != nil {
+		writeError(w, err)
 		return
 	}
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sessionStore(r).CreatePage(ctx, sessionName, &page); err != nil {
+		writeError(w, err)
+		return
+	}
+	setETag(w, rev.Current)
 	w.WriteHeader(http.StatusOK)
 }
 
 func handleUpdatePage(w http.ResponseWriter, r *http.Request) {
 	sessionName := r.URL.Query().Get("session")
 	if sessionName == "" {
-		http.Error(w, "session name required", http.StatusBadRequest)
+		httpError(w, "session name required", http.StatusBadRequest)
 		return
 	}
 	var page model.Page
 	if err := json.NewDecoder(r.Body).Decode(&page); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := page.Validate(); err != nil {
+		writeError(w, err)
 		return
 	}
-	if err := sessionStore.UpdatePage(r.Context(), sessionName, &page); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sessionStore(r).UpdatePage(ctx, sessionName, &page); err != nil {
+		writeError(w, err)
 		return
 	}
+	setETag(w, rev.Current)
 	w.WriteHeader(http.StatusOK)
 }
 
 func handleDeletePage(w http.ResponseWriter, r *http.Request) {
 	sessionName := r.URL.Query().Get("session")
 	if sessionName == "" {
-		http.Error(w, "session name required", http.StatusBadRequest)
+		httpError(w, "session name required", http.StatusBadRequest)
 		return
 	}
 	pageID := r.URL.Query().Get("id")
 	if pageID == "" {
-		http.Error(w, "page id required", http.StatusBadRequest)
+		httpError(w, "page id required", http.StatusBadRequest)
+		return
+	}
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sessionStore(r).DeletePage(ctx, sessionName, pageID); err != nil {
+		writeError(w, err)
+		return
+	}
+	setETag(w, rev.Current)
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleDuplicatePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if err := sessionStore.DeletePage(r.Context(), sessionName, pageID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	pageID := r.URL.Query().Get("id")
+	if pageID == "" {
+		httpError(w, "page id required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := sessionStore(r).DuplicatePage(ctx, sessionName, pageID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	setETag(w, rev.Current)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func handleMovePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		ID    string `json:"id"`
+		Index int    `json:"index"`
+		// ToSession moves the page into another session when set.
+		ToSession string `json:"toSession"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" {
+		httpError(w, "page id required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ToSession == "" || req.ToSession == sessionName {
+		err = sessionStore(r).MovePage(ctx, sessionName, req.ID, req.Index)
+	} else {
+		err = sessionStore(r).MovePageToSession(ctx, sessionName, req.ID, req.ToSession, req.Index)
+	}
+	if err != nil {
+		writeError(w, err)
 		return
 	}
+	setETag(w, rev.Current)
 	w.WriteHeader(http.StatusOK)
 }
 
+// History handlers
+
+// parseRevisionParam reads the required revision query parameter.
+func parseRevisionParam(r *http.Request) (int64, error) {
+	rev, err := strconv.ParseInt(r.URL.Query().Get("revision"), 10, 64)
+	if err != nil || rev < 0 {
+		return 0, fmt.Errorf("valid revision required")
+	}
+	return rev, nil
+}
+
+func handleListRevisions(w http.ResponseWriter, r *http.Request) {
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	revisions, err := sessionStore(r).ListRevisions(r.Context(), sessionName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func handleGetRevision(w http.ResponseWriter, r *http.Request) {
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	revision, err := parseRevisionParam(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	session, err := sessionStore(r).GetRevision(r.Context(), sessionName, revision)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func handleRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	revision, err := parseRevisionParam(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := sessionStore(r).RestoreRevision(ctx, sessionName, revision); err != nil {
+		writeError(w, err)
+		return
+	}
+	setETag(w, rev.Current)
+	w.WriteHeader(http.StatusOK)
+}
+
+// versionedStore returns the store as a store.VersionedStore, replying
+// 404 when version control is not enabled.
+func versionedStore(w http.ResponseWriter, r *http.Request) (store.VersionedStore, bool) {
+	vs, ok := sessionStore(r).(store.VersionedStore)
+	if !ok {
+		httpError(w, "version control is not enabled, start with --git", http.StatusNotFound)
+		return nil, false
+	}
+	return vs, true
+}
+
+func handleGitLog(w http.ResponseWriter, r *http.Request) {
+	vs, ok := versionedStore(w, r)
+	if !ok {
+		return
+	}
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			httpError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	commits, err := vs.Log(r.Context(), sessionName, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(commits)
+}
+
+func handleGitDiff(w http.ResponseWriter, r *http.Request) {
+	vs, ok := versionedStore(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	sessionName := q.Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	diff, err := vs.Diff(r.Context(), sessionName, q.Get("from"), q.Get("to"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, diff)
+}
+
+func handleGitCheckout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	vs, ok := versionedStore(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Session string `json:"session"`
+		Commit  string `json:"commit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Session == "" || req.Commit == "" {
+		httpError(w, "session and commit required", http.StatusBadRequest)
+		return
+	}
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := vs.Checkout(ctx, req.Session, req.Commit); err != nil {
+		writeError(w, err)
+		return
+	}
+	setETag(w, rev.Current)
+	w.WriteHeader(http.StatusOK)
+}
+
+// revisionContext attaches a store.Revision to the request context so the
+// store reports the revision it writes, and checks it against the If-Match
+// header when one is sent.
+func revisionContext(r *http.Request) (context.Context, *store.Revision, error) {
+	ifMatch, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		return nil, nil, err
+	}
+	rev := &store.Revision{IfMatch: ifMatch}
+	return store.WithRevision(r.Context(), rev), rev, nil
+}
+
+// parseIfMatch accepts the ETags written by setETag, with or without
+// quotes. An empty header or "*" means no precondition.
+func parseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	rev, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || rev <= 0 {
+		return 0, fmt.Errorf("invalid If-Match: %s", header)
+	}
+	return rev, nil
+}
+
+func setETag(w http.ResponseWriter, revision int64) {
+	if revision > 0 {
+		w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(revision, 10)))
+	}
+}
+
 // Avatar handlers
 func handleAvatarUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	sessionName := r.URL.Query().Get("session")
 	avatarName := r.URL.Query().Get("name")
 	if sessionName == "" || avatarName == "" {
-		http.Error(w, "session and name required", http.StatusBadRequest)
+		httpError(w, "session and name required", http.StatusBadRequest)
 		return
 	}
 
@@ -215,19 +600,19 @@ func handleAvatarUpload(w http.ResponseWriter, r *http.Request) {
 
 	file, _, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		httpError(w, "Error retrieving file", http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
 	data, err := io.ReadAll(file)
 	if err != nil {
-		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		httpError(w, "Error reading file", http.StatusInternalServerError)
 		return
 	}
 
-	if err := sessionStore.SaveAvatar(r.Context(), sessionName, avatarName, data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := sessionStore(r).SaveAvatar(r.Context(), sessionName, avatarName, data); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -237,13 +622,13 @@ func handleAvatarUpload(w http.ResponseWriter, r *http.Request) {
 func handleAvatarList(w http.ResponseWriter, r *http.Request) {
 	sessionName := r.URL.Query().Get("session")
 	if sessionName == "" {
-		http.Error(w, "session required", http.StatusBadRequest)
+		httpError(w, "session required", http.StatusBadRequest)
 		return
 	}
 
-	avatars, err := sessionStore.ListAvatars(r.Context(), sessionName)
+	avatars, err := sessionStore(r).ListAvatars(r.Context(), sessionName)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -255,12 +640,12 @@ func handleAvatarDelete(w http.ResponseWriter, r *http.Request) {
 	sessionName := r.URL.Query().Get("session")
 	avatarName := r.URL.Query().Get("name")
 	if sessionName == "" || avatarName == "" {
-		http.Error(w, "session and name required", http.StatusBadRequest)
+		httpError(w, "session and name required", http.StatusBadRequest)
 		return
 	}
 
-	if err := sessionStore.DeleteAvatar(r.Context(), sessionName, avatarName); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := sessionStore(r).DeleteAvatar(r.Context(), sessionName, avatarName); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -269,12 +654,12 @@ func handleAvatarDelete(w http.ResponseWriter, r *http.Request) {
 
 func handleAvatarRename(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	sessionName := r.URL.Query().Get("session")
 	if sessionName == "" {
-		http.Error(w, "session required", http.StatusBadRequest)
+		httpError(w, "session required", http.StatusBadRequest)
 		return
 	}
 
@@ -283,17 +668,17 @@ func handleAvatarRename(w http.ResponseWriter, r *http.Request) {
 		New string `json:"new"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		httpError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if payload.Old == "" || payload.New == "" {
-		http.Error(w, "old and new names required", http.StatusBadRequest)
+		httpError(w, "old and new names required", http.StatusBadRequest)
 		return
 	}
 
-	if err := sessionStore.RenameAvatar(r.Context(), sessionName, payload.Old, payload.New); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := sessionStore(r).RenameAvatar(r.Context(), sessionName, payload.Old, payload.New); err != nil {
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -303,17 +688,13 @@ func handleAvatarGet(w http.ResponseWriter, r *http.Request) {
 	sessionName := r.URL.Query().Get("session")
 	avatarName := r.URL.Query().Get("name")
 	if sessionName == "" || avatarName == "" {
-		http.Error(w, "session and name required", http.StatusBadRequest)
+		httpError(w, "session and name required", http.StatusBadRequest)
 		return
 	}
 
-	data, err := sessionStore.GetAvatar(r.Context(), sessionName, avatarName)
+	data, err := sessionStore(r).GetAvatar(r.Context(), sessionName, avatarName)
 	if err != nil {
-		if os.IsNotExist(err) {
-			http.Error(w, "Avatar not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeError(w, err)
 		return
 	}
 
@@ -334,22 +715,57 @@ func RegisterSessionRoutes(mux *http.ServeMux) {
 		fmt.Printf("Failed to init session store: %v\n", err)
 	}
 
-	mux.HandleFunc("/api/sessions/list", handleListSessions)
-	mux.HandleFunc("/api/sessions/create", handleCreateSession) // POST
-	mux.HandleFunc("/api/sessions/update", handleUpdateSession) // POST/PUT
-	mux.HandleFunc("/api/sessions/rename", handleRenameSession) // POST
-	mux.HandleFunc("/api/sessions/delete", handleDeleteSession) // DELETE or POST
-	mux.HandleFunc("/api/sessions/get", handleGetSession)
+	mux.HandleFunc("/api/sessions/list", inWorkspace(handleListSessions))
+	mux.HandleFunc("/api/sessions/create", inWorkspace(handleCreateSession))       // POST
+	mux.HandleFunc("/api/sessions/update", inWorkspace(handleUpdateSession))       // POST/PUT
+	mux.HandleFunc("/api/sessions/rename", inWorkspace(handleRenameSession))       // POST
+	mux.HandleFunc("/api/sessions/delete", inWorkspace(handleDeleteSession))       // DELETE or POST
+	mux.HandleFunc("/api/sessions/duplicate", inWorkspace(handleDuplicateSession)) // POST
+	mux.HandleFunc("/api/sessions/get", inWorkspace(handleGetSession))
 
 	// Page CRUD
-	mux.HandleFunc("/api/sessions/page/create", handleCreatePage)
-	mux.HandleFunc("/api/sessions/page/update", handleUpdatePage)
-	mux.HandleFunc("/api/sessions/page/delete", handleDeletePage)
+	mux.HandleFunc("/api/sessions/page/create", inWorkspace(handleCreatePage))
+	mux.HandleFunc("/api/sessions/page/update", inWorkspace(handleUpdatePage))
+	mux.HandleFunc("/api/sessions/page/delete", inWorkspace(handleDeletePage))
+	mux.HandleFunc("/api/sessions/page/move", inWorkspace(handleMovePage))           // POST
+	mux.HandleFunc("/api/sessions/page/duplicate", inWorkspace(handleDuplicatePage)) // POST
+
+	// History
+	mux.HandleFunc("/api/sessions/history/list", inWorkspace(handleListRevisions))
+	mux.HandleFunc("/api/sessions/history/get", inWorkspace(handleGetRevision))
+	mux.HandleFunc("/api/sessions/history/restore", inWorkspace(handleRestoreRevision)) // POST
+
+	// Version control, with --git
+	mux.HandleFunc("/api/sessions/git/log", inWorkspace(handleGitLog))
+	mux.HandleFunc("/api/sessions/git/diff", inWorkspace(handleGitDiff))
+	mux.HandleFunc("/api/sessions/git/checkout", inWorkspace(handleGitCheckout)) // POST
+
+	// Export
+	mux.HandleFunc("/api/sessions/export", inWorkspace(handleExport))
+	mux.HandleFunc("/api/sessions/import", inWorkspace(handleImport)) // POST
+	mux.HandleFunc("/api/import/diff", inWorkspace(handleImportDiff)) // POST
+
+	// Code pages linked to source files
+	mux.HandleFunc("/api/sessions/source/refresh", inWorkspace(handleSourceRefresh)) // POST
+	mux.HandleFunc("/api/sessions/source/watch", inWorkspace(handleSourceWatch))
+	mux.HandleFunc("/api/source/extract", handleSourceExtract) // POST
+
+	// Diffs of code_diff pages
+	mux.HandleFunc("/api/diff", handleDiff) // POST
+
+	// Highlighting
+	mux.HandleFunc("/api/highlight", handleHighlight) // POST
+	mux.HandleFunc("/api/highlight/styles", handleHighlightStyles)
+
+	// Workspaces
+	mux.HandleFunc("/api/workspaces", handleListWorkspaces)
+	mux.HandleFunc("/api/workspaces/select", handleSelectWorkspace) // POST
+	mux.HandleFunc("/api/workspaces/create", handleCreateWorkspace) // POST
 
 	// Avatar CRUD
-	mux.HandleFunc("/api/sessions/avatar/upload", handleAvatarUpload)
-	mux.HandleFunc("/api/sessions/avatar/list", handleAvatarList)
-	mux.HandleFunc("/api/sessions/avatar/delete", handleAvatarDelete)
-	mux.HandleFunc("/api/sessions/avatar/rename", handleAvatarRename)
-	mux.HandleFunc("/api/sessions/avatar/get", handleAvatarGet)
+	mux.HandleFunc("/api/sessions/avatar/upload", inWorkspace(handleAvatarUpload))
+	mux.HandleFunc("/api/sessions/avatar/list", inWorkspace(handleAvatarList))
+	mux.HandleFunc("/api/sessions/avatar/delete", inWorkspace(handleAvatarDelete))
+	mux.HandleFunc("/api/sessions/avatar/rename", inWorkspace(handleAvatarRename))
+	mux.HandleFunc("/api/sessions/avatar/get", inWorkspace(handleAvatarGet))
 }
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/store/memory"
+)
+
+// serve runs h on a request using the store s, as inWorkspace would.
+func serve(h http.HandlerFunc, s store.SessionStore, method string, target string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), storeKey{}, s))
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestSessionNames(t *testing.T) {
+	s := memory.New()
+	if err := s.Create(context.Background(), &model.Session{Name: "deck"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    any
+		status  int
+	}{
+		{"create", handleCreateSession, map[string]any{"name": "talk"}, http.StatusCreated},
+		{"create with a path", handleCreateSession, map[string]any{"name": "../talk"}, http.StatusBadRequest},
+		{"create hidden", handleCreateSession, map[string]any{"name": ".talk"}, http.StatusBadRequest},
+		{"rename with a path", handleRenameSession, map[string]any{"old": "deck", "new": "a/b"}, http.StatusBadRequest},
+		{"rename to a device", handleRenameSession, map[string]any{"old": "deck", "new": "NUL"}, http.StatusBadRequest},
+		{"duplicate with a path", handleDuplicateSession, map[string]any{"src": "deck", "dst": `..\deck`}, http.StatusBadRequest},
+		{"duplicate", handleDuplicateSession, map[string]any{"src": "deck", "dst": "copy"}, http.StatusCreated},
+		{"rename", handleRenameSession, map[string]any{"old": "copy", "new": "final"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := serve(tt.handler, s, http.MethodPost, "/", string(body))
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+	names, err := s.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range names {
+		got = append(got, n.Name)
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "deck,final,talk" {
+		t.Errorf("sessions %q", got)
+	}
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xhd2015/presentationer/pkg/model"
+	"github.com/xhd2015/presentationer/pkg/source"
+	"github.com/xhd2015/presentationer/pkg/source/goextract"
+)
+
+// sessionResponse is a session as the editor loads it: Sources tells by
+// page ID how the code pages linked to source files drifted from their
+// snapshots, with the code as it is now. The pages keep the snapshots, so
+// saving them back doesn't take a new one.
+type sessionResponse struct {
+	*model.Session
+	Sources map[string]source.PageStatus `json:"sources,omitempty"`
+}
+
+func resolveSources(session *model.Session) *sessionResponse {
+	resp := &sessionResponse{Session: session}
+	for _, st := range source.ResolvePages(session.Pages) {
+		if resp.Sources == nil {
+			resp.Sources = make(map[string]source.PageStatus)
+		}
+		resp.Sources[st.ID] = st
+	}
+	return resp
+}
+
+// handleSourceRefresh takes new snapshots of the code pages linked to
+// source files, all of them or the one of ?page=ID, and replies with
+// their status.
+func handleSourceRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	pageID := r.URL.Query().Get("page")
+
+	ctx, rev, err := revisionContext(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	session, err := sessionStore(r).Get(ctx, sessionName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	pages := session.Pages
+	if pageID != "" {
+		pages = nil
+		for i := range session.Pages {
+			if session.Pages[i].ID == pageID {
+				pages = session.Pages[i : i+1]
+			}
+		}
+		if pages == nil {
+			httpError(w, "page not found", http.StatusNotFound)
+			return
+		}
+	}
+	if rev.IfMatch == 0 {
+		// the pages are rewritten from the copy read above, so a change
+		// saved since must not be overwritten
+		rev.IfMatch = session.Revision
+	}
+	statuses, err := source.RefreshPages(pages)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rev.Current = session.Revision
+	for _, st := range statuses {
+		if st.Refreshed {
+			if err := sessionStore(r).Update(ctx, session); err != nil {
+				writeError(w, err)
+				return
+			}
+			break
+		}
+	}
+	if statuses == nil {
+		statuses = []source.PageStatus{}
+	}
+	setETag(w, rev.Current)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// handleSourceWatch streams the code pages of a session linked to source
+// files as server-sent events: each time some of the files change, a
+// "source" event carries the status and current code of every linked
+// page. The stream ends when the session is gone.
+func handleSourceWatch(w http.ResponseWriter, r *http.Request) {
+	sessionName := r.URL.Query().Get("session")
+	if sessionName == "" {
+		httpError(w, "session name required", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpError(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ctx := r.Context()
+	session, err := sessionStore(r).Get(ctx, sessionName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	watcher, err := source.NewWatcher()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Set(source.Files(session.Pages)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// the stream outlives the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case _, ok := <-watcher.Changes():
+			if !ok {
+				return
+			}
+			// the pages may have changed since, and with them the files
+			session, err := sessionStore(r).Get(ctx, sessionName)
+			if err != nil {
+				return
+			}
+			if err := watcher.Set(source.Files(session.Pages)); err != nil {
+				return
+			}
+			data, err := json.Marshal(source.ResolvePages(session.Pages))
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: source\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
+
+type extractRequest struct {
+	// Dir is the module directory, the working directory when empty.
+	Dir string `json:"dir"`
+	// Name is a qualified name like server.Serve or pkg/model.Page.Validate.
+	Name  string `json:"name"`
+	Elide bool   `json:"elide"`
+	// Focus is a focus config with the lines of the file, returned with
+	// the lines of the snippet.
+	Focus string `json:"focus"`
+}
+
+type extractResponse struct {
+	*goextract.Snippet
+	Focus string `json:"focus,omitempty"`
+}
+
+// handleSourceExtract extracts the declaration of a Go symbol from a
+// module directory.
+func handleSourceExtract(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req extractRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		httpError(w, "name required", http.StatusBadRequest)
+		return
+	}
+	dir, err := source.Root(req.Dir)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snippet, err := goextract.Extract(dir, req.Name, goextract.Options{Elide: req.Elide})
+	if err != nil {
+		if errors.Is(err, goextract.ErrNotFound) {
+			httpError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := &extractResponse{Snippet: snippet}
+	if req.Focus != "" {
+		resp.Focus = snippet.Focus(req.Focus)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/xhd2015/presentationer/pkg/config"
+	"github.com/xhd2015/presentationer/pkg/store"
+	"github.com/xhd2015/presentationer/pkg/workspace"
+)
+
+// SetWorkspaces replaces the workspaces the session routes use. It must be
+// called before the routes are registered.
+func SetWorkspaces(m *workspace.Manager) {
+	workspaces = m
+}
+
+type workspacesResponse struct {
+	Current    string                `json:"current"`
+	Workspaces []workspace.Workspace `json:"workspaces"`
+}
+
+func writeWorkspaces(w http.ResponseWriter, status int) {
+	list, current := workspaces.List()
+	if list == nil {
+		list = []workspace.Workspace{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(workspacesResponse{Current: current, Workspaces: list})
+}
+
+func handleListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	writeWorkspaces(w, http.StatusOK)
+}
+
+// handleSelectWorkspace makes a workspace the one requests without
+// ?workspace= use, and so the one new tabs and the next start open.
+func handleSelectWorkspace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := workspaces.Select(req.Name); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeWorkspaces(w, http.StatusOK)
+}
+
+// handleCreateWorkspace adds a workspace in workspaces/<name> under the
+// presentationer home directory. Workspaces elsewhere, or with another
+// store, are only set up in config.json, so a request can't point the
+// server at an arbitrary directory. The body must be JSON, which a form of
+// another site can't post without the browser asking first.
+func handleCreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		httpError(w, "Content-Type must be application/json", http.StatusBadRequest)
+		return
+	}
+	var ws workspace.Workspace
+	if err := json.NewDecoder(r.Body).Decode(&ws); err != nil {
+		httpError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := workspace.CheckName(ws.Name); err != nil {
+		writeError(w, err)
+		return
+	}
+	if ws.Root != "" || ws.Store != "" {
+		writeError(w, store.Errorf(store.ErrInvalid, "the root and store of a workspace can only be set in config.json"))
+		return
+	}
+	home, err := config.Home()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ws.Root = filepath.Join(home, "workspaces", ws.Name)
+	if err := workspaces.Create(ws); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeWorkspaces(w, http.StatusCreated)
+}